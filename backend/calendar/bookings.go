@@ -0,0 +1,65 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+
+	"pluralink/backend/models"
+)
+
+// BookingUID is the stable identifier used for a booking in every calendar
+// we publish, so rescheduling updates the existing event instead of adding
+// a new one.
+func BookingUID(bookingID uint) string {
+	return fmt.Sprintf("booking-%d@pluralink", bookingID)
+}
+
+// BookingEvent converts a booking into an event. The booking must have its
+// Provider, Client.User and Service relationships loaded. forProvider picks
+// the wording of the summary for whoever is subscribing.
+func BookingEvent(b *models.Booking, forProvider bool) Event {
+	loc := b.Provider.Location()
+
+	summary := b.Service.Name
+	if forProvider {
+		name := strings.TrimSpace(b.Client.User.FirstName + " " + b.Client.User.LastName)
+		if name != "" {
+			summary += " - " + name
+		}
+	} else if b.Provider.BusinessName != "" {
+		summary += " at " + b.Provider.BusinessName
+	}
+
+	return Event{
+		UID:          BookingUID(b.ID),
+		Sequence:     b.Sequence,
+		Summary:      summary,
+		Description:  b.Notes,
+		Location:     providerAddress(&b.Provider),
+		Start:        b.StartsAt(loc),
+		End:          b.EndsAt(loc),
+		Status:       bookingEventStatus(b.Status),
+		LastModified: b.UpdatedAt,
+	}
+}
+
+func bookingEventStatus(status models.BookingStatus) EventStatus {
+	switch status {
+	case models.StatusCancelled, models.StatusExpired:
+		return EventCancelled
+	case models.StatusPending:
+		return EventTentative
+	default:
+		return EventConfirmed
+	}
+}
+
+func providerAddress(p *models.ServiceProvider) string {
+	var parts []string
+	for _, s := range []string{p.Address, p.City, p.State, p.ZipCode, p.Country} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
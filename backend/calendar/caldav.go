@@ -0,0 +1,275 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"pluralink/backend/netguard"
+)
+
+var (
+	ErrPreconditionFailed = errors.New("caldav: precondition failed")
+	ErrInvalidSyncToken   = errors.New("caldav: sync token rejected")
+	ErrSyncNotSupported   = errors.New("caldav: sync-collection not supported")
+)
+
+// defaultClient is used by clients without their own. Servers are chosen
+// by providers, so it only reaches public addresses.
+var defaultClient = netguard.NewClient(30 * time.Second)
+
+// CalDAVClient talks to a single calendar collection on a CalDAV server
+// using HTTP basic authentication.
+type CalDAVClient struct {
+	HTTP          *http.Client
+	CollectionURL string
+	Username      string
+	Password      string
+}
+
+// Resource is a calendar object in the collection. Deleted is set for
+// entries reported as removed by sync-collection.
+type Resource struct {
+	Href    string
+	ETag    string
+	Deleted bool
+}
+
+// Check verifies the collection exists and the credentials are accepted.
+func (c *CalDAVClient) Check(ctx context.Context) error {
+	body := `<?xml version="1.0" encoding="utf-8"?>` +
+		`<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/></d:prop></d:propfind>`
+	resp, err := c.do(ctx, "PROPFIND", c.CollectionURL, strings.NewReader(body), map[string]string{
+		"Depth":        "0",
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return fmt.Errorf("caldav: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Put stores a calendar object at href. With an etag the write only
+// succeeds if the object is unchanged; without one it only succeeds if the
+// object does not exist yet. It returns the new ETag when the server
+// reports one.
+func (c *CalDAVClient) Put(ctx context.Context, href string, data []byte, etag string) (string, error) {
+	headers := map[string]string{"Content-Type": "text/calendar; charset=utf-8"}
+	if etag != "" {
+		headers["If-Match"] = etag
+	} else {
+		headers["If-None-Match"] = "*"
+	}
+
+	resp, err := c.do(ctx, http.MethodPut, c.resolve(href), bytes.NewReader(data), headers)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusNoContent, http.StatusOK:
+		return resp.Header.Get("ETag"), nil
+	case http.StatusPreconditionFailed:
+		return "", ErrPreconditionFailed
+	}
+	return "", fmt.Errorf("caldav: PUT %s: unexpected status %s", href, resp.Status)
+}
+
+// Delete removes the object at href. A missing object is not an error.
+func (c *CalDAVClient) Delete(ctx context.Context, href string) error {
+	resp, err := c.do(ctx, http.MethodDelete, c.resolve(href), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	}
+	return fmt.Errorf("caldav: DELETE %s: unexpected status %s", href, resp.Status)
+}
+
+// Get fetches the object at href.
+func (c *CalDAVClient) Get(ctx context.Context, href string) ([]byte, string, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.resolve(href), nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("caldav: GET %s: unexpected status %s", href, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes))
+	return data, resp.Header.Get("ETag"), err
+}
+
+// SyncCollection runs an RFC 6578 sync-collection report. An empty token
+// lists every object; otherwise only changes since the token are returned.
+func (c *CalDAVClient) SyncCollection(ctx context.Context, token string) ([]Resource, string, error) {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	sb.WriteString(`<d:sync-collection xmlns:d="DAV:"><d:sync-token>`)
+	xml.EscapeText(&sb, []byte(token))
+	sb.WriteString(`</d:sync-token><d:sync-level>1</d:sync-level><d:prop><d:getetag/></d:prop></d:sync-collection>`)
+
+	resp, err := c.do(ctx, "REPORT", c.CollectionURL, strings.NewReader(sb.String()), map[string]string{
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusMultiStatus:
+	case token != "" && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusConflict):
+		return nil, "", ErrInvalidSyncToken
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusBadRequest ||
+		resp.StatusCode == http.StatusNotImplemented || resp.StatusCode == http.StatusMethodNotAllowed:
+		return nil, "", ErrSyncNotSupported
+	default:
+		return nil, "", fmt.Errorf("caldav: sync-collection: unexpected status %s", resp.Status)
+	}
+
+	ms, err := decodeMultistatus(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return ms.resources(), ms.SyncToken, nil
+}
+
+// ListETags lists every object in the collection with its ETag. It is the
+// fallback for servers without sync-collection support.
+func (c *CalDAVClient) ListETags(ctx context.Context) ([]Resource, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>` +
+		`<d:propfind xmlns:d="DAV:"><d:prop><d:getetag/><d:resourcetype/></d:prop></d:propfind>`
+	resp, err := c.do(ctx, "PROPFIND", c.CollectionURL, strings.NewReader(body), map[string]string{
+		"Depth":        "1",
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("caldav: PROPFIND: unexpected status %s", resp.Status)
+	}
+
+	ms, err := decodeMultistatus(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// The collection itself is listed too; keep only calendar objects
+	collection := c.path(c.CollectionURL)
+	var resources []Resource
+	for _, r := range ms.resources() {
+		if !r.Deleted && c.path(r.Href) != collection {
+			resources = append(resources, r)
+		}
+	}
+	return resources, nil
+}
+
+// ObjectHref returns the href for a new object named name in the
+// collection.
+func (c *CalDAVClient) ObjectHref(name string) string {
+	return strings.TrimRight(c.CollectionURL, "/") + "/" + url.PathEscape(name)
+}
+
+func (c *CalDAVClient) do(ctx context.Context, method, target string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Username != "" || c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = defaultClient
+	}
+	return client.Do(req)
+}
+
+// resolve turns a server-relative href into an absolute URL.
+func (c *CalDAVClient) resolve(href string) string {
+	base, err := url.Parse(c.CollectionURL)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func (c *CalDAVClient) path(href string) string {
+	u, err := url.Parse(c.resolve(href))
+	if err != nil {
+		return href
+	}
+	return strings.TrimRight(u.Path, "/")
+}
+
+type multistatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+	SyncToken string        `xml:"DAV: sync-token"`
+}
+
+type davResponse struct {
+	Href     string        `xml:"DAV: href"`
+	Status   string        `xml:"DAV: status"`
+	Propstat []davPropstat `xml:"DAV: propstat"`
+}
+
+type davPropstat struct {
+	Status string `xml:"DAV: status"`
+	ETag   string `xml:"DAV: prop>getetag"`
+}
+
+func decodeMultistatus(r io.Reader) (*multistatus, error) {
+	var ms multistatus
+	if err := xml.NewDecoder(io.LimitReader(r, maxFeedBytes)).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("caldav: invalid multistatus: %w", err)
+	}
+	return &ms, nil
+}
+
+func (ms *multistatus) resources() []Resource {
+	var resources []Resource
+	for _, r := range ms.Responses {
+		res := Resource{Href: strings.TrimSpace(r.Href)}
+		if strings.Contains(r.Status, " 404 ") {
+			res.Deleted = true
+		}
+		for _, ps := range r.Propstat {
+			if strings.Contains(ps.Status, " 200 ") && ps.ETag != "" {
+				res.ETag = ps.ETag
+			}
+		}
+		resources = append(resources, res)
+	}
+	return resources
+}
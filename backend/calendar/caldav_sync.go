@@ -0,0 +1,336 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/secrets"
+
+	"gorm.io/gorm"
+)
+
+// pushMu serializes pushes within this process so quick successive changes
+// to a booking are written in order.
+var pushMu sync.Mutex
+
+// SealCalDAVPasswords encrypts passwords of accounts connected before
+// they were kept sealed.
+func SealCalDAVPasswords(db *gorm.DB) error {
+	var accounts []models.CalDAVAccount
+	if err := db.Where("password NOT LIKE ?", "v1:%").Find(&accounts).Error; err != nil {
+		return err
+	}
+	for _, acc := range accounts {
+		if secrets.IsSealed(acc.Password) {
+			continue
+		}
+		sealed, err := secrets.Default.Seal(acc.Password)
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&acc).Update("password", sealed).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// caldavClient returns a client for an account. The account's password
+// is only ever decrypted here, for the requests made with it.
+func (s *Syncer) caldavClient(acc *models.CalDAVAccount) (*CalDAVClient, error) {
+	password, err := secrets.Default.Open(acc.Password)
+	if err != nil {
+		return nil, fmt.Errorf("caldav: cannot decrypt password: %w", err)
+	}
+	return &CalDAVClient{
+		HTTP:          s.Client,
+		CollectionURL: acc.CollectionURL,
+		Username:      acc.Username,
+		Password:      password,
+	}, nil
+}
+
+// PushBooking writes a booking into its provider's CalDAV calendar, or
+// deletes it from there once the booking is cancelled or expired. Providers
+// without a CalDAV account are ignored.
+func (s *Syncer) PushBooking(ctx context.Context, bookingID uint) error {
+	pushMu.Lock()
+	defer pushMu.Unlock()
+
+	var booking models.Booking
+	if err := s.DB.Preload("Client").Preload("Client.User").
+		Preload("Provider").
+		Preload("Service").
+		First(&booking, bookingID).Error; err != nil {
+		return err
+	}
+
+	var acc models.CalDAVAccount
+	if err := s.DB.Where("provider_id = ? AND is_active = ?", booking.ProviderID, true).First(&acc).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	client, err := s.caldavClient(&acc)
+	if err != nil {
+		return err
+	}
+
+	var event models.CalDAVBookingEvent
+	found := s.DB.Where("account_id = ? AND booking_id = ?", acc.ID, booking.ID).First(&event).Error == nil
+
+	if booking.Status == models.StatusCancelled || booking.Status == models.StatusExpired {
+		if !found {
+			return nil
+		}
+		if err := client.Delete(ctx, event.Href); err != nil {
+			return err
+		}
+		return s.DB.Delete(&event).Error
+	}
+
+	if !found {
+		event = models.CalDAVBookingEvent{
+			AccountID: acc.ID,
+			BookingID: booking.ID,
+			Href:      client.ObjectHref(fmt.Sprintf("pluralink-booking-%d.ics", booking.ID)),
+		}
+	}
+
+	cal := Calendar{Events: []Event{BookingEvent(&booking, true)}}
+	data := []byte(cal.String())
+
+	// Bookings are owned by us: if the object was edited or removed on the
+	// server, overwrite or recreate it.
+	var etag string
+	for _, cond := range []string{event.ETag, "*", ""} {
+		etag, err = client.Put(ctx, event.Href, data, cond)
+		if !errors.Is(err, ErrPreconditionFailed) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	event.ETag = etag
+	return s.DB.Save(&event).Error
+}
+
+// PushUpcoming pushes every upcoming, still active booking of a provider.
+// It is used when an account is first connected.
+func (s *Syncer) PushUpcoming(ctx context.Context, providerID uint) error {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	var ids []uint
+	if err := s.DB.Model(&models.Booking{}).
+		Where("provider_id = ? AND date >= ? AND status NOT IN ?", providerID, today, []models.BookingStatus{models.StatusCancelled, models.StatusExpired}).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := s.PushBooking(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PullCalDAV imports the provider's own events from the server as busy
+// blocks and records the outcome on the account.
+func (s *Syncer) PullCalDAV(ctx context.Context, acc *models.CalDAVAccount) error {
+	err := s.pullCalDAV(ctx, acc)
+
+	now := time.Now()
+	acc.LastSyncedAt = &now
+	acc.LastError = ""
+	if err != nil {
+		acc.LastError = err.Error()
+	}
+	if saveErr := s.DB.Model(acc).Select("SyncToken", "LastSyncedAt", "LastError").Updates(acc).Error; saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+func (s *Syncer) pullCalDAV(ctx context.Context, acc *models.CalDAVAccount) error {
+	var provider models.ServiceProvider
+	if err := s.DB.First(&provider, acc.ProviderID).Error; err != nil {
+		return err
+	}
+	client, err := s.caldavClient(acc)
+	if err != nil {
+		return err
+	}
+
+	resources, token, err := client.SyncCollection(ctx, acc.SyncToken)
+	if errors.Is(err, ErrInvalidSyncToken) {
+		// The server forgot our token; start over from a full listing
+		if err := ForgetCalDAVResources(s.DB, acc.ID); err != nil {
+			return err
+		}
+		acc.SyncToken = ""
+		resources, token, err = client.SyncCollection(ctx, "")
+	}
+	if errors.Is(err, ErrSyncNotSupported) {
+		return s.pullCalDAVByETag(ctx, acc, client, &provider)
+	}
+	if err != nil {
+		return err
+	}
+
+	own, err := s.ownHrefs(client, acc.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range resources {
+		if own[client.path(r.Href)] {
+			continue
+		}
+		if r.Deleted {
+			err = s.forgetResource(acc, r.Href)
+		} else {
+			err = s.importResource(ctx, acc, client, &provider, r)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	acc.SyncToken = token
+	return nil
+}
+
+// pullCalDAVByETag compares a full ETag listing with what was imported
+// before, fetching only objects that changed.
+func (s *Syncer) pullCalDAVByETag(ctx context.Context, acc *models.CalDAVAccount, client *CalDAVClient, provider *models.ServiceProvider) error {
+	resources, err := client.ListETags(ctx)
+	if err != nil {
+		return err
+	}
+
+	own, err := s.ownHrefs(client, acc.ID)
+	if err != nil {
+		return err
+	}
+
+	var known []models.CalDAVResource
+	if err := s.DB.Where("account_id = ?", acc.ID).Find(&known).Error; err != nil {
+		return err
+	}
+	etags := map[string]string{}
+	for _, k := range known {
+		etags[client.path(k.Href)] = k.ETag
+	}
+
+	seen := map[string]bool{}
+	for _, r := range resources {
+		p := client.path(r.Href)
+		if own[p] {
+			continue
+		}
+		seen[p] = true
+		if etag, ok := etags[p]; ok && etag != "" && etag == r.ETag {
+			continue
+		}
+		if err := s.importResource(ctx, acc, client, provider, r); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range known {
+		if !seen[client.path(k.Href)] {
+			if err := s.forgetResource(acc, k.Href); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Syncer) importResource(ctx context.Context, acc *models.CalDAVAccount, client *CalDAVClient, provider *models.ServiceProvider, r Resource) error {
+	data, etag, err := client.Get(ctx, r.Href)
+	if err != nil {
+		return err
+	}
+	if r.ETag != "" {
+		etag = r.ETag
+	}
+
+	events, err := Parse(bytes.NewReader(data), provider.Location())
+	if err != nil {
+		return err
+	}
+
+	// Skip bookings we pushed ourselves, even if we lost track of the href
+	var personal []ParsedEvent
+	for _, e := range events {
+		if !isBookingUID(e.UID) {
+			personal = append(personal, e)
+		}
+	}
+
+	now := time.Now()
+	occurrences := Expand(personal, now.Add(-24*time.Hour), now.Add(syncHorizon))
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ReplaceBusyBlocks(tx, acc.ProviderID, models.BusySourceCalDAV, acc.ID, r.Href, occurrences); err != nil {
+			return err
+		}
+
+		var res models.CalDAVResource
+		if err := tx.Where("account_id = ? AND href = ?", acc.ID, r.Href).First(&res).Error; err != nil {
+			res = models.CalDAVResource{AccountID: acc.ID, Href: r.Href}
+		}
+		res.ETag = etag
+		return tx.Save(&res).Error
+	})
+}
+
+func (s *Syncer) forgetResource(acc *models.CalDAVAccount, href string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source = ? AND source_id = ? AND ref = ?", models.BusySourceCalDAV, acc.ID, href).
+			Delete(&models.BusyBlock{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("account_id = ? AND href = ?", acc.ID, href).Delete(&models.CalDAVResource{}).Error
+	})
+}
+
+// ownHrefs returns the paths of objects we wrote for bookings.
+func (s *Syncer) ownHrefs(client *CalDAVClient, accountID uint) (map[string]bool, error) {
+	var hrefs []string
+	if err := s.DB.Model(&models.CalDAVBookingEvent{}).Where("account_id = ?", accountID).Pluck("href", &hrefs).Error; err != nil {
+		return nil, err
+	}
+
+	own := make(map[string]bool, len(hrefs))
+	for _, h := range hrefs {
+		own[client.path(h)] = true
+	}
+	return own, nil
+}
+
+// ForgetCalDAVResources drops everything imported from an account.
+func ForgetCalDAVResources(db *gorm.DB, accountID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source = ? AND source_id = ?", models.BusySourceCalDAV, accountID).
+			Delete(&models.BusyBlock{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("account_id = ?", accountID).Delete(&models.CalDAVResource{}).Error
+	})
+}
+
+func isBookingUID(uid string) bool {
+	return strings.HasPrefix(uid, "booking-") && strings.HasSuffix(uid, "@pluralink")
+}
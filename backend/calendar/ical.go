@@ -0,0 +1,151 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	icsTimeFormat = "20060102T150405Z"
+	maxLineOctets = 75
+)
+
+// EventStatus mirrors the STATUS property of a VEVENT.
+type EventStatus string
+
+const (
+	EventTentative EventStatus = "TENTATIVE"
+	EventConfirmed EventStatus = "CONFIRMED"
+	EventCancelled EventStatus = "CANCELLED"
+)
+
+// Event is a single VEVENT. Start and End are absolute instants and are
+// written in UTC so every client resolves them identically.
+type Event struct {
+	UID          string
+	Sequence     int
+	Summary      string
+	Description  string
+	Location     string
+	Start        time.Time
+	End          time.Time
+	Status       EventStatus
+	LastModified time.Time
+}
+
+// Calendar is a VCALENDAR containing events.
+type Calendar struct {
+	Method   string // e.g. "PUBLISH" for feeds; empty for CalDAV objects
+	Name     string
+	TimeZone string // Advisory X-WR-TIMEZONE for clients that display it
+	Events   []Event
+}
+
+// WriteTo serializes the calendar as RFC 5545 text with CRLF line endings
+// and folded long lines.
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	lw := &lineWriter{w: w}
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:-//Pluralink//Bookings//EN")
+	lw.line("CALSCALE:GREGORIAN")
+	if c.Method != "" {
+		lw.line("METHOD:" + c.Method)
+	}
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + EscapeText(c.Name))
+	}
+	if c.TimeZone != "" {
+		lw.line("X-WR-TIMEZONE:" + c.TimeZone)
+	}
+	for _, e := range c.Events {
+		e.write(lw)
+	}
+	lw.line("END:VCALENDAR")
+
+	return lw.n, lw.err
+}
+
+// String returns the serialized calendar.
+func (c *Calendar) String() string {
+	var sb strings.Builder
+	c.WriteTo(&sb)
+	return sb.String()
+}
+
+func (e *Event) write(lw *lineWriter) {
+	stamp := e.LastModified
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+
+	lw.line("BEGIN:VEVENT")
+	lw.line("UID:" + e.UID)
+	lw.line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+	lw.line("DTSTAMP:" + FormatTime(stamp))
+	lw.line("LAST-MODIFIED:" + FormatTime(stamp))
+	lw.line("DTSTART:" + FormatTime(e.Start))
+	lw.line("DTEND:" + FormatTime(e.End))
+	lw.line("SUMMARY:" + EscapeText(e.Summary))
+	if e.Description != "" {
+		lw.line("DESCRIPTION:" + EscapeText(e.Description))
+	}
+	if e.Location != "" {
+		lw.line("LOCATION:" + EscapeText(e.Location))
+	}
+	if e.Status != "" {
+		lw.line("STATUS:" + string(e.Status))
+	}
+	lw.line("END:VEVENT")
+}
+
+// FormatTime renders t as an RFC 5545 UTC date-time.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(icsTimeFormat)
+}
+
+// EscapeText escapes a TEXT property value.
+func EscapeText(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return r.Replace(s)
+}
+
+type lineWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+// line writes a content line, folding it at 75 octets without splitting a
+// multi-byte UTF-8 sequence.
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+
+	var sb strings.Builder
+	width := 0
+	for _, r := range s {
+		size := len(string(r))
+		if width+size > maxLineOctets {
+			sb.WriteString("\r\n ")
+			width = 1
+		}
+		sb.WriteRune(r)
+		width += size
+	}
+	sb.WriteString("\r\n")
+
+	n, err := io.WriteString(lw.w, sb.String())
+	lw.n += int64(n)
+	lw.err = err
+}
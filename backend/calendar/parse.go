@@ -0,0 +1,268 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+var ErrNoCalendar = errors.New("no VCALENDAR found")
+
+// ParsedEvent is a VEVENT read from an external calendar. Recurring events
+// keep their RRULE here; use Expand to turn them into concrete occurrences.
+type ParsedEvent struct {
+	UID          string
+	Summary      string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	Cancelled    bool
+	Transparent  bool
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID time.Time
+}
+
+// Parse reads the VEVENTs of an iCalendar stream. Floating times and dates
+// are interpreted in loc.
+func Parse(r io.Reader, loc *time.Location) ([]ParsedEvent, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events   []ParsedEvent
+		current  *ParsedEvent
+		duration time.Duration
+		hasEnd   bool
+		depth    int
+		found    bool
+	)
+
+	for _, raw := range lines {
+		p := parseProperty(raw)
+		switch {
+		case p.name == "BEGIN" && p.value == "VCALENDAR":
+			found = true
+		case p.name == "BEGIN" && p.value == "VEVENT":
+			current = &ParsedEvent{}
+			duration, hasEnd, depth = 0, false, 0
+		case p.name == "BEGIN" && current != nil:
+			// Nested components such as VALARM carry their own properties
+			depth++
+		case p.name == "END" && p.value == "VEVENT" && current != nil:
+			if !hasEnd {
+				switch {
+				case duration > 0:
+					current.End = current.Start.Add(duration)
+				case current.AllDay:
+					current.End = current.Start.AddDate(0, 0, 1)
+				default:
+					current.End = current.Start
+				}
+			}
+			if current.UID != "" && !current.Start.IsZero() {
+				events = append(events, *current)
+			}
+			current = nil
+		case p.name == "END" && current != nil:
+			depth--
+		case current == nil || depth > 0:
+			continue
+		case p.name == "UID":
+			current.UID = p.value
+		case p.name == "SUMMARY":
+			current.Summary = unescapeText(p.value)
+		case p.name == "DTSTART":
+			t, allDay, err := parseDateTime(p, loc)
+			if err != nil {
+				return nil, err
+			}
+			current.Start, current.AllDay = t, allDay
+		case p.name == "DTEND":
+			t, _, err := parseDateTime(p, loc)
+			if err != nil {
+				return nil, err
+			}
+			current.End, hasEnd = t, true
+		case p.name == "DURATION":
+			d, err := parseDuration(p.value)
+			if err != nil {
+				return nil, err
+			}
+			duration = d
+		case p.name == "STATUS":
+			current.Cancelled = strings.EqualFold(p.value, "CANCELLED")
+		case p.name == "TRANSP":
+			current.Transparent = strings.EqualFold(p.value, "TRANSPARENT")
+		case p.name == "RRULE":
+			current.RRule = p.value
+		case p.name == "EXDATE":
+			for _, v := range strings.Split(p.value, ",") {
+				t, _, err := parseDateTime(property{name: p.name, params: p.params, value: v}, loc)
+				if err != nil {
+					return nil, err
+				}
+				current.ExDates = append(current.ExDates, t)
+			}
+		case p.name == "RECURRENCE-ID":
+			t, _, err := parseDateTime(p, loc)
+			if err != nil {
+				return nil, err
+			}
+			current.RecurrenceID = t
+		}
+	}
+
+	if !found {
+		return nil, ErrNoCalendar
+	}
+	return events, nil
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseProperty splits "NAME;PARAM=VALUE:value", honouring quoted
+// parameter values that may contain ':' or ';'.
+func parseProperty(line string) property {
+	p := property{params: map[string]string{}}
+
+	inQuotes := false
+	sep := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			sep = i
+			break
+		}
+	}
+	if sep < 0 {
+		p.name = strings.ToUpper(line)
+		return p
+	}
+
+	head := line[:sep]
+	p.value = line[sep+1:]
+
+	parts := splitUnquoted(head, ';')
+	p.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		k, v, _ := strings.Cut(param, "=")
+		p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+	return p
+}
+
+func splitUnquoted(s string, sep rune) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i, r := range s {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == sep && !inQuotes {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func parseDateTime(p property, loc *time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.value)
+
+	if tzid, ok := p.params["TZID"]; ok {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+
+	if p.params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// parseDuration handles RFC 5545 durations such as "PT1H30M" or "P1D".
+func parseDuration(s string) (time.Duration, error) {
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg = true
+		s = s[1:]
+	}
+	s = strings.TrimPrefix(s, "+")
+	if !strings.HasPrefix(s, "P") {
+		return 0, errors.New("invalid duration: " + s)
+	}
+	s = s[1:]
+
+	var d time.Duration
+	n := 0
+	inTime := false
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			n = n*10 + int(r-'0')
+		case r == 'T':
+			inTime = true
+		case r == 'W':
+			d += time.Duration(n) * 7 * 24 * time.Hour
+			n = 0
+		case r == 'D':
+			d += time.Duration(n) * 24 * time.Hour
+			n = 0
+		case r == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+			n = 0
+		case r == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+			n = 0
+		case r == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+			n = 0
+		default:
+			return 0, errors.New("invalid duration: " + s)
+		}
+	}
+
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+func unescapeText(s string) string {
+	r := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return r.Replace(s)
+}
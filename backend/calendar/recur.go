@@ -0,0 +1,455 @@
+package calendar
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bounds on the expansion of a single recurring event so a malformed or
+// unbounded rule cannot stall a sync.
+const (
+	maxOccurrences = 5000
+	maxPeriods     = 100000
+)
+
+// Occurrence is one concrete instance of a (possibly recurring) event.
+type Occurrence struct {
+	UID     string
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
+
+// Expand returns the occurrences of events that overlap [from, to).
+// Cancelled and transparent (free) events are skipped, EXDATEs are honoured
+// and RECURRENCE-ID overrides replace the instance they point at.
+func Expand(events []ParsedEvent, from, to time.Time) []Occurrence {
+	overrides := map[string]map[int64]bool{}
+	for _, e := range events {
+		if e.RecurrenceID.IsZero() {
+			continue
+		}
+		if overrides[e.UID] == nil {
+			overrides[e.UID] = map[int64]bool{}
+		}
+		overrides[e.UID][e.RecurrenceID.Unix()] = true
+	}
+
+	var occurrences []Occurrence
+	for _, e := range events {
+		if e.Cancelled || e.Transparent {
+			continue
+		}
+
+		length := e.End.Sub(e.Start)
+		starts := []time.Time{e.Start}
+		if e.RRule != "" && e.RecurrenceID.IsZero() {
+			// A rule we cannot follow keeps at least the instance it starts
+			// with, rather than blocking the wrong days
+			if r := parseRule(e.RRule); r.unsupported != "" {
+				log.Printf("Event %s repeats with %s, which is not supported; keeping its first instance only", e.UID, r.unsupported)
+			} else {
+				starts = expandRule(e.Start, r, from.Add(-length), to)
+			}
+		}
+
+		excluded := map[int64]bool{}
+		for _, t := range e.ExDates {
+			excluded[t.Unix()] = true
+		}
+
+		for _, start := range starts {
+			if excluded[start.Unix()] {
+				continue
+			}
+			if e.RecurrenceID.IsZero() && overrides[e.UID][start.Unix()] {
+				continue
+			}
+			end := start.Add(length)
+			if !start.Before(to) || !end.After(from) {
+				continue
+			}
+			occurrences = append(occurrences, Occurrence{
+				UID:     e.UID,
+				Summary: e.Summary,
+				Start:   start,
+				End:     end,
+			})
+		}
+	}
+
+	sort.Slice(occurrences, func(i, j int) bool {
+		return occurrences[i].Start.Before(occurrences[j].Start)
+	})
+	return occurrences
+}
+
+type byDay struct {
+	ordinal int // 0 means every matching weekday in the period
+	weekday time.Weekday
+}
+
+type rule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []byDay
+	byMonthDay []int
+	byMonth    []time.Month
+	bySetPos   []int
+
+	// unsupported names the first part of the rule expandRule cannot
+	// follow, if any
+	unsupported string
+}
+
+// supportedFreqs are the FREQ values ruleCandidates can expand.
+var supportedFreqs = map[string]bool{
+	"DAILY":   true,
+	"WEEKLY":  true,
+	"MONTHLY": true,
+	"YEARLY":  true,
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+func parseRule(s string) rule {
+	r := rule{interval: 1}
+	unsupported := func(part string) {
+		if r.unsupported == "" {
+			r.unsupported = part
+		}
+	}
+	for _, part := range strings.Split(s, ";") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k = strings.ToUpper(strings.TrimSpace(k)); k {
+		case "":
+		case "FREQ":
+			r.freq = strings.ToUpper(v)
+			if !supportedFreqs[r.freq] {
+				unsupported("FREQ=" + r.freq)
+			}
+		case "INTERVAL":
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				r.interval = n
+			}
+		case "COUNT":
+			r.count, _ = strconv.Atoi(v)
+		case "UNTIL":
+			if t, _, err := parseDateTime(property{value: v}, time.UTC); err == nil {
+				r.until = t
+			}
+		case "BYDAY":
+			for _, d := range strings.Split(v, ",") {
+				d = strings.ToUpper(strings.TrimSpace(d))
+				if len(d) < 2 {
+					continue
+				}
+				wd, ok := weekdays[d[len(d)-2:]]
+				if !ok {
+					continue
+				}
+				ordinal, _ := strconv.Atoi(d[:len(d)-2])
+				r.byDay = append(r.byDay, byDay{ordinal: ordinal, weekday: wd})
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(v, ",") {
+				if n, err := strconv.Atoi(d); err == nil && n != 0 {
+					r.byMonthDay = append(r.byMonthDay, n)
+				}
+			}
+		case "BYMONTH":
+			for _, m := range strings.Split(v, ",") {
+				if n, err := strconv.Atoi(m); err == nil && n >= 1 && n <= 12 {
+					r.byMonth = append(r.byMonth, time.Month(n))
+				}
+			}
+		case "BYSETPOS":
+			for _, p := range strings.Split(v, ",") {
+				if n, err := strconv.Atoi(p); err == nil && n != 0 {
+					r.bySetPos = append(r.bySetPos, n)
+				}
+			}
+		case "WKST":
+			// Weeks are taken to start on Monday
+			if strings.ToUpper(v) != "MO" {
+				unsupported(part)
+			}
+		default:
+			// BYWEEKNO, BYYEARDAY, BYHOUR, BYMINUTE, BYSECOND and
+			// extensions
+			unsupported(k)
+		}
+	}
+
+	// Combinations RFC 5545 leaves undefined or we do not expand
+	ordinals := false
+	for _, d := range r.byDay {
+		ordinals = ordinals || d.ordinal != 0
+	}
+	switch r.freq {
+	case "DAILY", "WEEKLY":
+		if ordinals {
+			unsupported("BYDAY with a position in " + r.freq)
+		}
+		if r.freq == "WEEKLY" && len(r.byMonthDay) > 0 {
+			unsupported("BYMONTHDAY in WEEKLY")
+		}
+	case "MONTHLY":
+		if ordinals && len(r.byMonthDay) > 0 {
+			unsupported("BYDAY with a position and BYMONTHDAY")
+		}
+	case "YEARLY":
+		if len(r.byDay) > 0 && len(r.byMonth) == 0 {
+			unsupported("BYDAY in YEARLY without BYMONTH")
+		}
+		if ordinals && len(r.byMonthDay) > 0 {
+			unsupported("BYDAY with a position and BYMONTHDAY")
+		}
+	}
+	return r
+}
+
+// expandRule lists occurrence starts in (from, limit). COUNT is applied
+// from dtstart regardless of the window. Instances are generated on the wall
+// clock of dtstart's location so a 9am meeting stays at 9am across DST
+// changes.
+func expandRule(dtstart time.Time, r rule, from, limit time.Time) []time.Time {
+	var starts []time.Time
+	emitted := 0
+
+	for period := 0; period < maxPeriods && len(starts) < maxOccurrences; period++ {
+		candidates, periodStart := ruleCandidates(dtstart, r, period)
+		if periodStart.IsZero() || !periodStart.Before(limit) {
+			break
+		}
+		if !r.until.IsZero() && periodStart.After(r.until) {
+			break
+		}
+
+		for _, t := range candidates {
+			if t.Before(dtstart) {
+				continue
+			}
+			if !r.until.IsZero() && t.After(r.until) {
+				return starts
+			}
+			if r.count > 0 && emitted >= r.count {
+				return starts
+			}
+			emitted++
+			if t.After(from) && t.Before(limit) {
+				starts = append(starts, t)
+			}
+		}
+	}
+	return starts
+}
+
+// ruleCandidates returns the sorted instances of period n together with
+// the first instant of that period. A zero period start means the rule
+// frequency is not supported.
+func ruleCandidates(dtstart time.Time, r rule, n int) ([]time.Time, time.Time) {
+	loc := dtstart.Location()
+	h, m, s := dtstart.Clock()
+	at := func(y int, mo time.Month, d int) time.Time {
+		return time.Date(y, mo, d, h, m, s, 0, loc)
+	}
+
+	var days []time.Time // Midnight of each candidate day
+	var periodStart time.Time
+
+	switch r.freq {
+	case "DAILY":
+		day := dtstart.AddDate(0, 0, n*r.interval)
+		periodStart = at(day.Year(), day.Month(), day.Day())
+		days = append(days, time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc))
+
+	case "WEEKLY":
+		// Weeks start on Monday (the RFC 5545 default WKST)
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		monday := dtstart.AddDate(0, 0, -offset+n*r.interval*7)
+		periodStart = time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, loc)
+		weekdays := r.byDay
+		if len(weekdays) == 0 {
+			weekdays = []byDay{{weekday: dtstart.Weekday()}}
+		}
+		for _, d := range weekdays {
+			days = append(days, periodStart.AddDate(0, 0, (int(d.weekday)+6)%7))
+		}
+
+	case "MONTHLY":
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(n*r.interval), 1, 0, 0, 0, 0, loc)
+		periodStart = first
+		days = monthDays(first, r, dtstart.Day())
+
+	case "YEARLY":
+		year := dtstart.Year() + n*r.interval
+		periodStart = time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+		months := r.byMonth
+		switch {
+		case len(months) > 0:
+		case len(r.byMonthDay) > 0:
+			// BYMONTHDAY alone picks those days of every month
+			for mo := time.January; mo <= time.December; mo++ {
+				months = append(months, mo)
+			}
+		default:
+			months = []time.Month{dtstart.Month()}
+		}
+		for _, mo := range months {
+			days = append(days, monthDays(time.Date(year, mo, 1, 0, 0, 0, 0, loc), r, dtstart.Day())...)
+		}
+
+	default:
+		return nil, time.Time{}
+	}
+
+	// BYMONTH, BYMONTHDAY and BYDAY narrow down what the period gave,
+	// where they did not pick the days already
+	var candidates []time.Time
+	seen := map[time.Time]bool{}
+	for _, d := range days {
+		if len(r.byMonth) > 0 && !containsMonth(r.byMonth, d.Month()) {
+			continue
+		}
+		if r.freq == "DAILY" {
+			if len(r.byMonthDay) > 0 && !matchesMonthDay(d, r.byMonthDay) {
+				continue
+			}
+			if len(r.byDay) > 0 && !matchesWeekday(d, r.byDay) {
+				continue
+			}
+		}
+		t := at(d.Year(), d.Month(), d.Day())
+		if !seen[t] {
+			seen[t] = true
+			candidates = append(candidates, t)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	return setPositions(candidates, r.bySetPos), periodStart
+}
+
+// monthDays returns the days of the month starting at first that a
+// MONTHLY rule, or a YEARLY one in that month, picks: by BYMONTHDAY, by
+// BYDAY, by both (days of the month on those weekdays, e.g. Friday the
+// 13th) or else the day of the month the event started on.
+func monthDays(first time.Time, r rule, startDay int) []time.Time {
+	var days []time.Time
+	add := func(day int) {
+		days = append(days, first.AddDate(0, 0, day-1))
+	}
+	switch {
+	case len(r.byMonthDay) > 0:
+		for _, d := range r.byMonthDay {
+			if day, ok := monthDay(first, d); ok && (len(r.byDay) == 0 || matchesWeekday(first.AddDate(0, 0, day-1), r.byDay)) {
+				add(day)
+			}
+		}
+	case len(r.byDay) > 0:
+		for _, d := range r.byDay {
+			for _, day := range weekdaysInMonth(first, d) {
+				add(day)
+			}
+		}
+	default:
+		if day, ok := monthDay(first, startDay); ok {
+			add(day)
+		}
+	}
+	return days
+}
+
+// setPositions keeps the BYSETPOS-th of a period's sorted instances,
+// counting from the end when negative. Without BYSETPOS it keeps them all.
+func setPositions(candidates []time.Time, positions []int) []time.Time {
+	if len(positions) == 0 {
+		return candidates
+	}
+	var kept []time.Time
+	for i, t := range candidates {
+		for _, p := range positions {
+			if p == i+1 || p == i-len(candidates) {
+				kept = append(kept, t)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+func containsMonth(months []time.Month, m time.Month) bool {
+	for _, mo := range months {
+		if mo == m {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesWeekday(day time.Time, days []byDay) bool {
+	for _, d := range days {
+		if d.weekday == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesMonthDay(day time.Time, monthDays []int) bool {
+	first := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	for _, d := range monthDays {
+		if md, ok := monthDay(first, d); ok && md == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(first time.Time) int {
+	return first.AddDate(0, 1, -1).Day()
+}
+
+// monthDay resolves a BYMONTHDAY value (negative counts from the end) to a
+// day of the month starting at first.
+func monthDay(first time.Time, d int) (int, bool) {
+	n := daysIn(first)
+	if d < 0 {
+		d = n + d + 1
+	}
+	return d, d >= 1 && d <= n
+}
+
+// weekdaysInMonth returns the days of the month matching a BYDAY entry,
+// e.g. "2TU" (second Tuesday), "-1FR" (last Friday) or "MO" (every Monday).
+func weekdaysInMonth(first time.Time, d byDay) []int {
+	var days []int
+	for day := 1; day <= daysIn(first); day++ {
+		if first.AddDate(0, 0, day-1).Weekday() == d.weekday {
+			days = append(days, day)
+		}
+	}
+
+	switch {
+	case d.ordinal == 0:
+		return days
+	case d.ordinal > 0 && d.ordinal <= len(days):
+		return []int{days[d.ordinal-1]}
+	case d.ordinal < 0 && -d.ordinal <= len(days):
+		return []int{days[len(days)+d.ordinal]}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestExpandRule(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(s string) time.Time {
+		t, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	local := func(s string) time.Time {
+		t, err := time.ParseInLocation("2006-01-02 15:04", s, berlin)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+
+	tests := []struct {
+		name    string
+		dtstart time.Time
+		rrule   string
+		from    time.Time
+		to      time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "daily with count",
+			dtstart: utc("2024-01-01 09:00"),
+			rrule:   "FREQ=DAILY;COUNT=3",
+			from:    utc("2023-12-31 00:00"),
+			to:      utc("2024-02-01 00:00"),
+			want:    []time.Time{utc("2024-01-01 09:00"), utc("2024-01-02 09:00"), utc("2024-01-03 09:00")},
+		},
+		{
+			name:    "count is kept from dtstart, not the window",
+			dtstart: utc("2024-01-01 09:00"),
+			rrule:   "FREQ=DAILY;COUNT=3",
+			from:    utc("2024-01-02 00:00"),
+			to:      utc("2024-02-01 00:00"),
+			want:    []time.Time{utc("2024-01-02 09:00"), utc("2024-01-03 09:00")},
+		},
+		{
+			name:    "daily on weekdays skips the weekend",
+			dtstart: utc("2024-01-04 09:00"), // Thursday
+			rrule:   "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+			from:    utc("2024-01-01 00:00"),
+			to:      utc("2024-01-09 00:00"),
+			want:    []time.Time{utc("2024-01-04 09:00"), utc("2024-01-05 09:00"), utc("2024-01-08 09:00")},
+		},
+		{
+			name:    "daily in some months only",
+			dtstart: utc("2024-01-30 09:00"),
+			rrule:   "FREQ=DAILY;BYMONTH=1,3;COUNT=3",
+			from:    utc("2024-01-01 00:00"),
+			to:      utc("2025-01-01 00:00"),
+			want:    []time.Time{utc("2024-01-30 09:00"), utc("2024-01-31 09:00"), utc("2024-03-01 09:00")},
+		},
+		{
+			name:    "weekly on two days",
+			dtstart: utc("2024-01-01 18:30"), // Monday
+			rrule:   "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+			from:    utc("2024-01-01 00:00"),
+			to:      utc("2024-02-01 00:00"),
+			want: []time.Time{
+				utc("2024-01-01 18:30"), utc("2024-01-03 18:30"),
+				utc("2024-01-08 18:30"), utc("2024-01-10 18:30"),
+			},
+		},
+		{
+			name:    "every other week",
+			dtstart: utc("2024-01-03 10:00"), // Wednesday
+			rrule:   "FREQ=WEEKLY;INTERVAL=2",
+			from:    utc("2024-01-01 00:00"),
+			to:      utc("2024-02-01 00:00"),
+			want:    []time.Time{utc("2024-01-03 10:00"), utc("2024-01-17 10:00"), utc("2024-01-31 10:00")},
+		},
+		{
+			name:    "last weekday of the month",
+			dtstart: utc("2024-01-31 16:00"),
+			rrule:   "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+			from:    utc("2024-01-01 00:00"),
+			to:      utc("2024-04-01 00:00"),
+			want:    []time.Time{utc("2024-01-31 16:00"), utc("2024-02-29 16:00"), utc("2024-03-29 16:00")},
+		},
+		{
+			name:    "second Tuesday of the month",
+			dtstart: utc("2024-01-09 12:00"),
+			rrule:   "FREQ=MONTHLY;BYDAY=2TU;COUNT=3",
+			from:    utc("2024-01-01 00:00"),
+			to:      utc("2025-01-01 00:00"),
+			want:    []time.Time{utc("2024-01-09 12:00"), utc("2024-02-13 12:00"), utc("2024-03-12 12:00")},
+		},
+		{
+			name:    "the 31st skips shorter months",
+			dtstart: utc("2024-01-31 08:00"),
+			rrule:   "FREQ=MONTHLY;BYMONTHDAY=31",
+			from:    utc("2024-01-01 00:00"),
+			to:      utc("2024-06-01 00:00"),
+			want:    []time.Time{utc("2024-01-31 08:00"), utc("2024-03-31 08:00"), utc("2024-05-31 08:00")},
+		},
+		{
+			name:    "monthly in some months only",
+			dtstart: utc("2024-01-15 08:00"),
+			rrule:   "FREQ=MONTHLY;BYMONTH=1,2",
+			from:    utc("2024-01-01 00:00"),
+			to:      utc("2025-03-01 00:00"),
+			want: []time.Time{
+				utc("2024-01-15 08:00"), utc("2024-02-15 08:00"),
+				utc("2025-01-15 08:00"), utc("2025-02-15 08:00"),
+			},
+		},
+		{
+			name:    "Friday the 13th",
+			dtstart: utc("2024-09-13 20:00"),
+			rrule:   "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13;COUNT=3",
+			from:    utc("2024-01-01 00:00"),
+			to:      utc("2026-01-01 00:00"),
+			want:    []time.Time{utc("2024-09-13 20:00"), utc("2024-12-13 20:00"), utc("2025-06-13 20:00")},
+		},
+		{
+			name:    "yearly in two months",
+			dtstart: utc("2024-01-10 09:00"),
+			rrule:   "FREQ=YEARLY;BYMONTH=1,7",
+			from:    utc("2024-01-01 00:00"),
+			to:      utc("2025-02-01 00:00"),
+			want:    []time.Time{utc("2024-01-10 09:00"), utc("2024-07-10 09:00"), utc("2025-01-10 09:00")},
+		},
+		{
+			name:    "yearly on the first Monday of September",
+			dtstart: utc("2024-09-02 09:00"),
+			rrule:   "FREQ=YEARLY;BYMONTH=9;BYDAY=1MO;COUNT=2",
+			from:    utc("2024-01-01 00:00"),
+			to:      utc("2030-01-01 00:00"),
+			want:    []time.Time{utc("2024-09-02 09:00"), utc("2025-09-01 09:00")},
+		},
+		{
+			name:    "until is inclusive",
+			dtstart: utc("2024-01-01 09:00"),
+			rrule:   "FREQ=DAILY;UNTIL=20240103T090000Z",
+			from:    utc("2023-12-31 00:00"),
+			to:      utc("2024-02-01 00:00"),
+			want:    []time.Time{utc("2024-01-01 09:00"), utc("2024-01-02 09:00"), utc("2024-01-03 09:00")},
+		},
+		{
+			name:    "wall clock time is kept across DST",
+			dtstart: local("2024-03-30 09:00"),
+			rrule:   "FREQ=DAILY;COUNT=3",
+			from:    utc("2024-03-29 00:00"),
+			to:      utc("2024-04-02 00:00"),
+			want:    []time.Time{local("2024-03-30 09:00"), local("2024-03-31 09:00"), local("2024-04-01 09:00")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := parseRule(tt.rrule)
+			if r.unsupported != "" {
+				t.Fatalf("parseRule(%q) flagged %s as unsupported", tt.rrule, r.unsupported)
+			}
+			got := expandRule(tt.dtstart, r, tt.from, tt.to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRuleUnsupported(t *testing.T) {
+	for _, rrule := range []string{
+		"FREQ=HOURLY;INTERVAL=2",
+		"FREQ=YEARLY;BYWEEKNO=20",
+		"FREQ=YEARLY;BYDAY=20MO",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;WKST=SU;BYDAY=SU,MO",
+		"FREQ=DAILY;BYHOUR=9,17",
+	} {
+		if r := parseRule(rrule); r.unsupported == "" {
+			t.Errorf("parseRule(%q) was taken as supported", rrule)
+		}
+	}
+}
+
+func TestExpandKeepsFirstInstanceOfUnsupportedRule(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	events := []ParsedEvent{{
+		UID:   "weekno",
+		Start: start,
+		End:   start.Add(time.Hour),
+		RRule: "FREQ=YEARLY;BYWEEKNO=1,2",
+	}}
+
+	got := Expand(events, start.AddDate(0, 0, -1), start.AddDate(1, 0, 0))
+	if len(got) != 1 || !got[0].Start.Equal(start) {
+		t.Fatalf("got %v, want only the instance at %v", got, start)
+	}
+}
@@ -0,0 +1,159 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/netguard"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// How far ahead recurring events are expanded into busy blocks
+	syncHorizon = 180 * 24 * time.Hour
+	// Maximum size of an ICS document we are willing to read
+	maxFeedBytes = 10 << 20
+)
+
+// Syncer imports events from providers' external ICS and CalDAV calendars
+// as busy blocks, and pushes bookings to CalDAV calendars.
+type Syncer struct {
+	DB     *gorm.DB
+	Client *http.Client
+}
+
+// NewSyncer returns a syncer whose client only reaches public addresses,
+// as providers choose the calendars it fetches.
+func NewSyncer(db *gorm.DB) *Syncer {
+	return &Syncer{
+		DB:     db,
+		Client: netguard.NewClient(30 * time.Second),
+	}
+}
+
+// SyncAll syncs every active external calendar and CalDAV account.
+func (s *Syncer) SyncAll(ctx context.Context) {
+	var calendars []models.ExternalCalendar
+	if err := s.DB.Where("is_active = ?", true).Find(&calendars).Error; err != nil {
+		log.Println("Failed to load external calendars:", err)
+		return
+	}
+
+	for i := range calendars {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.Sync(ctx, &calendars[i]); err != nil {
+			log.Printf("Failed to sync external calendar %d: %v", calendars[i].ID, err)
+		}
+	}
+
+	var accounts []models.CalDAVAccount
+	if err := s.DB.Where("is_active = ?", true).Find(&accounts).Error; err != nil {
+		log.Println("Failed to load CalDAV accounts:", err)
+		return
+	}
+
+	for i := range accounts {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.PullCalDAV(ctx, &accounts[i]); err != nil {
+			log.Printf("Failed to sync CalDAV account %d: %v", accounts[i].ID, err)
+		}
+	}
+}
+
+// Sync fetches one calendar and replaces its busy blocks. The outcome is
+// recorded on the calendar so providers can see broken feeds.
+func (s *Syncer) Sync(ctx context.Context, cal *models.ExternalCalendar) error {
+	err := s.sync(ctx, cal)
+
+	now := time.Now()
+	cal.LastSyncedAt = &now
+	cal.LastError = ""
+	if err != nil {
+		cal.LastError = err.Error()
+	}
+	if saveErr := s.DB.Model(cal).Select("LastSyncedAt", "LastError").Updates(cal).Error; saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+func (s *Syncer) sync(ctx context.Context, cal *models.ExternalCalendar) error {
+	var provider models.ServiceProvider
+	if err := s.DB.First(&provider, cal.ProviderID).Error; err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, FeedURL(cal.URL), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/calendar")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	events, err := Parse(io.LimitReader(resp.Body, maxFeedBytes), provider.Location())
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	occurrences := Expand(events, now.Add(-24*time.Hour), now.Add(syncHorizon))
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		return ReplaceBusyBlocks(tx, cal.ProviderID, models.BusySourceICS, cal.ID, "", occurrences)
+	})
+}
+
+// ReplaceBusyBlocks swaps every block previously imported from a source
+// object for the given occurrences. ICS feeds are a single object with an
+// empty ref.
+func ReplaceBusyBlocks(tx *gorm.DB, providerID uint, source models.BusySource, sourceID uint, ref string, occurrences []Occurrence) error {
+	if err := tx.Where("source = ? AND source_id = ? AND ref = ?", source, sourceID, ref).Delete(&models.BusyBlock{}).Error; err != nil {
+		return err
+	}
+	if len(occurrences) == 0 {
+		return nil
+	}
+
+	blocks := make([]models.BusyBlock, 0, len(occurrences))
+	for _, o := range occurrences {
+		blocks = append(blocks, models.BusyBlock{
+			ProviderID: providerID,
+			Source:     source,
+			SourceID:   sourceID,
+			Ref:        ref,
+			UID:        o.UID,
+			Summary:    o.Summary,
+			StartAt:    o.Start,
+			EndAt:      o.End,
+		})
+	}
+	return tx.CreateInBatches(blocks, 200).Error
+}
+
+// FeedURL normalizes webcal:// subscription links to HTTPS.
+func FeedURL(raw string) string {
+	if strings.HasPrefix(strings.ToLower(raw), "webcal://") {
+		return "https://" + raw[len("webcal://"):]
+	}
+	return raw
+}
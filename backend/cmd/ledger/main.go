@@ -0,0 +1,419 @@
+// Command ledger runs the platform's books: the commission it takes, the
+// payouts it owes providers and their statements.
+//
+//	ledger commission list
+//	ledger commission set [-provider 12 | -category Tattoo] -rate 15
+//	ledger commission delete [-provider 12 | -category Tattoo]
+//	ledger payouts create -to 2026-11-01 [-from 2026-10-01]
+//	ledger payouts list [-batch 3] [-status pending]
+//	ledger payouts paid -id 7 -reference TRF-0042
+//	ledger payouts failed -id 7 -reason "Account closed"
+//	ledger statement -provider 12 [-from 2026-10-01] [-to 2026-11-01] [-currency usd] > statement.csv
+//	ledger statement -payout 7 > payout.csv
+//	ledger balances
+//	ledger backfill
+//
+// Commission set without -provider or -category is the default rule.
+// Rates are percentages and apply to payments made afterwards. Dates are
+// midnight UTC. backfill posts payments made before the ledger existed;
+// it is safe to run again.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"pluralink/backend/config"
+	"pluralink/backend/database"
+	"pluralink/backend/ledger"
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+	"pluralink/backend/payments"
+
+	"gorm.io/gorm"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	config.LoadConfig()
+	if err := payments.Setup(config.AppConfig); err != nil {
+		log.Fatal(err)
+	}
+	database.Connect()
+
+	switch os.Args[1] {
+	case "commission":
+		commission(os.Args[2:])
+	case "payouts":
+		payouts(os.Args[2:])
+	case "statement":
+		statement(os.Args[2:])
+	case "balances":
+		balances()
+	case "backfill":
+		backfill()
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: ledger commission|payouts|statement|balances|backfill [flags]")
+	os.Exit(2)
+}
+
+func commission(args []string) {
+	if len(args) < 1 {
+		usage()
+	}
+	switch args[0] {
+	case "list":
+		var rules []models.CommissionRule
+		if err := database.DB.Order("provider_id, category_id").Find(&rules).Error; err != nil {
+			log.Fatal("Failed to load commission rules: ", err)
+		}
+		for _, r := range rules {
+			fmt.Printf("%-32s %s\n", scope(r), money.FormatBasis(r.Rate))
+		}
+	case "set":
+		fs := flag.NewFlagSet("commission set", flag.ExitOnError)
+		providerID := fs.Uint("provider", 0, "provider id, for a rule of its own")
+		category := fs.String("category", "", "category name, for its services")
+		rate := fs.String("rate", "", "rate in percent, e.g. 12.5")
+		fs.Parse(args[1:])
+
+		bp, err := money.ParseBasis(*rate)
+		if err != nil {
+			log.Fatal(err)
+		}
+		rule := ruleFor(*providerID, *category)
+		if err := database.DB.Where(&rule, "ProviderID", "CategoryID").FirstOrInit(&rule).Error; err != nil {
+			log.Fatal("Failed to load commission rule: ", err)
+		}
+		rule.Rate = bp
+		if err := database.DB.Save(&rule).Error; err != nil {
+			log.Fatal("Failed to save commission rule: ", err)
+		}
+		log.Printf("Commission rule %d saved: %s %s", rule.ID, scope(rule), money.FormatBasis(rule.Rate))
+	case "delete":
+		fs := flag.NewFlagSet("commission delete", flag.ExitOnError)
+		providerID := fs.Uint("provider", 0, "provider id of the rule")
+		category := fs.String("category", "", "category name of the rule")
+		fs.Parse(args[1:])
+
+		rule := ruleFor(*providerID, *category)
+		if err := database.DB.Where(&rule, "ProviderID", "CategoryID").First(&rule).Error; err != nil {
+			log.Fatal("No such commission rule")
+		}
+		if err := database.DB.Delete(&rule).Error; err != nil {
+			log.Fatal("Failed to delete commission rule: ", err)
+		}
+		log.Printf("Commission rule %d deleted", rule.ID)
+	default:
+		usage()
+	}
+}
+
+// ruleFor returns an unsaved rule for a provider or a category by name,
+// or the default rule if given neither.
+func ruleFor(providerID uint, category string) models.CommissionRule {
+	rule := models.CommissionRule{ProviderID: providerID}
+	if providerID != 0 && category != "" {
+		log.Fatal("Give a provider or a category, not both")
+	}
+	if providerID != 0 {
+		if err := database.DB.First(&models.ServiceProvider{}, providerID).Error; err != nil {
+			log.Fatalf("Unknown provider %d", providerID)
+		}
+	}
+	if category != "" {
+		var c models.Category
+		if err := database.DB.Where("LOWER(name) = LOWER(?)", strings.TrimSpace(category)).First(&c).Error; err != nil {
+			log.Fatalf("Unknown category %q", category)
+		}
+		rule.CategoryID = c.ID
+	}
+	return rule
+}
+
+func scope(r models.CommissionRule) string {
+	switch {
+	case r.ProviderID != 0:
+		var p models.ServiceProvider
+		database.DB.Unscoped().First(&p, r.ProviderID)
+		return fmt.Sprintf("provider %d (%s)", r.ProviderID, p.BusinessName)
+	case r.CategoryID != 0:
+		var c models.Category
+		database.DB.First(&c, r.CategoryID)
+		return fmt.Sprintf("category %s", c.Name)
+	}
+	return "default"
+}
+
+func payouts(args []string) {
+	if len(args) < 1 {
+		usage()
+	}
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("payouts create", flag.ExitOnError)
+		from := fs.String("from", "", "start of the period, YYYY-MM-DD (defaults to the end of the last batch)")
+		to := fs.String("to", "", "end of the period, YYYY-MM-DD, not included")
+		fs.Parse(args[1:])
+
+		end := parseDate("to", *to)
+		var start time.Time
+		if *from != "" {
+			start = parseDate("from", *from)
+		} else {
+			var last models.PayoutBatch
+			if err := database.DB.Order("period_end DESC").First(&last).Error; err == nil {
+				start = last.PeriodEnd
+			}
+		}
+		if !start.Before(end) {
+			log.Fatal("from must be before to")
+		}
+
+		batch, err := ledger.CreateBatch(database.DB, start, end)
+		if err != nil {
+			log.Fatal("Failed to create payouts: ", err)
+		}
+		for _, p := range batch.Payouts {
+			fmt.Printf("%-6d provider %-6d %14s\n", p.ID, p.ProviderID, money.Format(p.Amount, p.Currency))
+		}
+		log.Printf("Batch %d created with %d payouts", batch.ID, len(batch.Payouts))
+	case "list":
+		fs := flag.NewFlagSet("payouts list", flag.ExitOnError)
+		batchID := fs.Uint("batch", 0, "only this batch's payouts")
+		status := fs.String("status", "", "only payouts with this status: pending, paid or failed")
+		fs.Parse(args[1:])
+
+		query := database.DB.Preload("Provider", func(db *gorm.DB) *gorm.DB { return db.Unscoped() })
+		if *batchID != 0 {
+			query = query.Where("batch_id = ?", *batchID)
+		}
+		if *status != "" {
+			query = query.Where("status = ?", *status)
+		}
+		var list []models.Payout
+		if err := query.Order("id").Find(&list).Error; err != nil {
+			log.Fatal("Failed to load payouts: ", err)
+		}
+		for _, p := range list {
+			name := ""
+			if p.Provider != nil {
+				name = p.Provider.BusinessName
+			}
+			fmt.Printf("%-6d batch %-4d %-30s %14s %-8s %s\n", p.ID, p.BatchID, name,
+				money.Format(p.Amount, p.Currency), p.Status, p.Reference+p.FailureReason)
+		}
+	case "paid":
+		fs := flag.NewFlagSet("payouts paid", flag.ExitOnError)
+		id := fs.Uint("id", 0, "payout id")
+		reference := fs.String("reference", "", "the bank transfer's reference")
+		fs.Parse(args[1:])
+
+		payout, err := ledger.MarkPaid(database.DB, *id, *reference)
+		if err != nil {
+			log.Fatal(settleError(*id, err))
+		}
+		log.Printf("Payout %d of %s marked paid", payout.ID, money.Format(payout.Amount, payout.Currency))
+	case "failed":
+		fs := flag.NewFlagSet("payouts failed", flag.ExitOnError)
+		id := fs.Uint("id", 0, "payout id")
+		reason := fs.String("reason", "", "why the transfer failed")
+		fs.Parse(args[1:])
+
+		payout, err := ledger.MarkFailed(database.DB, *id, *reason)
+		if err != nil {
+			log.Fatal(settleError(*id, err))
+		}
+		log.Printf("Payout %d marked failed; %s is owed again", payout.ID, money.Format(payout.Amount, payout.Currency))
+	default:
+		usage()
+	}
+}
+
+func settleError(id uint, err error) string {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Sprintf("No such payout %d", id)
+	case errors.Is(err, ledger.ErrNotPending):
+		return fmt.Sprintf("Payout %d is not pending", id)
+	}
+	return fmt.Sprintf("Failed to update payout %d: %v", id, err)
+}
+
+func statement(args []string) {
+	fs := flag.NewFlagSet("statement", flag.ExitOnError)
+	providerID := fs.Uint("provider", 0, "provider id")
+	payoutID := fs.Uint("payout", 0, "payout id, for what it settled instead of a period")
+	from := fs.String("from", "", "start of the period, YYYY-MM-DD (defaults to the start of this month)")
+	to := fs.String("to", "", "end of the period, YYYY-MM-DD, not included (defaults to now)")
+	currency := fs.String("currency", "", "currency (defaults to the provider's)")
+	fs.Parse(args)
+
+	var s *ledger.Statement
+	var err error
+	if *payoutID != 0 {
+		var payout models.Payout
+		if err := database.DB.First(&payout, *payoutID).Error; err != nil {
+			log.Fatalf("No such payout %d", *payoutID)
+		}
+		s, err = ledger.PayoutStatement(database.DB, &payout)
+	} else {
+		var provider models.ServiceProvider
+		if err := database.DB.Unscoped().First(&provider, *providerID).Error; err != nil {
+			log.Fatalf("Unknown provider %d", *providerID)
+		}
+		end := time.Now().UTC()
+		if *to != "" {
+			end = parseDate("to", *to)
+		}
+		start := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if *from != "" {
+			start = parseDate("from", *from)
+		}
+		if !start.Before(end) {
+			log.Fatal("from must be before to")
+		}
+		cur := provider.Currency
+		if *currency != "" {
+			cur = strings.ToLower(*currency)
+		}
+		s, err = ledger.PeriodStatement(database.DB, provider.ID, cur, start, end)
+	}
+	if err != nil {
+		log.Fatal("Failed to build statement: ", err)
+	}
+	os.Stdout.Write(s.CSV())
+}
+
+// balances prints what each account holds. Every currency's accounts add
+// up to zero, or the books are off.
+func balances() {
+	var rows []struct {
+		Account    models.LedgerAccount
+		ProviderID *uint
+		Currency   string
+		Balance    int64
+	}
+	if err := database.DB.Model(&models.LedgerEntry{}).
+		Select("account, provider_id, currency, SUM(amount) AS balance").
+		Group("account, provider_id, currency").Order("currency, account, provider_id").
+		Scan(&rows).Error; err != nil {
+		log.Fatal("Failed to load balances: ", err)
+	}
+	totals := map[string]int64{}
+	for _, r := range rows {
+		name := string(r.Account)
+		if r.ProviderID != nil {
+			name = fmt.Sprintf("%s %d", r.Account, *r.ProviderID)
+		}
+		// Debits are positive; what is owed to providers and kept as
+		// commission shows as credit
+		fmt.Printf("%-24s %-4s %14s\n", name, strings.ToUpper(r.Currency), money.Decimal(r.Balance, r.Currency))
+		totals[r.Currency] += r.Balance
+	}
+	for currency, total := range totals {
+		if total != 0 {
+			log.Fatalf("The %s books are off by %s", strings.ToUpper(currency), money.Decimal(total, currency))
+		}
+	}
+}
+
+// backfill posts what went through the gateway before the ledger existed.
+// Everything is posted under the key it would have had, so nothing is
+// posted twice.
+func backfill() {
+	db := database.DB
+	count := 0
+	run := func(what string, id uint, post func(tx *gorm.DB) error) {
+		if err := db.Transaction(post); err != nil {
+			log.Fatalf("Failed to post %s %d: %v", what, id, err)
+		}
+		count++
+	}
+
+	var paid []models.Payment
+	if err := db.Where("status = ?", models.PaymentSucceeded).Order("id").Find(&paid).Error; err != nil {
+		log.Fatal("Failed to load payments: ", err)
+	}
+	for i := range paid {
+		p := &paid[i]
+		at := p.UpdatedAt
+		if p.SucceededAt != nil {
+			at = *p.SucceededAt
+		}
+		run("payment", p.ID, func(tx *gorm.DB) error { return ledger.RecordPayment(tx, p, at) })
+		if p.DisputeStatus == models.DisputeLost {
+			run("chargeback of payment", p.ID, func(tx *gorm.DB) error { return ledger.RecordChargeback(tx, p, p.Amount, p.UpdatedAt) })
+		}
+	}
+
+	var refunds []models.Refund
+	if err := db.Where("status = ?", models.RefundStatusSucceeded).Order("id").Find(&refunds).Error; err != nil {
+		log.Fatal("Failed to load refunds: ", err)
+	}
+	for i := range refunds {
+		r := &refunds[i]
+		run("refund", r.ID, func(tx *gorm.DB) error { return ledger.RecordRefund(tx, r, r.UpdatedAt) })
+	}
+
+	var cards []models.GiftCard
+	if err := db.Where("intent_id IS NOT NULL AND status <> ? AND status <> ?", models.GiftCardPending, models.GiftCardVoid).
+		Order("id").Find(&cards).Error; err != nil {
+		log.Fatal("Failed to load gift cards: ", err)
+	}
+	for i := range cards {
+		c := &cards[i]
+		run("gift card", c.ID, func(tx *gorm.DB) error { return ledger.RecordGiftCard(tx, c, c.UpdatedAt) })
+	}
+
+	var packages []models.ClientPackage
+	if err := db.Where("intent_id IS NOT NULL AND status = ?", models.ClientPackageActive).
+		Order("id").Find(&packages).Error; err != nil {
+		log.Fatal("Failed to load packages: ", err)
+	}
+	for i := range packages {
+		cp := &packages[i]
+		run("package", cp.ID, func(tx *gorm.DB) error { return ledger.RecordPackage(tx, cp, cp.UpdatedAt) })
+	}
+
+	var months []models.MembershipPayment
+	if err := db.Where("status = ?", models.PaymentSucceeded).Order("id").Find(&months).Error; err != nil {
+		log.Fatal("Failed to load membership payments: ", err)
+	}
+	for i := range months {
+		p := &months[i]
+		var m models.Membership
+		if err := db.First(&m, p.MembershipID).Error; err != nil {
+			log.Fatalf("Failed to load membership %d: %v", p.MembershipID, err)
+		}
+		at := p.UpdatedAt
+		if p.SucceededAt != nil {
+			at = *p.SucceededAt
+		}
+		run("membership payment", p.ID, func(tx *gorm.DB) error { return ledger.RecordMembershipPayment(tx, &m, p, at) })
+	}
+
+	log.Printf("Checked %d payments, refunds and purchases", count)
+}
+
+func parseDate(name, s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		log.Fatalf("Invalid %s %q. Use YYYY-MM-DD", name, s)
+	}
+	return t
+}
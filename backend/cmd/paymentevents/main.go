@@ -0,0 +1,194 @@
+// Command paymentevents works with payment gateway webhooks outside the
+// server.
+//
+//	paymentevents sign -type payment.succeeded -intent pi_123 -amount 2500 [-url http://localhost:8080/api/payments/webhook]
+//	paymentevents replay [-failed] [event ids...]
+//
+// sign builds an event the configured gateway would send, signed with
+// PAYMENT_WEBHOOK_SECRET, and prints it or posts it to the receiver.
+// replay applies stored events again, by database id or all failed ones.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"pluralink/backend/config"
+	"pluralink/backend/database"
+	"pluralink/backend/jobs"
+	"pluralink/backend/models"
+	"pluralink/backend/payments"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	config.LoadConfig()
+	if err := payments.Setup(config.AppConfig); err != nil {
+		log.Fatal(err)
+	}
+
+	switch os.Args[1] {
+	case "sign":
+		sign(os.Args[2:])
+	case "replay":
+		replay(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: paymentevents sign|replay [flags]")
+	os.Exit(2)
+}
+
+func sign(args []string) {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	eventType := fs.String("type", payments.EventPaymentSucceeded, "event type, e.g. payment.succeeded, refund.updated, dispute.opened")
+	id := fs.String("id", "", "event id (random by default)")
+	intentID := fs.String("intent", "", "intent the event is about")
+	refundID := fs.String("refund", "", "refund the event is about, for refund events")
+	disputeID := fs.String("dispute", "", "dispute the event is about, for dispute events")
+	amount := fs.Int64("amount", 0, "amount in minor units")
+	currency := fs.String("currency", payments.DefaultCurrency, "currency")
+	status := fs.String("status", "", "refund (pending, succeeded, failed) or dispute (open, won, lost) status")
+	reason := fs.String("reason", "", "decline, refund failure or dispute reason")
+	url := fs.String("url", "", "receiver to post the event to, instead of printing it")
+	fs.Parse(args)
+
+	signer, ok := payments.DefaultGateway.(payments.EventSigner)
+	if !ok {
+		log.Fatalf("The %s gateway cannot sign events", payments.DefaultGateway.Name())
+	}
+
+	event := &payments.Event{ID: *id, Type: *eventType}
+	switch *eventType {
+	case payments.EventPaymentSucceeded, payments.EventPaymentFailed, payments.EventPaymentProcessing, payments.EventPaymentCanceled:
+		intent := &payments.Intent{ID: *intentID, Amount: *amount, Currency: *currency}
+		switch *eventType {
+		case payments.EventPaymentSucceeded:
+			intent.Status = payments.IntentSucceeded
+		case payments.EventPaymentFailed:
+			intent.Status = payments.IntentRequiresPayment
+			intent.LastError = *reason
+			if intent.LastError == "" {
+				intent.LastError = "Your card was declined."
+			}
+		case payments.EventPaymentProcessing:
+			intent.Status = payments.IntentProcessing
+		case payments.EventPaymentCanceled:
+			intent.Status = payments.IntentCanceled
+		}
+		event.Intent = intent
+	case payments.EventRefundUpdated:
+		event.Refund = &payments.Refund{
+			ID:            *refundID,
+			IntentID:      *intentID,
+			Status:        payments.RefundStatus(orDefault(*status, string(payments.RefundSucceeded))),
+			Amount:        *amount,
+			Currency:      *currency,
+			FailureReason: *reason,
+		}
+	case payments.EventDisputeOpened, payments.EventDisputeClosed:
+		disputeStatus := payments.DisputeOpen
+		if *eventType == payments.EventDisputeClosed {
+			disputeStatus = payments.DisputeLost
+		}
+		event.Dispute = &payments.Dispute{
+			ID:       *disputeID,
+			IntentID: *intentID,
+			Amount:   *amount,
+			Status:   payments.DisputeStatus(orDefault(*status, string(disputeStatus))),
+			Reason:   orDefault(*reason, "fraudulent"),
+		}
+	default:
+		log.Fatalf("Unknown event type %q", *eventType)
+	}
+
+	payload, header, err := signer.SignEvent(event, time.Now())
+	if err != nil {
+		log.Fatal("Failed to sign event: ", err)
+	}
+
+	if *url == "" {
+		for key := range header {
+			fmt.Printf("%s: %s\n", key, header.Get(key))
+		}
+		fmt.Printf("\n%s\n", payload)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, *url, bytes.NewReader(payload))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header = header
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatal("Failed to post event: ", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Printf("%s %s\n%s\n", event.ID, resp.Status, body)
+}
+
+func replay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	failed := fs.Bool("failed", false, "replay every event that failed to process")
+	fs.Parse(args)
+
+	database.Connect()
+	service := payments.NewService(database.DB)
+
+	var ids []uint
+	for _, arg := range fs.Args() {
+		id, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid event id %q", arg)
+		}
+		ids = append(ids, uint(id))
+	}
+	if *failed {
+		var failedIDs []uint
+		if err := database.DB.Model(&models.PaymentEvent{}).
+			Where("status = ? AND gateway = ?", models.PaymentEventFailed, service.Gateway.Name()).
+			Order("id").Pluck("id", &failedIDs).Error; err != nil {
+			log.Fatal("Failed to load failed events: ", err)
+		}
+		ids = append(ids, failedIDs...)
+	}
+	if len(ids) == 0 {
+		log.Fatal("Nothing to replay: pass event ids or -failed")
+	}
+
+	errs := 0
+	for _, id := range ids {
+		if err := jobs.ProcessPaymentEvent(database.DB, service, id, true); err != nil {
+			log.Printf("Event %d: %v", id, err)
+			errs++
+			continue
+		}
+		log.Printf("Event %d: replayed", id)
+	}
+	if errs > 0 {
+		os.Exit(1)
+	}
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
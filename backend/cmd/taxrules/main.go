@@ -0,0 +1,149 @@
+// Command taxrules manages the sales tax and VAT rules bookings are charged
+// under.
+//
+//	taxrules list
+//	taxrules set -country us -state ca -name "Sales tax" -rate 7.25 [-inclusive] [-exempt Tattoo,Massage]
+//	taxrules delete -country us [-state ca]
+//
+// Countries and states are matched against what providers entered in their
+// profile, ignoring case. A rule without a state covers the rest of its
+// country. Changes apply to bookings made afterwards.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"pluralink/backend/config"
+	"pluralink/backend/database"
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+
+	"gorm.io/gorm"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	config.LoadConfig()
+	database.Connect()
+
+	switch os.Args[1] {
+	case "list":
+		list()
+	case "set":
+		set(os.Args[2:])
+	case "delete":
+		remove(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: taxrules list|set|delete [flags]")
+	os.Exit(2)
+}
+
+func list() {
+	var rules []models.TaxRule
+	if err := database.DB.Preload("ExemptCategories").Order("country, state").Find(&rules).Error; err != nil {
+		log.Fatal("Failed to load tax rules: ", err)
+	}
+	for _, r := range rules {
+		region := r.Country
+		if r.State != "" {
+			region += "/" + r.State
+		}
+		pricing := "exclusive"
+		if r.Inclusive {
+			pricing = "inclusive"
+		}
+		var exempt []string
+		for _, c := range r.ExemptCategories {
+			exempt = append(exempt, c.Name)
+		}
+		fmt.Printf("%-24s %-12s %6.2f%% %-9s %s\n", region, r.Name, float64(r.Rate)/100, pricing, strings.Join(exempt, ","))
+	}
+}
+
+func set(args []string) {
+	fs := flag.NewFlagSet("set", flag.ExitOnError)
+	country := fs.String("country", "", "country, as providers enter it")
+	state := fs.String("state", "", "state, or none for the whole country")
+	name := fs.String("name", "Tax", "name printed on receipts, e.g. VAT")
+	rate := fs.String("rate", "", "rate in percent, e.g. 7.25")
+	inclusive := fs.Bool("inclusive", false, "prices already include the tax")
+	exempt := fs.String("exempt", "", "comma-separated names of exempt categories")
+	fs.Parse(args)
+
+	if models.TaxRegion(*country) == "" {
+		log.Fatal("A country is required")
+	}
+	bp, err := money.ParseBasis(*rate)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if bp >= 10000 {
+		log.Fatal("A tax rate must be under 100%")
+	}
+
+	var categories []models.Category
+	for _, n := range strings.Split(*exempt, ",") {
+		if n = strings.TrimSpace(n); n == "" {
+			continue
+		}
+		var category models.Category
+		if err := database.DB.Where("LOWER(name) = LOWER(?)", n).First(&category).Error; err != nil {
+			log.Fatalf("Unknown category %q", n)
+		}
+		categories = append(categories, category)
+	}
+
+	rule := models.TaxRule{Country: models.TaxRegion(*country), State: models.TaxRegion(*state)}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&rule, "Country", "State").FirstOrInit(&rule).Error; err != nil {
+			return err
+		}
+		rule.Name = strings.TrimSpace(*name)
+		rule.Rate = bp
+		rule.Inclusive = *inclusive
+		if err := tx.Save(&rule).Error; err != nil {
+			return err
+		}
+		return tx.Model(&rule).Association("ExemptCategories").Replace(categories)
+	})
+	if err != nil {
+		log.Fatal("Failed to save tax rule: ", err)
+	}
+	log.Printf("Tax rule %d saved", rule.ID)
+}
+
+func remove(args []string) {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	country := fs.String("country", "", "country of the rule")
+	state := fs.String("state", "", "state of the rule, or none for the country's")
+	fs.Parse(args)
+
+	var rule models.TaxRule
+	if err := database.DB.Where("country = ? AND state = ?", models.TaxRegion(*country), models.TaxRegion(*state)).
+		First(&rule).Error; err != nil {
+		log.Fatal("No such tax rule")
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&rule).Association("ExemptCategories").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&rule).Error
+	})
+	if err != nil {
+		log.Fatal("Failed to delete tax rule: ", err)
+	}
+	log.Printf("Tax rule %d deleted", rule.ID)
+}
@@ -18,6 +18,44 @@ type Config struct {
 	OAuthClientID string
 	OAuthSecret    string
 	OAuthRedirect  string
+	PublicBaseURL  string
+	CalendarSyncInterval string
+	JobWorkers     string
+	PendingBookingTTL string
+	SMTPHost       string
+	SMTPPort       string
+	SMTPUsername   string
+	SMTPPassword   string
+	SMTPFrom       string
+	SMTPSinkAddr   string
+	SMSGatewayURL  string
+	SMSGatewayKey  string
+	SMSFrom        string
+	PushEnabled    string
+	PushURL        string
+	PushAccessToken string
+	NotificationsFake string
+	UploadDir      string
+	MaxUploadSize  string
+	StorageDriver  string
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3PathStyle    string
+	FileURLSigningKey string
+	SignedURLTTL   string
+	PaymentGateway string
+	PaymentCurrency string
+	StripeSecretKey string
+	StripePublishableKey string
+	PaymentWebhookSecret string
+	PaymentFeeRate string
+	PaymentFeeFixed string
+	TipWindow      string
+	AllowPrivateURLs string
+	CredentialsKey string
 }
 
 var AppConfig *Config
@@ -39,6 +77,44 @@ func LoadConfig() {
 		OAuthClientID:  getEnv("OAUTH_CLIENT_ID", ""),
 		OAuthSecret:    getEnv("OAUTH_SECRET", ""),
 		OAuthRedirect:  getEnv("OAUTH_REDIRECT", "http://localhost:8080/api/auth/callback"),
+		PublicBaseURL:  getEnv("PUBLIC_BASE_URL", "http://localhost:8080"),
+		CalendarSyncInterval: getEnv("CALENDAR_SYNC_INTERVAL", "15m"),
+		JobWorkers:     getEnv("JOB_WORKERS", "4"),
+		PendingBookingTTL: getEnv("PENDING_BOOKING_TTL", "0"), // 0 keeps pending bookings until their start time
+		SMTPHost:       getEnv("SMTP_HOST", ""), // Email is disabled when empty
+		SMTPPort:       getEnv("SMTP_PORT", "587"),
+		SMTPUsername:   getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:   getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:       getEnv("SMTP_FROM", "Pluralink <no-reply@pluralink.local>"),
+		SMTPSinkAddr:   getEnv("SMTP_SINK_ADDR", ""), // Runs a local SMTP sink and sends email there instead
+		SMSGatewayURL:  getEnv("SMS_GATEWAY_URL", ""), // SMS is disabled when empty
+		SMSGatewayKey:  getEnv("SMS_GATEWAY_KEY", ""),
+		SMSFrom:        getEnv("SMS_FROM", "Pluralink"),
+		PushEnabled:    getEnv("PUSH_ENABLED", "true"),
+		PushURL:        getEnv("PUSH_URL", ""), // Defaults to Expo's push service
+		PushAccessToken: getEnv("PUSH_ACCESS_TOKEN", ""),
+		NotificationsFake: getEnv("NOTIFICATIONS_FAKE", "false"), // Record and log messages instead of sending them
+		UploadDir:      getEnv("UPLOAD_DIR", "uploads"),
+		MaxUploadSize:  getEnv("MAX_UPLOAD_SIZE", "10485760"), // Bytes per file
+		StorageDriver:  getEnv("STORAGE_DRIVER", "local"), // local (UPLOAD_DIR) or s3
+		S3Endpoint:     getEnv("S3_ENDPOINT", "http://localhost:9000"), // A local MinIO by default
+		S3Region:       getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:       getEnv("S3_BUCKET", "pluralink"),
+		S3AccessKey:    getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:    getEnv("S3_SECRET_KEY", ""),
+		S3PathStyle:    getEnv("S3_PATH_STYLE", "true"), // MinIO needs path-style addressing
+		FileURLSigningKey: getEnv("FILE_URL_SIGNING_KEY", ""), // Defaults to JWT_SECRET
+		SignedURLTTL:   getEnv("SIGNED_URL_TTL", "15m"),
+		PaymentGateway: getEnv("PAYMENT_GATEWAY", "fake"), // fake (in memory, no money moves) or stripe
+		PaymentCurrency: getEnv("PAYMENT_CURRENCY", "usd"),
+		StripeSecretKey: getEnv("STRIPE_SECRET_KEY", ""),
+		StripePublishableKey: getEnv("STRIPE_PUBLISHABLE_KEY", ""),
+		PaymentWebhookSecret: getEnv("PAYMENT_WEBHOOK_SECRET", ""), // Stripe's "whsec_..."; the fake gateway defaults to JWT_SECRET
+		PaymentFeeRate: getEnv("PAYMENT_FEE_RATE", "0"), // Percent the gateway keeps of each payment, taken from what providers are owed
+		PaymentFeeFixed: getEnv("PAYMENT_FEE_FIXED", "0"), // Plus this, in minor units
+		TipWindow:      getEnv("TIP_WINDOW", "72h"), // How long after a completed booking ends clients may tip
+		AllowPrivateURLs: getEnv("ALLOW_PRIVATE_URLS", "false"), // Lets webhooks and calendars reach local and private addresses; for development only
+		CredentialsKey: getEnv("CREDENTIALS_KEY", ""), // Encrypts stored third-party passwords; defaults to JWT_SECRET
 	}
 }
 
@@ -0,0 +1,232 @@
+// Package credits covers bookings with the sessions of clients' packages
+// and the monthly allowance of their memberships, and prices bookings for
+// members.
+package credits
+
+import (
+	"errors"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Rejection is why a booking cannot be made as asked, worded for the
+// client.
+type Rejection string
+
+func (r Rejection) Error() string { return string(r) }
+func (Rejection) Rejected()       {}
+
+const (
+	ErrCovered Rejection = "Booking is already covered by your package or membership"
+)
+
+// Cover uses a session of one of the client's packages, or else of a
+// membership's allowance, for a new booking of a service they include,
+// holding it until tx ends so that it is not used twice. Packages that
+// expire soonest go first. It reports whether the booking is covered, in
+// which case its whole Price is Prepaid. The booking's ClientID,
+// ProviderID, ServiceID and Price must be set. Record the use once the
+// booking is created.
+func Cover(tx *gorm.DB, booking *models.Booking, now time.Time) (bool, error) {
+	var pkg models.ClientPackage
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("client_id = ? AND provider_id = ? AND status = ? AND remaining > 0 AND (expires_at IS NULL OR expires_at > ?)",
+			booking.ClientID, booking.ProviderID, models.ClientPackageActive, now).
+		Where("package_id IN (?)", tx.Table("package_services").Select("package_id").Where("service_id = ?", booking.ServiceID)).
+		Order("expires_at ASC NULLS LAST, id").First(&pkg).Error
+	if err == nil {
+		booking.ClientPackageID = &pkg.ID
+		booking.Prepaid = booking.Price
+		return true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	memberships, err := current(tx, booking, now, true)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range memberships {
+		if m.Used >= m.Allowance {
+			continue
+		}
+		booking.MembershipID = &m.ID
+		booking.Prepaid = booking.Price
+		return true, nil
+	}
+	return false, nil
+}
+
+// MemberPrice takes the best discount of the client's current memberships
+// off a new booking that is not covered, after any other discount. The
+// booking's ClientID, ProviderID, ServiceID, Price and Currency must be
+// set.
+func MemberPrice(tx *gorm.DB, booking *models.Booking, now time.Time) error {
+	if booking.Prepaid > 0 {
+		return nil
+	}
+	memberships, err := current(tx, booking, now, false)
+	if err != nil {
+		return err
+	}
+	var best *models.Membership
+	for i, m := range memberships {
+		if m.Discount > 0 && (best == nil || m.Discount > best.Discount) {
+			best = &memberships[i]
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	left := money.New(booking.Price-booking.Discount-booking.RewardDiscount, booking.Currency)
+	booking.MembershipID = &best.ID
+	booking.MemberDiscount = left.Percent(best.Discount).Amount
+	return nil
+}
+
+// Record uses up the package session or allowance that covers a created
+// booking.
+func Record(tx *gorm.DB, booking *models.Booking) error {
+	if booking.Prepaid <= 0 {
+		return nil
+	}
+	use := models.CreditUse{BookingID: booking.ID}
+	switch {
+	case booking.ClientPackageID != nil:
+		if err := tx.Model(&models.ClientPackage{}).Where("id = ?", *booking.ClientPackageID).
+			Update("remaining", gorm.Expr("remaining - 1")).Error; err != nil {
+			return err
+		}
+		use.ClientPackageID = booking.ClientPackageID
+	case booking.MembershipID != nil:
+		var m models.Membership
+		if err := tx.First(&m, *booking.MembershipID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&m).Update("used", gorm.Expr("used + 1")).Error; err != nil {
+			return err
+		}
+		use.MembershipID, use.PeriodStart = &m.ID, m.PeriodStart
+	default:
+		return nil
+	}
+	return tx.Create(&use).Error
+}
+
+// Restore gives back the package session or allowance used by a booking
+// that was cancelled or expired. An allowance only comes back while its
+// month lasts. It does nothing if the use was already given back, so
+// calling it again is safe.
+func Restore(db *gorm.DB, bookingID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var use models.CreditUse
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("booking_id = ? AND restored_at IS NULL", bookingID).First(&use).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case use.ClientPackageID != nil:
+			if err := tx.Model(&models.ClientPackage{}).Where("id = ?", *use.ClientPackageID).
+				Update("remaining", gorm.Expr("remaining + 1")).Error; err != nil {
+				return err
+			}
+		case use.MembershipID != nil && use.PeriodStart != nil:
+			if err := tx.Model(&models.Membership{}).
+				Where("id = ? AND period_start = ? AND used > 0", *use.MembershipID, *use.PeriodStart).
+				Update("used", gorm.Expr("used - 1")).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&use).Update("restored_at", time.Now()).Error
+	})
+}
+
+// ActivatePackage puts a paid package's sessions on it and starts its
+// validity. Packages already activated are left alone.
+func ActivatePackage(tx *gorm.DB, cp *models.ClientPackage, now time.Time) error {
+	if cp.Status == models.ClientPackageActive {
+		return nil
+	}
+	var pkg models.Package
+	if err := tx.Unscoped().First(&pkg, cp.PackageID).Error; err != nil {
+		return err
+	}
+	cp.Status = models.ClientPackageActive
+	cp.Remaining = cp.Sessions
+	cp.FailureMessage = ""
+	cp.PaidAt = &now
+	if pkg.ValidityDays > 0 {
+		expires := now.AddDate(0, 0, pkg.ValidityDays)
+		cp.ExpiresAt = &expires
+	}
+	return tx.Model(cp).Select("status", "remaining", "failure_message", "paid_at", "expires_at").Updates(cp).Error
+}
+
+// StartPeriod moves a membership into the month a payment paid for,
+// refilling its allowance with the plan's current allowance and discount.
+// Payments for months already started are ignored.
+func StartPeriod(tx *gorm.DB, m *models.Membership, payment *models.MembershipPayment) error {
+	if m.PeriodStart != nil && !payment.PeriodStart.After(*m.PeriodStart) {
+		return nil
+	}
+	var plan models.MembershipPlan
+	if err := tx.Unscoped().First(&plan, m.PlanID).Error; err != nil {
+		return err
+	}
+	start, end := payment.PeriodStart, payment.PeriodEnd
+	m.PeriodStart, m.PeriodEnd = &start, &end
+	m.Used = 0
+	m.Allowance = plan.Allowance
+	m.Discount = plan.Discount
+	// A month paid late brings a lapsed membership back
+	if m.Status != models.MembershipCancelled {
+		m.Status = models.MembershipActive
+	}
+	return tx.Model(m).Select("period_start", "period_end", "used", "allowance", "discount", "status").Updates(m).Error
+}
+
+// current returns the client's memberships with the provider whose month
+// is running and that include the booking's service, locked if asked.
+func current(tx *gorm.DB, booking *models.Booking, now time.Time, lock bool) ([]models.Membership, error) {
+	query := tx
+	if lock {
+		query = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var memberships []models.Membership
+	if err := query.Where("client_id = ? AND provider_id = ? AND status = ? AND period_start <= ? AND period_end > ?",
+		booking.ClientID, booking.ProviderID, models.MembershipActive, now, now).
+		Order("id").Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	var covering []models.Membership
+	for _, m := range memberships {
+		var total, matching int64
+		if err := tx.Table("membership_plan_services").Where("membership_plan_id = ?", m.PlanID).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		if total > 0 {
+			if err := tx.Table("membership_plan_services").
+				Where("membership_plan_id = ? AND service_id = ?", m.PlanID, booking.ServiceID).Count(&matching).Error; err != nil {
+				return nil, err
+			}
+			if matching == 0 {
+				continue
+			}
+		}
+		covering = append(covering, m)
+	}
+	return covering, nil
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// migrateConfirmations adds when bookings were confirmed, which their
+// status stops telling once they are rescheduled. It runs before
+// AutoMigrate and only once, while the column is missing. Bookings
+// rescheduled before then count as confirmed unless they still await
+// payment, which no confirmed booking could, as they were treated so far.
+func migrateConfirmations(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&models.Booking{}) || m.HasColumn(&models.Booking{}, "ConfirmedAt") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&models.Booking{}, "ConfirmedAt"); err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE bookings SET confirmed_at = updated_at WHERE status IN ? OR (status = ? AND payment_status <> ?)",
+			[]models.BookingStatus{models.StatusConfirmed, models.StatusCompleted},
+			models.StatusRescheduled, models.PaymentDue).Error
+	})
+}
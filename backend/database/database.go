@@ -14,8 +14,9 @@ import (
 
 var DB *gorm.DB
 
-func Connect() {
-	dsn := fmt.Sprintf(
+// DSN returns the connection string for the configured database.
+func DSN() string {
+	return fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
 		config.AppConfig.DBHost,
 		config.AppConfig.DBUser,
@@ -23,9 +24,11 @@ func Connect() {
 		config.AppConfig.DBName,
 		config.AppConfig.DBPort,
 	)
+}
 
+func Connect() {
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+	DB, err = gorm.Open(postgres.Open(DSN()), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 
@@ -37,6 +40,13 @@ func Connect() {
 }
 
 func Migrate() {
+	if err := migrateMoney(DB); err != nil {
+		log.Fatal("Failed to migrate prices to minor units:", err)
+	}
+	if err := migrateConfirmations(DB); err != nil {
+		log.Fatal("Failed to migrate booking confirmations:", err)
+	}
+
 	err := DB.AutoMigrate(
 		&models.User{},
 		&models.ServiceProvider{},
@@ -46,11 +56,57 @@ func Migrate() {
 		&models.Availability{},
 		&models.Booking{},
 		&models.Review{},
+		&models.CalendarFeed{},
+		&models.ExternalCalendar{},
+		&models.BusyBlock{},
+		&models.CalDAVAccount{},
+		&models.CalDAVBookingEvent{},
+		&models.CalDAVResource{},
+		&models.Job{},
+		&models.Reminder{},
+		&models.DeviceToken{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.DigestItem{},
+		&models.BookingEvent{},
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
+		&models.BookingMessage{},
+		&models.MessageAttachment{},
+		&models.Upload{},
+		&models.PortfolioItem{},
+		&models.Payment{},
+		&models.Refund{},
+		&models.PaymentEvent{},
+		&models.Invoice{},
+		&models.TaxRule{},
+		&models.PromoCode{},
+		&models.PromoRedemption{},
+		&models.GiftCard{},
+		&models.GiftCardEntry{},
+		&models.LoyaltyProgram{},
+		&models.LoyaltyReward{},
+		&models.LoyaltyAccount{},
+		&models.LoyaltyEntry{},
+		&models.Package{},
+		&models.ClientPackage{},
+		&models.MembershipPlan{},
+		&models.Membership{},
+		&models.MembershipPayment{},
+		&models.CreditUse{},
+		&models.CommissionRule{},
+		&models.LedgerTransaction{},
+		&models.LedgerEntry{},
+		&models.PayoutBatch{},
+		&models.Payout{},
 	)
 
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	if err := backfillBookingPrices(DB); err != nil {
+		log.Fatal("Failed to backfill booking prices:", err)
+	}
 
 	log.Println("Database migration completed")
 }
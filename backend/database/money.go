@@ -0,0 +1,105 @@
+package database
+
+import (
+	"strings"
+
+	"pluralink/backend/config"
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+
+	"gorm.io/gorm"
+)
+
+// migrateMoney moves service prices from decimal floats to minor units of
+// their provider's currency, which providers gain from their country. It
+// runs before AutoMigrate, which would otherwise cut prices to whole units,
+// and does nothing once prices are integers.
+func migrateMoney(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&models.Service{}) {
+		return nil
+	}
+	columns, err := m.ColumnTypes(&models.Service{})
+	if err != nil {
+		return err
+	}
+	float := false
+	for _, col := range columns {
+		if col.Name() == "price" {
+			switch strings.ToLower(col.DatabaseTypeName()) {
+			case "float4", "float8", "numeric", "real", "double precision":
+				float = true
+			}
+		}
+	}
+	if !float {
+		return nil
+	}
+
+	fallback := strings.ToLower(config.AppConfig.PaymentCurrency)
+	return db.Transaction(func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if !m.HasColumn(&models.ServiceProvider{}, "Currency") {
+			if err := m.AddColumn(&models.ServiceProvider{}, "Currency"); err != nil {
+				return err
+			}
+		}
+		var providers []models.ServiceProvider
+		if err := tx.Unscoped().Select("id", "country").Find(&providers).Error; err != nil {
+			return err
+		}
+		for _, p := range providers {
+			currency := money.ForCountry(p.Country)
+			if currency == "" {
+				currency = fallback
+			}
+			if err := tx.Unscoped().Model(&models.ServiceProvider{}).Where("id = ?", p.ID).
+				Update("currency", currency).Error; err != nil {
+				return err
+			}
+		}
+
+		if !m.HasColumn(&models.Service{}, "Currency") {
+			if err := m.AddColumn(&models.Service{}, "Currency"); err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec(`UPDATE services SET currency = service_providers.currency
+			FROM service_providers WHERE service_providers.id = services.provider_id`).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("ALTER TABLE services ADD COLUMN price_minor bigint").Error; err != nil {
+			return err
+		}
+		var currencies []string
+		if err := tx.Model(&models.Service{}).Unscoped().Distinct().Pluck("currency", &currencies).Error; err != nil {
+			return err
+		}
+		for _, currency := range currencies {
+			factor := money.FromFloat(1, currency)
+			if err := tx.Exec("UPDATE services SET price_minor = ROUND(price * ?) WHERE currency = ?",
+				factor, currency).Error; err != nil {
+				return err
+			}
+		}
+		for _, stmt := range []string{
+			"UPDATE services SET price_minor = ROUND(price * 100) WHERE price_minor IS NULL",
+			"ALTER TABLE services DROP COLUMN price",
+			"ALTER TABLE services RENAME COLUMN price_minor TO price",
+			"ALTER TABLE services ALTER COLUMN price SET NOT NULL",
+		} {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// backfillBookingPrices records the price of bookings made before bookings
+// kept one, from their service.
+func backfillBookingPrices(db *gorm.DB) error {
+	return db.Exec(`UPDATE bookings SET price = services.price, currency = services.currency
+		FROM services WHERE services.id = bookings.service_id AND (bookings.currency IS NULL OR bookings.currency = '')`).Error
+}
@@ -0,0 +1,191 @@
+// Package giftcards issues providers' gift cards and keeps the ledger of
+// what is paid with them.
+package giftcards
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Rejection is why a gift card cannot pay for a booking, worded for the
+// client.
+type Rejection string
+
+func (r Rejection) Error() string { return string(r) }
+func (Rejection) Rejected()       {}
+
+const (
+	ErrNotFound      Rejection = "Gift card not found"
+	ErrNotActive     Rejection = "Gift card is not active"
+	ErrWrongProvider Rejection = "Gift card is for another provider"
+	ErrWrongCurrency Rejection = "Gift card is in another currency"
+	ErrEmpty         Rejection = "Gift card has no balance left"
+)
+
+// codeAlphabet leaves out letters and digits that read alike.
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// NewCode returns a random code of 16 characters in groups of four.
+func NewCode() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for i, v := range b {
+		if i > 0 && i%4 == 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(codeAlphabet[int(v)%len(codeAlphabet)])
+	}
+	return sb.String(), nil
+}
+
+// Normalize writes a code as typed the way it is stored: upper case, in
+// groups of four.
+func Normalize(code string) string {
+	var raw []rune
+	for _, r := range strings.ToUpper(code) {
+		if r != '-' && r != ' ' {
+			raw = append(raw, r)
+		}
+	}
+	var sb strings.Builder
+	for i, r := range raw {
+		if i > 0 && i%4 == 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// Mask hides all but the last group of a code.
+func Mask(code string) string {
+	if i := strings.LastIndex(code, "-"); i >= 0 {
+		return "XXXX-XXXX-XXXX" + code[i:]
+	}
+	return code
+}
+
+// Find returns the gift card with a code.
+func Find(db *gorm.DB, code string) (*models.GiftCard, error) {
+	var card models.GiftCard
+	err := db.Where("code = ?", Normalize(code)).First(&card).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// Activate puts a card's face value on it. Cards already activated are left
+// alone.
+func Activate(tx *gorm.DB, card *models.GiftCard) error {
+	if card.Status == models.GiftCardActive {
+		return nil
+	}
+	card.Status = models.GiftCardActive
+	card.Balance = card.Amount
+	card.FailureMessage = ""
+	if err := tx.Model(card).Select("status", "balance", "failure_message").Updates(card).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.GiftCardEntry{
+		GiftCardID: card.ID,
+		Kind:       models.GiftCardIssue,
+		Amount:     card.Amount,
+		Balance:    card.Balance,
+	}).Error
+}
+
+// Apply puts a gift card towards a new booking, holding the card's row
+// until tx ends so that its balance is not spent twice. It covers as much
+// of the booking's Total as the balance allows and sets GiftCardAmount.
+// Record the redemption once the booking is created.
+func Apply(tx *gorm.DB, code string, booking *models.Booking) (*models.GiftCard, error) {
+	card, err := Find(tx.Clauses(clause.Locking{Strength: "UPDATE"}), code)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case card.Status != models.GiftCardActive:
+		return nil, ErrNotActive
+	case card.ProviderID != booking.ProviderID:
+		return nil, ErrWrongProvider
+	case card.Currency != booking.Currency:
+		return nil, ErrWrongCurrency
+	case card.Balance <= 0:
+		return nil, ErrEmpty
+	}
+	booking.GiftCardAmount = min(card.Balance, booking.Total())
+	return card, nil
+}
+
+// Record takes what a created booking was paid with a gift card off its
+// balance.
+func Record(tx *gorm.DB, card *models.GiftCard, booking *models.Booking) error {
+	if booking.GiftCardAmount <= 0 {
+		return nil
+	}
+	return post(tx, card, models.GiftCardRedemption, -booking.GiftCardAmount, booking.ID)
+}
+
+// Restore returns up to amount of what gift cards paid for a cancelled
+// booking to them. It does nothing if the booking was already restored,
+// so calling it again is safe.
+func Restore(db *gorm.DB, bookingID uint, amount int64) error {
+	if amount <= 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var entries []models.GiftCardEntry
+		if err := tx.Where("booking_id = ?", bookingID).Order("id").Find(&entries).Error; err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.Kind == models.GiftCardRefund {
+				return nil
+			}
+		}
+
+		for _, e := range entries {
+			if amount <= 0 {
+				break
+			}
+			back := min(-e.Amount, amount)
+			var card models.GiftCard
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&card, e.GiftCardID).Error; err != nil {
+				return err
+			}
+			if err := post(tx, &card, models.GiftCardRefund, back, bookingID); err != nil {
+				return err
+			}
+			amount -= back
+		}
+		return nil
+	})
+}
+
+// post moves a card's balance by amount and records it in the ledger.
+func post(tx *gorm.DB, card *models.GiftCard, kind models.GiftCardEntryKind, amount int64, bookingID uint) error {
+	card.Balance += amount
+	if err := tx.Model(card).Update("balance", card.Balance).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.GiftCardEntry{
+		GiftCardID: card.ID,
+		Kind:       kind,
+		Amount:     amount,
+		Balance:    card.Balance,
+		BookingID:  &bookingID,
+	}).Error
+}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"net/http"
-	"strconv"
 
 	"pluralink/backend/models"
 	"pluralink/backend/utils"
@@ -1,12 +1,25 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"strconv"
 	"time"
 
+	"pluralink/backend/credits"
+	"pluralink/backend/giftcards"
+	"pluralink/backend/jobs"
+	"pluralink/backend/loyalty"
 	"pluralink/backend/models"
+	"pluralink/backend/money"
+	"pluralink/backend/notifications"
+	"pluralink/backend/payments"
+	"pluralink/backend/promos"
+	"pluralink/backend/realtime"
+	"pluralink/backend/reminders"
+	"pluralink/backend/taxes"
 	"pluralink/backend/utils"
+	"pluralink/backend/webhooks"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -20,12 +33,23 @@ func NewBookingHandler(db *gorm.DB) *BookingHandler {
 	return &BookingHandler{DB: db}
 }
 
+// rejection is an error from promos, gift cards, loyalty or credits that
+// tells the client why their booking cannot be made as asked.
+type rejection interface {
+	error
+	Rejected()
+}
+
 type CreateBookingRequest struct {
-	ProviderID uint      `json:"provider_id" binding:"required"`
-	ServiceID  uint      `json:"service_id" binding:"required"`
-	Date       time.Time `json:"date" binding:"required"`
-	StartTime  string    `json:"start_time" binding:"required"`
-	Notes      string    `json:"notes"`
+	ProviderID   uint      `json:"provider_id" binding:"required"`
+	ServiceID    uint      `json:"service_id" binding:"required"`
+	Date         time.Time `json:"date" binding:"required"`
+	StartTime    string    `json:"start_time" binding:"required"`
+	Notes        string    `json:"notes"`
+	PromoCode    string    `json:"promo_code"`
+	GiftCardCode string    `json:"gift_card_code"` // Pays as much of the booking as its balance covers
+	RewardID     uint      `json:"reward_id"`      // Loyalty reward to redeem points for
+	UseCredits   *bool     `json:"use_credits"`    // Cover the booking with a package or membership if possible, defaults to true
 }
 
 func (h *BookingHandler) CreateBooking(c *gin.Context) {
@@ -75,7 +99,7 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	endTimeStr := endTime.Format("15:04")
 
 	// Check if time slot is available
-	if !h.isTimeSlotAvailable(req.ProviderID, req.Date, req.StartTime, endTimeStr) {
+	if !h.isTimeSlotAvailable(&provider, req.Date, req.StartTime, endTimeStr) {
 		utils.BadRequestResponse(c, "Time slot is not available")
 		return
 	}
@@ -83,7 +107,7 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	// Check for conflicting bookings
 	var conflictingBooking models.Booking
 	if err := h.DB.Where("provider_id = ? AND date = ? AND status NOT IN ? AND ((start_time <= ? AND end_time > ?) OR (start_time < ? AND end_time >= ?))",
-		req.ProviderID, req.Date, []models.BookingStatus{models.StatusCancelled}, req.StartTime, req.StartTime, endTimeStr, endTimeStr).
+		req.ProviderID, req.Date, []models.BookingStatus{models.StatusCancelled, models.StatusExpired}, req.StartTime, req.StartTime, endTimeStr, endTimeStr).
 		First(&conflictingBooking).Error; err == nil {
 		utils.BadRequestResponse(c, "Time slot is already booked")
 		return
@@ -100,14 +124,98 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 		Notes:      req.Notes,
 	}
 
-	if err := h.DB.Create(&booking).Error; err != nil {
+	// The price is kept as booked, whatever the service costs later
+	booking.Price, booking.Currency = service.Price, service.Currency
+
+	// Credits, discounts, rewards, tax and gift cards are settled as the
+	// booking is created, holding the package or membership, promo code,
+	// points and gift card against concurrent bookings
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if req.UseCredits == nil || *req.UseCredits {
+			covered, err := credits.Cover(tx, &booking, now)
+			if err != nil {
+				return err
+			}
+			if covered && (req.PromoCode != "" || req.RewardID != 0) {
+				return credits.ErrCovered
+			}
+		}
+		var promo *models.PromoCode
+		if req.PromoCode != "" {
+			var err error
+			if promo, err = promos.Apply(tx, req.PromoCode, &booking, &service, now); err != nil {
+				return err
+			}
+		}
+		if req.RewardID != 0 {
+			if err := loyalty.Apply(tx, req.RewardID, &booking); err != nil {
+				return err
+			}
+		}
+		if err := credits.MemberPrice(tx, &booking, now); err != nil {
+			return err
+		}
+		if err := taxes.Apply(tx, &booking, &provider, &service); err != nil {
+			return err
+		}
+		var card *models.GiftCard
+		if req.GiftCardCode != "" {
+			var err error
+			if card, err = giftcards.Apply(tx, req.GiftCardCode, &booking); err != nil {
+				return err
+			}
+		}
+
+		// Bookings taking payment wait for it before they can be confirmed
+		booking.PaymentStatus = models.PaymentNotRequired
+		if _, due := payments.Due(&service, money.New(booking.Payable(), booking.Currency)); due.Amount > 0 {
+			booking.PaymentStatus = models.PaymentDue
+		}
+
+		if err := tx.Create(&booking).Error; err != nil {
+			return err
+		}
+		if promo != nil {
+			if err := promos.Record(tx, promo, &booking); err != nil {
+				return err
+			}
+		}
+		if err := loyalty.Record(tx, &booking); err != nil {
+			return err
+		}
+		if err := credits.Record(tx, &booking); err != nil {
+			return err
+		}
+		if card != nil {
+			return giftcards.Record(tx, card, &booking)
+		}
+		return nil
+	})
+	var rejected rejection
+	if errors.As(err, &rejected) {
+		utils.BadRequestResponse(c, rejected.Error())
+		return
+	}
+	if err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to create booking")
 		return
 	}
 
+	// The client can retry opening the payment if the gateway is down
+	if booking.PaymentStatus == models.PaymentDue {
+		if _, err := payments.NewService(h.DB).Start(c.Request.Context(), &booking, &service); err != nil {
+			log.Printf("Failed to start payment for booking %d: %v", booking.ID, err)
+		}
+	}
+
+	h.pushToCalendar(booking.ID)
+	notify(h.DB, notifications.Event{Type: notifications.EventBookingRequested, BookingID: booking.ID, ActorUserID: userID.(uint)})
+	publishUpdate(h.DB, realtime.EventBookingCreated, &booking, "")
+
 	h.DB.Preload("Client").Preload("Client.User").
 		Preload("Provider").Preload("Provider.User").
-		Preload("Service").First(&booking, booking.ID)
+		Preload("Service").Preload("Payments").First(&booking, booking.ID)
 
 	utils.SuccessResponse(c, http.StatusCreated, "Booking created successfully", booking)
 }
@@ -160,6 +268,7 @@ func (h *BookingHandler) GetBooking(c *gin.Context) {
 		Preload("Provider").Preload("Provider.User").
 		Preload("Service").
 		Preload("Review").
+		Preload("Payments").
 		First(&booking, id).Error; err != nil {
 		utils.NotFoundResponse(c, "Booking not found")
 		return
@@ -213,8 +322,8 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 		}
 	}
 
-	if booking.Status == models.StatusCancelled {
-		utils.BadRequestResponse(c, "Booking is already cancelled")
+	if booking.Status == models.StatusCancelled || booking.Status == models.StatusExpired {
+		utils.BadRequestResponse(c, "Booking is already "+string(booking.Status))
 		return
 	}
 
@@ -223,22 +332,114 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 		return
 	}
 
+	// Settle open payments first, so one that went through at the last
+	// moment is refunded like the rest
+	if booking.PaymentStatus == models.PaymentDue {
+		if err := payments.NewService(h.DB).CancelOpen(c.Request.Context(), booking.ID); err != nil {
+			log.Printf("Failed to cancel payments for booking %d: %v", booking.ID, err)
+		}
+		h.DB.First(&booking, booking.ID)
+	}
+
+	previous := booking.Status
 	booking.Status = models.StatusCancelled
-	if err := h.DB.Save(&booking).Error; err != nil {
+	booking.Sequence++
+	// Only if nobody moved it on meanwhile, and without writing back the
+	// rest of the row as read
+	result := h.DB.Model(&booking).Where("status = ?", previous).
+		Select("status", "sequence").Updates(&booking)
+	if result.Error != nil {
 		utils.InternalServerErrorResponse(c, "Failed to cancel booking")
 		return
 	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "Booking was changed meanwhile, please try again")
+		return
+	}
+
+	h.pushToCalendar(booking.ID)
+	if err := reminders.Cancel(h.DB, booking.ID); err != nil {
+		log.Printf("Failed to cancel reminders for booking %d: %v", booking.ID, err)
+	}
+	h.refundCancelled(&booking, previous, userRole == models.RoleProvider)
+	notify(h.DB, notifications.Event{Type: notifications.EventBookingCancelled, BookingID: booking.ID, ActorUserID: userID.(uint)})
+	publishUpdate(h.DB, realtime.EventBookingStatusChanged, &booking, previous)
 
 	utils.SuccessResponse(c, http.StatusOK, "Booking cancelled successfully", booking)
 }
 
+// ConfirmBooking lets the provider accept a pending or rescheduled booking,
+// which also schedules its reminders.
+func (h *BookingHandler) ConfirmBooking(c *gin.Context) {
+	id := c.Param("id")
+	userID, _ := c.Get("user_id")
+
+	var provider models.ServiceProvider
+	if err := h.DB.Where("user_id = ?", userID).First(&provider).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider profile not found")
+		return
+	}
+
+	var booking models.Booking
+	if err := h.DB.Preload("Provider").First(&booking, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Booking not found")
+		return
+	}
+
+	if booking.ProviderID != provider.ID {
+		utils.ErrorResponse(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	if booking.Status != models.StatusPending && booking.Status != models.StatusRescheduled {
+		utils.BadRequestResponse(c, "Only pending or rescheduled bookings can be confirmed")
+		return
+	}
+
+	if booking.PaymentStatus == models.PaymentDue {
+		utils.BadRequestResponse(c, "Booking cannot be confirmed until the client has paid")
+		return
+	}
+
+	previous := booking.Status
+	now := time.Now()
+	booking.Status = models.StatusConfirmed
+	booking.ConfirmedAt = &now
+	booking.Sequence++
+	// Only if nobody cancelled it meanwhile, and without writing back
+	// payment columns a gateway event may have changed
+	result := h.DB.Model(&booking).Where("status = ?", previous).
+		Select("status", "confirmed_at", "sequence").Updates(&booking)
+	if result.Error != nil {
+		utils.InternalServerErrorResponse(c, "Failed to confirm booking")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "Booking was changed meanwhile, please try again")
+		return
+	}
+
+	h.pushToCalendar(booking.ID)
+	if err := reminders.Schedule(h.DB, &booking); err != nil {
+		log.Printf("Failed to schedule reminders for booking %d: %v", booking.ID, err)
+	}
+	notify(h.DB, notifications.Event{Type: notifications.EventBookingConfirmed, BookingID: booking.ID, ActorUserID: userID.(uint)})
+	publishUpdate(h.DB, realtime.EventBookingStatusChanged, &booking, previous)
+
+	h.DB.Preload("Client").Preload("Client.User").
+		Preload("Provider").Preload("Provider.User").
+		Preload("Service").First(&booking, booking.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, "Booking confirmed successfully", booking)
+}
+
 func (h *BookingHandler) RescheduleBooking(c *gin.Context) {
 	id := c.Param("id")
 	userID, _ := c.Get("user_id")
 	userRole, _ := c.Get("user_role")
 
 	var booking models.Booking
-	if err := h.DB.Preload("Service").First(&booking, id).Error; err != nil {
+	if err := h.DB.Preload("Service").Preload("Provider").First(&booking, id).Error; err != nil {
 		utils.NotFoundResponse(c, "Booking not found")
 		return
 	}
@@ -260,6 +461,12 @@ func (h *BookingHandler) RescheduleBooking(c *gin.Context) {
 		}
 	}
 
+	// Cancelled and expired bookings were already refunded
+	if booking.Status != models.StatusPending && booking.Status != models.StatusConfirmed && booking.Status != models.StatusRescheduled {
+		utils.BadRequestResponse(c, "Only pending, confirmed or rescheduled bookings can be rescheduled")
+		return
+	}
+
 	var req struct {
 		Date      time.Time `json:"date" binding:"required"`
 		StartTime string    `json:"start_time" binding:"required"`
@@ -280,7 +487,7 @@ func (h *BookingHandler) RescheduleBooking(c *gin.Context) {
 	endTimeStr := endTime.Format("15:04")
 
 	// Check if new time slot is available
-	if !h.isTimeSlotAvailable(booking.ProviderID, req.Date, req.StartTime, endTimeStr) {
+	if !h.isTimeSlotAvailable(&booking.Provider, req.Date, req.StartTime, endTimeStr) {
 		utils.BadRequestResponse(c, "Time slot is not available")
 		return
 	}
@@ -288,21 +495,43 @@ func (h *BookingHandler) RescheduleBooking(c *gin.Context) {
 	// Check for conflicting bookings (excluding current booking)
 	var conflictingBooking models.Booking
 	if err := h.DB.Where("provider_id = ? AND date = ? AND id != ? AND status NOT IN ? AND ((start_time <= ? AND end_time > ?) OR (start_time < ? AND end_time >= ?))",
-		booking.ProviderID, req.Date, booking.ID, []models.BookingStatus{models.StatusCancelled}, req.StartTime, req.StartTime, endTimeStr, endTimeStr).
+		booking.ProviderID, req.Date, booking.ID, []models.BookingStatus{models.StatusCancelled, models.StatusExpired}, req.StartTime, req.StartTime, endTimeStr, endTimeStr).
 		First(&conflictingBooking).Error; err == nil {
 		utils.BadRequestResponse(c, "Time slot is already booked")
 		return
 	}
 
+	previous := booking.Status
+	wasActive := booking.Active()
+
 	booking.Date = req.Date
 	booking.StartTime = req.StartTime
 	booking.EndTime = endTimeStr
 	booking.Status = models.StatusRescheduled
+	booking.Sequence++
 
-	if err := h.DB.Save(&booking).Error; err != nil {
+	// Only if nobody cancelled it meanwhile
+	result := h.DB.Model(&booking).Where("status = ?", previous).
+		Select("date", "start_time", "end_time", "status", "sequence").Updates(&booking)
+	if result.Error != nil {
 		utils.InternalServerErrorResponse(c, "Failed to reschedule booking")
 		return
 	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "Booking was changed meanwhile, please try again")
+		return
+	}
+
+	h.pushToCalendar(booking.ID)
+	if wasActive {
+		if err := reminders.Schedule(h.DB, &booking); err != nil {
+			log.Printf("Failed to reschedule reminders for booking %d: %v", booking.ID, err)
+		}
+	} else if err := reminders.Cancel(h.DB, booking.ID); err != nil {
+		log.Printf("Failed to cancel reminders for booking %d: %v", booking.ID, err)
+	}
+	notify(h.DB, notifications.Event{Type: notifications.EventBookingRescheduled, BookingID: booking.ID, ActorUserID: userID.(uint)})
+	publishUpdate(h.DB, realtime.EventBookingRescheduled, &booking, previous)
 
 	h.DB.Preload("Client").Preload("Client.User").
 		Preload("Provider").Preload("Provider.User").
@@ -311,11 +540,22 @@ func (h *BookingHandler) RescheduleBooking(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "Booking rescheduled successfully", booking)
 }
 
-func (h *BookingHandler) isTimeSlotAvailable(providerID uint, date time.Time, startTime, endTime string) bool {
+func (h *BookingHandler) isTimeSlotAvailable(provider *models.ServiceProvider, date time.Time, startTime, endTime string) bool {
 	dayOfWeek := int(date.Weekday())
 
 	var availability models.Availability
-	if err := h.DB.Where("provider_id = ? AND day_of_week = ? AND is_available = ?", providerID, dayOfWeek, true).First(&availability).Error; err != nil {
+	if err := h.DB.Where("provider_id = ? AND day_of_week = ? AND is_available = ?", provider.ID, dayOfWeek, true).First(&availability).Error; err != nil {
+		return false
+	}
+
+	// Reject slots overlapping events imported from the provider's calendars
+	slot := models.Booking{Date: date, StartTime: startTime, EndTime: endTime}
+	loc := provider.Location()
+	var busy int64
+	h.DB.Model(&models.BusyBlock{}).
+		Where("provider_id = ? AND start_at < ? AND end_at > ?", provider.ID, slot.EndsAt(loc), slot.StartsAt(loc)).
+		Count(&busy)
+	if busy > 0 {
 		return false
 	}
 
@@ -323,3 +563,91 @@ func (h *BookingHandler) isTimeSlotAvailable(providerID uint, date time.Time, st
 	return true
 }
 
+// refundCancelled works out what goes back to the client of a booking
+// that was just cancelled: package sessions, points and gift cards are
+// credited at once and a refund of what was paid is queued.
+func (h *BookingHandler) refundCancelled(booking *models.Booking, previous models.BookingStatus, byProvider bool) {
+	// Sessions and points spent on the booking go back in full
+	if booking.Prepaid > 0 {
+		if err := credits.Restore(h.DB, booking.ID); err != nil {
+			log.Printf("Failed to restore credit for booking %d: %v", booking.ID, err)
+		}
+	}
+	if booking.RewardPoints > 0 {
+		if err := loyalty.Restore(h.DB, booking.ID); err != nil {
+			log.Printf("Failed to restore points for booking %d: %v", booking.ID, err)
+		}
+	}
+	if booking.PaymentStatus != models.PaymentPaid && booking.GiftCardAmount == 0 {
+		return
+	}
+
+	var provider models.ServiceProvider
+	if err := h.DB.First(&provider, booking.ProviderID).Error; err != nil {
+		log.Printf("Failed to load provider of booking %d: %v", booking.ID, err)
+		return
+	}
+	before := *booking
+	before.Status = previous
+	now := time.Now()
+
+	// Gift cards get back their part by the same policy as payments
+	if booking.GiftCardAmount > 0 {
+		credit, _ := payments.RefundDue(&before, &provider, booking.GiftCardAmount, byProvider, now)
+		if err := giftcards.Restore(h.DB, booking.ID, credit); err != nil {
+			log.Printf("Failed to credit gift cards for booking %d: %v", booking.ID, err)
+		}
+	}
+	if booking.PaymentStatus != models.PaymentPaid {
+		return
+	}
+
+	paid, err := payments.NewService(h.DB).Paid(booking.ID)
+	if err != nil {
+		log.Printf("Failed to total payments of booking %d: %v", booking.ID, err)
+		return
+	}
+	amount, reason := payments.RefundDue(&before, &provider, paid, byProvider, now)
+	if err := jobs.ScheduleRefund(h.DB, booking.ID, amount, reason); err != nil {
+		log.Printf("Failed to schedule refund for booking %d: %v", booking.ID, err)
+		return
+	}
+	h.DB.First(booking, booking.ID)
+}
+
+// pushToCalendar queues mirroring a booking change into the provider's
+// CalDAV calendar so it is retried if the server is unreachable.
+func (h *BookingHandler) pushToCalendar(bookingID uint) {
+	if err := jobs.Enqueue(h.DB, jobs.TypePushBookingToCalendar, jobs.BookingPayload{BookingID: bookingID}); err != nil {
+		log.Printf("Failed to queue calendar push for booking %d: %v", bookingID, err)
+	}
+}
+
+// notify queues notifications about an event. Failing to queue them is
+// logged but never fails the request.
+func notify(db *gorm.DB, event notifications.Event) {
+	if err := notifications.Publish(db, event); err != nil {
+		log.Printf("Failed to queue %s notification for booking %d: %v", event.Type, event.BookingID, err)
+	}
+}
+
+// publishUpdate streams a booking change to the provider and client and
+// sends it to the provider's webhooks.
+func publishUpdate(db *gorm.DB, eventType string, booking *models.Booking, previous models.BookingStatus) {
+	if err := realtime.Publish(db, eventType, booking, previous); err != nil {
+		log.Printf("Failed to publish %s for booking %d: %v", eventType, booking.ID, err)
+	}
+
+	webhookEvent := webhooks.StatusEvent(booking.Status)
+	switch eventType {
+	case realtime.EventBookingCreated:
+		webhookEvent = webhooks.EventBookingCreated
+	case realtime.EventBookingRescheduled:
+		webhookEvent = webhooks.EventBookingRescheduled
+	case realtime.EventBookingPaymentUpdated:
+		webhookEvent = webhooks.PaymentEvent(booking)
+	}
+	if err := jobs.PublishBookingWebhook(db, webhookEvent, booking.ID, previous); err != nil {
+		log.Printf("Failed to publish webhook for booking %d: %v", booking.ID, err)
+	}
+}
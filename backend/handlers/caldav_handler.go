@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"pluralink/backend/calendar"
+	"pluralink/backend/models"
+	"pluralink/backend/netguard"
+	"pluralink/backend/secrets"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CalDAVHandler struct {
+	DB     *gorm.DB
+	Syncer *calendar.Syncer
+}
+
+func NewCalDAVHandler(db *gorm.DB) *CalDAVHandler {
+	return &CalDAVHandler{
+		DB:     db,
+		Syncer: calendar.NewSyncer(db),
+	}
+}
+
+type ConnectCalDAVRequest struct {
+	CollectionURL string `json:"collection_url" binding:"required"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+}
+
+func (h *CalDAVHandler) GetAccount(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var acc models.CalDAVAccount
+	if err := h.DB.Where("provider_id = ?", provider.ID).First(&acc).Error; err != nil {
+		utils.NotFoundResponse(c, "CalDAV account not connected")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "CalDAV account retrieved successfully", acc)
+}
+
+// ConnectAccount creates or replaces the provider's CalDAV connection after
+// checking the collection is reachable with the given credentials.
+func (h *CalDAVHandler) ConnectAccount(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req ConnectCalDAVRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	if err := netguard.CheckURL(c.Request.Context(), req.CollectionURL); err != nil {
+		utils.BadRequestResponse(c, "Invalid collection URL: "+err.Error())
+		return
+	}
+	u, err := url.Parse(req.CollectionURL)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid collection URL")
+		return
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	client := &calendar.CalDAVClient{
+		HTTP:          h.Syncer.Client,
+		CollectionURL: u.String(),
+		Username:      req.Username,
+		Password:      req.Password,
+	}
+	if err := client.Check(c.Request.Context()); err != nil {
+		utils.BadRequestResponse(c, "Could not reach CalDAV collection: "+err.Error())
+		return
+	}
+
+	password, err := secrets.Default.Seal(req.Password)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to save CalDAV account")
+		return
+	}
+
+	var acc models.CalDAVAccount
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("provider_id = ?", provider.ID).First(&acc).Error; err == nil {
+			// Pointing at another collection invalidates everything synced so far
+			if err := calendar.ForgetCalDAVResources(tx, acc.ID); err != nil {
+				return err
+			}
+			if err := tx.Where("account_id = ?", acc.ID).Delete(&models.CalDAVBookingEvent{}).Error; err != nil {
+				return err
+			}
+		} else {
+			acc = models.CalDAVAccount{ProviderID: provider.ID}
+		}
+
+		acc.CollectionURL = client.CollectionURL
+		acc.Username = req.Username
+		acc.Password = password
+		acc.SyncToken = ""
+		acc.IsActive = true
+		acc.LastError = ""
+		return tx.Save(&acc).Error
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to save CalDAV account")
+		return
+	}
+
+	go func(acc models.CalDAVAccount) {
+		ctx := context.Background()
+		if err := h.Syncer.PushUpcoming(ctx, acc.ProviderID); err != nil {
+			log.Printf("Failed to push bookings to CalDAV account %d: %v", acc.ID, err)
+		}
+		h.Syncer.PullCalDAV(ctx, &acc)
+	}(acc)
+
+	utils.SuccessResponse(c, http.StatusOK, "CalDAV account connected successfully", acc)
+}
+
+func (h *CalDAVHandler) SyncAccount(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var acc models.CalDAVAccount
+	if err := h.DB.Where("provider_id = ?", provider.ID).First(&acc).Error; err != nil {
+		utils.NotFoundResponse(c, "CalDAV account not connected")
+		return
+	}
+
+	if err := h.Syncer.PullCalDAV(c.Request.Context(), &acc); err != nil {
+		utils.ErrorResponse(c, http.StatusBadGateway, "Failed to sync CalDAV account: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "CalDAV account synced successfully", acc)
+}
+
+// DisconnectAccount removes the connection and the busy time imported
+// through it. Events already written to the server are left in place.
+func (h *CalDAVHandler) DisconnectAccount(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var acc models.CalDAVAccount
+	if err := h.DB.Where("provider_id = ?", provider.ID).First(&acc).Error; err != nil {
+		utils.NotFoundResponse(c, "CalDAV account not connected")
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := calendar.ForgetCalDAVResources(tx, acc.ID); err != nil {
+			return err
+		}
+		if err := tx.Where("account_id = ?", acc.ID).Delete(&models.CalDAVBookingEvent{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&acc).Error
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to disconnect CalDAV account")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "CalDAV account disconnected successfully", nil)
+}
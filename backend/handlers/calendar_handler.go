@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"pluralink/backend/calendar"
+	"pluralink/backend/config"
+	"pluralink/backend/models"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CalendarHandler struct {
+	DB *gorm.DB
+}
+
+func NewCalendarHandler(db *gorm.DB) *CalendarHandler {
+	return &CalendarHandler{DB: db}
+}
+
+func (h *CalendarHandler) GetFeed(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var feed models.CalendarFeed
+	if err := h.DB.Where("user_id = ?", userID).First(&feed).Error; err != nil {
+		utils.NotFoundResponse(c, "Calendar feed not enabled")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Calendar feed retrieved successfully", feedResponse(feed))
+}
+
+// RegenerateFeed enables the feed or replaces its token, which invalidates
+// any URL handed out before.
+func (h *CalendarHandler) RegenerateFeed(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	token, err := utils.GenerateToken(32)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to generate token")
+		return
+	}
+
+	var feed models.CalendarFeed
+	if err := h.DB.Where("user_id = ?", userID).First(&feed).Error; err != nil {
+		feed = models.CalendarFeed{UserID: userID.(uint)}
+	}
+	feed.Token = token
+
+	if err := h.DB.Save(&feed).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to save calendar feed")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Calendar feed generated successfully", feedResponse(feed))
+}
+
+func (h *CalendarHandler) RevokeFeed(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := h.DB.Where("user_id = ?", userID).Delete(&models.CalendarFeed{}).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to revoke calendar feed")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Calendar feed revoked successfully", nil)
+}
+
+// ServeFeed is the public subscription endpoint. The token in the URL is the
+// only credential, since calendar apps cannot send bearer tokens.
+func (h *CalendarHandler) ServeFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	var feed models.CalendarFeed
+	if err := h.DB.Preload("User").Where("token = ?", token).First(&feed).Error; err != nil {
+		c.String(http.StatusNotFound, "calendar not found")
+		return
+	}
+
+	if !feed.User.IsActive {
+		c.String(http.StatusNotFound, "calendar not found")
+		return
+	}
+
+	query := h.DB.Preload("Client").Preload("Client.User").
+		Preload("Provider").
+		Preload("Service")
+
+	cal := calendar.Calendar{Method: "PUBLISH", Name: "Pluralink bookings"}
+	forProvider := feed.User.Role == models.RoleProvider
+	if forProvider {
+		var provider models.ServiceProvider
+		if err := h.DB.Where("user_id = ?", feed.UserID).First(&provider).Error; err != nil {
+			c.String(http.StatusNotFound, "calendar not found")
+			return
+		}
+		query = query.Where("provider_id = ?", provider.ID)
+		cal.Name = provider.BusinessName
+		cal.TimeZone = provider.Location().String()
+	} else {
+		var client models.Client
+		if err := h.DB.Where("user_id = ?", feed.UserID).First(&client).Error; err != nil {
+			c.String(http.StatusNotFound, "calendar not found")
+			return
+		}
+		query = query.Where("client_id = ?", client.ID)
+	}
+
+	var bookings []models.Booking
+	if err := query.Order("date ASC, start_time ASC").Find(&bookings).Error; err != nil {
+		c.String(http.StatusInternalServerError, "failed to load bookings")
+		return
+	}
+
+	for i := range bookings {
+		cal.Events = append(cal.Events, calendar.BookingEvent(&bookings[i], forProvider))
+	}
+
+	c.Header("Content-Disposition", `inline; filename="pluralink.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(cal.String()))
+}
+
+func feedResponse(feed models.CalendarFeed) gin.H {
+	return gin.H{
+		"url":        strings.TrimRight(config.AppConfig.PublicBaseURL, "/") + "/api/calendar/feed/" + feed.Token + ".ics",
+		"created_at": feed.CreatedAt,
+		"updated_at": feed.UpdatedAt,
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type EarningsHandler struct {
+	DB *gorm.DB
+}
+
+func NewEarningsHandler(db *gorm.DB) *EarningsHandler {
+	return &EarningsHandler{DB: db}
+}
+
+// EarningsResponse is what a provider took through the app over a period,
+// in minor units. Services paid outside the app are not included.
+type EarningsResponse struct {
+	From      time.Time    `json:"from"`
+	To        time.Time    `json:"to"`
+	Currency  string       `json:"currency"`
+	Collected int64        `json:"collected"` // Deposits and prepayments
+	Refunded  int64        `json:"refunded"`
+	Tips      int64        `json:"tips"`
+	Net       int64        `json:"net"`
+	TipList   []TipEarning `json:"tip_list"`
+}
+
+// TipEarning is one tip a provider received.
+type TipEarning struct {
+	BookingID   uint      `json:"booking_id"`
+	PaymentID   uint      `json:"payment_id"`
+	ServiceName string    `json:"service_name"`
+	ClientName  string    `json:"client_name"`
+	Amount      int64     `json:"amount"`
+	TippedAt    time.Time `json:"tipped_at"`
+}
+
+// GetEarnings sums up what the provider took between from and to (RFC
+// 3339), defaulting to the current month so far.
+func (h *EarningsHandler) GetEarnings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var provider models.ServiceProvider
+	if err := h.DB.Where("user_id = ?", userID).First(&provider).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider profile not found")
+		return
+	}
+
+	to := time.Now().In(provider.Location())
+	from := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, to.Location())
+	from, to, ok := queryPeriod(c, from, to)
+	if !ok {
+		return
+	}
+
+	resp := EarningsResponse{From: from, To: to, Currency: provider.Currency, TipList: []TipEarning{}}
+
+	succeeded := h.DB.Table("payments").
+		Joins("JOIN bookings ON bookings.id = payments.booking_id").
+		Where("bookings.provider_id = ? AND payments.status = ? AND payments.succeeded_at >= ? AND payments.succeeded_at < ?",
+			provider.ID, models.PaymentSucceeded, from, to).
+		Session(&gorm.Session{})
+	if err := succeeded.Where("payments.kind <> ?", models.PaymentTip).
+		Select("COALESCE(SUM(payments.amount), 0)").Row().Scan(&resp.Collected); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch earnings")
+		return
+	}
+	if err := h.DB.Table("refunds").
+		Joins("JOIN bookings ON bookings.id = refunds.booking_id").
+		Where("bookings.provider_id = ? AND refunds.status = ? AND refunds.updated_at >= ? AND refunds.updated_at < ?",
+			provider.ID, models.RefundStatusSucceeded, from, to).
+		Select("COALESCE(SUM(refunds.amount), 0)").Row().Scan(&resp.Refunded); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch earnings")
+		return
+	}
+
+	var tips []struct {
+		TipEarning
+		FirstName string
+		LastName  string
+	}
+	if err := succeeded.Where("payments.kind = ?", models.PaymentTip).
+		Joins("JOIN services ON services.id = bookings.service_id").
+		Joins("JOIN clients ON clients.id = bookings.client_id").
+		Joins("JOIN users ON users.id = clients.user_id").
+		Select("payments.booking_id, payments.id AS payment_id, services.name AS service_name, " +
+			"users.first_name, users.last_name, payments.amount, payments.succeeded_at AS tipped_at").
+		Order("payments.succeeded_at DESC").
+		Scan(&tips).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch tips")
+		return
+	}
+	for _, t := range tips {
+		tip := t.TipEarning
+		tip.ClientName = strings.TrimSpace(t.FirstName + " " + t.LastName)
+		resp.Tips += tip.Amount
+		resp.TipList = append(resp.TipList, tip)
+	}
+	resp.Net = resp.Collected + resp.Tips - resp.Refunded
+
+	utils.SuccessResponse(c, http.StatusOK, "Earnings retrieved successfully", resp)
+}
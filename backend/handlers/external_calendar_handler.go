@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"pluralink/backend/calendar"
+	"pluralink/backend/models"
+	"pluralink/backend/netguard"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ExternalCalendarHandler struct {
+	DB     *gorm.DB
+	Syncer *calendar.Syncer
+}
+
+func NewExternalCalendarHandler(db *gorm.DB) *ExternalCalendarHandler {
+	return &ExternalCalendarHandler{
+		DB:     db,
+		Syncer: calendar.NewSyncer(db),
+	}
+}
+
+type CreateExternalCalendarRequest struct {
+	Name string `json:"name"`
+	URL  string `json:"url" binding:"required"`
+}
+
+func (h *ExternalCalendarHandler) GetExternalCalendars(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var provider models.ServiceProvider
+	if err := h.DB.Where("user_id = ?", userID).First(&provider).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider profile not found")
+		return
+	}
+
+	var calendars []models.ExternalCalendar
+	if err := h.DB.Where("provider_id = ?", provider.ID).Order("created_at ASC").Find(&calendars).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch external calendars")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "External calendars retrieved successfully", calendars)
+}
+
+func (h *ExternalCalendarHandler) CreateExternalCalendar(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var provider models.ServiceProvider
+	if err := h.DB.Where("user_id = ?", userID).First(&provider).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider profile not found")
+		return
+	}
+
+	var req CreateExternalCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || u.Host == "" {
+		utils.BadRequestResponse(c, "Invalid calendar URL")
+		return
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "webcal":
+	default:
+		utils.BadRequestResponse(c, "Calendar URL must use http, https or webcal")
+		return
+	}
+	if err := netguard.CheckURL(c.Request.Context(), calendar.FeedURL(req.URL)); err != nil {
+		utils.BadRequestResponse(c, "Invalid calendar URL: "+err.Error())
+		return
+	}
+
+	cal := models.ExternalCalendar{
+		ProviderID: provider.ID,
+		Name:       req.Name,
+		URL:        req.URL,
+		IsActive:   true,
+	}
+
+	if err := h.DB.Create(&cal).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create external calendar")
+		return
+	}
+
+	// Import right away so the provider sees whether the URL works
+	h.Syncer.Sync(c.Request.Context(), &cal)
+
+	utils.SuccessResponse(c, http.StatusCreated, "External calendar created successfully", cal)
+}
+
+func (h *ExternalCalendarHandler) SyncExternalCalendar(c *gin.Context) {
+	cal, ok := h.findOwnCalendar(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Syncer.Sync(c.Request.Context(), &cal); err != nil {
+		utils.ErrorResponse(c, http.StatusBadGateway, "Failed to sync calendar: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "External calendar synced successfully", cal)
+}
+
+func (h *ExternalCalendarHandler) DeleteExternalCalendar(c *gin.Context) {
+	cal, ok := h.findOwnCalendar(c)
+	if !ok {
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source = ? AND source_id = ?", models.BusySourceICS, cal.ID).Delete(&models.BusyBlock{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&cal).Error
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete external calendar")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "External calendar deleted successfully", nil)
+}
+
+// GetBusyBlocks lists the provider's imported busy time, defaulting to the
+// next 30 days.
+func (h *ExternalCalendarHandler) GetBusyBlocks(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var provider models.ServiceProvider
+	if err := h.DB.Where("user_id = ?", userID).First(&provider).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider profile not found")
+		return
+	}
+
+	from := time.Now()
+	to := from.AddDate(0, 0, 30)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid from. Use RFC 3339")
+			return
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid to. Use RFC 3339")
+			return
+		}
+		to = t
+	}
+
+	var blocks []models.BusyBlock
+	if err := h.DB.Where("provider_id = ? AND start_at < ? AND end_at > ?", provider.ID, to, from).
+		Order("start_at ASC").
+		Find(&blocks).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch busy blocks")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Busy blocks retrieved successfully", blocks)
+}
+
+func (h *ExternalCalendarHandler) findOwnCalendar(c *gin.Context) (models.ExternalCalendar, bool) {
+	id := c.Param("id")
+	userID, _ := c.Get("user_id")
+
+	var cal models.ExternalCalendar
+	var provider models.ServiceProvider
+	if err := h.DB.Where("user_id = ?", userID).First(&provider).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider profile not found")
+		return cal, false
+	}
+
+	if err := h.DB.Where("id = ? AND provider_id = ?", id, provider.ID).First(&cal).Error; err != nil {
+		utils.NotFoundResponse(c, "External calendar not found")
+		return cal, false
+	}
+
+	return cal, true
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"pluralink/backend/giftcards"
+	"pluralink/backend/models"
+	"pluralink/backend/payments"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type GiftCardHandler struct {
+	DB       *gorm.DB
+	Payments *payments.Service
+}
+
+func NewGiftCardHandler(db *gorm.DB) *GiftCardHandler {
+	return &GiftCardHandler{DB: db, Payments: payments.NewService(db)}
+}
+
+// IssueGiftCardRequest is a gift card a provider sold outside the app.
+type IssueGiftCardRequest struct {
+	Amount         int64  `json:"amount" binding:"required,min=1"` // In minor units of the provider's currency
+	RecipientName  string `json:"recipient_name"`
+	RecipientEmail string `json:"recipient_email"`
+	Message        string `json:"message"`
+}
+
+// PurchaseGiftCardRequest is a gift card a client buys in the app.
+type PurchaseGiftCardRequest struct {
+	ProviderID     uint   `json:"provider_id" binding:"required"`
+	Amount         int64  `json:"amount" binding:"required,min=1"` // In minor units of the provider's currency
+	RecipientName  string `json:"recipient_name"`
+	RecipientEmail string `json:"recipient_email"`
+	Message        string `json:"message"`
+}
+
+type GiftCardBalanceRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// GiftCardPurchaseResponse is a gift card being bought, with what the app
+// needs to collect its payment through the gateway. The code is only shown
+// once it is paid.
+type GiftCardPurchaseResponse struct {
+	models.GiftCard
+	ClientSecret   string `json:"client_secret,omitempty"`
+	PublishableKey string `json:"publishable_key,omitempty"`
+}
+
+// GiftCardBalance is what anyone holding a code can learn about it.
+type GiftCardBalance struct {
+	Code         string                `json:"code"` // Masked
+	ProviderID   uint                  `json:"provider_id"`
+	ProviderName string                `json:"provider_name"`
+	Status       models.GiftCardStatus `json:"status"`
+	Balance      int64                 `json:"balance"` // In minor units of Currency
+	Currency     string                `json:"currency"`
+}
+
+// GetGiftCards lists the provider's gift cards, newest first.
+func (h *GiftCardHandler) GetGiftCards(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	query := h.DB.Where("provider_id = ?", provider.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var cards []models.GiftCard
+	if err := query.Order("created_at DESC").Find(&cards).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch gift cards")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Gift cards retrieved successfully", cards)
+}
+
+// GetGiftCard returns one of the provider's gift cards with its ledger.
+func (h *GiftCardHandler) GetGiftCard(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var card models.GiftCard
+	if err := h.DB.Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("id = ? AND provider_id = ?", c.Param("id"), provider.ID).First(&card).Error; err != nil {
+		utils.NotFoundResponse(c, "Gift card not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Gift card retrieved successfully", card)
+}
+
+// IssueGiftCard records a gift card the provider sold and was paid for
+// outside the app. It is active at once.
+func (h *GiftCardHandler) IssueGiftCard(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req IssueGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	code, err := giftcards.NewCode()
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to issue gift card")
+		return
+	}
+	card := models.GiftCard{
+		ProviderID:     provider.ID,
+		Code:           code,
+		Amount:         req.Amount,
+		Currency:       provider.Currency,
+		Status:         models.GiftCardPending,
+		RecipientName:  req.RecipientName,
+		RecipientEmail: req.RecipientEmail,
+		Message:        req.Message,
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&card).Error; err != nil {
+			return err
+		}
+		return giftcards.Activate(tx, &card)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to issue gift card")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Gift card issued successfully", card)
+}
+
+// PurchaseGiftCard opens the payment of a gift card a client buys from a
+// provider. The card becomes active once the payment goes through.
+func (h *GiftCardHandler) PurchaseGiftCard(c *gin.Context) {
+	client, ok := currentClient(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req PurchaseGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	var provider models.ServiceProvider
+	if err := h.DB.First(&provider, req.ProviderID).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider not found")
+		return
+	}
+
+	code, err := giftcards.NewCode()
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create gift card")
+		return
+	}
+	card := models.GiftCard{
+		ProviderID:     provider.ID,
+		Code:           code,
+		Amount:         req.Amount,
+		Currency:       provider.Currency,
+		Status:         models.GiftCardPending,
+		PurchaserID:    &client.ID,
+		RecipientName:  req.RecipientName,
+		RecipientEmail: req.RecipientEmail,
+		Message:        req.Message,
+	}
+	if err := h.DB.Create(&card).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create gift card")
+		return
+	}
+
+	if err := h.Payments.StartGiftCard(c.Request.Context(), &card, provider.BusinessName); err != nil {
+		log.Printf("Failed to start payment for gift card %d: %v", card.ID, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Gift card payment started successfully", h.purchase(card))
+}
+
+// ConfirmGiftCardPurchase charges a payment method for a gift card the
+// client is buying.
+func (h *GiftCardHandler) ConfirmGiftCardPurchase(c *gin.Context) {
+	var req ConfirmPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	card, ok := h.findPurchasedCard(c)
+	if !ok {
+		return
+	}
+	if card.Status != models.GiftCardPending {
+		utils.BadRequestResponse(c, "Gift card is not waiting for payment")
+		return
+	}
+
+	if card.IntentID == nil {
+		var provider models.ServiceProvider
+		h.DB.First(&provider, card.ProviderID)
+		if err := h.Payments.StartGiftCard(c.Request.Context(), &card, provider.BusinessName); err != nil {
+			log.Printf("Failed to start payment for gift card %d: %v", card.ID, err)
+			utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+			return
+		}
+	}
+	if err := h.Payments.ConfirmGiftCard(c.Request.Context(), &card, req.PaymentMethod); err != nil {
+		log.Printf("Failed to confirm payment of gift card %d: %v", card.ID, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "Payment could not be processed, try again later")
+		return
+	}
+
+	if card.Status == models.GiftCardPending && card.FailureMessage != "" {
+		utils.ErrorResponse(c, http.StatusPaymentRequired, card.FailureMessage)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Gift card purchased successfully", h.purchase(card))
+}
+
+// RefreshGiftCardPurchase reads a gift card's payment back from the
+// gateway, for payments the app completed directly with it.
+func (h *GiftCardHandler) RefreshGiftCardPurchase(c *gin.Context) {
+	card, ok := h.findPurchasedCard(c)
+	if !ok {
+		return
+	}
+	if card.Status == models.GiftCardPending && card.IntentID != nil {
+		if err := h.Payments.RefreshGiftCard(c.Request.Context(), &card); err != nil {
+			log.Printf("Failed to refresh payment of gift card %d: %v", card.ID, err)
+			utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+			return
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Gift card refreshed successfully", h.purchase(card))
+}
+
+// GetPurchasedGiftCards lists the gift cards the client bought in the app.
+func (h *GiftCardHandler) GetPurchasedGiftCards(c *gin.Context) {
+	client, ok := currentClient(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var cards []models.GiftCard
+	if err := h.DB.Where("purchaser_id = ?", client.ID).Order("created_at DESC").Find(&cards).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch gift cards")
+		return
+	}
+	for i := range cards {
+		if cards[i].Status != models.GiftCardActive {
+			cards[i].Code = ""
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Gift cards retrieved successfully", cards)
+}
+
+// GetGiftCardBalance tells whoever holds a code what is left on it. The
+// code is posted rather than put in the URL so it stays out of logs.
+func (h *GiftCardHandler) GetGiftCardBalance(c *gin.Context) {
+	var req GiftCardBalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	card, err := giftcards.Find(h.DB, req.Code)
+	if errors.Is(err, giftcards.ErrNotFound) || (err == nil && card.Status == models.GiftCardPending) {
+		utils.NotFoundResponse(c, "Gift card not found")
+		return
+	}
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch gift card")
+		return
+	}
+	var provider models.ServiceProvider
+	h.DB.First(&provider, card.ProviderID)
+
+	utils.SuccessResponse(c, http.StatusOK, "Gift card balance retrieved successfully", GiftCardBalance{
+		Code:         giftcards.Mask(card.Code),
+		ProviderID:   card.ProviderID,
+		ProviderName: provider.BusinessName,
+		Status:       card.Status,
+		Balance:      card.Balance,
+		Currency:     card.Currency,
+	})
+}
+
+// purchase describes a card being bought to its purchaser, hiding its code
+// until it is paid.
+func (h *GiftCardHandler) purchase(card models.GiftCard) GiftCardPurchaseResponse {
+	resp := GiftCardPurchaseResponse{GiftCard: card}
+	if card.Status != models.GiftCardActive {
+		resp.Code = ""
+	}
+	if card.Status == models.GiftCardPending {
+		resp.ClientSecret = card.ClientSecret
+		resp.PublishableKey = h.Payments.Gateway.PublishableKey()
+	}
+	return resp
+}
+
+func (h *GiftCardHandler) findPurchasedCard(c *gin.Context) (models.GiftCard, bool) {
+	var card models.GiftCard
+	client, ok := currentClient(h.DB, c)
+	if !ok {
+		return card, false
+	}
+
+	if err := h.DB.Where("id = ? AND purchaser_id = ?", c.Param("id"), client.ID).First(&card).Error; err != nil {
+		utils.NotFoundResponse(c, "Gift card not found")
+		return card, false
+	}
+	return card, true
+}
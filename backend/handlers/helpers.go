@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// currentProvider loads the provider profile of the signed-in user,
+// answering 404 if they have none.
+func currentProvider(db *gorm.DB, c *gin.Context) (models.ServiceProvider, bool) {
+	userID, _ := c.Get("user_id")
+
+	var provider models.ServiceProvider
+	if err := db.Where("user_id = ?", userID).First(&provider).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider profile not found")
+		return provider, false
+	}
+	return provider, true
+}
+
+// currentClient loads the client profile of the signed-in user, answering
+// 404 if they have none.
+func currentClient(db *gorm.DB, c *gin.Context) (models.Client, bool) {
+	userID, _ := c.Get("user_id")
+
+	var client models.Client
+	if err := db.Where("user_id = ?", userID).First(&client).Error; err != nil {
+		utils.NotFoundResponse(c, "Client profile not found")
+		return client, false
+	}
+	return client, true
+}
+
+// queryPeriod reads the period a report covers from the from and to query
+// parameters (RFC 3339), keeping the defaults given for those left out. It
+// answers 400 if either is invalid or they are out of order.
+func queryPeriod(c *gin.Context, from, to time.Time) (time.Time, time.Time, bool) {
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid from. Use RFC 3339")
+			return from, to, false
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid to. Use RFC 3339")
+			return from, to, false
+		}
+		to = t
+	}
+	if !from.Before(to) {
+		utils.BadRequestResponse(c, "from must be before to")
+		return from, to, false
+	}
+	return from, to, true
+}
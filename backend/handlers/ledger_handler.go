@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"pluralink/backend/ledger"
+	"pluralink/backend/models"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LedgerHandler struct {
+	DB *gorm.DB
+}
+
+func NewLedgerHandler(db *gorm.DB) *LedgerHandler {
+	return &LedgerHandler{DB: db}
+}
+
+// GetLedgerBalance returns what the platform owes the provider, by
+// currency.
+func (h *LedgerHandler) GetLedgerBalance(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	balances, err := ledger.Balances(h.DB, provider.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch balance")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Balance retrieved successfully", balances)
+}
+
+// GetLedgerStatement returns the provider's statement between from and to
+// (RFC 3339), defaulting to the current month so far, in their currency
+// unless another is asked for. It is a CSV unless format=json is asked
+// for.
+func (h *LedgerHandler) GetLedgerStatement(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	to := time.Now().In(provider.Location())
+	from := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, to.Location())
+	from, to, ok = queryPeriod(c, from, to)
+	if !ok {
+		return
+	}
+	currency := provider.Currency
+	if v := c.Query("currency"); v != "" {
+		currency = strings.ToLower(v)
+	}
+
+	statement, err := ledger.PeriodStatement(h.DB, provider.ID, currency, from, to)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to build statement")
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%s-%s.csv", from.Format("20060102"), to.Format("20060102"))
+	h.respond(c, statement, filename)
+}
+
+// GetPayouts lists the provider's payouts, newest first.
+func (h *LedgerHandler) GetPayouts(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var payouts []models.Payout
+	if err := h.DB.Preload("Batch").Where("provider_id = ?", provider.ID).
+		Order("created_at DESC").Find(&payouts).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch payouts")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Payouts retrieved successfully", payouts)
+}
+
+// GetPayoutStatement returns what one of the provider's payouts settled.
+// It is a CSV unless format=json is asked for.
+func (h *LedgerHandler) GetPayoutStatement(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var payout models.Payout
+	if err := h.DB.Where("id = ? AND provider_id = ?", c.Param("id"), provider.ID).First(&payout).Error; err != nil {
+		utils.NotFoundResponse(c, "Payout not found")
+		return
+	}
+	statement, err := ledger.PayoutStatement(h.DB, &payout)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to build statement")
+		return
+	}
+
+	h.respond(c, statement, fmt.Sprintf("payout-%d.csv", payout.ID))
+}
+
+func (h *LedgerHandler) respond(c *gin.Context, statement *ledger.Statement, filename string) {
+	if c.Query("format") == "json" {
+		utils.SuccessResponse(c, http.StatusOK, "Statement retrieved successfully", statement)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", statement.CSV())
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"pluralink/backend/loyalty"
+	"pluralink/backend/models"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LoyaltyHandler struct {
+	DB *gorm.DB
+}
+
+func NewLoyaltyHandler(db *gorm.DB) *LoyaltyHandler {
+	return &LoyaltyHandler{DB: db}
+}
+
+type LoyaltyProgramRequest struct {
+	IsActive         *bool `json:"is_active"` // Defaults to true
+	PointsPerBooking int64 `json:"points_per_booking"`
+	PointsPerUnit    int64 `json:"points_per_unit"`
+}
+
+// LoyaltyRewardRequest creates a reward or replaces one's settings.
+type LoyaltyRewardRequest struct {
+	Name      string                   `json:"name" binding:"required"`
+	Points    int64                    `json:"points" binding:"required,min=1"`
+	Kind      models.LoyaltyRewardKind `json:"kind" binding:"required"`
+	ServiceID *uint                    `json:"service_id"` // For free_service
+	Amount    int64                    `json:"amount"`     // For discount, in minor units of the provider's currency
+	IsActive  *bool                    `json:"is_active"`  // Defaults to true
+}
+
+// LoyaltyStatus is a client's standing with a provider's program.
+type LoyaltyStatus struct {
+	Program *models.LoyaltyProgram `json:"program"`
+	Account models.LoyaltyAccount  `json:"account"`
+}
+
+// GetProviderLoyalty shows a provider's program and the rewards on offer,
+// to anyone.
+func (h *LoyaltyHandler) GetProviderLoyalty(c *gin.Context) {
+	var program models.LoyaltyProgram
+	if err := h.DB.Preload("Rewards", "is_active = ?", true).Preload("Rewards.Service").
+		Where("provider_id = ? AND is_active = ?", c.Param("id"), true).First(&program).Error; err != nil {
+		utils.NotFoundResponse(c, "Loyalty program not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Loyalty program retrieved successfully", program)
+}
+
+// GetLoyaltyProgram returns the provider's program with all its rewards.
+func (h *LoyaltyHandler) GetLoyaltyProgram(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var program models.LoyaltyProgram
+	if err := h.DB.Preload("Rewards").Preload("Rewards.Service").
+		Where("provider_id = ?", provider.ID).First(&program).Error; err != nil {
+		utils.NotFoundResponse(c, "Loyalty program not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Loyalty program retrieved successfully", program)
+}
+
+// UpdateLoyaltyProgram sets up the provider's program or changes how
+// points are earned. Points already earned are kept.
+func (h *LoyaltyHandler) UpdateLoyaltyProgram(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req LoyaltyProgramRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	if req.PointsPerBooking < 0 || req.PointsPerUnit < 0 {
+		utils.BadRequestResponse(c, "Points cannot be negative")
+		return
+	}
+	if req.PointsPerBooking == 0 && req.PointsPerUnit == 0 {
+		utils.BadRequestResponse(c, "Set points per booking, per unit spent or both")
+		return
+	}
+
+	program, err := loyalty.ProgramFor(h.DB, provider.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update loyalty program")
+		return
+	}
+	if program == nil {
+		program = &models.LoyaltyProgram{ProviderID: provider.ID}
+	}
+	program.PointsPerBooking = req.PointsPerBooking
+	program.PointsPerUnit = req.PointsPerUnit
+	program.IsActive = req.IsActive == nil || *req.IsActive
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Rewards").Save(program).Error; err != nil {
+			return err
+		}
+		// Creating leaves false to the column's default
+		if !program.IsActive {
+			return tx.Model(program).Update("is_active", false).Error
+		}
+		return nil
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update loyalty program")
+		return
+	}
+
+	h.DB.Preload("Rewards").Preload("Rewards.Service").First(program, program.ID)
+	utils.SuccessResponse(c, http.StatusOK, "Loyalty program updated successfully", program)
+}
+
+func (h *LoyaltyHandler) CreateLoyaltyReward(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req LoyaltyRewardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	reward := models.LoyaltyReward{ProviderID: provider.ID}
+	if msg := h.applyRewardRequest(&provider, &reward, &req); msg != "" {
+		utils.BadRequestResponse(c, msg)
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Service").Create(&reward).Error; err != nil {
+			return err
+		}
+		// Create leaves false to the column's default
+		if !reward.IsActive {
+			return tx.Model(&reward).Update("is_active", false).Error
+		}
+		return nil
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create reward")
+		return
+	}
+
+	h.DB.Preload("Service").First(&reward, reward.ID)
+	utils.SuccessResponse(c, http.StatusCreated, "Reward created successfully", reward)
+}
+
+// UpdateLoyaltyReward replaces a reward's settings. Bookings it was
+// already redeemed for keep their discount.
+func (h *LoyaltyHandler) UpdateLoyaltyReward(c *gin.Context) {
+	reward, provider, ok := h.findOwnReward(c)
+	if !ok {
+		return
+	}
+
+	var req LoyaltyRewardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	if msg := h.applyRewardRequest(&provider, &reward, &req); msg != "" {
+		utils.BadRequestResponse(c, msg)
+		return
+	}
+
+	if err := h.DB.Omit("Service").Save(&reward).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update reward")
+		return
+	}
+
+	h.DB.Preload("Service").First(&reward, reward.ID)
+	utils.SuccessResponse(c, http.StatusOK, "Reward updated successfully", reward)
+}
+
+// DeleteLoyaltyReward withdraws a reward. Points spent on it are kept.
+func (h *LoyaltyHandler) DeleteLoyaltyReward(c *gin.Context) {
+	reward, _, ok := h.findOwnReward(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(&reward).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete reward")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Reward deleted successfully", nil)
+}
+
+// GetLoyaltyMembers lists the clients holding points with the provider,
+// highest balance first.
+func (h *LoyaltyHandler) GetLoyaltyMembers(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var accounts []models.LoyaltyAccount
+	if err := h.DB.Preload("Client").Preload("Client.User").
+		Where("provider_id = ?", provider.ID).
+		Order("balance DESC").Find(&accounts).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch members")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Members retrieved successfully", accounts)
+}
+
+// GetLoyaltyAccounts lists the client's points with each provider.
+func (h *LoyaltyHandler) GetLoyaltyAccounts(c *gin.Context) {
+	client, ok := currentClient(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var accounts []models.LoyaltyAccount
+	if err := h.DB.Preload("Provider").Where("client_id = ?", client.ID).
+		Order("updated_at DESC").Find(&accounts).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch loyalty points")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Loyalty points retrieved successfully", accounts)
+}
+
+// GetLoyaltyAccount returns the client's points with a provider, with
+// their history and the rewards they can be spent on.
+func (h *LoyaltyHandler) GetLoyaltyAccount(c *gin.Context) {
+	client, ok := currentClient(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var status LoyaltyStatus
+	var program models.LoyaltyProgram
+	err := h.DB.Preload("Rewards", "is_active = ?", true).Preload("Rewards.Service").
+		Where("provider_id = ? AND is_active = ?", c.Param("provider_id"), true).First(&program).Error
+	if err == nil {
+		status.Program = &program
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.InternalServerErrorResponse(c, "Failed to fetch loyalty points")
+		return
+	}
+
+	err = h.DB.Preload("Provider").Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("id DESC") }).
+		Where("provider_id = ? AND client_id = ?", c.Param("provider_id"), client.ID).First(&status.Account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if status.Program == nil {
+			utils.NotFoundResponse(c, "Loyalty program not found")
+			return
+		}
+		status.Account = models.LoyaltyAccount{ProviderID: program.ProviderID, ClientID: client.ID}
+	} else if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch loyalty points")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Loyalty points retrieved successfully", status)
+}
+
+// applyRewardRequest validates a request and sets it on reward. It returns
+// a message for the client if the request is invalid.
+func (h *LoyaltyHandler) applyRewardRequest(provider *models.ServiceProvider, reward *models.LoyaltyReward, req *LoyaltyRewardRequest) string {
+	reward.ServiceID, reward.Amount = nil, 0
+	switch req.Kind {
+	case models.RewardFreeService:
+		if req.ServiceID == nil {
+			return "service_id is required for a free service"
+		}
+		var service models.Service
+		if err := h.DB.Where("id = ? AND provider_id = ?", *req.ServiceID, provider.ID).First(&service).Error; err != nil {
+			return "Service not found"
+		}
+		reward.ServiceID = &service.ID
+	case models.RewardDiscount:
+		if req.Amount < 1 {
+			return "Amount off must be positive"
+		}
+		reward.Amount = req.Amount
+	default:
+		return "Invalid kind. Use free_service or discount"
+	}
+
+	reward.Name = req.Name
+	reward.Points = req.Points
+	reward.Kind = req.Kind
+	reward.IsActive = req.IsActive == nil || *req.IsActive
+	return ""
+}
+
+func (h *LoyaltyHandler) findOwnReward(c *gin.Context) (models.LoyaltyReward, models.ServiceProvider, bool) {
+	var reward models.LoyaltyReward
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return reward, provider, false
+	}
+
+	if err := h.DB.Where("id = ? AND provider_id = ?", c.Param("id"), provider.ID).First(&reward).Error; err != nil {
+		utils.NotFoundResponse(c, "Reward not found")
+		return reward, provider, false
+	}
+	return reward, provider, true
+}
@@ -0,0 +1,483 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/payments"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type MembershipHandler struct {
+	DB       *gorm.DB
+	Payments *payments.Service
+}
+
+func NewMembershipHandler(db *gorm.DB) *MembershipHandler {
+	return &MembershipHandler{DB: db, Payments: payments.NewService(db)}
+}
+
+// MembershipPlanRequest creates a plan or replaces one's settings.
+type MembershipPlanRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Price       int64  `json:"price" binding:"required,min=1"` // Each month, in minor units of the provider's currency
+	Allowance   int    `json:"allowance"`                      // Sessions included each month
+	Discount    int    `json:"discount"`                       // Percent off bookings beyond the allowance
+	ServiceIDs  []uint `json:"service_ids"`                    // None for every service
+	IsActive    *bool  `json:"is_active"`                      // Defaults to true
+}
+
+type JoinMembershipRequest struct {
+	PlanID uint `json:"plan_id" binding:"required"`
+}
+
+// MembershipPaymentResponse is a month of a membership being paid for,
+// with what the app needs to collect it through the gateway.
+type MembershipPaymentResponse struct {
+	models.MembershipPayment
+	ClientSecret   string `json:"client_secret,omitempty"`
+	PublishableKey string `json:"publishable_key,omitempty"`
+}
+
+// MembershipCheckout is a membership with the payment it is waiting for,
+// if any.
+type MembershipCheckout struct {
+	Membership models.Membership          `json:"membership"`
+	Payment    *MembershipPaymentResponse `json:"payment"`
+}
+
+// GetProviderMembershipPlans lists the plans a provider offers, to anyone.
+func (h *MembershipHandler) GetProviderMembershipPlans(c *gin.Context) {
+	var plans []models.MembershipPlan
+	if err := h.DB.Preload("Services").Where("provider_id = ? AND is_active = ?", c.Param("id"), true).
+		Order("price").Find(&plans).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch membership plans")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Membership plans retrieved successfully", plans)
+}
+
+// GetMembershipPlans lists all of the provider's plans.
+func (h *MembershipHandler) GetMembershipPlans(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var plans []models.MembershipPlan
+	if err := h.DB.Preload("Services").Where("provider_id = ?", provider.ID).
+		Order("created_at DESC").Find(&plans).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch membership plans")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Membership plans retrieved successfully", plans)
+}
+
+func (h *MembershipHandler) CreateMembershipPlan(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req MembershipPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	plan := models.MembershipPlan{ProviderID: provider.ID}
+	services, msg := h.applyRequest(&provider, &plan, &req)
+	if msg != "" {
+		utils.BadRequestResponse(c, msg)
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Services").Create(&plan).Error; err != nil {
+			return err
+		}
+		// Create leaves false to the column's default
+		if !plan.IsActive {
+			if err := tx.Model(&plan).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&plan).Association("Services").Replace(services)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create membership plan")
+		return
+	}
+
+	h.DB.Preload("Services").First(&plan, plan.ID)
+	utils.SuccessResponse(c, http.StatusCreated, "Membership plan created successfully", plan)
+}
+
+// UpdateMembershipPlan replaces a plan's settings. Members pay the new
+// price from their next month, when they also get the new allowance and
+// discount.
+func (h *MembershipHandler) UpdateMembershipPlan(c *gin.Context) {
+	plan, provider, ok := h.findOwnPlan(c)
+	if !ok {
+		return
+	}
+
+	var req MembershipPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	services, msg := h.applyRequest(&provider, &plan, &req)
+	if msg != "" {
+		utils.BadRequestResponse(c, msg)
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Services").Save(&plan).Error; err != nil {
+			return err
+		}
+		return tx.Model(&plan).Association("Services").Replace(services)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update membership plan")
+		return
+	}
+
+	h.DB.Preload("Services").First(&plan, plan.ID)
+	utils.SuccessResponse(c, http.StatusOK, "Membership plan updated successfully", plan)
+}
+
+// DeleteMembershipPlan closes a plan to new members. Current members keep
+// it until they cancel.
+func (h *MembershipHandler) DeleteMembershipPlan(c *gin.Context) {
+	plan, _, ok := h.findOwnPlan(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(&plan).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete membership plan")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Membership plan deleted successfully", nil)
+}
+
+// GetMembers lists the provider's memberships, newest first. Filter with
+// ?status=.
+func (h *MembershipHandler) GetMembers(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	query := h.DB.Where("provider_id = ?", provider.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var memberships []models.Membership
+	if err := query.Preload("Plan", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Client").Preload("Client.User").
+		Order("created_at DESC").Find(&memberships).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch members")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Members retrieved successfully", memberships)
+}
+
+// JoinMembership signs the client up to a plan and opens the payment of
+// its first month. The membership starts once that is paid.
+func (h *MembershipHandler) JoinMembership(c *gin.Context) {
+	client, ok := currentClient(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req JoinMembershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	var plan models.MembershipPlan
+	if err := h.DB.Where("id = ? AND is_active = ?", req.PlanID, true).First(&plan).Error; err != nil {
+		utils.NotFoundResponse(c, "Membership plan not found")
+		return
+	}
+
+	// Joining again before paying picks up where the client left off
+	var m models.Membership
+	err := h.DB.Where("plan_id = ? AND client_id = ? AND status IN ?", plan.ID, client.ID,
+		[]models.MembershipStatus{models.MembershipPending, models.MembershipActive, models.MembershipPastDue}).
+		First(&m).Error
+	switch {
+	case err == nil && m.Status != models.MembershipPending:
+		utils.BadRequestResponse(c, "You are already a member of this plan")
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		m = models.Membership{
+			PlanID:     plan.ID,
+			ProviderID: plan.ProviderID,
+			ClientID:   client.ID,
+			Status:     models.MembershipPending,
+		}
+		if err := h.DB.Omit("Plan", "Client", "Payments").Create(&m).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to create membership")
+			return
+		}
+	case err != nil:
+		utils.InternalServerErrorResponse(c, "Failed to create membership")
+		return
+	}
+
+	payment, err := h.Payments.OpenPeriod(c.Request.Context(), &m, time.Now())
+	if err != nil {
+		log.Printf("Failed to start payment for membership %d: %v", m.ID, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+		return
+	}
+
+	m.Plan = plan
+	utils.SuccessResponse(c, http.StatusCreated, "Membership payment started successfully", MembershipCheckout{
+		Membership: m,
+		Payment:    h.payment(*payment),
+	})
+}
+
+// GetMemberships lists the client's memberships with their payments.
+func (h *MembershipHandler) GetMemberships(c *gin.Context) {
+	client, ok := currentClient(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var memberships []models.Membership
+	if err := h.DB.Preload("Plan", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).Preload("Plan.Services").
+		Preload("Payments", func(db *gorm.DB) *gorm.DB { return db.Order("period_start DESC") }).
+		Where("client_id = ?", client.ID).
+		Order("created_at DESC").Find(&memberships).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch memberships")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Memberships retrieved successfully", memberships)
+}
+
+// PayMembership returns the open payment of a membership waiting for its
+// first month or past due, opening it if needed.
+func (h *MembershipHandler) PayMembership(c *gin.Context) {
+	m, ok := h.findMembership(c)
+	if !ok {
+		return
+	}
+	if m.Status != models.MembershipPending && m.Status != models.MembershipPastDue {
+		utils.BadRequestResponse(c, "Membership is not waiting for payment")
+		return
+	}
+
+	payment, err := h.Payments.OpenPeriod(c.Request.Context(), &m, time.Now())
+	if err != nil {
+		log.Printf("Failed to start payment for membership %d: %v", m.ID, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Membership payment retrieved successfully", MembershipCheckout{
+		Membership: m,
+		Payment:    h.payment(*payment),
+	})
+}
+
+// ConfirmMembershipPayment charges a payment method for a month of the
+// client's membership. The method is charged again on renewal.
+func (h *MembershipHandler) ConfirmMembershipPayment(c *gin.Context) {
+	var req ConfirmPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	m, payment, ok := h.findPayment(c)
+	if !ok {
+		return
+	}
+	if payment.Status == models.PaymentSucceeded || payment.Status == models.PaymentCanceled {
+		utils.BadRequestResponse(c, "Payment is not waiting for payment")
+		return
+	}
+
+	if payment.IntentID == nil {
+		opened, err := h.Payments.OpenPeriod(c.Request.Context(), &m, time.Now())
+		if err != nil || opened.ID != payment.ID {
+			log.Printf("Failed to start membership payment %d: %v", payment.ID, err)
+			utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+			return
+		}
+		payment = *opened
+	}
+	if err := h.Payments.ConfirmMembershipPayment(c.Request.Context(), &payment, req.PaymentMethod); err != nil {
+		log.Printf("Failed to confirm membership payment %d: %v", payment.ID, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "Payment could not be processed, try again later")
+		return
+	}
+
+	if payment.Status != models.PaymentSucceeded && payment.FailureMessage != "" {
+		utils.ErrorResponse(c, http.StatusPaymentRequired, payment.FailureMessage)
+		return
+	}
+	h.DB.First(&m, m.ID)
+	utils.SuccessResponse(c, http.StatusOK, "Membership paid successfully", MembershipCheckout{
+		Membership: m,
+		Payment:    h.payment(payment),
+	})
+}
+
+// RefreshMembershipPayment reads a membership payment back from the
+// gateway, for payments the app completed directly with it.
+func (h *MembershipHandler) RefreshMembershipPayment(c *gin.Context) {
+	m, payment, ok := h.findPayment(c)
+	if !ok {
+		return
+	}
+	if payment.Status != models.PaymentSucceeded && payment.Status != models.PaymentCanceled && payment.IntentID != nil {
+		if err := h.Payments.RefreshMembershipPayment(c.Request.Context(), &payment); err != nil {
+			log.Printf("Failed to refresh membership payment %d: %v", payment.ID, err)
+			utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+			return
+		}
+	}
+
+	h.DB.First(&m, m.ID)
+	utils.SuccessResponse(c, http.StatusOK, "Membership payment refreshed successfully", MembershipCheckout{
+		Membership: m,
+		Payment:    h.payment(payment),
+	})
+}
+
+// CancelMembership ends the client's membership. One that is paid up runs
+// to the end of its month; one waiting for payment ends now.
+func (h *MembershipHandler) CancelMembership(c *gin.Context) {
+	m, ok := h.findMembership(c)
+	if !ok {
+		return
+	}
+
+	switch m.Status {
+	case models.MembershipActive:
+		if err := h.DB.Model(&m).Update("cancel_at_period_end", true).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to cancel membership")
+			return
+		}
+	case models.MembershipPending, models.MembershipPastDue:
+		result := h.DB.Model(&models.Membership{}).Where("id = ? AND status = ?", m.ID, m.Status).
+			Update("status", models.MembershipCancelled)
+		if result.Error != nil {
+			utils.InternalServerErrorResponse(c, "Failed to cancel membership")
+			return
+		}
+		if result.RowsAffected == 0 {
+			utils.BadRequestResponse(c, "Membership changed, try again")
+			return
+		}
+		if err := h.Payments.CancelMembershipPayments(c.Request.Context(), m.ID); err != nil {
+			log.Printf("Failed to cancel payments of membership %d: %v", m.ID, err)
+		}
+	default:
+		utils.BadRequestResponse(c, "Membership has already ended")
+		return
+	}
+
+	h.DB.First(&m, m.ID)
+	utils.SuccessResponse(c, http.StatusOK, "Membership cancelled successfully", m)
+}
+
+// applyRequest validates a request and sets it on plan, returning the
+// services it covers. It returns a message for the client if the request
+// is invalid.
+func (h *MembershipHandler) applyRequest(provider *models.ServiceProvider, plan *models.MembershipPlan, req *MembershipPlanRequest) ([]models.Service, string) {
+	if req.Allowance < 0 {
+		return nil, "allowance cannot be negative"
+	}
+	if req.Discount < 0 || req.Discount > 100 {
+		return nil, "Discount must be between 0 and 100"
+	}
+	if req.Allowance == 0 && req.Discount == 0 {
+		return nil, "Set an allowance, a discount or both"
+	}
+	var services []models.Service
+	if len(req.ServiceIDs) > 0 {
+		if err := h.DB.Where("id IN ? AND provider_id = ?", req.ServiceIDs, provider.ID).Find(&services).Error; err != nil || len(services) != len(uniqueIDs(req.ServiceIDs)) {
+			return nil, "Service not found"
+		}
+	}
+
+	plan.Name = req.Name
+	plan.Description = req.Description
+	plan.Price = req.Price
+	plan.Currency = provider.Currency
+	plan.Allowance = req.Allowance
+	plan.Discount = req.Discount
+	plan.IsActive = req.IsActive == nil || *req.IsActive
+	return services, ""
+}
+
+// payment adds what the app needs to pay for a membership payment that is
+// still open.
+func (h *MembershipHandler) payment(p models.MembershipPayment) *MembershipPaymentResponse {
+	resp := &MembershipPaymentResponse{MembershipPayment: p}
+	if p.Status != models.PaymentSucceeded && p.Status != models.PaymentCanceled {
+		resp.ClientSecret = p.ClientSecret
+		resp.PublishableKey = h.Payments.Gateway.PublishableKey()
+	}
+	return resp
+}
+
+func (h *MembershipHandler) findOwnPlan(c *gin.Context) (models.MembershipPlan, models.ServiceProvider, bool) {
+	var plan models.MembershipPlan
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return plan, provider, false
+	}
+
+	if err := h.DB.Where("id = ? AND provider_id = ?", c.Param("id"), provider.ID).First(&plan).Error; err != nil {
+		utils.NotFoundResponse(c, "Membership plan not found")
+		return plan, provider, false
+	}
+	return plan, provider, true
+}
+
+func (h *MembershipHandler) findMembership(c *gin.Context) (models.Membership, bool) {
+	var m models.Membership
+	client, ok := currentClient(h.DB, c)
+	if !ok {
+		return m, false
+	}
+
+	if err := h.DB.Where("id = ? AND client_id = ?", c.Param("id"), client.ID).First(&m).Error; err != nil {
+		utils.NotFoundResponse(c, "Membership not found")
+		return m, false
+	}
+	return m, true
+}
+
+func (h *MembershipHandler) findPayment(c *gin.Context) (models.Membership, models.MembershipPayment, bool) {
+	var payment models.MembershipPayment
+	m, ok := h.findMembership(c)
+	if !ok {
+		return m, payment, false
+	}
+
+	if err := h.DB.Where("id = ? AND membership_id = ?", c.Param("payment_id"), m.ID).First(&payment).Error; err != nil {
+		utils.NotFoundResponse(c, "Payment not found")
+		return m, payment, false
+	}
+	return m, payment, true
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/notifications"
+	"pluralink/backend/storage"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	maxMessageLength   = 4000
+	maxMessageFiles    = 5
+	defaultMaxFileSize = 10 << 20
+)
+
+type MessageHandler struct {
+	DB          *gorm.DB
+	Store       storage.Store
+	MaxFileSize int64
+}
+
+func NewMessageHandler(db *gorm.DB) *MessageHandler {
+	return &MessageHandler{
+		DB:          db,
+		Store:       storage.Default,
+		MaxFileSize: maxUploadSize(),
+	}
+}
+
+type SendMessageRequest struct {
+	Body string `json:"body" form:"body"`
+}
+
+// GetMessages lists a booking's conversation, newest first. Query
+// parameters: page (from 1) and limit (up to 100).
+func (h *MessageHandler) GetMessages(c *gin.Context) {
+	booking, ok := h.findOwnBooking(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("user_id")
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.BadRequestResponse(c, "Invalid page")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		utils.BadRequestResponse(c, "Invalid limit. Use 1 to 100")
+		return
+	}
+
+	query := h.DB.Model(&models.BookingMessage{}).Where("booking_id = ?", booking.ID).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch messages")
+		return
+	}
+
+	var messages []models.BookingMessage
+	if err := query.Preload("Attachments").
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&messages).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch messages")
+		return
+	}
+
+	var unread int64
+	if err := query.Where("sender_id <> ? AND read_at IS NULL", userID).Count(&unread).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch messages")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Messages retrieved successfully", gin.H{
+		"messages":     messages,
+		"unread_count": unread,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+	})
+}
+
+// SendMessage adds a message to a booking's conversation. It accepts JSON
+// with a body, or a multipart form with a body field and up to five files
+// in attachments.
+func (h *MessageHandler) SendMessage(c *gin.Context) {
+	booking, ok := h.findOwnBooking(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("user_id")
+
+	var req SendMessageRequest
+	var files []*multipart.FileHeader
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxMessageFiles*h.MaxFileSize+1<<20)
+		form, err := c.MultipartForm()
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid upload")
+			return
+		}
+		if bodies := form.Value["body"]; len(bodies) > 0 {
+			req.Body = bodies[0]
+		}
+		files = form.File["attachments"]
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" && len(files) == 0 {
+		utils.BadRequestResponse(c, "Message needs a body or an attachment")
+		return
+	}
+	if len([]rune(req.Body)) > maxMessageLength {
+		utils.BadRequestResponse(c, fmt.Sprintf("Message is too long. Use at most %d characters", maxMessageLength))
+		return
+	}
+	if len(files) > maxMessageFiles {
+		utils.BadRequestResponse(c, fmt.Sprintf("Send at most %d attachments per message", maxMessageFiles))
+		return
+	}
+
+	var attachments []models.MessageAttachment
+	prefix := storage.PrivatePrefix + "messages/" + strconv.FormatUint(uint64(booking.ID), 10)
+	policy := storage.Policy{MaxSize: h.MaxFileSize, Types: storage.AttachmentTypes}
+	for _, f := range files {
+		obj, err := storage.Save(c.Request.Context(), h.Store, prefix, f, policy)
+		if err != nil {
+			var invalid *storage.ValidationError
+			if errors.As(err, &invalid) {
+				utils.BadRequestResponse(c, invalid.Error())
+				return
+			}
+			log.Printf("Failed to store message attachment: %v", err)
+			utils.InternalServerErrorResponse(c, "Failed to store attachment")
+			return
+		}
+		attachments = append(attachments, models.MessageAttachment{
+			FileName:    obj.FileName,
+			ContentType: obj.ContentType,
+			Size:        obj.Size,
+			Path:        obj.Key,
+		})
+	}
+
+	message := models.BookingMessage{
+		BookingID:   booking.ID,
+		SenderID:    userID.(uint),
+		Body:        req.Body,
+		Attachments: attachments,
+	}
+	if err := h.DB.Create(&message).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to send message")
+		return
+	}
+
+	notify(h.DB, notifications.Event{
+		Type:        notifications.EventMessageReceived,
+		BookingID:   booking.ID,
+		MessageID:   message.ID,
+		ActorUserID: message.SenderID,
+	})
+
+	utils.SuccessResponse(c, http.StatusCreated, "Message sent successfully", message)
+}
+
+// MarkMessagesRead records that the current user has seen every message
+// the other side sent so far.
+func (h *MessageHandler) MarkMessagesRead(c *gin.Context) {
+	booking, ok := h.findOwnBooking(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("user_id")
+
+	now := time.Now()
+	result := h.DB.Model(&models.BookingMessage{}).
+		Where("booking_id = ? AND sender_id <> ? AND read_at IS NULL", booking.ID, userID).
+		Update("read_at", now)
+	if result.Error != nil {
+		utils.InternalServerErrorResponse(c, "Failed to mark messages as read")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Messages marked as read", gin.H{
+		"updated": result.RowsAffected,
+		"read_at": now,
+	})
+}
+
+// GetAttachment serves a file sent in a booking's conversation.
+func (h *MessageHandler) GetAttachment(c *gin.Context) {
+	booking, ok := h.findOwnBooking(c)
+	if !ok {
+		return
+	}
+
+	var attachment models.MessageAttachment
+	if err := h.DB.Joins("JOIN booking_messages ON booking_messages.id = message_attachments.message_id").
+		Where("message_attachments.id = ? AND message_attachments.message_id = ? AND booking_messages.booking_id = ?",
+			c.Param("attachment_id"), c.Param("message_id"), booking.ID).
+		First(&attachment).Error; err != nil {
+		utils.NotFoundResponse(c, "Attachment not found")
+		return
+	}
+
+	file, err := h.Store.Get(c.Request.Context(), attachment.Path)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			utils.NotFoundResponse(c, "Attachment not found")
+			return
+		}
+		log.Printf("Failed to read message attachment %d: %v", attachment.ID, err)
+		utils.InternalServerErrorResponse(c, "Failed to read attachment")
+		return
+	}
+	defer file.Body.Close()
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, file.Size, attachment.ContentType, file.Body, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+	})
+}
+
+// findOwnBooking loads the booking in the path if the current user is its
+// client or provider.
+func (h *MessageHandler) findOwnBooking(c *gin.Context) (models.Booking, bool) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+
+	var booking models.Booking
+	if err := h.DB.First(&booking, c.Param("id")).Error; err != nil {
+		utils.NotFoundResponse(c, "Booking not found")
+		return booking, false
+	}
+
+	if userRole == models.RoleProvider {
+		var provider models.ServiceProvider
+		h.DB.Where("user_id = ?", userID).First(&provider)
+		if booking.ProviderID != provider.ID {
+			utils.ErrorResponse(c, http.StatusForbidden, "Access denied")
+			return booking, false
+		}
+	} else {
+		var client models.Client
+		h.DB.Where("user_id = ?", userID).First(&client)
+		if booking.ClientID != client.ID {
+			utils.ErrorResponse(c, http.StatusForbidden, "Access denied")
+			return booking, false
+		}
+	}
+
+	return booking, true
+}
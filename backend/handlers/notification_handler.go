@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/notifications"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type NotificationHandler struct {
+	DB *gorm.DB
+}
+
+func NewNotificationHandler(db *gorm.DB) *NotificationHandler {
+	return &NotificationHandler{DB: db}
+}
+
+type MarkNotificationsReadRequest struct {
+	IDs []uint `json:"ids"`
+	All bool   `json:"all"`
+}
+
+// GetNotifications lists the current user's inbox, newest first. Query
+// parameters: page (from 1), limit (up to 100) and unread=true.
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.BadRequestResponse(c, "Invalid page")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		utils.BadRequestResponse(c, "Invalid limit. Use 1 to 100")
+		return
+	}
+
+	query := h.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch notifications")
+		return
+	}
+
+	var list []models.Notification
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&list).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch notifications")
+		return
+	}
+
+	unread, err := h.unreadCount(userID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch notifications")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notifications retrieved successfully", gin.H{
+		"notifications": list,
+		"unread_count":  unread,
+		"total":         total,
+		"page":          page,
+		"limit":         limit,
+	})
+}
+
+// MarkRead marks the given notifications, or all of them, as read.
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req MarkNotificationsReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	if !req.All && len(req.IDs) == 0 {
+		utils.BadRequestResponse(c, "Provide ids or set all to true")
+		return
+	}
+
+	query := h.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if !req.All {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	result := query.Update("read_at", time.Now())
+	if result.Error != nil {
+		utils.InternalServerErrorResponse(c, "Failed to mark notifications as read")
+		return
+	}
+
+	unread, err := h.unreadCount(userID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to mark notifications as read")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notifications marked as read", gin.H{
+		"updated":      result.RowsAffected,
+		"unread_count": unread,
+	})
+}
+
+func (h *NotificationHandler) unreadCount(userID interface{}) (int64, error) {
+	var n int64
+	err := h.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
+
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	pref, ok := h.loadPreference(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notification preferences retrieved successfully", preferencesResponse(&pref))
+}
+
+// UpdatePreferences changes the current user's preferences. Fields and
+// event types left out of the request keep their current values.
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	pref, ok := h.loadPreference(c)
+	if !ok {
+		return
+	}
+
+	settings := notifications.SettingsOf(&pref)
+	settings.Channels = nil
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	if err := notifications.ApplySettings(&pref, settings); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	if err := h.DB.Save(&pref).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update notification preferences")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notification preferences updated successfully", preferencesResponse(&pref))
+}
+
+// loadPreference returns the current user's preferences. Providers who
+// never set a time zone default to their business's.
+func (h *NotificationHandler) loadPreference(c *gin.Context) (models.NotificationPreference, bool) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+
+	pref, err := notifications.LoadPreference(h.DB, userID.(uint))
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch notification preferences")
+		return pref, false
+	}
+
+	if pref.TimeZone == "" && userRole == models.RoleProvider {
+		var provider models.ServiceProvider
+		if err := h.DB.Where("user_id = ?", userID).First(&provider).Error; err == nil {
+			pref.TimeZone = provider.TimeZone
+		}
+	}
+	return pref, true
+}
+
+func preferencesResponse(pref *models.NotificationPreference) gin.H {
+	return gin.H{
+		"preferences": notifications.SettingsOf(pref),
+		"event_types": notifications.EventTypes,
+		"channels":    notifications.ChannelNames,
+	}
+}
+
+type RegisterDeviceRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Platform string `json:"platform" binding:"omitempty,oneof=ios android web"`
+}
+
+// RegisterDevice stores the app's push token for the current user. If the
+// token was registered by someone else, the device changed hands.
+func (h *NotificationHandler) RegisterDevice(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	var device models.DeviceToken
+	if err := h.DB.Where("token = ?", req.Token).First(&device).Error; err != nil {
+		device = models.DeviceToken{Token: req.Token}
+	}
+	device.UserID = userID.(uint)
+	device.Platform = req.Platform
+
+	if err := h.DB.Save(&device).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to register device")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Device registered successfully", device)
+}
+
+// UnregisterDevice stops push notifications to a device, e.g. on sign out.
+func (h *NotificationHandler) UnregisterDevice(c *gin.Context) {
+	token := c.Param("token")
+	userID, _ := c.Get("user_id")
+
+	if err := h.DB.Where("token = ? AND user_id = ?", token, userID).Delete(&models.DeviceToken{}).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to unregister device")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Device unregistered successfully", nil)
+}
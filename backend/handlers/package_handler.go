@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+	"pluralink/backend/payments"
+	"pluralink/backend/taxes"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PackageHandler struct {
+	DB       *gorm.DB
+	Payments *payments.Service
+}
+
+func NewPackageHandler(db *gorm.DB) *PackageHandler {
+	return &PackageHandler{DB: db, Payments: payments.NewService(db)}
+}
+
+// PackageRequest creates a package or replaces one's settings.
+type PackageRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Description  string `json:"description"`
+	Price        int64  `json:"price" binding:"required,min=1"` // In minor units of the provider's currency
+	Sessions     int    `json:"sessions" binding:"required,min=1"`
+	ValidityDays int    `json:"validity_days"` // 0 for no expiry
+	ServiceIDs   []uint `json:"service_ids" binding:"required,min=1"`
+	IsActive     *bool  `json:"is_active"` // Defaults to true
+}
+
+type PurchasePackageRequest struct {
+	PackageID uint `json:"package_id" binding:"required"`
+}
+
+// PackagePurchaseResponse is a package being bought, with what the app
+// needs to collect its payment through the gateway.
+type PackagePurchaseResponse struct {
+	models.ClientPackage
+	ClientSecret   string `json:"client_secret,omitempty"`
+	PublishableKey string `json:"publishable_key,omitempty"`
+}
+
+// GetProviderPackages lists the packages a provider has on sale, to
+// anyone.
+func (h *PackageHandler) GetProviderPackages(c *gin.Context) {
+	var packages []models.Package
+	if err := h.DB.Preload("Services").Where("provider_id = ? AND is_active = ?", c.Param("id"), true).
+		Order("price").Find(&packages).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch packages")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Packages retrieved successfully", packages)
+}
+
+// GetPackages lists all of the provider's packages.
+func (h *PackageHandler) GetPackages(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var packages []models.Package
+	if err := h.DB.Preload("Services").Where("provider_id = ?", provider.ID).
+		Order("created_at DESC").Find(&packages).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch packages")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Packages retrieved successfully", packages)
+}
+
+func (h *PackageHandler) CreatePackage(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req PackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	pkg := models.Package{ProviderID: provider.ID}
+	services, msg := h.applyRequest(&provider, &pkg, &req)
+	if msg != "" {
+		utils.BadRequestResponse(c, msg)
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Services").Create(&pkg).Error; err != nil {
+			return err
+		}
+		// Create leaves false to the column's default
+		if !pkg.IsActive {
+			if err := tx.Model(&pkg).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&pkg).Association("Services").Replace(services)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create package")
+		return
+	}
+
+	h.DB.Preload("Services").First(&pkg, pkg.ID)
+	utils.SuccessResponse(c, http.StatusCreated, "Package created successfully", pkg)
+}
+
+// UpdatePackage replaces a package's settings. Packages already sold keep
+// the sessions and expiry they were bought with, but cover the services
+// the package now includes.
+func (h *PackageHandler) UpdatePackage(c *gin.Context) {
+	pkg, provider, ok := h.findOwnPackage(c)
+	if !ok {
+		return
+	}
+
+	var req PackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	services, msg := h.applyRequest(&provider, &pkg, &req)
+	if msg != "" {
+		utils.BadRequestResponse(c, msg)
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Services").Save(&pkg).Error; err != nil {
+			return err
+		}
+		return tx.Model(&pkg).Association("Services").Replace(services)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update package")
+		return
+	}
+
+	h.DB.Preload("Services").First(&pkg, pkg.ID)
+	utils.SuccessResponse(c, http.StatusOK, "Package updated successfully", pkg)
+}
+
+// DeletePackage takes a package off sale. Packages already sold can still
+// be used.
+func (h *PackageHandler) DeletePackage(c *gin.Context) {
+	pkg, _, ok := h.findOwnPackage(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(&pkg).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete package")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Package deleted successfully", nil)
+}
+
+// GetSoldPackages lists the packages clients bought from the provider,
+// newest first. Filter with ?status=.
+func (h *PackageHandler) GetSoldPackages(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	query := h.DB.Where("provider_id = ?", provider.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var sold []models.ClientPackage
+	if err := query.Preload("Package", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Client").Preload("Client.User").
+		Order("created_at DESC").Find(&sold).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch packages")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Packages retrieved successfully", sold)
+}
+
+// PurchasePackage opens the payment of a package a client buys. Its
+// sessions can be used once the payment goes through.
+func (h *PackageHandler) PurchasePackage(c *gin.Context) {
+	client, ok := currentClient(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req PurchasePackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	var pkg models.Package
+	if err := h.DB.Preload("Services").Where("id = ? AND is_active = ?", req.PackageID, true).First(&pkg).Error; err != nil {
+		utils.NotFoundResponse(c, "Package not found")
+		return
+	}
+	var provider models.ServiceProvider
+	if err := h.DB.First(&provider, pkg.ProviderID).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider not found")
+		return
+	}
+	// The bookings it covers carry no tax, so the package is taxed instead
+	tax, err := taxes.OnSale(h.DB, &provider, money.New(pkg.Price, pkg.Currency), pkg.Services)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to work out tax")
+		return
+	}
+
+	cp := models.ClientPackage{
+		PackageID:    pkg.ID,
+		ProviderID:   pkg.ProviderID,
+		ClientID:     client.ID,
+		Sessions:     pkg.Sessions,
+		Price:        pkg.Price,
+		Currency:     pkg.Currency,
+		Tax:          tax.Tax,
+		TaxName:      tax.Name,
+		TaxRate:      tax.Rate,
+		TaxInclusive: tax.Inclusive,
+		Status:       models.ClientPackagePending,
+	}
+	if err := h.DB.Omit("Package", "Client").Create(&cp).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create package")
+		return
+	}
+
+	if err := h.Payments.StartPackage(c.Request.Context(), &cp, pkg.Name); err != nil {
+		log.Printf("Failed to start payment for package %d: %v", cp.ID, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+		return
+	}
+
+	cp.Package = pkg
+	utils.SuccessResponse(c, http.StatusCreated, "Package payment started successfully", h.purchase(cp))
+}
+
+// ConfirmPackagePurchase charges a payment method for a package the client
+// is buying.
+func (h *PackageHandler) ConfirmPackagePurchase(c *gin.Context) {
+	var req ConfirmPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	cp, ok := h.findPurchasedPackage(c)
+	if !ok {
+		return
+	}
+	if cp.Status != models.ClientPackagePending {
+		utils.BadRequestResponse(c, "Package is not waiting for payment")
+		return
+	}
+
+	if cp.IntentID == nil {
+		if err := h.Payments.StartPackage(c.Request.Context(), &cp, cp.Package.Name); err != nil {
+			log.Printf("Failed to start payment for package %d: %v", cp.ID, err)
+			utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+			return
+		}
+	}
+	if err := h.Payments.ConfirmPackage(c.Request.Context(), &cp, req.PaymentMethod); err != nil {
+		log.Printf("Failed to confirm payment of package %d: %v", cp.ID, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "Payment could not be processed, try again later")
+		return
+	}
+
+	if cp.Status == models.ClientPackagePending && cp.FailureMessage != "" {
+		utils.ErrorResponse(c, http.StatusPaymentRequired, cp.FailureMessage)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Package purchased successfully", h.purchase(cp))
+}
+
+// RefreshPackagePurchase reads a package's payment back from the gateway,
+// for payments the app completed directly with it.
+func (h *PackageHandler) RefreshPackagePurchase(c *gin.Context) {
+	cp, ok := h.findPurchasedPackage(c)
+	if !ok {
+		return
+	}
+	if cp.Status == models.ClientPackagePending && cp.IntentID != nil {
+		if err := h.Payments.RefreshPackage(c.Request.Context(), &cp); err != nil {
+			log.Printf("Failed to refresh payment of package %d: %v", cp.ID, err)
+			utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+			return
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Package refreshed successfully", h.purchase(cp))
+}
+
+// GetPurchasedPackages lists the packages the client bought, with the
+// sessions left on each.
+func (h *PackageHandler) GetPurchasedPackages(c *gin.Context) {
+	client, ok := currentClient(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var packages []models.ClientPackage
+	if err := h.DB.Preload("Package", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).Preload("Package.Services").
+		Where("client_id = ? AND status <> ?", client.ID, models.ClientPackageVoid).
+		Order("created_at DESC").Find(&packages).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch packages")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Packages retrieved successfully", packages)
+}
+
+// applyRequest validates a request and sets it on pkg, returning the
+// services it covers. It returns a message for the client if the request
+// is invalid.
+func (h *PackageHandler) applyRequest(provider *models.ServiceProvider, pkg *models.Package, req *PackageRequest) ([]models.Service, string) {
+	if req.ValidityDays < 0 {
+		return nil, "validity_days cannot be negative"
+	}
+	var services []models.Service
+	if err := h.DB.Where("id IN ? AND provider_id = ?", req.ServiceIDs, provider.ID).Find(&services).Error; err != nil || len(services) != len(uniqueIDs(req.ServiceIDs)) {
+		return nil, "Service not found"
+	}
+
+	pkg.Name = req.Name
+	pkg.Description = req.Description
+	pkg.Price = req.Price
+	pkg.Currency = provider.Currency
+	pkg.Sessions = req.Sessions
+	pkg.ValidityDays = req.ValidityDays
+	pkg.IsActive = req.IsActive == nil || *req.IsActive
+	return services, ""
+}
+
+// purchase adds what the app needs to pay for a package still waiting for
+// payment.
+func (h *PackageHandler) purchase(cp models.ClientPackage) PackagePurchaseResponse {
+	resp := PackagePurchaseResponse{ClientPackage: cp}
+	if cp.Status == models.ClientPackagePending {
+		resp.ClientSecret = cp.ClientSecret
+		resp.PublishableKey = h.Payments.Gateway.PublishableKey()
+	}
+	return resp
+}
+
+func (h *PackageHandler) findOwnPackage(c *gin.Context) (models.Package, models.ServiceProvider, bool) {
+	var pkg models.Package
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return pkg, provider, false
+	}
+
+	if err := h.DB.Where("id = ? AND provider_id = ?", c.Param("id"), provider.ID).First(&pkg).Error; err != nil {
+		utils.NotFoundResponse(c, "Package not found")
+		return pkg, provider, false
+	}
+	return pkg, provider, true
+}
+
+func (h *PackageHandler) findPurchasedPackage(c *gin.Context) (models.ClientPackage, bool) {
+	var cp models.ClientPackage
+	client, ok := currentClient(h.DB, c)
+	if !ok {
+		return cp, false
+	}
+
+	if err := h.DB.Preload("Package", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("id = ? AND client_id = ?", c.Param("id"), client.ID).First(&cp).Error; err != nil {
+		utils.NotFoundResponse(c, "Package not found")
+		return cp, false
+	}
+	return cp, true
+}
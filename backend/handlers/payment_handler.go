@@ -0,0 +1,580 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"pluralink/backend/invoices"
+	"pluralink/backend/jobs"
+	"pluralink/backend/models"
+	"pluralink/backend/payments"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PaymentHandler struct {
+	DB       *gorm.DB
+	Payments *payments.Service
+}
+
+func NewPaymentHandler(db *gorm.DB) *PaymentHandler {
+	return &PaymentHandler{DB: db, Payments: payments.NewService(db)}
+}
+
+type ConfirmPaymentRequest struct {
+	PaymentMethod string `json:"payment_method" binding:"required"`
+}
+
+type StartTipRequest struct {
+	Amount int64 `json:"amount" binding:"required,min=1"` // In minor units
+}
+
+type UpdatePaymentPolicyRequest struct {
+	PaymentPolicy  models.PaymentPolicy `json:"payment_policy" binding:"required"`
+	DepositPercent int                  `json:"deposit_percent"`
+}
+
+// RefundPolicy is what clients get back when they cancel a paid booking.
+type RefundPolicy struct {
+	CancellationCutoff     int `json:"cancellation_cutoff"`      // Minutes before start
+	LateCancellationRefund int `json:"late_cancellation_refund"` // Percent, after the cutoff
+}
+
+// OpenPaymentResponse is a payment the client can still complete, with
+// what the app needs to collect it through the gateway.
+type OpenPaymentResponse struct {
+	models.Payment
+	ClientSecret   string `json:"client_secret,omitempty"`
+	PublishableKey string `json:"publishable_key,omitempty"`
+}
+
+// BookingPaymentResponse is the payment state of a booking.
+type BookingPaymentResponse struct {
+	BookingID     uint                        `json:"booking_id"`
+	PaymentStatus models.BookingPaymentStatus `json:"payment_status"`
+	Payments      []models.Payment            `json:"payments"`
+	Open          *OpenPaymentResponse        `json:"open,omitempty"`
+	RefundStatus  models.BookingRefundStatus  `json:"refund_status"`
+	RefundAmount  int64                       `json:"refund_amount"`
+	RefundReason  string                      `json:"refund_reason,omitempty"`
+	Refunds       []models.Refund             `json:"refunds"`
+	TipAmount     int64                       `json:"tip_amount"`
+	// Until when the client can tip, if they still can
+	TipUntil *time.Time `json:"tip_until,omitempty"`
+	// What would go back to the client if the current user cancelled now
+	CancellationRefund *int64 `json:"cancellation_refund,omitempty"`
+}
+
+// GetPayment returns a booking's payments and refunds. The client also
+// gets the secret of the open payment, if there is one.
+func (h *PaymentHandler) GetPayment(c *gin.Context) {
+	booking, ok := h.findOwnBooking(c)
+	if !ok {
+		return
+	}
+
+	resp := BookingPaymentResponse{
+		BookingID:     booking.ID,
+		PaymentStatus: booking.PaymentStatus,
+		Payments:      []models.Payment{},
+		RefundStatus:  booking.RefundStatus,
+		RefundAmount:  booking.RefundAmount,
+		RefundReason:  booking.RefundReason,
+		Refunds:       []models.Refund{},
+		TipAmount:     booking.TipAmount,
+	}
+	if err := h.DB.Where("booking_id = ?", booking.ID).Order("created_at").Find(&resp.Payments).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch payments")
+		return
+	}
+	if err := h.DB.Where("booking_id = ?", booking.ID).Order("created_at").Find(&resp.Refunds).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch refunds")
+		return
+	}
+
+	var paid int64
+	for i := range resp.Payments {
+		if resp.Payments[i].Open() {
+			resp.Open = h.openPayment(c, resp.Payments[i])
+		}
+		if resp.Payments[i].Status == models.PaymentSucceeded && resp.Payments[i].Kind != models.PaymentTip {
+			paid += resp.Payments[i].Amount
+		}
+	}
+
+	var provider models.ServiceProvider
+	if err := h.DB.First(&provider, booking.ProviderID).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider not found")
+		return
+	}
+	cancellable := booking.Status != models.StatusCancelled && booking.Status != models.StatusCompleted &&
+		booking.Status != models.StatusExpired
+	if cancellable && paid > 0 {
+		userRole, _ := c.Get("user_role")
+		refund, _ := payments.RefundDue(&booking, &provider, paid, userRole == models.RoleProvider, time.Now())
+		resp.CancellationRefund = &refund
+	}
+	if until := payments.TipUntil(&booking, &provider); until != nil && time.Now().Before(*until) {
+		resp.TipUntil = until
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Payment retrieved successfully", resp)
+}
+
+// StartPayment opens a payment for a booking that needs one, or returns the
+// one already open.
+func (h *PaymentHandler) StartPayment(c *gin.Context) {
+	booking, ok := h.findPayableBooking(c)
+	if !ok {
+		return
+	}
+
+	var service models.Service
+	if err := h.DB.First(&service, booking.ServiceID).Error; err != nil {
+		utils.NotFoundResponse(c, "Service not found")
+		return
+	}
+
+	payment, err := h.Payments.Start(c.Request.Context(), &booking, &service)
+	if err != nil {
+		if errors.Is(err, payments.ErrAlreadyPaid) {
+			utils.BadRequestResponse(c, "Booking is already paid")
+			return
+		}
+		log.Printf("Failed to start payment for booking %d: %v", booking.ID, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Payment started successfully", h.openPayment(c, *payment))
+}
+
+// ConfirmPayment charges a payment method the app collected from the
+// gateway to the booking's open payment.
+func (h *PaymentHandler) ConfirmPayment(c *gin.Context) {
+	var req ConfirmPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	booking, ok := h.findPayableBooking(c)
+	if !ok {
+		return
+	}
+	payment, ok := h.findOpenPayment(c, booking.ID)
+	if !ok {
+		return
+	}
+
+	changed, err := h.Payments.Confirm(c.Request.Context(), &payment, req.PaymentMethod)
+	if err != nil {
+		log.Printf("Failed to confirm payment %d: %v", payment.ID, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "Payment could not be processed, try again later")
+		return
+	}
+	h.announce(booking.ID, changed)
+
+	if payment.Status == models.PaymentRequiresPayment && payment.FailureMessage != "" {
+		utils.ErrorResponse(c, http.StatusPaymentRequired, payment.FailureMessage)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Payment processed successfully", payment)
+}
+
+// RefreshPayment reads the open payment's state back from the gateway, for
+// payments the app completed directly with it.
+func (h *PaymentHandler) RefreshPayment(c *gin.Context) {
+	booking, ok := h.findOwnBooking(c)
+	if !ok {
+		return
+	}
+	payment, ok := h.findOpenPayment(c, booking.ID)
+	if !ok {
+		return
+	}
+
+	changed, err := h.Payments.Refresh(c.Request.Context(), &payment)
+	if err != nil {
+		log.Printf("Failed to refresh payment %d: %v", payment.ID, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+		return
+	}
+	h.announce(booking.ID, changed)
+	if payment.Kind == models.PaymentTip && payment.Status == models.PaymentSucceeded {
+		jobs.TipReceived(h.DB, booking.ID)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Payment refreshed successfully", payment)
+}
+
+// StartTip opens a payment for a tip on a completed booking, or returns the
+// one already open for the same amount.
+func (h *PaymentHandler) StartTip(c *gin.Context) {
+	var req StartTipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	booking, ok := h.findTippableBooking(c)
+	if !ok {
+		return
+	}
+	var provider models.ServiceProvider
+	if err := h.DB.First(&provider, booking.ProviderID).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider not found")
+		return
+	}
+
+	payment, err := h.Payments.StartTip(c.Request.Context(), &booking, &provider, req.Amount)
+	if err != nil {
+		switch {
+		case errors.Is(err, payments.ErrAlreadyTipped):
+			utils.BadRequestResponse(c, "Booking is already tipped")
+		case errors.Is(err, payments.ErrTipClosed):
+			utils.BadRequestResponse(c, "Booking can no longer be tipped")
+		default:
+			log.Printf("Failed to start tip for booking %d: %v", booking.ID, err)
+			utils.ErrorResponse(c, http.StatusBadGateway, "Payment provider is unavailable, try again later")
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Tip started successfully", h.openPayment(c, *payment))
+}
+
+// ConfirmTip charges a payment method to the booking's open tip.
+func (h *PaymentHandler) ConfirmTip(c *gin.Context) {
+	var req ConfirmPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	booking, ok := h.findTippableBooking(c)
+	if !ok {
+		return
+	}
+	var payment models.Payment
+	if err := h.DB.Where("booking_id = ? AND kind = ? AND status IN ?", booking.ID, models.PaymentTip,
+		[]models.PaymentStatus{models.PaymentRequiresPayment, models.PaymentProcessing}).
+		Order("created_at DESC").First(&payment).Error; err != nil {
+		utils.NotFoundResponse(c, "No open tip for this booking")
+		return
+	}
+
+	if _, err := h.Payments.Confirm(c.Request.Context(), &payment, req.PaymentMethod); err != nil {
+		log.Printf("Failed to confirm tip %d: %v", payment.ID, err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "Payment could not be processed, try again later")
+		return
+	}
+
+	if payment.Status == models.PaymentRequiresPayment && payment.FailureMessage != "" {
+		utils.ErrorResponse(c, http.StatusPaymentRequired, payment.FailureMessage)
+		return
+	}
+	if payment.Status == models.PaymentSucceeded {
+		jobs.TipReceived(h.DB, booking.ID)
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Tip processed successfully", payment)
+}
+
+// GetReceipt returns the numbered invoice, or receipt once it is paid, of a
+// booking that was paid for or completed. It is a PDF unless format=json
+// is asked for.
+func (h *PaymentHandler) GetReceipt(c *gin.Context) {
+	booking, ok := h.findOwnBooking(c)
+	if !ok {
+		return
+	}
+	if err := h.DB.Preload("Provider").Preload("Service").Preload("Client.User").
+		First(&booking, booking.ID).Error; err != nil {
+		utils.NotFoundResponse(c, "Booking not found")
+		return
+	}
+
+	receipt, err := invoices.Build(h.DB, &booking)
+	if errors.Is(err, invoices.ErrNotBillable) {
+		utils.BadRequestResponse(c, "Only paid or completed bookings have a receipt")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to build receipt for booking %d: %v", booking.ID, err)
+		utils.InternalServerErrorResponse(c, "Failed to build receipt")
+		return
+	}
+
+	if c.Query("format") == "json" {
+		utils.SuccessResponse(c, http.StatusOK, "Receipt retrieved successfully", receipt)
+		return
+	}
+	filename := fmt.Sprintf("%s-%s.pdf", strings.ToLower(receipt.Title), receipt.Reference)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "application/pdf", receipt.PDF())
+}
+
+// UpdatePaymentPolicy sets what one of the provider's services asks
+// clients to pay when booking.
+func (h *PaymentHandler) UpdatePaymentPolicy(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req UpdatePaymentPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	switch req.PaymentPolicy {
+	case models.PaymentNone, models.PaymentFull:
+		req.DepositPercent = 0
+	case models.PaymentDeposit:
+		if req.DepositPercent < 1 || req.DepositPercent > 99 {
+			utils.BadRequestResponse(c, "Deposit percent must be between 1 and 99")
+			return
+		}
+	default:
+		utils.BadRequestResponse(c, "Invalid payment policy. Use none, deposit or full")
+		return
+	}
+
+	var provider models.ServiceProvider
+	if err := h.DB.Where("user_id = ?", userID).First(&provider).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider profile not found")
+		return
+	}
+	var service models.Service
+	if err := h.DB.Where("id = ? AND provider_id = ?", c.Param("id"), provider.ID).First(&service).Error; err != nil {
+		utils.NotFoundResponse(c, "Service not found")
+		return
+	}
+
+	service.PaymentPolicy = req.PaymentPolicy
+	service.DepositPercent = req.DepositPercent
+	if _, due := payments.Due(&service, service.PriceMoney()); service.PaymentPolicy != models.PaymentNone && due.Amount <= 0 {
+		utils.BadRequestResponse(c, "Service price is too low to take payments")
+		return
+	}
+	if err := h.DB.Model(&service).Select("payment_policy", "deposit_percent").Updates(&service).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update payment policy")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Payment policy updated successfully", service)
+}
+
+func (h *PaymentHandler) GetRefundPolicy(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var provider models.ServiceProvider
+	if err := h.DB.Where("user_id = ?", userID).First(&provider).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider profile not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Refund policy retrieved successfully", RefundPolicy{
+		CancellationCutoff:     provider.CancellationCutoff,
+		LateCancellationRefund: provider.LateCancellationRefund,
+	})
+}
+
+// UpdateRefundPolicy changes what clients get back when they cancel. It
+// applies to cancellations from now on.
+func (h *PaymentHandler) UpdateRefundPolicy(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req RefundPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	if req.CancellationCutoff < 0 || req.CancellationCutoff > 30*24*60 {
+		utils.BadRequestResponse(c, "Cancellation cutoff must be between 0 and 43200 minutes")
+		return
+	}
+	if req.LateCancellationRefund < 0 || req.LateCancellationRefund > 100 {
+		utils.BadRequestResponse(c, "Late cancellation refund must be between 0 and 100 percent")
+		return
+	}
+
+	var provider models.ServiceProvider
+	if err := h.DB.Where("user_id = ?", userID).First(&provider).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider profile not found")
+		return
+	}
+	if err := h.DB.Model(&provider).Updates(map[string]interface{}{
+		"cancellation_cutoff":      req.CancellationCutoff,
+		"late_cancellation_refund": req.LateCancellationRefund,
+	}).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update refund policy")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Refund policy updated successfully", req)
+}
+
+// RetryRefund issues a booking's failed refunds again.
+func (h *PaymentHandler) RetryRefund(c *gin.Context) {
+	booking, ok := h.findOwnBooking(c)
+	if !ok {
+		return
+	}
+
+	retried, err := h.Payments.RetryRefunds(booking.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retry refund")
+		return
+	}
+	if !retried {
+		utils.BadRequestResponse(c, "Only failed refunds can be retried")
+		return
+	}
+	if err := jobs.EnqueueRefunds(h.DB, booking.ID); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retry refund")
+		return
+	}
+	jobs.PaymentUpdated(h.DB, booking.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, "Refund retry scheduled successfully", nil)
+}
+
+// maxWebhookPayload bounds what a gateway webhook may weigh
+const maxWebhookPayload = 1 << 20
+
+// ReceiveWebhook takes an event from the payment gateway. It answers 200
+// once the event is applied, or was before, and an error otherwise so the
+// gateway sends it again.
+func (h *PaymentHandler) ReceiveWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookPayload))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid payload")
+		return
+	}
+	if err := h.Payments.Gateway.VerifyWebhook(payload, c.Request.Header, time.Now()); err != nil {
+		utils.BadRequestResponse(c, "Invalid signature")
+		return
+	}
+
+	stored, err := h.Payments.RecordEvent(payload)
+	if errors.Is(err, payments.ErrInvalidEvent) {
+		utils.BadRequestResponse(c, "Invalid event")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to record payment event: %v", err)
+		utils.InternalServerErrorResponse(c, "Failed to record event")
+		return
+	}
+	if err := jobs.ProcessPaymentEvent(h.DB, h.Payments, stored.ID, false); err != nil {
+		log.Printf("Failed to process payment event %s: %v", stored.EventID, err)
+		utils.InternalServerErrorResponse(c, "Failed to process event")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Event received", gin.H{"id": stored.EventID})
+}
+
+// announce follows up a booking becoming paid.
+func (h *PaymentHandler) announce(bookingID uint, changed bool) {
+	if changed {
+		jobs.RefundLatePayment(h.DB, bookingID)
+		jobs.PaymentUpdated(h.DB, bookingID)
+	}
+}
+
+// openPayment adds the client-only details to an open payment.
+func (h *PaymentHandler) openPayment(c *gin.Context, payment models.Payment) *OpenPaymentResponse {
+	resp := &OpenPaymentResponse{Payment: payment}
+	if userRole, _ := c.Get("user_role"); userRole == models.RoleClient {
+		resp.ClientSecret = payment.ClientSecret
+		resp.PublishableKey = h.Payments.Gateway.PublishableKey()
+	}
+	return resp
+}
+
+func (h *PaymentHandler) findOwnBooking(c *gin.Context) (models.Booking, bool) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+
+	var booking models.Booking
+	if err := h.DB.First(&booking, c.Param("id")).Error; err != nil {
+		utils.NotFoundResponse(c, "Booking not found")
+		return booking, false
+	}
+
+	if userRole == models.RoleProvider {
+		var provider models.ServiceProvider
+		h.DB.Where("user_id = ?", userID).First(&provider)
+		if booking.ProviderID != provider.ID {
+			utils.ErrorResponse(c, http.StatusForbidden, "Access denied")
+			return booking, false
+		}
+	} else {
+		var client models.Client
+		h.DB.Where("user_id = ?", userID).First(&client)
+		if booking.ClientID != client.ID {
+			utils.ErrorResponse(c, http.StatusForbidden, "Access denied")
+			return booking, false
+		}
+	}
+
+	return booking, true
+}
+
+// findPayableBooking finds one of the client's bookings that is waiting for
+// payment.
+func (h *PaymentHandler) findPayableBooking(c *gin.Context) (models.Booking, bool) {
+	if userRole, _ := c.Get("user_role"); userRole != models.RoleClient {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only clients can pay for bookings")
+		return models.Booking{}, false
+	}
+	booking, ok := h.findOwnBooking(c)
+	if !ok {
+		return booking, false
+	}
+
+	if booking.PaymentStatus != models.PaymentDue {
+		utils.BadRequestResponse(c, "Booking has no payment due")
+		return booking, false
+	}
+	if booking.Status != models.StatusPending && booking.Status != models.StatusRescheduled {
+		utils.BadRequestResponse(c, "Only pending or rescheduled bookings can be paid")
+		return booking, false
+	}
+	return booking, true
+}
+
+// findTippableBooking finds one of the client's completed bookings.
+func (h *PaymentHandler) findTippableBooking(c *gin.Context) (models.Booking, bool) {
+	if userRole, _ := c.Get("user_role"); userRole != models.RoleClient {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only clients can tip")
+		return models.Booking{}, false
+	}
+	booking, ok := h.findOwnBooking(c)
+	if !ok {
+		return booking, false
+	}
+
+	if booking.Status != models.StatusCompleted {
+		utils.BadRequestResponse(c, "Only completed bookings can be tipped")
+		return booking, false
+	}
+	return booking, true
+}
+
+func (h *PaymentHandler) findOpenPayment(c *gin.Context, bookingID uint) (models.Payment, bool) {
+	var payment models.Payment
+	if err := h.DB.Where("booking_id = ? AND status IN ?", bookingID,
+		[]models.PaymentStatus{models.PaymentRequiresPayment, models.PaymentProcessing}).
+		Order("created_at DESC").First(&payment).Error; err != nil {
+		utils.NotFoundResponse(c, "No open payment for this booking")
+		return payment, false
+	}
+	return payment, true
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"pluralink/backend/models"
+	"pluralink/backend/storage"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// thumbnailSide is the longest side of portfolio thumbnails in pixels.
+	thumbnailSide = 400
+	// portfolioPreviewSize is how many items providers show in listings.
+	portfolioPreviewSize = 6
+)
+
+type PortfolioHandler struct {
+	DB     *gorm.DB
+	Store  storage.Store
+	Signer *storage.Signer
+}
+
+func NewPortfolioHandler(db *gorm.DB) *PortfolioHandler {
+	return &PortfolioHandler{
+		DB:     db,
+		Store:  storage.Default,
+		Signer: storage.DefaultSigner,
+	}
+}
+
+type CreatePortfolioItemRequest struct {
+	UploadID   uint   `json:"upload_id" binding:"required"` // A portfolio upload from POST /uploads
+	Caption    string `json:"caption"`
+	CategoryID *uint  `json:"category_id"`
+	ServiceID  *uint  `json:"service_id"`
+	IsCover    bool   `json:"is_cover"`
+}
+
+// UpdatePortfolioItemRequest changes the fields that are present. A
+// category_id or service_id of 0 removes the tag.
+type UpdatePortfolioItemRequest struct {
+	Caption    *string `json:"caption"`
+	CategoryID *uint   `json:"category_id"`
+	ServiceID  *uint   `json:"service_id"`
+	IsCover    *bool   `json:"is_cover"`
+}
+
+type ReorderPortfolioRequest struct {
+	IDs []uint `json:"ids" binding:"required"` // Every item of the portfolio, in the new order
+}
+
+// GetProviderPortfolio lists a provider's whole gallery. Query parameters
+// category_id and service_id narrow it to a tag.
+func (h *PortfolioHandler) GetProviderPortfolio(c *gin.Context) {
+	var provider models.ServiceProvider
+	if err := h.DB.First(&provider, c.Param("id")).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider not found")
+		return
+	}
+
+	query := h.DB.Where("provider_id = ?", provider.ID)
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		query = query.Where("category_id = ?", categoryID)
+	}
+	if serviceID := c.Query("service_id"); serviceID != "" {
+		query = query.Where("service_id = ?", serviceID)
+	}
+
+	var items []models.PortfolioItem
+	if err := query.Order("position ASC, id ASC").Find(&items).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch portfolio")
+		return
+	}
+	fillPortfolioURLs(h.Signer, items)
+
+	utils.SuccessResponse(c, http.StatusOK, "Portfolio retrieved successfully", items)
+}
+
+// GetPortfolio lists the current provider's gallery.
+func (h *PortfolioHandler) GetPortfolio(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var items []models.PortfolioItem
+	if err := h.DB.Where("provider_id = ?", provider.ID).Order("position ASC, id ASC").Find(&items).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch portfolio")
+		return
+	}
+	fillPortfolioURLs(h.Signer, items)
+
+	utils.SuccessResponse(c, http.StatusOK, "Portfolio retrieved successfully", items)
+}
+
+// CreatePortfolioItem adds an uploaded image to the end of the gallery and
+// makes its thumbnail.
+func (h *PortfolioHandler) CreatePortfolioItem(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req CreatePortfolioItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	var upload models.Upload
+	if err := h.DB.Where("id = ? AND user_id = ? AND purpose = ?", req.UploadID, provider.UserID, models.UploadPortfolio).
+		First(&upload).Error; err != nil {
+		utils.BadRequestResponse(c, "Upload not found. Upload the image with purpose portfolio first")
+		return
+	}
+	if msg := h.checkTags(&provider, req.CategoryID, req.ServiceID); msg != "" {
+		utils.BadRequestResponse(c, msg)
+		return
+	}
+
+	item := models.PortfolioItem{
+		ProviderID: provider.ID,
+		UploadID:   upload.ID,
+		ImageKey:   upload.Key,
+		Caption:    req.Caption,
+		CategoryID: nonZero(req.CategoryID),
+		ServiceID:  nonZero(req.ServiceID),
+		IsCover:    req.IsCover,
+	}
+
+	thumb, err := storage.MakeThumbnail(c.Request.Context(), h.Store, upload.Key, thumbnailSide)
+	switch {
+	case err == nil:
+		item.ThumbnailKey = thumb.Key
+		item.Width, item.Height = thumb.Width, thumb.Height
+	case errors.Is(err, storage.ErrUnsupportedImage):
+		// Shown at full size
+	default:
+		log.Printf("Failed to make thumbnail of %s: %v", upload.Key, err)
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var last struct{ Max *int }
+		if err := tx.Model(&models.PortfolioItem{}).Select("MAX(position) AS max").
+			Where("provider_id = ?", provider.ID).Scan(&last).Error; err != nil {
+			return err
+		}
+		if last.Max != nil {
+			item.Position = *last.Max + 1
+		}
+		if item.IsCover {
+			if err := clearCover(tx, provider.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&item).Error
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to add portfolio item")
+		return
+	}
+	item.ImageURL = h.Signer.URL(item.ImageKey)
+	item.ThumbnailURL = thumbnailURL(h.Signer, &item)
+
+	utils.SuccessResponse(c, http.StatusCreated, "Portfolio item added successfully", item)
+}
+
+func (h *PortfolioHandler) UpdatePortfolioItem(c *gin.Context) {
+	item, provider, ok := h.findOwnItem(c)
+	if !ok {
+		return
+	}
+
+	var req UpdatePortfolioItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	if msg := h.checkTags(&provider, req.CategoryID, req.ServiceID); msg != "" {
+		utils.BadRequestResponse(c, msg)
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Caption != nil {
+		updates["caption"] = *req.Caption
+	}
+	if req.CategoryID != nil {
+		updates["category_id"] = nonZero(req.CategoryID)
+	}
+	if req.ServiceID != nil {
+		updates["service_id"] = nonZero(req.ServiceID)
+	}
+	if req.IsCover != nil {
+		updates["is_cover"] = *req.IsCover
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if req.IsCover != nil && *req.IsCover {
+			if err := clearCover(tx, provider.ID); err != nil {
+				return err
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(&item).Updates(updates).Error
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update portfolio item")
+		return
+	}
+
+	h.DB.First(&item, item.ID)
+	item.ImageURL = h.Signer.URL(item.ImageKey)
+	item.ThumbnailURL = thumbnailURL(h.Signer, &item)
+
+	utils.SuccessResponse(c, http.StatusOK, "Portfolio item updated successfully", item)
+}
+
+// DeletePortfolioItem removes an image from the gallery. The upload itself
+// stays until it is deleted from /uploads.
+func (h *PortfolioHandler) DeletePortfolioItem(c *gin.Context) {
+	item, _, ok := h.findOwnItem(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(&item).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete portfolio item")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Portfolio item deleted successfully", nil)
+}
+
+// ReorderPortfolio sets the gallery order from a list of every item ID.
+func (h *PortfolioHandler) ReorderPortfolio(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req ReorderPortfolioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	var ids []uint
+	if err := h.DB.Model(&models.PortfolioItem{}).Where("provider_id = ?", provider.ID).Pluck("id", &ids).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to reorder portfolio")
+		return
+	}
+	owned := map[uint]bool{}
+	for _, id := range ids {
+		owned[id] = true
+	}
+	seen := map[uint]bool{}
+	for _, id := range req.IDs {
+		if !owned[id] || seen[id] {
+			utils.BadRequestResponse(c, "IDs must list each portfolio item exactly once")
+			return
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(owned) {
+		utils.BadRequestResponse(c, "IDs must list each portfolio item exactly once")
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range req.IDs {
+			if err := tx.Model(&models.PortfolioItem{}).Where("id = ?", id).Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to reorder portfolio")
+		return
+	}
+
+	h.GetPortfolio(c)
+}
+
+// checkTags validates tags against the provider's categories and services.
+// It returns a message for the client, or "" when they are fine.
+func (h *PortfolioHandler) checkTags(provider *models.ServiceProvider, categoryID, serviceID *uint) string {
+	if categoryID != nil && *categoryID != 0 {
+		var count int64
+		h.DB.Model(&models.Category{}).Where("id = ?", *categoryID).Count(&count)
+		if count == 0 {
+			return "Category not found"
+		}
+	}
+	if serviceID != nil && *serviceID != 0 {
+		var count int64
+		h.DB.Model(&models.Service{}).Where("id = ? AND provider_id = ?", *serviceID, provider.ID).Count(&count)
+		if count == 0 {
+			return "Service not found"
+		}
+	}
+	return ""
+}
+
+func (h *PortfolioHandler) findOwnItem(c *gin.Context) (models.PortfolioItem, models.ServiceProvider, bool) {
+	var item models.PortfolioItem
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return item, provider, false
+	}
+
+	if err := h.DB.Where("id = ? AND provider_id = ?", c.Param("id"), provider.ID).First(&item).Error; err != nil {
+		utils.NotFoundResponse(c, "Portfolio item not found")
+		return item, provider, false
+	}
+	return item, provider, true
+}
+
+func clearCover(tx *gorm.DB, providerID uint) error {
+	return tx.Model(&models.PortfolioItem{}).
+		Where("provider_id = ? AND is_cover = ?", providerID, true).
+		Update("is_cover", false).Error
+}
+
+func nonZero(id *uint) *uint {
+	if id == nil || *id == 0 {
+		return nil
+	}
+	return id
+}
+
+func thumbnailURL(signer *storage.Signer, item *models.PortfolioItem) string {
+	if item.ThumbnailKey == "" {
+		return signer.URL(item.ImageKey)
+	}
+	return signer.URL(item.ThumbnailKey)
+}
+
+func fillPortfolioURLs(signer *storage.Signer, items []models.PortfolioItem) {
+	for i := range items {
+		items[i].ImageURL = signer.URL(items[i].ImageKey)
+		items[i].ThumbnailURL = thumbnailURL(signer, &items[i])
+	}
+}
+
+// attachPortfolioPreviews fills the cover image and the first few gallery
+// items of each provider, with one query for all of them.
+func attachPortfolioPreviews(db *gorm.DB, providers []models.ServiceProvider) error {
+	if len(providers) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(providers))
+	for i, p := range providers {
+		ids[i] = p.ID
+	}
+
+	// The cover ranks first so it is part of the preview wherever it sits
+	var items []models.PortfolioItem
+	if err := db.Raw(`SELECT * FROM (
+			SELECT portfolio_items.*, ROW_NUMBER() OVER (
+				PARTITION BY provider_id ORDER BY is_cover DESC, position, id
+			) AS preview_rank
+			FROM portfolio_items
+			WHERE provider_id IN ? AND deleted_at IS NULL
+		) ranked
+		WHERE preview_rank <= ?
+		ORDER BY provider_id, position, id`, ids, portfolioPreviewSize).
+		Scan(&items).Error; err != nil {
+		return err
+	}
+	fillPortfolioURLs(storage.DefaultSigner, items)
+
+	byProvider := map[uint][]models.PortfolioItem{}
+	for _, item := range items {
+		byProvider[item.ProviderID] = append(byProvider[item.ProviderID], item)
+	}
+	for i := range providers {
+		preview := byProvider[providers[i].ID]
+		providers[i].Portfolio = preview
+		providers[i].CoverImage = nil
+		for j := range preview {
+			if preview[j].IsCover {
+				cover := preview[j]
+				providers[i].CoverImage = &cover
+			}
+		}
+		if providers[i].CoverImage == nil && len(preview) > 0 {
+			cover := preview[0]
+			providers[i].CoverImage = &cover
+		}
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+	"pluralink/backend/promos"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// promoCodePattern is what codes may look like once upper-cased.
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,40}$`)
+
+type PromoHandler struct {
+	DB *gorm.DB
+}
+
+func NewPromoHandler(db *gorm.DB) *PromoHandler {
+	return &PromoHandler{DB: db}
+}
+
+// PromoCodeRequest creates a promo code or replaces one's settings.
+type PromoCodeRequest struct {
+	Code             string           `json:"code" binding:"required"`
+	Description      string           `json:"description"`
+	Kind             models.PromoKind `json:"kind" binding:"required"`
+	Value            int64            `json:"value" binding:"required"` // Percent off, or minor units off
+	StartsAt         *time.Time       `json:"starts_at"`
+	EndsAt           *time.Time       `json:"ends_at"`
+	MaxRedemptions   int              `json:"max_redemptions"`
+	MaxPerClient     int              `json:"max_per_client"`
+	FirstBookingOnly bool             `json:"first_booking_only"`
+	IsActive         *bool            `json:"is_active"`   // Defaults to true
+	ServiceIDs       []uint           `json:"service_ids"` // None for every service
+}
+
+type CheckPromoCodeRequest struct {
+	ProviderID uint   `json:"provider_id" binding:"required"`
+	ServiceID  uint   `json:"service_id" binding:"required"`
+	Code       string `json:"code" binding:"required"`
+}
+
+// PromoQuote is what a promo code would take off a service booked now.
+type PromoQuote struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	Price       int64  `json:"price"` // In minor units of Currency
+	Discount    int64  `json:"discount"`
+	Currency    string `json:"currency"`
+}
+
+func (h *PromoHandler) GetPromoCodes(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var codes []models.PromoCode
+	if err := h.DB.Preload("Services").Where("provider_id = ?", provider.ID).
+		Order("created_at DESC").Find(&codes).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch promo codes")
+		return
+	}
+	for i := range codes {
+		n, err := promos.Redemptions(h.DB, codes[i].ID)
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch promo codes")
+			return
+		}
+		codes[i].Redemptions = n
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Promo codes retrieved successfully", codes)
+}
+
+func (h *PromoHandler) CreatePromoCode(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req PromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	promo := models.PromoCode{ProviderID: provider.ID}
+	services, msg := h.applyRequest(&provider, &promo, &req)
+	if msg != "" {
+		utils.BadRequestResponse(c, msg)
+		return
+	}
+
+	var existing int64
+	h.DB.Model(&models.PromoCode{}).Where("provider_id = ? AND code = ?", provider.ID, promo.Code).Count(&existing)
+	if existing > 0 {
+		utils.BadRequestResponse(c, "A promo code with this code already exists")
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Services").Create(&promo).Error; err != nil {
+			return err
+		}
+		// Create leaves false to the column's default
+		if !promo.IsActive {
+			if err := tx.Model(&promo).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&promo).Association("Services").Replace(services)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create promo code")
+		return
+	}
+
+	h.DB.Preload("Services").First(&promo, promo.ID)
+	utils.SuccessResponse(c, http.StatusCreated, "Promo code created successfully", promo)
+}
+
+// UpdatePromoCode replaces a promo code's settings. Bookings already made
+// with it keep their discount.
+func (h *PromoHandler) UpdatePromoCode(c *gin.Context) {
+	promo, provider, ok := h.findOwnPromoCode(c)
+	if !ok {
+		return
+	}
+
+	var req PromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	services, msg := h.applyRequest(&provider, &promo, &req)
+	if msg != "" {
+		utils.BadRequestResponse(c, msg)
+		return
+	}
+
+	var existing int64
+	h.DB.Model(&models.PromoCode{}).Where("provider_id = ? AND code = ? AND id <> ?", provider.ID, promo.Code, promo.ID).Count(&existing)
+	if existing > 0 {
+		utils.BadRequestResponse(c, "A promo code with this code already exists")
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Services").Save(&promo).Error; err != nil {
+			return err
+		}
+		return tx.Model(&promo).Association("Services").Replace(services)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update promo code")
+		return
+	}
+
+	h.DB.Preload("Services").First(&promo, promo.ID)
+	utils.SuccessResponse(c, http.StatusOK, "Promo code updated successfully", promo)
+}
+
+// DeletePromoCode withdraws a promo code. Its redemptions are kept.
+func (h *PromoHandler) DeletePromoCode(c *gin.Context) {
+	promo, _, ok := h.findOwnPromoCode(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(&promo).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete promo code")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Promo code deleted successfully", nil)
+}
+
+// GetPromoRedemptions lists the bookings made with a promo code, newest
+// first, cancelled ones included.
+func (h *PromoHandler) GetPromoRedemptions(c *gin.Context) {
+	promo, _, ok := h.findOwnPromoCode(c)
+	if !ok {
+		return
+	}
+
+	var redemptions []models.PromoRedemption
+	if err := h.DB.Where("promo_code_id = ?", promo.ID).
+		Preload("Booking").Preload("Booking.Client").Preload("Booking.Client.User").Preload("Booking.Service").
+		Order("created_at DESC").Find(&redemptions).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch redemptions")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Redemptions retrieved successfully", redemptions)
+}
+
+// CheckPromoCode tells a client what a promo code would take off a service
+// if they booked it now.
+func (h *PromoHandler) CheckPromoCode(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CheckPromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	var client models.Client
+	if err := h.DB.Where("user_id = ?", userID).First(&client).Error; err != nil {
+		utils.NotFoundResponse(c, "Client profile not found")
+		return
+	}
+	var service models.Service
+	if err := h.DB.Where("id = ? AND provider_id = ?", req.ServiceID, req.ProviderID).First(&service).Error; err != nil {
+		utils.NotFoundResponse(c, "Service not found")
+		return
+	}
+
+	promo, err := promos.Find(h.DB, req.ProviderID, req.Code)
+	var discount money.Money
+	if err == nil {
+		discount, err = promos.Check(h.DB, promo, client.ID, &service, time.Now())
+	}
+	var rejection promos.Rejection
+	if errors.As(err, &rejection) {
+		utils.BadRequestResponse(c, rejection.Error())
+		return
+	}
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to check promo code")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Promo code applies", PromoQuote{
+		Code:        promo.Code,
+		Description: promo.Description,
+		Price:       service.Price,
+		Discount:    discount.Amount,
+		Currency:    service.Currency,
+	})
+}
+
+// applyRequest validates a request and sets it on promo. It returns the
+// services the code is limited to, or a message for the client.
+func (h *PromoHandler) applyRequest(provider *models.ServiceProvider, promo *models.PromoCode, req *PromoCodeRequest) ([]models.Service, string) {
+	code := promos.Normalize(req.Code)
+	if !promoCodePattern.MatchString(code) {
+		return nil, "Code must be 3 to 40 letters, digits, dashes or underscores"
+	}
+	switch req.Kind {
+	case models.PromoPercent:
+		if req.Value < 1 || req.Value > 100 {
+			return nil, "Percent off must be between 1 and 100"
+		}
+	case models.PromoFixed:
+		if req.Value < 1 {
+			return nil, "Amount off must be positive"
+		}
+	default:
+		return nil, "Invalid kind. Use percent or fixed"
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.StartsAt.Before(*req.EndsAt) {
+		return nil, "starts_at must be before ends_at"
+	}
+	if req.MaxRedemptions < 0 || req.MaxPerClient < 0 {
+		return nil, "Limits cannot be negative"
+	}
+
+	var services []models.Service
+	if len(req.ServiceIDs) > 0 {
+		if err := h.DB.Where("id IN ? AND provider_id = ?", req.ServiceIDs, provider.ID).Find(&services).Error; err != nil || len(services) != len(uniqueIDs(req.ServiceIDs)) {
+			return nil, "Service not found"
+		}
+	}
+
+	promo.Code = code
+	promo.Description = req.Description
+	promo.Kind = req.Kind
+	promo.Value = req.Value
+	promo.StartsAt = req.StartsAt
+	promo.EndsAt = req.EndsAt
+	promo.MaxRedemptions = req.MaxRedemptions
+	promo.MaxPerClient = req.MaxPerClient
+	promo.FirstBookingOnly = req.FirstBookingOnly
+	promo.IsActive = req.IsActive == nil || *req.IsActive
+	return services, ""
+}
+
+func (h *PromoHandler) findOwnPromoCode(c *gin.Context) (models.PromoCode, models.ServiceProvider, bool) {
+	var promo models.PromoCode
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return promo, provider, false
+	}
+
+	if err := h.DB.Where("id = ? AND provider_id = ?", c.Param("id"), provider.ID).First(&promo).Error; err != nil {
+		utils.NotFoundResponse(c, "Promo code not found")
+		return promo, provider, false
+	}
+	return promo, provider, true
+}
+
+func uniqueIDs(ids []uint) map[uint]bool {
+	seen := map[uint]bool{}
+	for _, id := range ids {
+		seen[id] = true
+	}
+	return seen
+}
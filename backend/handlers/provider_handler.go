@@ -1,14 +1,20 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"pluralink/backend/models"
+	"pluralink/backend/money"
+	"pluralink/backend/payments"
 	"pluralink/backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProviderHandler struct {
@@ -44,6 +50,10 @@ func (h *ProviderHandler) GetProviders(c *gin.Context) {
 		utils.InternalServerErrorResponse(c, "Failed to fetch providers")
 		return
 	}
+	if err := attachPortfolioPreviews(h.DB, providers); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch providers")
+		return
+	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Providers retrieved successfully", providers)
 }
@@ -62,6 +72,12 @@ func (h *ProviderHandler) GetProvider(c *gin.Context) {
 		utils.NotFoundResponse(c, "Provider not found")
 		return
 	}
+	providers := []models.ServiceProvider{provider}
+	if err := attachPortfolioPreviews(h.DB, providers); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch provider")
+		return
+	}
+	provider = providers[0]
 
 	utils.SuccessResponse(c, http.StatusOK, "Provider retrieved successfully", provider)
 }
@@ -81,6 +97,8 @@ func (h *ProviderHandler) CreateProvider(c *gin.Context) {
 		Longitude    float64 `json:"longitude"`
 		Phone        string  `json:"phone"`
 		Website      string  `json:"website"`
+		TimeZone     string  `json:"time_zone"`
+		Currency     string  `json:"currency"` // Defaults to the country's
 		CategoryIDs  []uint  `json:"category_ids"`
 	}
 
@@ -89,6 +107,24 @@ func (h *ProviderHandler) CreateProvider(c *gin.Context) {
 		return
 	}
 
+	if req.TimeZone == "" {
+		req.TimeZone = "UTC"
+	} else if _, err := time.LoadLocation(req.TimeZone); err != nil {
+		utils.BadRequestResponse(c, "Invalid time zone")
+		return
+	}
+
+	if req.Currency == "" {
+		req.Currency = money.ForCountry(req.Country)
+	}
+	if req.Currency == "" {
+		req.Currency = payments.DefaultCurrency
+	}
+	if !money.Valid(req.Currency) {
+		utils.BadRequestResponse(c, "Invalid currency. Use an ISO 4217 code")
+		return
+	}
+
 	// Check if provider already exists
 	var existing models.ServiceProvider
 	if err := h.DB.Where("user_id = ?", userID).First(&existing).Error; err == nil {
@@ -109,6 +145,8 @@ func (h *ProviderHandler) CreateProvider(c *gin.Context) {
 		Longitude:    req.Longitude,
 		Phone:        req.Phone,
 		Website:      req.Website,
+		TimeZone:     req.TimeZone,
+		Currency:     strings.ToLower(req.Currency),
 	}
 
 	if err := h.DB.Create(&provider).Error; err != nil {
@@ -149,6 +187,8 @@ func (h *ProviderHandler) UpdateProvider(c *gin.Context) {
 		Longitude    float64  `json:"longitude"`
 		Phone        string   `json:"phone"`
 		Website      string   `json:"website"`
+		TimeZone     string   `json:"time_zone"`
+		Currency     string   `json:"currency"`
 		CategoryIDs  []uint   `json:"category_ids"`
 	}
 
@@ -190,8 +230,108 @@ func (h *ProviderHandler) UpdateProvider(c *gin.Context) {
 	if req.Website != "" {
 		provider.Website = req.Website
 	}
+	if req.TimeZone != "" {
+		if _, err := time.LoadLocation(req.TimeZone); err != nil {
+			utils.BadRequestResponse(c, "Invalid time zone")
+			return
+		}
+		provider.TimeZone = req.TimeZone
+	}
+	currency := ""
+	if req.Currency != "" && !strings.EqualFold(req.Currency, provider.Currency) {
+		if !money.Valid(req.Currency) {
+			utils.BadRequestResponse(c, "Invalid currency. Use an ISO 4217 code")
+			return
+		}
+		currency = strings.ToLower(req.Currency)
+	}
 
-	if err := h.DB.Save(&provider).Error; err != nil {
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Hold the provider row while checking nothing is priced in the
+		// old currency yet
+		var locked models.ServiceProvider
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, provider.ID).Error; err != nil {
+			return err
+		}
+		previousCurrency := locked.Currency
+		provider.Currency = previousCurrency
+		if currency != "" && currency != previousCurrency {
+			if err := checkCurrencyChange(tx, provider.ID); err != nil {
+				return err
+			}
+			provider.Currency = currency
+		}
+
+		if err := tx.Save(&provider).Error; err != nil {
+			return err
+		}
+		if provider.Currency == previousCurrency {
+			return nil
+		}
+		// Services, discounts, rewards, packages and plans keep their amounts, in the new
+		// currency's minor units
+		var services []models.Service
+		if err := tx.Where("provider_id = ?", provider.ID).Find(&services).Error; err != nil {
+			return err
+		}
+		for _, s := range services {
+			if err := tx.Model(&s).Updates(map[string]interface{}{
+				"price":    money.Rescale(s.Price, previousCurrency, provider.Currency),
+				"currency": provider.Currency,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		var codes []models.PromoCode
+		if err := tx.Where("provider_id = ? AND kind = ?", provider.ID, models.PromoFixed).Find(&codes).Error; err != nil {
+			return err
+		}
+		for _, p := range codes {
+			if err := tx.Model(&p).Update("value", money.Rescale(p.Value, previousCurrency, provider.Currency)).Error; err != nil {
+				return err
+			}
+		}
+		var rewards []models.LoyaltyReward
+		if err := tx.Where("provider_id = ? AND kind = ?", provider.ID, models.RewardDiscount).Find(&rewards).Error; err != nil {
+			return err
+		}
+		for _, r := range rewards {
+			if err := tx.Model(&r).Update("amount", money.Rescale(r.Amount, previousCurrency, provider.Currency)).Error; err != nil {
+				return err
+			}
+		}
+		var packages []models.Package
+		if err := tx.Where("provider_id = ?", provider.ID).Find(&packages).Error; err != nil {
+			return err
+		}
+		for _, p := range packages {
+			if err := tx.Model(&p).Updates(map[string]interface{}{
+				"price":    money.Rescale(p.Price, previousCurrency, provider.Currency),
+				"currency": provider.Currency,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		var plans []models.MembershipPlan
+		if err := tx.Where("provider_id = ?", provider.ID).Find(&plans).Error; err != nil {
+			return err
+		}
+		for _, p := range plans {
+			if err := tx.Model(&p).Updates(map[string]interface{}{
+				"price":    money.Rescale(p.Price, previousCurrency, provider.Currency),
+				"currency": provider.Currency,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	var rejected rejection
+	if errors.As(err, &rejected) {
+		utils.BadRequestResponse(c, rejected.Error())
+		return
+	}
+	if err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to update provider")
 		return
 	}
@@ -208,6 +348,50 @@ func (h *ProviderHandler) UpdateProvider(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "Provider updated successfully", provider)
 }
 
+// currencyInUse tells why a provider's currency cannot be changed.
+type currencyInUse string
+
+func (e currencyInUse) Error() string { return string(e) }
+func (currencyInUse) Rejected()       {}
+
+// checkCurrencyChange refuses a new currency while amounts in the old one
+// are paid or held: payments, gift card balances, package sales and
+// running memberships would no longer add up.
+func checkCurrencyChange(tx *gorm.DB, providerID uint) error {
+	var taken int64
+	if err := tx.Model(&models.Payment{}).Joins("JOIN bookings ON bookings.id = payments.booking_id").
+		Where("bookings.provider_id = ?", providerID).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return currencyInUse("Currency cannot be changed after taking payments")
+	}
+	var held int64
+	if err := tx.Model(&models.GiftCard{}).Where("provider_id = ? AND status <> ?", providerID, models.GiftCardVoid).
+		Count(&held).Error; err != nil {
+		return err
+	}
+	if held > 0 {
+		return currencyInUse("Currency cannot be changed while gift cards are outstanding")
+	}
+	if err := tx.Model(&models.ClientPackage{}).Where("provider_id = ? AND status <> ?", providerID, models.ClientPackageVoid).
+		Count(&held).Error; err != nil {
+		return err
+	}
+	if held > 0 {
+		return currencyInUse("Currency cannot be changed after selling packages")
+	}
+	if err := tx.Model(&models.Membership{}).Where("provider_id = ? AND status IN ?", providerID,
+		[]models.MembershipStatus{models.MembershipPending, models.MembershipActive, models.MembershipPastDue}).
+		Count(&held).Error; err != nil {
+		return err
+	}
+	if held > 0 {
+		return currencyInUse("Currency cannot be changed while memberships are running")
+	}
+	return nil
+}
+
 func (h *ProviderHandler) GetProviderReviews(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
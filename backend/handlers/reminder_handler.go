@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+
+	"pluralink/backend/models"
+	"pluralink/backend/reminders"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ReminderHandler struct {
+	DB *gorm.DB
+}
+
+func NewReminderHandler(db *gorm.DB) *ReminderHandler {
+	return &ReminderHandler{DB: db}
+}
+
+type UpdateReminderSettingsRequest struct {
+	OffsetsMinutes []int `json:"offsets_minutes"`
+}
+
+func (h *ReminderHandler) GetSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var provider models.ServiceProvider
+	if err := h.DB.Where("user_id = ?", userID).First(&provider).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider profile not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Reminder settings retrieved successfully", gin.H{
+		"offsets_minutes": reminders.Offsets(&provider),
+	})
+}
+
+// UpdateSettings changes the offsets used for bookings confirmed from now
+// on; reminders already scheduled keep their times.
+func (h *ReminderHandler) UpdateSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var provider models.ServiceProvider
+	if err := h.DB.Where("user_id = ?", userID).First(&provider).Error; err != nil {
+		utils.NotFoundResponse(c, "Provider profile not found")
+		return
+	}
+
+	var req UpdateReminderSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	offsets, err := reminders.FormatOffsets(req.OffsetsMinutes)
+	if err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	if err := h.DB.Model(&provider).Update("reminder_offsets", offsets).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update reminder settings")
+		return
+	}
+	provider.ReminderOffsets = offsets
+
+	utils.SuccessResponse(c, http.StatusOK, "Reminder settings updated successfully", gin.H{
+		"offsets_minutes": reminders.Offsets(&provider),
+	})
+}
+
+func (h *ReminderHandler) GetBookingReminders(c *gin.Context) {
+	id := c.Param("id")
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+
+	var booking models.Booking
+	if err := h.DB.First(&booking, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Booking not found")
+		return
+	}
+
+	// Verify user has access to this booking
+	if userRole == models.RoleProvider {
+		var provider models.ServiceProvider
+		h.DB.Where("user_id = ?", userID).First(&provider)
+		if booking.ProviderID != provider.ID {
+			utils.ErrorResponse(c, http.StatusForbidden, "Access denied")
+			return
+		}
+	} else {
+		var client models.Client
+		h.DB.Where("user_id = ?", userID).First(&client)
+		if booking.ClientID != client.ID {
+			utils.ErrorResponse(c, http.StatusForbidden, "Access denied")
+			return
+		}
+	}
+
+	var list []models.Reminder
+	if err := h.DB.Where("booking_id = ?", booking.ID).Order("send_at ASC").Find(&list).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch reminders")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Reminders retrieved successfully", list)
+}
@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"strconv"
 
+	"pluralink/backend/jobs"
 	"pluralink/backend/models"
+	"pluralink/backend/notifications"
 	"pluralink/backend/utils"
+	"pluralink/backend/webhooks"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -89,6 +93,16 @@ func (h *ReviewHandler) CreateReview(c *gin.Context) {
 		return
 	}
 
+	notify(h.DB, notifications.Event{
+		Type:        notifications.EventReviewReceived,
+		BookingID:   review.BookingID,
+		ReviewID:    review.ID,
+		ActorUserID: review.ReviewerID,
+	})
+	if err := jobs.PublishWebhook(h.DB, booking.ProviderID, webhooks.EventReviewCreated, webhooks.NewReviewData(&review)); err != nil {
+		log.Printf("Failed to publish webhook for review %d: %v", review.ID, err)
+	}
+
 	h.DB.Preload("Booking").First(&review, review.ID)
 
 	utils.SuccessResponse(c, http.StatusCreated, "Review created successfully", review)
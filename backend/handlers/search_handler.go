@@ -32,8 +32,8 @@ func (h *SearchHandler) SearchProviders(c *gin.Context) {
 	// Location-based search (simple distance calculation)
 	if latStr := c.Query("latitude"); latStr != "" {
 		if lonStr := c.Query("longitude"); lonStr != "" {
-			lat, err1 := strconv.ParseFloat(latStr, 64)
-			lon, err2 := strconv.ParseFloat(lonStr, 64)
+			_, err1 := strconv.ParseFloat(latStr, 64)
+			_, err2 := strconv.ParseFloat(lonStr, 64)
 			if err1 == nil && err2 == nil {
 				// Simple bounding box search (for production, use PostGIS for accurate distance)
 				// This is a placeholder - implement proper geospatial query
@@ -61,6 +61,10 @@ func (h *SearchHandler) SearchProviders(c *gin.Context) {
 		utils.InternalServerErrorResponse(c, "Failed to search providers")
 		return
 	}
+	if err := attachPortfolioPreviews(h.DB, providers); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to search providers")
+		return
+	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Search completed successfully", providers)
 }
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/realtime"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// Most events replayed to a resuming client before it is told to reload
+	resumeLimit = 500
+	// Comment lines keep proxies from closing idle streams
+	heartbeatInterval = 25 * time.Second
+)
+
+type StreamHandler struct {
+	DB  *gorm.DB
+	Hub *realtime.Hub
+}
+
+func NewStreamHandler(db *gorm.DB, hub *realtime.Hub) *StreamHandler {
+	return &StreamHandler{DB: db, Hub: hub}
+}
+
+// StreamBookings streams changes to the current user's bookings as
+// Server-Sent Events. Clients resume by sending the last event ID they saw
+// in the Last-Event-ID header or the last_event_id query parameter. If too
+// much was missed, a "reset" event tells them to reload their bookings.
+func (h *StreamHandler) StreamBookings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	uid := userID.(uint)
+
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+	var after uint64
+	resuming := lastID != ""
+	if resuming {
+		var err error
+		if after, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+			utils.BadRequestResponse(c, "Invalid last event ID")
+			return
+		}
+	}
+
+	// Subscribe before reading the backlog so nothing falls in between
+	sub := h.Hub.Subscribe(uid)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprint(w, "retry: 5000\n\n")
+
+	sent := map[uint]bool{}
+	if resuming {
+		backlog, err := realtime.Since(h.DB, uid, uint(after), resumeLimit+1)
+		if err != nil {
+			log.Printf("Failed to load booking events for user %d: %v", uid, err)
+			return
+		}
+		if len(backlog) > resumeLimit {
+			// Move the client's position to the newest event so it does
+			// not get reset again on its next reconnect
+			var latest uint
+			h.DB.Model(&models.BookingEvent{}).
+				Where("provider_user_id = ? OR client_user_id = ?", uid, uid).
+				Select("COALESCE(MAX(id), 0)").Row().Scan(&latest)
+			fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", latest)
+		} else {
+			for i := range backlog {
+				if !writeEvent(w, &backlog[i]) {
+					return
+				}
+				sent[backlog[i].ID] = true
+			}
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// Fell behind; the client reconnects and resumes
+				return
+			}
+			if sent[event.ID] {
+				continue
+			}
+			if !writeEvent(w, event) {
+				return
+			}
+			w.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		}
+	}
+}
+
+func writeEvent(w gin.ResponseWriter, event *models.BookingEvent) bool {
+	data, err := realtime.Encode(event)
+	if err != nil {
+		log.Printf("Failed to encode booking event %d: %v", event.ID, err)
+		return true
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err == nil
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/taxes"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TaxHandler struct {
+	DB *gorm.DB
+}
+
+func NewTaxHandler(db *gorm.DB) *TaxHandler {
+	return &TaxHandler{DB: db}
+}
+
+// TaxSummaryResponse is the tax a provider charged over a period, in minor
+// units, for filing returns. Bookings count on the day of the appointment:
+// completed ones for their price, cancelled ones for the fee kept.
+// Packages and membership months count on the day they were paid, as the
+// bookings they cover carry no tax.
+type TaxSummaryResponse struct {
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Currency string           `json:"currency"`
+	Rates    []TaxSummaryLine `json:"rates"`
+	Taxable  int64            `json:"taxable"` // Sales taxed, net of tax
+	Exempt   int64            `json:"exempt"`  // Sales not taxed
+	Tax      int64            `json:"tax"`
+}
+
+// TaxSummaryLine is what was charged at one rate.
+type TaxSummaryLine struct {
+	Name      string `json:"name"`
+	Rate      int    `json:"rate"` // In hundredths of a percent
+	Inclusive bool   `json:"inclusive"`
+	Bookings  int    `json:"bookings"`
+	Sales     int    `json:"sales"` // Packages and membership months
+	Net       int64  `json:"net"`
+	Tax       int64  `json:"tax"`
+}
+
+// GetTaxRule returns the tax rule the provider's bookings are charged
+// under, or null when their region has none.
+func (h *TaxHandler) GetTaxRule(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+	rule, err := taxes.RuleFor(h.DB, &provider)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch tax rule")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Tax rule retrieved successfully", rule)
+}
+
+// GetTaxSummary sums up the tax on the provider's bookings, packages and
+// memberships between from and to (RFC 3339), defaulting to the current
+// month.
+func (h *TaxHandler) GetTaxSummary(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	loc := provider.Location()
+	now := time.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 1, 0)
+	from, to, ok = queryPeriod(c, from, to)
+	if !ok {
+		return
+	}
+
+	var bookings []models.Booking
+	if err := h.DB.Where("provider_id = ? AND date >= ? AND date < ? AND status IN ?",
+		provider.ID, from.In(loc).Format("2006-01-02"), to.In(loc).Format("2006-01-02"),
+		[]models.BookingStatus{models.StatusCompleted, models.StatusCancelled, models.StatusExpired}).
+		Order("date, start_time").Find(&bookings).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch bookings")
+		return
+	}
+
+	kept, err := h.keptAmounts(bookings)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch payments")
+		return
+	}
+
+	var packages []models.ClientPackage
+	if err := h.DB.Where("provider_id = ? AND status = ? AND paid_at >= ? AND paid_at < ?",
+		provider.ID, models.ClientPackageActive, from, to).Find(&packages).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch packages")
+		return
+	}
+	var months []models.MembershipPayment
+	if err := h.DB.Joins("JOIN memberships ON memberships.id = membership_payments.membership_id").
+		Where("memberships.provider_id = ? AND membership_payments.status = ? AND membership_payments.succeeded_at >= ? AND membership_payments.succeeded_at < ?",
+			provider.ID, models.PaymentSucceeded, from, to).Find(&months).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch membership payments")
+		return
+	}
+
+	resp := TaxSummaryResponse{From: from, To: to, Currency: provider.Currency, Rates: []TaxSummaryLine{}}
+	lines := map[TaxSummaryLine]int{} // Index in Rates, keyed by name, rate and inclusive
+	add := func(key TaxSummaryLine, net, tax int64, sale bool) {
+		if key.Rate <= 0 {
+			resp.Exempt += net
+			return
+		}
+		idx, ok := lines[key]
+		if !ok {
+			idx = len(resp.Rates)
+			lines[key] = idx
+			resp.Rates = append(resp.Rates, key)
+		}
+		if sale {
+			resp.Rates[idx].Sales++
+		} else {
+			resp.Rates[idx].Bookings++
+		}
+		resp.Rates[idx].Net += net
+		resp.Rates[idx].Tax += tax
+		resp.Taxable += net
+		resp.Tax += tax
+	}
+
+	for i := range bookings {
+		b := &bookings[i]
+		var net, tax int64
+		if b.Status == models.StatusCompleted {
+			net, tax = b.Total()-b.Tax, b.Tax
+		} else if kept[b.ID] > 0 {
+			net, tax = taxes.Split(b, kept[b.ID])
+		} else {
+			continue
+		}
+		add(TaxSummaryLine{Name: b.TaxName, Rate: b.TaxRate, Inclusive: b.TaxInclusive}, net, tax, false)
+	}
+	for _, p := range packages {
+		add(TaxSummaryLine{Name: p.TaxName, Rate: p.TaxRate, Inclusive: p.TaxInclusive}, p.Total()-p.Tax, p.Tax, true)
+	}
+	for _, m := range months {
+		add(TaxSummaryLine{Name: m.TaxName, Rate: m.TaxRate, Inclusive: m.TaxInclusive}, m.Total()-m.Tax, m.Tax, true)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Tax summary retrieved successfully", resp)
+}
+
+// keptAmounts returns what the provider kept of what was paid for
+// cancelled bookings, by card or gift card and tips aside, by booking.
+func (h *TaxHandler) keptAmounts(bookings []models.Booking) (map[uint]int64, error) {
+	var ids []uint
+	for _, b := range bookings {
+		if b.Status != models.StatusCompleted {
+			ids = append(ids, b.ID)
+		}
+	}
+	kept := map[uint]int64{}
+	if len(ids) == 0 {
+		return kept, nil
+	}
+
+	var sums []struct {
+		BookingID uint
+		Amount    int64
+	}
+	if err := h.DB.Model(&models.Payment{}).
+		Where("booking_id IN ? AND status = ? AND kind <> ?", ids, models.PaymentSucceeded, models.PaymentTip).
+		Select("booking_id, SUM(amount) AS amount").Group("booking_id").Scan(&sums).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range sums {
+		kept[s.BookingID] += s.Amount
+	}
+	sums = nil
+	if err := h.DB.Model(&models.Refund{}).
+		Where("booking_id IN ? AND status = ?", ids, models.RefundStatusSucceeded).
+		Select("booking_id, SUM(amount) AS amount").Group("booking_id").Scan(&sums).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range sums {
+		kept[s.BookingID] -= s.Amount
+	}
+	// Gift card redemptions are negative and their credits back positive
+	sums = nil
+	if err := h.DB.Model(&models.GiftCardEntry{}).
+		Where("booking_id IN ?", ids).
+		Select("booking_id, SUM(amount) AS amount").Group("booking_id").Scan(&sums).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range sums {
+		kept[s.BookingID] -= s.Amount
+	}
+	return kept, nil
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pluralink/backend/config"
+	"pluralink/backend/models"
+	"pluralink/backend/storage"
+	"pluralink/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type uploadRule struct {
+	types   map[string]string
+	private bool
+}
+
+// uploadRules sets, for each purpose, which files are accepted and whether
+// they need a signed URL to download.
+var uploadRules = map[models.UploadPurpose]uploadRule{
+	models.UploadAvatar:       {types: storage.ImageTypes},
+	models.UploadPortfolio:    {types: storage.ImageTypes},
+	models.UploadReviewPhoto:  {types: storage.ImageTypes},
+	models.UploadReference:    {types: storage.ImageTypes, private: true},
+	models.UploadVerification: {types: storage.DocumentTypes, private: true},
+}
+
+type UploadHandler struct {
+	DB          *gorm.DB
+	Store       storage.Store
+	Signer      *storage.Signer
+	MaxFileSize int64
+}
+
+func NewUploadHandler(db *gorm.DB) *UploadHandler {
+	return &UploadHandler{
+		DB:          db,
+		Store:       storage.Default,
+		Signer:      storage.DefaultSigner,
+		MaxFileSize: maxUploadSize(),
+	}
+}
+
+// maxUploadSize is the configured limit for a single file.
+func maxUploadSize() int64 {
+	size, err := strconv.ParseInt(config.AppConfig.MaxUploadSize, 10, 64)
+	if err != nil || size <= 0 {
+		return defaultMaxFileSize
+	}
+	return size
+}
+
+// CreateUpload stores a file sent as multipart form data with the fields
+// file and purpose.
+func (h *UploadHandler) CreateUpload(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.MaxFileSize+1<<20)
+	purpose := models.UploadPurpose(c.PostForm("purpose"))
+	rule, ok := uploadRules[purpose]
+	if !ok {
+		utils.BadRequestResponse(c, "Invalid purpose. Use avatar, portfolio, review_photo, reference_image or verification_document")
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		utils.BadRequestResponse(c, "Missing file")
+		return
+	}
+
+	prefix := storage.PublicPrefix
+	if rule.private {
+		prefix = storage.PrivatePrefix
+	}
+	policy := storage.Policy{MaxSize: h.MaxFileSize, Types: rule.types}
+	obj, err := storage.Save(c.Request.Context(), h.Store, prefix+string(purpose), header, policy)
+	if err != nil {
+		var invalid *storage.ValidationError
+		if errors.As(err, &invalid) {
+			utils.BadRequestResponse(c, invalid.Error())
+			return
+		}
+		log.Printf("Failed to store upload: %v", err)
+		utils.InternalServerErrorResponse(c, "Failed to store file")
+		return
+	}
+
+	upload := models.Upload{
+		UserID:      userID.(uint),
+		Purpose:     purpose,
+		Key:         obj.Key,
+		FileName:    obj.FileName,
+		ContentType: obj.ContentType,
+		Size:        obj.Size,
+		SHA256:      obj.SHA256,
+		Private:     rule.private,
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockStoredFile(tx, obj.Key); err != nil {
+			return err
+		}
+		// Save skips content it finds stored already, which deleting the
+		// last upload sharing it may have removed since
+		exists, err := h.Store.Exists(c.Request.Context(), obj.Key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if _, err := storage.Save(c.Request.Context(), h.Store, prefix+string(purpose), header, policy); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&upload).Error
+	})
+	if err != nil {
+		log.Printf("Failed to store upload: %v", err)
+		utils.InternalServerErrorResponse(c, "Failed to store file")
+		return
+	}
+	upload.URL = h.Signer.URL(upload.Key)
+
+	utils.SuccessResponse(c, http.StatusCreated, "File uploaded successfully", upload)
+}
+
+// GetUploads lists the current user's files, optionally of one purpose.
+func (h *UploadHandler) GetUploads(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	query := h.DB.Where("user_id = ?", userID)
+	if purpose := c.Query("purpose"); purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+
+	var uploads []models.Upload
+	if err := query.Order("created_at DESC").Find(&uploads).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch uploads")
+		return
+	}
+	for i := range uploads {
+		uploads[i].URL = h.Signer.URL(uploads[i].Key)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Uploads retrieved successfully", uploads)
+}
+
+// GetUpload returns one of the current user's files with a fresh URL.
+func (h *UploadHandler) GetUpload(c *gin.Context) {
+	upload, ok := h.findOwnUpload(c)
+	if !ok {
+		return
+	}
+	upload.URL = h.Signer.URL(upload.Key)
+
+	utils.SuccessResponse(c, http.StatusOK, "Upload retrieved successfully", upload)
+}
+
+// DeleteUpload removes a file. The stored content, and the thumbnail of a
+// portfolio image, go once no other upload shares it.
+func (h *UploadHandler) DeleteUpload(c *gin.Context) {
+	upload, ok := h.findOwnUpload(c)
+	if !ok {
+		return
+	}
+
+	var inUse int64
+	if err := h.DB.Model(&models.PortfolioItem{}).Where("upload_id = ?", upload.ID).Count(&inUse).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete upload")
+		return
+	}
+	if inUse > 0 {
+		utils.BadRequestResponse(c, "Upload is in the portfolio. Remove it from the portfolio first")
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockStoredFile(tx, upload.Key); err != nil {
+			return err
+		}
+		if err := tx.Delete(&upload).Error; err != nil {
+			return err
+		}
+		var shared int64
+		if err := tx.Model(&models.Upload{}).Where("key = ?", upload.Key).Count(&shared).Error; err != nil || shared > 0 {
+			return err
+		}
+
+		keys := []string{upload.Key}
+		if upload.Purpose == models.UploadPortfolio {
+			keys = append(keys, storage.ThumbnailKey(upload.Key, thumbnailSide))
+		}
+		for _, key := range keys {
+			if err := h.Store.Delete(c.Request.Context(), key); err != nil {
+				log.Printf("Failed to delete stored file %s: %v", key, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete upload")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Upload deleted successfully", nil)
+}
+
+// ServeFile streams a stored file. Public files need nothing; private
+// files need the expires and signature of a URL from the signer.
+func (h *UploadHandler) ServeFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if !storage.ValidKey(key) {
+		utils.NotFoundResponse(c, "File not found")
+		return
+	}
+
+	public := storage.IsPublic(key)
+	if !public && !h.Signer.Verify(key, c.Query("expires"), c.Query("signature"), time.Now()) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Link is invalid or has expired")
+		return
+	}
+
+	file, err := h.Store.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			utils.NotFoundResponse(c, "File not found")
+			return
+		}
+		log.Printf("Failed to read stored file %s: %v", key, err)
+		utils.InternalServerErrorResponse(c, "Failed to read file")
+		return
+	}
+	defer file.Body.Close()
+
+	// Names are content hashes, so a key never changes content
+	if public {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "private, no-store")
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, file.Size, file.ContentType, file.Body, nil)
+}
+
+func (h *UploadHandler) findOwnUpload(c *gin.Context) (models.Upload, bool) {
+	userID, _ := c.Get("user_id")
+
+	var upload models.Upload
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&upload).Error; err != nil {
+		utils.NotFoundResponse(c, "Upload not found")
+		return upload, false
+	}
+	return upload, true
+}
+
+// lockStoredFile holds the stored file at key until tx ends, so uploads of
+// the same content are not created while the last one sharing it is being
+// deleted.
+func lockStoredFile(tx *gorm.DB, key string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"pluralink/backend/jobs"
+	"pluralink/backend/models"
+	"pluralink/backend/netguard"
+	"pluralink/backend/utils"
+	"pluralink/backend/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WebhookHandler struct {
+	DB *gorm.DB
+}
+
+func NewWebhookHandler(db *gorm.DB) *WebhookHandler {
+	return &WebhookHandler{DB: db}
+}
+
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Description string   `json:"description"`
+	Events      []string `json:"events" binding:"required"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url"`
+	Description *string  `json:"description"`
+	Events      []string `json:"events"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+// WebhookEndpointResponse is an endpoint with its subscribed events. The
+// secret is only filled in when it was just created or rotated.
+type WebhookEndpointResponse struct {
+	models.WebhookEndpoint
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+func webhookResponse(endpoint models.WebhookEndpoint, withSecret bool) WebhookEndpointResponse {
+	resp := WebhookEndpointResponse{
+		WebhookEndpoint: endpoint,
+		Events:          webhooks.Events(&endpoint),
+	}
+	if withSecret {
+		resp.Secret = endpoint.Secret
+	}
+	return resp
+}
+
+// GetWebhookEventTypes lists the events endpoints can subscribe to.
+func (h *WebhookHandler) GetWebhookEventTypes(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, "Webhook event types retrieved successfully", webhooks.EventTypes)
+}
+
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var endpoints []models.WebhookEndpoint
+	if err := h.DB.Where("provider_id = ?", provider.ID).Order("created_at ASC").Find(&endpoints).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch webhooks")
+		return
+	}
+
+	list := make([]WebhookEndpointResponse, 0, len(endpoints))
+	for _, e := range endpoints {
+		list = append(list, webhookResponse(e, false))
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhooks retrieved successfully", list)
+}
+
+// CreateWebhook registers an endpoint. The response carries the signing
+// secret, which is not shown again.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	if err := netguard.CheckURL(c.Request.Context(), req.URL); err != nil {
+		utils.BadRequestResponse(c, "Invalid webhook URL: "+err.Error())
+		return
+	}
+	events, err := webhooks.ParseEvents(req.Events)
+	if err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create webhook")
+		return
+	}
+
+	endpoint := models.WebhookEndpoint{
+		ProviderID:  provider.ID,
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      events,
+		Secret:      secret,
+		IsActive:    true,
+	}
+
+	if err := h.DB.Create(&endpoint).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create webhook")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Webhook created successfully", webhookResponse(endpoint, true))
+}
+
+// UpdateWebhook changes an endpoint. Turning a disabled endpoint back on
+// clears its failure count.
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	endpoint, ok := h.findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.URL != nil {
+		if err := netguard.CheckURL(c.Request.Context(), *req.URL); err != nil {
+			utils.BadRequestResponse(c, "Invalid webhook URL: "+err.Error())
+			return
+		}
+		updates["url"] = *req.URL
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Events != nil {
+		events, err := webhooks.ParseEvents(req.Events)
+		if err != nil {
+			utils.BadRequestResponse(c, err.Error())
+			return
+		}
+		updates["events"] = events
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+		if *req.IsActive && !endpoint.IsActive {
+			updates["consecutive_failures"] = 0
+			updates["disabled_at"] = nil
+			updates["disabled_reason"] = ""
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := h.DB.Model(&endpoint).Updates(updates).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to update webhook")
+			return
+		}
+	}
+
+	h.DB.First(&endpoint, endpoint.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook updated successfully", webhookResponse(endpoint, false))
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	endpoint, ok := h.findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(&endpoint).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete webhook")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook deleted successfully", nil)
+}
+
+// RotateWebhookSecret replaces the signing secret. Deliveries still queued
+// are signed with the new one.
+func (h *WebhookHandler) RotateWebhookSecret(c *gin.Context) {
+	endpoint, ok := h.findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to rotate webhook secret")
+		return
+	}
+	if err := h.DB.Model(&endpoint).Update("secret", secret).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to rotate webhook secret")
+		return
+	}
+	endpoint.Secret = secret
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook secret rotated successfully", webhookResponse(endpoint, true))
+}
+
+// TestWebhook queues a ping event to the endpoint.
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	endpoint, ok := h.findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if delivery, err = webhooks.Ping(tx, &endpoint); err != nil {
+			return err
+		}
+		return jobs.EnqueueWebhookDelivery(tx, delivery.ID)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to send test event")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Test event queued successfully", delivery)
+}
+
+// GetWebhookDeliveries lists an endpoint's deliveries, newest first. Query
+// parameters: page (from 1), limit (up to 100) and status.
+func (h *WebhookHandler) GetWebhookDeliveries(c *gin.Context) {
+	endpoint, ok := h.findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.BadRequestResponse(c, "Invalid page")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		utils.BadRequestResponse(c, "Invalid limit. Use 1 to 100")
+		return
+	}
+
+	query := h.DB.Model(&models.WebhookDelivery{}).Where("endpoint_id = ?", endpoint.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch webhook deliveries")
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch webhook deliveries")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook deliveries retrieved successfully", gin.H{
+		"deliveries": deliveries,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+// RedeliverWebhook sends a past delivery's event again, with the same
+// event ID, as a new delivery.
+func (h *WebhookHandler) RedeliverWebhook(c *gin.Context) {
+	endpoint, ok := h.findOwnWebhook(c)
+	if !ok {
+		return
+	}
+	if !endpoint.IsActive {
+		utils.BadRequestResponse(c, "Webhook is disabled. Enable it before redelivering")
+		return
+	}
+
+	var previous models.WebhookDelivery
+	if err := h.DB.Where("id = ? AND endpoint_id = ?", c.Param("delivery_id"), endpoint.ID).First(&previous).Error; err != nil {
+		utils.NotFoundResponse(c, "Webhook delivery not found")
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if delivery, err = webhooks.Redeliver(tx, &previous); err != nil {
+			return err
+		}
+		return jobs.EnqueueWebhookDelivery(tx, delivery.ID)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to redeliver webhook")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Webhook redelivery queued successfully", delivery)
+}
+
+func (h *WebhookHandler) findOwnWebhook(c *gin.Context) (models.WebhookEndpoint, bool) {
+	var endpoint models.WebhookEndpoint
+	provider, ok := currentProvider(h.DB, c)
+	if !ok {
+		return endpoint, false
+	}
+
+	if err := h.DB.Where("id = ? AND provider_id = ?", c.Param("id"), provider.ID).First(&endpoint).Error; err != nil {
+		utils.NotFoundResponse(c, "Webhook not found")
+		return endpoint, false
+	}
+	return endpoint, true
+}
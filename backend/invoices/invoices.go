@@ -0,0 +1,329 @@
+// Package invoices numbers and renders the invoices and receipts of
+// bookings.
+package invoices
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"pluralink/backend/giftcards"
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+	"pluralink/backend/taxes"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotBillable is returned for bookings with nothing to bill: neither
+// paid for nor completed.
+var ErrNotBillable = errors.New("booking has nothing to bill")
+
+// Party is who an invoice is from or to.
+type Party struct {
+	Name    string   `json:"name"`
+	Address []string `json:"address"`
+	Email   string   `json:"email,omitempty"`
+	Phone   string   `json:"phone,omitempty"`
+	Website string   `json:"website,omitempty"`
+}
+
+// Line is one item billed.
+type Line struct {
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	Amount      int64  `json:"amount"`
+}
+
+// Entry is money that moved for the booking. Refunds are negative.
+type Entry struct {
+	Date        time.Time `json:"date"`
+	Description string    `json:"description"`
+	Amount      int64     `json:"amount"`
+}
+
+// Receipt is what an invoice or receipt shows, in minor units. Once
+// nothing is left to pay it is a receipt.
+type Receipt struct {
+	Title          string    `json:"title"` // Invoice or Receipt
+	Reference      string    `json:"reference"`
+	IssuedAt       time.Time `json:"issued_at"`
+	BookingID      uint      `json:"booking_id"`
+	Start          time.Time `json:"start"` // In the provider's time zone
+	Status         string    `json:"status"`
+	From           Party     `json:"from"`
+	To             Party     `json:"to"`
+	Currency       string    `json:"currency"`
+	Lines          []Line    `json:"lines"`
+	Subtotal       int64     `json:"subtotal"`
+	Discount       int64     `json:"discount"`
+	PromoCode      string    `json:"promo_code,omitempty"` // That gave the discount
+	RewardDiscount int64     `json:"reward_discount"`
+	Reward         string    `json:"reward,omitempty"` // Loyalty reward that gave RewardDiscount
+	MemberDiscount int64     `json:"member_discount"`
+	Prepaid        int64     `json:"prepaid"`
+	PrepaidBy      string    `json:"prepaid_by,omitempty"` // Package or membership plan that covered Prepaid
+	Tax            int64     `json:"tax"`
+	TaxName        string    `json:"tax_name,omitempty"`
+	TaxRate        int       `json:"tax_rate"`     // In hundredths of a percent
+	TaxIncluded    bool      `json:"tax_included"` // Tax is part of the lines rather than added to them
+	Tips           int64     `json:"tips"`
+	Total          int64     `json:"total"`
+	Entries        []Entry   `json:"entries"`
+	Paid           int64     `json:"paid"` // Net of refunds
+	Balance        int64     `json:"balance"`
+}
+
+// Issue returns the booking's invoice, numbering it with the provider's
+// next number the first time.
+func Issue(db *gorm.DB, booking *models.Booking) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Numbering is serialised per provider by locking their row
+		var provider models.ServiceProvider
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&provider, booking.ProviderID).Error; err != nil {
+			return err
+		}
+		err := tx.Where("booking_id = ?", booking.ID).First(&invoice).Error
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var last int
+		if err := tx.Model(&models.Invoice{}).Where("provider_id = ?", provider.ID).
+			Select("COALESCE(MAX(number), 0)").Row().Scan(&last); err != nil {
+			return err
+		}
+		invoice = models.Invoice{
+			ProviderID: provider.ID,
+			Number:     last + 1,
+			BookingID:  booking.ID,
+			IssuedAt:   time.Now(),
+		}
+		return tx.Create(&invoice).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// Build puts together the receipt of a booking, issuing its invoice if it
+// has none yet. The booking needs its Provider, Service and Client.User
+// loaded.
+func Build(db *gorm.DB, booking *models.Booking) (*Receipt, error) {
+	var paymentRows []models.Payment
+	if err := db.Where("booking_id = ? AND status = ?", booking.ID, models.PaymentSucceeded).
+		Order("succeeded_at").Find(&paymentRows).Error; err != nil {
+		return nil, err
+	}
+	var refundRows []models.Refund
+	if err := db.Where("booking_id = ? AND status = ?", booking.ID, models.RefundStatusSucceeded).
+		Order("updated_at").Find(&refundRows).Error; err != nil {
+		return nil, err
+	}
+	var giftRows []struct {
+		models.GiftCardEntry
+		Code string
+	}
+	if err := db.Table("gift_card_entries").
+		Joins("JOIN gift_cards ON gift_cards.id = gift_card_entries.gift_card_id").
+		Where("gift_card_entries.booking_id = ?", booking.ID).
+		Select("gift_card_entries.*, gift_cards.code").
+		Order("gift_card_entries.id").Scan(&giftRows).Error; err != nil {
+		return nil, err
+	}
+	if booking.Status != models.StatusCompleted && len(paymentRows) == 0 && len(giftRows) == 0 {
+		return nil, ErrNotBillable
+	}
+
+	invoice, err := Issue(db, booking)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &booking.Provider
+	loc := provider.Location()
+	r := &Receipt{
+		Reference: invoice.Reference(),
+		IssuedAt:  invoice.IssuedAt.In(loc),
+		BookingID: booking.ID,
+		Start:     booking.StartsAt(loc),
+		Status:    string(booking.Status),
+		From: Party{
+			Name:    provider.BusinessName,
+			Address: address(provider.Address, provider.City, provider.State, provider.ZipCode, provider.Country),
+			Phone:   provider.Phone,
+			Website: provider.Website,
+		},
+		To: Party{
+			Name:    strings.TrimSpace(booking.Client.User.FirstName + " " + booking.Client.User.LastName),
+			Address: address(booking.Client.Address, booking.Client.City, booking.Client.State, booking.Client.ZipCode, booking.Client.Country),
+			Email:   booking.Client.User.Email,
+			Phone:   booking.Client.User.Phone,
+		},
+		Currency:    booking.Currency,
+		TaxName:     booking.TaxName,
+		TaxRate:     booking.TaxRate,
+		TaxIncluded: booking.TaxInclusive,
+		Lines:       []Line{},
+		Entries:     []Entry{},
+	}
+
+	var paid, refunded int64
+	for _, p := range paymentRows {
+		if p.Kind == models.PaymentTip {
+			r.Tips += p.Amount
+		}
+		paid += p.Amount
+		r.Entries = append(r.Entries, Entry{Date: succeededAt(&p).In(loc), Description: entryDescription(&p), Amount: p.Amount})
+	}
+	for _, rf := range refundRows {
+		refunded += rf.Amount
+		r.Entries = append(r.Entries, Entry{Date: rf.UpdatedAt.In(loc), Description: "Refund", Amount: -rf.Amount})
+	}
+	// Gift cards pay like payments, and are credited back like refunds
+	for _, g := range giftRows {
+		description := "Gift card " + giftcards.Mask(g.Code)
+		if g.Kind == models.GiftCardRefund {
+			refunded += g.Amount
+			description = "Returned to gift card " + giftcards.Mask(g.Code)
+		} else {
+			paid -= g.Amount
+		}
+		r.Entries = append(r.Entries, Entry{Date: g.CreatedAt.In(loc), Description: description, Amount: -g.Amount})
+	}
+	sort.SliceStable(r.Entries, func(i, j int) bool { return r.Entries[i].Date.Before(r.Entries[j].Date) })
+
+	closed := booking.Status == models.StatusCancelled || booking.Status == models.StatusExpired
+	if closed {
+		// What was kept of the payments is a cancellation fee, the
+		// booking's tax included
+		if kept := paid - r.Tips - refunded; kept > 0 {
+			fee, tax := taxes.Split(booking, kept)
+			if booking.TaxInclusive {
+				fee = kept
+			}
+			r.Tax = tax
+			r.Lines = append(r.Lines, Line{Description: "Cancellation fee: " + booking.Service.Name, Quantity: 1, Amount: fee})
+		}
+	} else {
+		r.Lines = append(r.Lines, Line{Description: booking.Service.Name, Quantity: 1, Amount: booking.Price})
+		r.Discount, r.PromoCode = booking.Discount, booking.PromoCode
+		if booking.RewardDiscount > 0 {
+			var reward models.LoyaltyReward
+			if booking.RewardID != nil {
+				db.Unscoped().First(&reward, *booking.RewardID)
+			}
+			r.RewardDiscount, r.Reward = booking.RewardDiscount, reward.Name
+		}
+		r.MemberDiscount, r.Prepaid = booking.MemberDiscount, booking.Prepaid
+		if booking.Prepaid > 0 {
+			r.PrepaidBy = prepaidBy(db, booking)
+		}
+		r.Tax = booking.Tax
+	}
+	for _, l := range r.Lines {
+		r.Subtotal += l.Amount * int64(l.Quantity)
+	}
+	if r.Tips > 0 {
+		r.Lines = append(r.Lines, Line{Description: "Tip", Quantity: 1, Amount: r.Tips})
+	}
+	r.Total = r.Subtotal - r.Discount - r.RewardDiscount - r.MemberDiscount - r.Prepaid + r.Tips
+	if !r.TaxIncluded {
+		r.Total += r.Tax
+	}
+	r.Paid = paid - refunded
+
+	r.Balance = r.Total - r.Paid
+	// Whatever was not paid in the app was settled at the appointment
+	if booking.Status == models.StatusCompleted && r.Balance > 0 {
+		r.Entries = append(r.Entries, Entry{Date: booking.EndsAt(loc), Description: "Paid at the appointment", Amount: r.Balance})
+		r.Paid += r.Balance
+		r.Balance = 0
+	}
+	if r.Balance < 0 {
+		r.Balance = 0
+	}
+	r.Title = "Invoice"
+	if r.Balance == 0 {
+		r.Title = "Receipt"
+	}
+	return r, nil
+}
+
+func entryDescription(p *models.Payment) string {
+	switch p.Kind {
+	case models.PaymentDeposit:
+		return "Deposit"
+	case models.PaymentTip:
+		return "Tip"
+	}
+	return "Payment"
+}
+
+// prepaidBy names the package or membership plan that covered a booking.
+func prepaidBy(db *gorm.DB, booking *models.Booking) string {
+	switch {
+	case booking.ClientPackageID != nil:
+		var pkg models.Package
+		db.Unscoped().Joins("JOIN client_packages ON client_packages.package_id = packages.id").
+			Where("client_packages.id = ?", *booking.ClientPackageID).First(&pkg)
+		return pkg.Name
+	case booking.MembershipID != nil:
+		var plan models.MembershipPlan
+		db.Unscoped().Joins("JOIN memberships ON memberships.plan_id = membership_plans.id").
+			Where("memberships.id = ?", *booking.MembershipID).First(&plan)
+		return plan.Name
+	}
+	return ""
+}
+
+func succeededAt(p *models.Payment) time.Time {
+	if p.SucceededAt != nil {
+		return *p.SucceededAt
+	}
+	return p.UpdatedAt
+}
+
+func address(street, city, state, zip, country string) []string {
+	var lines []string
+	if street != "" {
+		lines = append(lines, street)
+	}
+	var locality []string
+	for _, s := range []string{city, state, zip} {
+		if s != "" {
+			locality = append(locality, s)
+		}
+	}
+	if len(locality) > 0 {
+		lines = append(lines, strings.Join(locality, ", "))
+	}
+	if country != "" {
+		lines = append(lines, country)
+	}
+	return lines
+}
+
+// TaxLabel describes the tax, e.g. "VAT 20%" or "Includes VAT 20%".
+func (r *Receipt) TaxLabel() string {
+	name := r.TaxName
+	if name == "" {
+		name = "Tax"
+	}
+	label := name + " " + money.FormatBasis(r.TaxRate)
+	if r.TaxIncluded {
+		return "Includes " + label
+	}
+	return label
+}
+
+// formatAmount writes minor units as e.g. "USD 12.50". Codes rather than
+// symbols keep to what the PDF fonts can show.
+func formatAmount(amount int64, currency string) string {
+	return strings.ToUpper(currency) + " " + money.Decimal(amount, currency)
+}
@@ -0,0 +1,120 @@
+package invoices
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const amountRight = pageWidth - margin
+
+// PDF renders the receipt as a one or more page A4 document.
+func (r *Receipt) PDF() []byte {
+	d := newDocument()
+
+	d.advance(8)
+	d.text(margin, fontBold, 20, r.Title)
+	d.textRight(amountRight, 10, r.Reference)
+	d.advance(16)
+	d.text(margin, fontRegular, 10, "Issued "+r.IssuedAt.Format("January 2, 2006"))
+	d.advance(28)
+
+	// Who from and to, side by side
+	top := d.y
+	party(d, margin, "From", r.From)
+	bottom := d.y
+	d.y = top
+	party(d, pageWidth/2, "Bill to", r.To)
+	d.y = min(d.y, bottom)
+	d.advance(24)
+
+	d.text(margin, fontRegular, 10, fmt.Sprintf("Booking #%d on %s (%s)",
+		r.BookingID, r.Start.Format("Mon, Jan 2 2006 at 15:04 MST"), r.Status))
+	d.advance(28)
+
+	d.text(margin, fontBold, 10, "Description")
+	d.text(pageWidth-margin-170, fontBold, 10, "Qty")
+	d.textRight(amountRight, 10, "Amount")
+	d.advance(6)
+	d.rule()
+	for _, l := range r.Lines {
+		d.advance(16)
+		d.text(margin, fontRegular, 10, l.Description)
+		d.text(pageWidth-margin-170, fontRegular, 10, strconv.Itoa(l.Quantity))
+		d.textRight(amountRight, 10, formatAmount(l.Amount*int64(l.Quantity), r.Currency))
+	}
+	d.advance(10)
+	d.rule()
+
+	total(d, "Subtotal", r.Subtotal, r.Currency, fontRegular)
+	if r.Discount != 0 {
+		label := "Discount"
+		if r.PromoCode != "" {
+			label += " (" + r.PromoCode + ")"
+		}
+		total(d, label, -r.Discount, r.Currency, fontRegular)
+	}
+	if r.RewardDiscount != 0 {
+		label := "Loyalty reward"
+		if r.Reward != "" {
+			label += " (" + r.Reward + ")"
+		}
+		total(d, label, -r.RewardDiscount, r.Currency, fontRegular)
+	}
+	if r.MemberDiscount != 0 {
+		total(d, "Member pricing", -r.MemberDiscount, r.Currency, fontRegular)
+	}
+	if r.Prepaid != 0 {
+		label := "Prepaid"
+		if r.PrepaidBy != "" {
+			label += " (" + r.PrepaidBy + ")"
+		}
+		total(d, label, -r.Prepaid, r.Currency, fontRegular)
+	}
+	if r.Tax != 0 {
+		total(d, r.TaxLabel(), r.Tax, r.Currency, fontRegular)
+	}
+	if r.Tips != 0 {
+		total(d, "Tips", r.Tips, r.Currency, fontRegular)
+	}
+	total(d, "Total", r.Total, r.Currency, fontBold)
+
+	if len(r.Entries) > 0 {
+		d.advance(28)
+		d.text(margin, fontBold, 10, "Payments")
+		d.advance(6)
+		d.rule()
+		for _, e := range r.Entries {
+			d.advance(16)
+			d.text(margin, fontRegular, 10, e.Date.Format("Jan 2, 2006"))
+			d.text(margin+90, fontRegular, 10, e.Description)
+			d.textRight(amountRight, 10, formatAmount(e.Amount, r.Currency))
+		}
+		d.advance(10)
+		d.rule()
+		total(d, "Paid", r.Paid, r.Currency, fontRegular)
+	}
+	total(d, "Balance due", r.Balance, r.Currency, fontBold)
+
+	return d.bytes()
+}
+
+func party(d *document, x float64, heading string, p Party) {
+	d.text(x, fontBold, 10, heading)
+	lines := []string{p.Name}
+	lines = append(lines, p.Address...)
+	for _, s := range []string{p.Email, p.Phone, p.Website} {
+		if s != "" {
+			lines = append(lines, s)
+		}
+	}
+	for _, line := range lines {
+		d.advance(14)
+		d.text(x, fontRegular, 10, line)
+	}
+}
+
+func total(d *document, label string, amount int64, currency, font string) {
+	d.advance(16)
+	d.text(pageWidth-margin-170, font, 10, label)
+	d.textRight(amountRight, 10, formatAmount(amount, currency))
+}
@@ -0,0 +1,199 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"pluralink/backend/credits"
+	"pluralink/backend/giftcards"
+	"pluralink/backend/loyalty"
+	"pluralink/backend/models"
+	"pluralink/backend/payments"
+	"pluralink/backend/realtime"
+	"pluralink/backend/webhooks"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TypeExpirePendingBookings = "bookings.expire_pending"
+	TypeCompletePastBookings  = "bookings.complete_past"
+	TypePurgeFinishedJobs     = "jobs.purge_finished"
+	TypePurgeBookingEvents    = "bookings.purge_events"
+)
+
+// How long finished jobs are kept for inspection before being purged
+const finishedJobRetention = 7 * 24 * time.Hour
+
+// How far back streaming clients can resume
+const bookingEventRetention = 3 * 24 * time.Hour
+
+// RegisterBookingJobs wires the time-driven booking transitions. A booking
+// the provider never confirmed, even if rescheduled since, expires once its
+// start time passes, or after pendingTTL if that is positive. Confirmed
+// bookings complete once they end.
+func RegisterBookingJobs(r *Runner, pendingTTL time.Duration) {
+	r.Register(TypeExpirePendingBookings, expirePendingBookings(r.DB, pendingTTL))
+	r.Every(TypeExpirePendingBookings, 5*time.Minute)
+
+	r.Register(TypeCompletePastBookings, completePastBookings(r.DB))
+	r.Every(TypeCompletePastBookings, 5*time.Minute)
+
+	r.Register(TypePurgeFinishedJobs, purgeFinishedJobs(r.DB))
+	r.Every(TypePurgeFinishedJobs, time.Hour)
+
+	r.Register(TypePurgeBookingEvents, purgeBookingEvents(r.DB))
+	r.Every(TypePurgeBookingEvents, time.Hour)
+}
+
+func expirePendingBookings(db *gorm.DB, pendingTTL time.Duration) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		now := time.Now()
+
+		var bookings []models.Booking
+		query := db.WithContext(ctx).Preload("Provider").
+			Where("status = ? OR (status = ? AND confirmed_at IS NULL)", models.StatusPending, models.StatusRescheduled)
+		// Bookings dated up to and including tomorrow (UTC) may already
+		// have begun in zones ahead of UTC, so all of them are looked at;
+		// the start time in the provider's zone decides below
+		if pendingTTL > 0 {
+			query = query.Where("date < ? OR created_at < ?", now.AddDate(0, 0, 2), now.Add(-pendingTTL))
+		} else {
+			query = query.Where("date < ?", now.AddDate(0, 0, 2))
+		}
+		if err := query.Find(&bookings).Error; err != nil {
+			return err
+		}
+
+		for _, b := range bookings {
+			started := b.StartsAt(b.Provider.Location()).Before(now)
+			stale := pendingTTL > 0 && b.CreatedAt.Before(now.Add(-pendingTTL))
+			if !started && !stale {
+				continue
+			}
+			// Settled only by whoever expired it, even if what followed
+			// failed, as a retry would not find it again
+			moved, err := transition(db, b.ID, b.Status, models.StatusExpired)
+			if moved && (b.PaymentStatus != models.PaymentNotRequired || b.GiftCardAmount > 0 || b.RewardPoints > 0 || b.Prepaid > 0) {
+				settleExpiredPayments(ctx, db, b.ID)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func completePastBookings(db *gorm.DB) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		now := time.Now()
+
+		var bookings []models.Booking
+		if err := db.WithContext(ctx).Preload("Provider").
+			Where("status IN ? AND confirmed_at IS NOT NULL AND date < ?",
+				[]models.BookingStatus{models.StatusConfirmed, models.StatusRescheduled}, now.AddDate(0, 0, 2)).
+			Find(&bookings).Error; err != nil {
+			return err
+		}
+
+		for _, b := range bookings {
+			if b.EndsAt(b.Provider.Location()).After(now) {
+				continue
+			}
+			if _, err := transition(db, b.ID, b.Status, models.StatusCompleted); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// settleExpiredPayments cancels the open payments of a booking that expired
+// before the provider confirmed it and refunds what was paid in full, to
+// gift cards, packages and loyalty points too.
+func settleExpiredPayments(ctx context.Context, db *gorm.DB, bookingID uint) {
+	service := payments.NewService(db)
+	if err := service.CancelOpen(ctx, bookingID); err != nil {
+		log.Printf("Failed to cancel payments for expired booking %d: %v", bookingID, err)
+	}
+
+	var booking models.Booking
+	if err := db.First(&booking, bookingID).Error; err != nil {
+		log.Printf("Failed to load booking %d: %v", bookingID, err)
+		return
+	}
+	if booking.Status != models.StatusExpired {
+		return
+	}
+	if err := giftcards.Restore(db, bookingID, booking.GiftCardAmount); err != nil {
+		log.Printf("Failed to credit gift cards for expired booking %d: %v", bookingID, err)
+	}
+	if err := loyalty.Restore(db, bookingID); err != nil {
+		log.Printf("Failed to restore points for expired booking %d: %v", bookingID, err)
+	}
+	if err := credits.Restore(db, bookingID); err != nil {
+		log.Printf("Failed to restore credit for expired booking %d: %v", bookingID, err)
+	}
+	if booking.PaymentStatus != models.PaymentPaid {
+		return
+	}
+	paid, err := service.Paid(bookingID)
+	if err == nil {
+		err = ScheduleRefund(db, bookingID, paid, models.RefundReasonExpired)
+	}
+	if err != nil {
+		log.Printf("Failed to schedule refund for expired booking %d: %v", bookingID, err)
+	}
+}
+
+// transition moves a booking between statuses unless a request changed it
+// in the meantime, reporting whether it moved.
+func transition(db *gorm.DB, bookingID uint, from, to models.BookingStatus) (bool, error) {
+	result := db.Model(&models.Booking{}).
+		Where("id = ? AND status = ?", bookingID, from).
+		Updates(map[string]interface{}{
+			"status":   to,
+			"sequence": gorm.Expr("sequence + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	log.Printf("Booking %d moved from %s to %s", bookingID, from, to)
+
+	var booking models.Booking
+	if err := db.First(&booking, bookingID).Error; err == nil {
+		if err := realtime.Publish(db, realtime.EventBookingStatusChanged, &booking, from); err != nil {
+			log.Printf("Failed to publish status change of booking %d: %v", bookingID, err)
+		}
+	}
+	if err := PublishBookingWebhook(db, webhooks.StatusEvent(to), bookingID, from); err != nil {
+		log.Printf("Failed to publish webhook for booking %d: %v", bookingID, err)
+	}
+	if to == models.StatusCompleted {
+		if err := loyalty.Accrue(db, bookingID); err != nil {
+			log.Printf("Failed to credit points for booking %d: %v", bookingID, err)
+		}
+	}
+	return true, Enqueue(db, TypePushBookingToCalendar, BookingPayload{BookingID: bookingID})
+}
+
+func purgeFinishedJobs(db *gorm.DB) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		return db.WithContext(ctx).
+			Where("status IN ? AND completed_at < ?", []models.JobStatus{models.JobSucceeded, models.JobFailed}, time.Now().Add(-finishedJobRetention)).
+			Delete(&models.Job{}).Error
+	}
+}
+
+func purgeBookingEvents(db *gorm.DB) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		return db.WithContext(ctx).
+			Where("created_at < ?", time.Now().Add(-bookingEventRetention)).
+			Delete(&models.BookingEvent{}).Error
+	}
+}
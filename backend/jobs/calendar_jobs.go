@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"pluralink/backend/calendar"
+	"pluralink/backend/models"
+)
+
+const (
+	TypeSyncCalendars         = "calendar.sync_all"
+	TypePushBookingToCalendar = "calendar.push_booking"
+)
+
+// BookingPayload is the payload of jobs about a single booking.
+type BookingPayload struct {
+	BookingID uint `json:"booking_id"`
+}
+
+// RegisterCalendarJobs wires external calendar imports and CalDAV pushes.
+func RegisterCalendarJobs(r *Runner, syncer *calendar.Syncer, syncInterval time.Duration) {
+	r.Register(TypeSyncCalendars, func(ctx context.Context, job *models.Job) error {
+		syncer.SyncAll(ctx)
+		return nil
+	})
+	r.Every(TypeSyncCalendars, syncInterval)
+
+	r.Register(TypePushBookingToCalendar, func(ctx context.Context, job *models.Job) error {
+		var p BookingPayload
+		if err := Decode(job, &p); err != nil {
+			return err
+		}
+		return syncer.PushBooking(ctx, p.BookingID)
+	})
+}
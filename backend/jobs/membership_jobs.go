@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/payments"
+
+	"gorm.io/gorm"
+)
+
+const TypeRenewMemberships = "memberships.renew"
+
+// How long a membership stays past due before it lapses
+const membershipGrace = 7 * 24 * time.Hour
+
+// RegisterMembershipJobs wires the monthly renewal of memberships.
+func RegisterMembershipJobs(r *Runner, service *payments.Service) {
+	r.Register(TypeRenewMemberships, renewMemberships(r.DB, service))
+	r.Every(TypeRenewMemberships, 15*time.Minute)
+}
+
+// renewMemberships charges memberships whose month ended for the next one.
+// A membership that cannot be charged is past due until the client pays,
+// and lapses if they do not within the grace period. Memberships set to
+// cancel end instead.
+func renewMemberships(db *gorm.DB, service *payments.Service) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		now := time.Now()
+
+		var due []models.Membership
+		if err := db.WithContext(ctx).
+			Where("status IN ? AND period_end <= ?", []models.MembershipStatus{models.MembershipActive, models.MembershipPastDue}, now).
+			Find(&due).Error; err != nil {
+			return err
+		}
+
+		for i := range due {
+			m := &due[i]
+			switch {
+			case m.Status == models.MembershipActive && m.CancelAtPeriodEnd:
+				if _, err := moveMembership(db, m.ID, m.Status, models.MembershipCancelled); err != nil {
+					return err
+				}
+			case m.Status == models.MembershipActive:
+				moved, err := moveMembership(db, m.ID, m.Status, models.MembershipPastDue)
+				if err != nil {
+					return err
+				}
+				if !moved {
+					continue
+				}
+				if _, err := service.Renew(ctx, m, now); err != nil {
+					log.Printf("Failed to renew membership %d: %v", m.ID, err)
+				}
+			case m.PeriodEnd.Add(membershipGrace).Before(now):
+				moved, err := moveMembership(db, m.ID, m.Status, models.MembershipLapsed)
+				if err != nil {
+					return err
+				}
+				if moved {
+					if err := service.CancelMembershipPayments(ctx, m.ID); err != nil {
+						log.Printf("Failed to cancel payments of lapsed membership %d: %v", m.ID, err)
+					}
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// moveMembership changes a membership's status unless a request changed
+// it in the meantime, and reports whether it did.
+func moveMembership(db *gorm.DB, id uint, from, to models.MembershipStatus) (bool, error) {
+	result := db.Model(&models.Membership{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	if result.RowsAffected > 0 {
+		log.Printf("Membership %d moved from %s to %s", id, from, to)
+	}
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"context"
+	"log"
+
+	"pluralink/backend/models"
+	"pluralink/backend/payments"
+	"pluralink/backend/realtime"
+	"pluralink/backend/webhooks"
+
+	"gorm.io/gorm"
+)
+
+const TypeIssueRefunds = "payments.issue_refunds"
+
+// Refunds are retried for longer than most jobs, as money is owed
+const refundMaxAttempts = 10
+
+// RegisterPaymentJobs wires issuing refunds through the payment gateway.
+func RegisterPaymentJobs(r *Runner, service *payments.Service) {
+	r.Register(TypeIssueRefunds, func(ctx context.Context, job *models.Job) error {
+		var p BookingPayload
+		if err := Decode(job, &p); err != nil {
+			return err
+		}
+		changed, err := service.IssueRefunds(ctx, p.BookingID)
+		if changed {
+			PaymentUpdated(r.DB, p.BookingID)
+		}
+		return err
+	})
+}
+
+// EnqueueRefunds schedules issuing a booking's pending refund.
+func EnqueueRefunds(db *gorm.DB, bookingID uint) error {
+	return EnqueueWithAttempts(db, TypeIssueRefunds, BookingPayload{BookingID: bookingID}, refundMaxAttempts)
+}
+
+// ScheduleRefund records that what was paid for a cancelled booking is
+// owed back and queues issuing it, unless a refund was already decided.
+func ScheduleRefund(db *gorm.DB, bookingID uint, amount int64, reason string) error {
+	if amount <= 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Booking{}).
+			Where("id = ? AND refund_status = ?", bookingID, models.RefundNone).
+			Updates(map[string]interface{}{
+				"refund_status": models.RefundPending,
+				"refund_amount": amount,
+				"refund_reason": reason,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return EnqueueRefunds(tx, bookingID)
+	})
+}
+
+// RefundLatePayment refunds in full a booking that was just paid after it
+// had been cancelled or had expired.
+func RefundLatePayment(db *gorm.DB, bookingID uint) {
+	var booking models.Booking
+	if err := db.First(&booking, bookingID).Error; err != nil {
+		log.Printf("Failed to load booking %d: %v", bookingID, err)
+		return
+	}
+	closed := booking.Status == models.StatusCancelled || booking.Status == models.StatusExpired
+	if !closed || booking.PaymentStatus != models.PaymentPaid || booking.RefundStatus != models.RefundNone {
+		return
+	}
+
+	paid, err := payments.NewService(db).Paid(bookingID)
+	if err == nil {
+		err = ScheduleRefund(db, bookingID, paid, models.RefundReasonLatePayment)
+	}
+	if err != nil {
+		log.Printf("Failed to schedule refund of late payment for booking %d: %v", bookingID, err)
+	}
+}
+
+// ProcessPaymentEvent applies a stored gateway webhook and passes on what
+// it changed, as if the app had made the change itself.
+func ProcessPaymentEvent(db *gorm.DB, service *payments.Service, eventID uint, replay bool) error {
+	outcome, err := service.HandleEvent(eventID, replay)
+	if err != nil {
+		return err
+	}
+	if outcome.Paid {
+		RefundLatePayment(db, outcome.BookingID)
+	}
+	if outcome.Changed {
+		PaymentUpdated(db, outcome.BookingID)
+	}
+	if outcome.Tipped {
+		TipReceived(db, outcome.BookingID)
+	}
+	return nil
+}
+
+// TipReceived tells both parties and the provider's webhooks that a tip on
+// a booking went through.
+func TipReceived(db *gorm.DB, bookingID uint) {
+	var booking models.Booking
+	if err := db.First(&booking, bookingID).Error; err != nil {
+		log.Printf("Failed to load booking %d: %v", bookingID, err)
+		return
+	}
+	if err := realtime.Publish(db, realtime.EventBookingPaymentUpdated, &booking, ""); err != nil {
+		log.Printf("Failed to publish tip on booking %d: %v", bookingID, err)
+	}
+	if err := PublishBookingWebhook(db, webhooks.EventBookingTipped, bookingID, ""); err != nil {
+		log.Printf("Failed to publish webhook for booking %d: %v", bookingID, err)
+	}
+}
+
+// PaymentUpdated tells both parties and the provider's webhooks that a
+// booking's payment or refund status changed.
+func PaymentUpdated(db *gorm.DB, bookingID uint) {
+	var booking models.Booking
+	if err := db.First(&booking, bookingID).Error; err != nil {
+		log.Printf("Failed to load booking %d: %v", bookingID, err)
+		return
+	}
+	if err := realtime.Publish(db, realtime.EventBookingPaymentUpdated, &booking, ""); err != nil {
+		log.Printf("Failed to publish payment update of booking %d: %v", bookingID, err)
+	}
+	if err := PublishBookingWebhook(db, webhooks.PaymentEvent(&booking), bookingID, ""); err != nil {
+		log.Printf("Failed to publish webhook for booking %d: %v", bookingID, err)
+	}
+}
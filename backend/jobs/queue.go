@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"encoding/json"
+	"time"
+
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultMaxAttempts = 5
+
+// Enqueue schedules a job to run as soon as a worker is free.
+func Enqueue(db *gorm.DB, jobType string, payload interface{}) error {
+	return EnqueueAt(db, jobType, payload, time.Now())
+}
+
+// EnqueueAt schedules a job to run no earlier than runAt.
+func EnqueueAt(db *gorm.DB, jobType string, payload interface{}, runAt time.Time) error {
+	job, err := newJob(jobType, payload, runAt)
+	if err != nil {
+		return err
+	}
+	return db.Create(job).Error
+}
+
+// EnqueueWithAttempts schedules a job to run as soon as a worker is free,
+// trying it up to maxAttempts times.
+func EnqueueWithAttempts(db *gorm.DB, jobType string, payload interface{}, maxAttempts int) error {
+	job, err := newJob(jobType, payload, time.Now())
+	if err != nil {
+		return err
+	}
+	job.MaxAttempts = maxAttempts
+	return db.Create(job).Error
+}
+
+// EnqueueUnique schedules a job unless one with the same key was ever
+// enqueued. It reports whether a job was created.
+func EnqueueUnique(db *gorm.DB, key, jobType string, payload interface{}, runAt time.Time) (bool, error) {
+	job, err := newJob(jobType, payload, runAt)
+	if err != nil {
+		return false, err
+	}
+	job.UniqueKey = &key
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Decode unmarshals a job's payload into v.
+func Decode(job *models.Job, v interface{}) error {
+	return json.Unmarshal([]byte(job.Payload), v)
+}
+
+func newJob(jobType string, payload interface{}, runAt time.Time) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      models.JobPending,
+		RunAt:       runAt,
+		MaxAttempts: defaultMaxAttempts,
+	}, nil
+}
@@ -0,0 +1,256 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	baseBackoff = 30 * time.Second
+	maxBackoff  = time.Hour
+)
+
+// Handler performs one job. Returning an error schedules a retry with
+// exponential backoff until the job runs out of attempts.
+type Handler func(ctx context.Context, job *models.Job) error
+
+type schedule struct {
+	jobType string
+	every   time.Duration
+}
+
+// Runner polls the jobs table and executes due jobs with a pool of
+// workers. Several runners, on one or many servers, can share the table:
+// claims use FOR UPDATE SKIP LOCKED and recurring jobs are deduplicated by
+// unique key.
+type Runner struct {
+	DB           *gorm.DB
+	Workers      int
+	PollInterval time.Duration
+	LockTimeout  time.Duration // Jobs running longer are presumed dead and retried
+
+	id        string
+	mu        sync.RWMutex
+	handlers  map[string]Handler
+	schedules []schedule
+}
+
+func NewRunner(db *gorm.DB, workers int) *Runner {
+	host, _ := os.Hostname()
+	return &Runner{
+		DB:           db,
+		Workers:      workers,
+		PollInterval: 2 * time.Second,
+		LockTimeout:  10 * time.Minute,
+		id:           fmt.Sprintf("%s-%d", host, os.Getpid()),
+		handlers:     map[string]Handler{},
+	}
+}
+
+// Register sets the handler for a job type. Only registered types are
+// claimed by this runner.
+func (r *Runner) Register(jobType string, h Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[jobType] = h
+}
+
+// Every enqueues a job of the given type once per interval, aligned to the
+// interval boundary, no matter how many runners are started.
+func (r *Runner) Every(jobType string, every time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schedules = append(r.schedules, schedule{jobType: jobType, every: every})
+}
+
+// Start launches the workers, the scheduler and the stale lock reaper. It
+// returns immediately; everything stops when ctx is done.
+func (r *Runner) Start(ctx context.Context) {
+	for i := 0; i < r.Workers; i++ {
+		go r.work(ctx)
+	}
+	go r.loop(ctx, 15*time.Second, r.schedule)
+	go r.loop(ctx, r.LockTimeout/2, r.reap)
+
+	log.Printf("Job runner %s started with %d workers", r.id, r.Workers)
+}
+
+func (r *Runner) loop(ctx context.Context, every time.Duration, fn func(time.Time)) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		fn(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := r.claim()
+		if err != nil {
+			log.Println("Failed to claim job:", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(r.PollInterval):
+			}
+			continue
+		}
+		r.run(ctx, job)
+	}
+}
+
+// claim locks the next due job of a registered type for this runner.
+func (r *Runner) claim() (*models.Job, error) {
+	r.mu.RLock()
+	types := make([]string, 0, len(r.handlers))
+	for t := range r.handlers {
+		types = append(types, t)
+	}
+	r.mu.RUnlock()
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var job models.Job
+	err := r.DB.Raw(`
+		UPDATE jobs
+		SET status = ?, locked_by = ?, locked_at = ?, attempts = attempts + 1, updated_at = ?
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = ? AND run_at <= ? AND type IN ?
+			ORDER BY run_at, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING *`,
+		models.JobRunning, r.id, now, now,
+		models.JobPending, now, types,
+	).Scan(&job).Error
+	if err != nil || job.ID == 0 {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *Runner) run(ctx context.Context, job *models.Job) {
+	r.mu.RLock()
+	handler := r.handlers[job.Type]
+	r.mu.RUnlock()
+
+	jobCtx, cancel := context.WithTimeout(ctx, r.LockTimeout)
+	err := safeCall(jobCtx, handler, job)
+	cancel()
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"locked_by":  "",
+		"locked_at":  nil,
+		"last_error": "",
+		"updated_at": now,
+	}
+
+	switch {
+	case err == nil:
+		updates["status"] = models.JobSucceeded
+		updates["completed_at"] = now
+	case job.Attempts < job.MaxAttempts:
+		updates["status"] = models.JobPending
+		updates["run_at"] = now.Add(backoff(job.Attempts))
+		updates["last_error"] = err.Error()
+		log.Printf("Job %d (%s) failed, will retry: %v", job.ID, job.Type, err)
+	default:
+		updates["status"] = models.JobFailed
+		updates["completed_at"] = now
+		updates["last_error"] = err.Error()
+		log.Printf("Job %d (%s) failed permanently: %v", job.ID, job.Type, err)
+	}
+
+	// Only touch the row if we still own it; the reaper may have handed it
+	// to another worker after a timeout.
+	if err := r.DB.Model(&models.Job{}).
+		Where("id = ? AND locked_by = ?", job.ID, r.id).
+		Updates(updates).Error; err != nil {
+		log.Printf("Failed to record result of job %d: %v", job.ID, err)
+	}
+}
+
+func safeCall(ctx context.Context, h Handler, job *models.Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v\n%s", p, debug.Stack())
+		}
+	}()
+	if h == nil {
+		return errors.New("no handler registered")
+	}
+	return h(ctx, job)
+}
+
+// schedule enqueues the current occurrence of every recurring job.
+func (r *Runner) schedule(now time.Time) {
+	r.mu.RLock()
+	schedules := append([]schedule(nil), r.schedules...)
+	r.mu.RUnlock()
+
+	for _, s := range schedules {
+		slot := now.Truncate(s.every)
+		key := fmt.Sprintf("%s@%d", s.jobType, slot.Unix())
+		if _, err := EnqueueUnique(r.DB, key, s.jobType, struct{}{}, slot); err != nil {
+			log.Printf("Failed to schedule %s: %v", s.jobType, err)
+		}
+	}
+}
+
+// reap returns jobs whose worker disappeared mid-run to the queue.
+func (r *Runner) reap(now time.Time) {
+	result := r.DB.Model(&models.Job{}).
+		Where("status = ? AND locked_at < ?", models.JobRunning, now.Add(-r.LockTimeout)).
+		Updates(map[string]interface{}{
+			"status":     models.JobPending,
+			"locked_by":  "",
+			"locked_at":  nil,
+			"run_at":     now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		log.Println("Failed to reap stale jobs:", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("Requeued %d stale jobs", result.RowsAffected)
+	}
+}
+
+// backoff grows exponentially from 30s, capped at an hour, with up to 10%
+// jitter so retries from a burst of failures spread out.
+func backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := maxBackoff
+	if attempt < 8 {
+		d = baseBackoff << (attempt - 1)
+		if d > maxBackoff {
+			d = maxBackoff
+		}
+	}
+	return d + time.Duration(rand.Int63n(int64(d/10)+1))
+}
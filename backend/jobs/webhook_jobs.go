@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+
+	"pluralink/backend/models"
+	"pluralink/backend/webhooks"
+
+	"gorm.io/gorm"
+)
+
+const TypeDeliverWebhook = "webhooks.deliver"
+
+type WebhookDeliveryPayload struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+// RegisterWebhookJobs wires deliveries to provider webhook endpoints.
+func RegisterWebhookJobs(r *Runner, sender *webhooks.Sender) {
+	r.Register(TypeDeliverWebhook, func(ctx context.Context, job *models.Job) error {
+		var p WebhookDeliveryPayload
+		if err := Decode(job, &p); err != nil {
+			return err
+		}
+		return sender.Deliver(ctx, p.DeliveryID, job.Attempts >= job.MaxAttempts)
+	})
+}
+
+// EnqueueWebhookDelivery schedules a recorded delivery.
+func EnqueueWebhookDelivery(db *gorm.DB, deliveryID uint) error {
+	return EnqueueWithAttempts(db, TypeDeliverWebhook, WebhookDeliveryPayload{DeliveryID: deliveryID}, webhooks.MaxAttempts)
+}
+
+// PublishWebhook records an event for the provider's subscribed endpoints
+// and schedules the deliveries.
+func PublishWebhook(db *gorm.DB, providerID uint, eventType string, data interface{}) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		deliveries, err := webhooks.CreateDeliveries(tx, providerID, eventType, data)
+		if err != nil {
+			return err
+		}
+		for _, d := range deliveries {
+			if err := EnqueueWebhookDelivery(tx, d.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PublishBookingWebhook sends a booking event to the provider's endpoints.
+func PublishBookingWebhook(db *gorm.DB, eventType string, bookingID uint, previous models.BookingStatus) error {
+	if eventType == "" {
+		return nil
+	}
+	var booking models.Booking
+	if err := db.Preload("Provider").Preload("Service").Preload("Client.User").
+		First(&booking, bookingID).Error; err != nil {
+		return err
+	}
+	return PublishWebhook(db, booking.ProviderID, eventType, webhooks.NewBookingData(&booking, previous))
+}
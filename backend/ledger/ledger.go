@@ -0,0 +1,230 @@
+// Package ledger keeps the platform's double-entry books: what clients pay
+// through the gateway, the commission and fees taken from it, and what is
+// owed and paid out to each provider.
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Fee is what the gateway keeps of each payment it collects.
+type Fee struct {
+	Rate  int   // In hundredths of a percent
+	Fixed int64 // In minor units of the payment's currency
+}
+
+// GatewayFee is the fee configured at startup. Providers bear it, as it
+// is taken from what they are owed.
+var GatewayFee Fee
+
+// Of returns the fee on a payment, never more than the payment.
+func (f Fee) Of(amount money.Money) int64 {
+	if amount.Amount <= 0 {
+		return 0
+	}
+	return min(amount.PercentBasis(f.Rate).Amount+f.Fixed, amount.Amount)
+}
+
+// CommissionRate returns the platform's commission on a provider's sales
+// in a category, in hundredths of a percent. Purchases that are not for a
+// service, such as gift cards, have category 0.
+func CommissionRate(db *gorm.DB, providerID, categoryID uint) (int, error) {
+	var rules []models.CommissionRule
+	if err := db.Where("provider_id IN ? AND category_id IN ?", []uint{0, providerID}, []uint{0, categoryID}).
+		Find(&rules).Error; err != nil {
+		return 0, err
+	}
+	rate, best := 0, -1
+	for _, r := range rules {
+		// A provider's rule beats a category's, which beats the default
+		rank := 0
+		if r.ProviderID != 0 {
+			rank += 2
+		}
+		if r.CategoryID != 0 {
+			rank++
+		}
+		if rank > best {
+			rate, best = r.Rate, rank
+		}
+	}
+	return rate, nil
+}
+
+// RecordPayment posts a booking payment or tip that went through, with the
+// commission and the gateway's fee on it. Tips carry no commission.
+func RecordPayment(tx *gorm.DB, payment *models.Payment, at time.Time) error {
+	var booking models.Booking
+	if err := tx.Unscoped().First(&booking, payment.BookingID).Error; err != nil {
+		return err
+	}
+	key := fmt.Sprintf("payment-%d", payment.ID)
+	amount := money.New(payment.Amount, payment.Currency)
+	if payment.Kind == models.PaymentTip {
+		return charge(tx, key, models.LedgerTip, booking.ProviderID, &booking.ID,
+			fmt.Sprintf("Tip on booking #%d", booking.ID), amount, 0, at)
+	}
+
+	var service models.Service
+	if err := tx.Unscoped().First(&service, booking.ServiceID).Error; err != nil {
+		return err
+	}
+	rate, err := CommissionRate(tx, booking.ProviderID, service.CategoryID)
+	if err != nil {
+		return err
+	}
+	return charge(tx, key, models.LedgerCharge, booking.ProviderID, &booking.ID,
+		fmt.Sprintf("Payment for booking #%d", booking.ID), amount, rate, at)
+}
+
+// RecordGiftCard posts the payment of a gift card a client bought in the
+// app.
+func RecordGiftCard(tx *gorm.DB, card *models.GiftCard, at time.Time) error {
+	return purchase(tx, fmt.Sprintf("gift-card-%d", card.ID), card.ProviderID,
+		"Gift card sold", money.New(card.Amount, card.Currency), at)
+}
+
+// RecordPackage posts the payment of a package a client bought.
+func RecordPackage(tx *gorm.DB, cp *models.ClientPackage, at time.Time) error {
+	var pkg models.Package
+	if err := tx.Unscoped().First(&pkg, cp.PackageID).Error; err != nil {
+		return err
+	}
+	return purchase(tx, fmt.Sprintf("client-package-%d", cp.ID), cp.ProviderID,
+		fmt.Sprintf("Package sold: %s", pkg.Name), money.New(cp.Total(), cp.Currency), at)
+}
+
+// RecordMembershipPayment posts the payment of a month of a membership.
+func RecordMembershipPayment(tx *gorm.DB, m *models.Membership, payment *models.MembershipPayment, at time.Time) error {
+	return purchase(tx, fmt.Sprintf("membership-payment-%d", payment.ID), m.ProviderID,
+		fmt.Sprintf("Membership #%d from %s", m.ID, payment.PeriodStart.Format("2 January 2006")),
+		money.New(payment.Total(), payment.Currency), at)
+}
+
+// RecordRefund posts a refund that went through. The commission taken on
+// its payment comes back in proportion; the gateway's fee does not.
+func RecordRefund(tx *gorm.DB, refund *models.Refund, at time.Time) error {
+	return reverse(tx, fmt.Sprintf("refund-%d", refund.ID), models.LedgerRefund,
+		fmt.Sprintf("Refund on booking #%d", refund.BookingID), refund.PaymentID, refund.Amount, at)
+}
+
+// RecordChargeback posts a dispute the provider lost, like a refund of
+// the disputed amount.
+func RecordChargeback(tx *gorm.DB, payment *models.Payment, amount int64, at time.Time) error {
+	return reverse(tx, fmt.Sprintf("payment-%d-chargeback", payment.ID), models.LedgerChargeback,
+		fmt.Sprintf("Chargeback on booking #%d", payment.BookingID), payment.ID, amount, at)
+}
+
+// purchase posts a payment for something a client bought from a provider
+// apart from bookings, at the commission of sales outside any category.
+func purchase(tx *gorm.DB, key string, providerID uint, description string, amount money.Money, at time.Time) error {
+	rate, err := CommissionRate(tx, providerID, 0)
+	if err != nil {
+		return err
+	}
+	return charge(tx, key, models.LedgerCharge, providerID, nil, description, amount, rate, at)
+}
+
+// charge posts money a client paid for a provider, then the commission at
+// rate and the gateway's fee taken from it.
+func charge(tx *gorm.DB, key string, kind models.LedgerKind, providerID uint, bookingID *uint, description string, amount money.Money, rate int, at time.Time) error {
+	t := models.LedgerTransaction{
+		Key: key, Kind: kind, ProviderID: providerID, BookingID: bookingID,
+		Description: description, Amount: amount.Amount, Currency: amount.Currency, OccurredAt: at,
+	}
+	if err := post(tx, t, account(models.AccountGateway, amount.Amount), provider(providerID, -amount.Amount)); err != nil {
+		return err
+	}
+
+	commission := amount.PercentBasis(rate).Amount
+	t.Key, t.Kind, t.Amount = key+"-commission", models.LedgerCommission, -commission
+	t.Description = "Commission of " + money.FormatBasis(rate)
+	if err := post(tx, t, provider(providerID, commission), account(models.AccountCommission, -commission)); err != nil {
+		return err
+	}
+
+	fee := GatewayFee.Of(amount)
+	t.Key, t.Kind, t.Amount = key+"-fee", models.LedgerFee, -fee
+	t.Description = "Payment processing fee"
+	return post(tx, t, provider(providerID, fee), account(models.AccountGateway, -fee))
+}
+
+// reverse posts money returned to a client from a payment, giving back
+// the same share of the commission taken on it.
+func reverse(tx *gorm.DB, key string, kind models.LedgerKind, description string, paymentID uint, amount int64, at time.Time) error {
+	var payment models.Payment
+	if err := tx.First(&payment, paymentID).Error; err != nil {
+		return err
+	}
+	var booking models.Booking
+	if err := tx.Unscoped().First(&booking, payment.BookingID).Error; err != nil {
+		return err
+	}
+	t := models.LedgerTransaction{
+		Key: key, Kind: kind, ProviderID: booking.ProviderID, BookingID: &booking.ID,
+		Description: description, Amount: -amount, Currency: payment.Currency, OccurredAt: at,
+	}
+	if err := post(tx, t, provider(booking.ProviderID, amount), account(models.AccountGateway, -amount)); err != nil {
+		return err
+	}
+
+	var taken models.LedgerTransaction
+	err := tx.Where("key = ?", fmt.Sprintf("payment-%d-commission", payment.ID)).First(&taken).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || payment.Amount <= 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	back := -taken.Amount * amount / payment.Amount
+	t.Key, t.Kind, t.Amount = key+"-commission", models.LedgerCommission, back
+	t.Description = "Commission returned"
+	return post(tx, t, account(models.AccountCommission, back), provider(booking.ProviderID, -back))
+}
+
+// post records a transaction with its entries, which must add up to zero.
+// A transaction whose key was posted before is skipped, so posting again
+// is safe, as is posting one that moves nothing.
+func post(tx *gorm.DB, t models.LedgerTransaction, entries ...models.LedgerEntry) error {
+	var sum int64
+	moves := false
+	for i := range entries {
+		sum += entries[i].Amount
+		moves = moves || entries[i].Amount != 0
+		entries[i].Currency = t.Currency
+	}
+	if sum != 0 {
+		return fmt.Errorf("ledger transaction %s is off by %d", t.Key, sum)
+	}
+	if !moves {
+		return nil
+	}
+
+	t.ID = 0
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit("Entries").Create(&t).Error; err != nil {
+		return err
+	}
+	if t.ID == 0 {
+		return nil
+	}
+	for i := range entries {
+		entries[i].TransactionID = t.ID
+	}
+	return tx.Create(&entries).Error
+}
+
+func account(a models.LedgerAccount, amount int64) models.LedgerEntry {
+	return models.LedgerEntry{Account: a, Amount: amount}
+}
+
+func provider(id uint, amount int64) models.LedgerEntry {
+	return models.LedgerEntry{Account: models.AccountProvider, ProviderID: &id, Amount: amount}
+}
@@ -0,0 +1,147 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotPending is returned for payouts that were already paid or failed.
+var ErrNotPending = errors.New("payout is not pending")
+
+// CreateBatch pays out what each provider is owed for transactions before
+// end that no payout settled yet, with one payout per provider and
+// currency. What a provider owes the platform, e.g. after refunds, is
+// carried over to a later batch, as are transactions after end.
+func CreateBatch(db *gorm.DB, start, end time.Time) (*models.PayoutBatch, error) {
+	batch := models.PayoutBatch{PeriodStart: start, PeriodEnd: end}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var open []models.LedgerTransaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("payout_id IS NULL AND occurred_at < ?", end).
+			Order("id").Find(&open).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Payouts").Create(&batch).Error; err != nil {
+			return err
+		}
+
+		type owner struct {
+			providerID uint
+			currency   string
+		}
+		var owners []owner
+		settles := map[owner][]uint{}
+		owed := map[owner]int64{}
+		for _, t := range open {
+			o := owner{t.ProviderID, t.Currency}
+			if _, ok := settles[o]; !ok {
+				owners = append(owners, o)
+			}
+			settles[o] = append(settles[o], t.ID)
+			owed[o] += t.Amount
+		}
+
+		now := time.Now()
+		for _, o := range owners {
+			if owed[o] <= 0 {
+				continue
+			}
+			payout := models.Payout{
+				BatchID:    batch.ID,
+				ProviderID: o.providerID,
+				Amount:     owed[o],
+				Currency:   o.currency,
+				Status:     models.PayoutPending,
+			}
+			if err := tx.Omit("Batch", "Provider").Create(&payout).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.LedgerTransaction{}).Where("id IN ?", settles[o]).
+				Update("payout_id", payout.ID).Error; err != nil {
+				return err
+			}
+			if err := post(tx, models.LedgerTransaction{
+				Key:         fmt.Sprintf("payout-%d", payout.ID),
+				Kind:        models.LedgerPayout,
+				ProviderID:  o.providerID,
+				PayoutID:    &payout.ID,
+				Description: fmt.Sprintf("Payout #%d", payout.ID),
+				Amount:      -payout.Amount,
+				Currency:    payout.Currency,
+				OccurredAt:  now,
+			}, provider(o.providerID, payout.Amount), account(models.AccountPayouts, -payout.Amount)); err != nil {
+				return err
+			}
+			batch.Payouts = append(batch.Payouts, payout)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// MarkPaid records that a pending payout reached the provider's bank,
+// with the transfer's reference.
+func MarkPaid(db *gorm.DB, id uint, reference string) (*models.Payout, error) {
+	return settle(db, id, func(tx *gorm.DB, p *models.Payout, now time.Time) error {
+		p.Status, p.Reference, p.PaidAt = models.PayoutPaid, reference, &now
+		if err := tx.Model(p).Select("status", "reference", "paid_at").Updates(p).Error; err != nil {
+			return err
+		}
+		return post(tx, models.LedgerTransaction{
+			Key:         fmt.Sprintf("payout-%d-sent", p.ID),
+			Kind:        models.LedgerPayoutSent,
+			ProviderID:  p.ProviderID,
+			PayoutID:    &p.ID,
+			Description: fmt.Sprintf("Payout #%d sent", p.ID),
+			Currency:    p.Currency,
+			OccurredAt:  now,
+		}, account(models.AccountPayouts, p.Amount), account(models.AccountGateway, -p.Amount))
+	})
+}
+
+// MarkFailed records that a pending payout did not go through. Its amount
+// is owed to the provider again and goes in the next batch.
+func MarkFailed(db *gorm.DB, id uint, reason string) (*models.Payout, error) {
+	return settle(db, id, func(tx *gorm.DB, p *models.Payout, now time.Time) error {
+		p.Status, p.FailureReason = models.PayoutFailed, reason
+		if err := tx.Model(p).Select("status", "failure_reason").Updates(p).Error; err != nil {
+			return err
+		}
+		return post(tx, models.LedgerTransaction{
+			Key:         fmt.Sprintf("payout-%d-return", p.ID),
+			Kind:        models.LedgerPayoutReturn,
+			ProviderID:  p.ProviderID,
+			Description: fmt.Sprintf("Payout #%d returned", p.ID),
+			Amount:      p.Amount,
+			Currency:    p.Currency,
+			OccurredAt:  now,
+		}, account(models.AccountPayouts, p.Amount), provider(p.ProviderID, -p.Amount))
+	})
+}
+
+// settle locks a pending payout and applies its outcome.
+func settle(db *gorm.DB, id uint, apply func(tx *gorm.DB, p *models.Payout, now time.Time) error) (*models.Payout, error) {
+	var payout models.Payout
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payout, id).Error; err != nil {
+			return err
+		}
+		if payout.Status != models.PayoutPending {
+			return ErrNotPending
+		}
+		return apply(tx, &payout, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
@@ -0,0 +1,158 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+
+	"gorm.io/gorm"
+)
+
+// Statement is what a provider was owed over a period, or what one payout
+// settled, line by line. Amounts are to the provider, in minor units of
+// Currency: negative ones were taken from them.
+type Statement struct {
+	ProviderID uint            `json:"provider_id"`
+	Currency   string          `json:"currency"`
+	From       *time.Time      `json:"from,omitempty"`
+	To         *time.Time      `json:"to,omitempty"`
+	PayoutID   *uint           `json:"payout_id,omitempty"`
+	Opening    int64           `json:"opening"`
+	Lines      []StatementLine `json:"lines"`
+	Closing    int64           `json:"closing"`
+}
+
+type StatementLine struct {
+	TransactionID uint              `json:"transaction_id"`
+	Date          time.Time         `json:"date"`
+	Kind          models.LedgerKind `json:"kind"`
+	BookingID     *uint             `json:"booking_id,omitempty"`
+	PayoutID      *uint             `json:"payout_id,omitempty"`
+	Description   string            `json:"description"`
+	Amount        int64             `json:"amount"`
+	Balance       int64             `json:"balance"` // After the line
+}
+
+// Balance is what the platform owes a provider in one currency.
+type Balance struct {
+	Currency  string `json:"currency"`
+	Owed      int64  `json:"owed"`       // Not paid out yet, negative if the provider owes the platform
+	InTransit int64  `json:"in_transit"` // Paid out and waiting to reach their bank
+}
+
+// Balances returns what the platform owes a provider, by currency.
+func Balances(db *gorm.DB, providerID uint) ([]Balance, error) {
+	var owed []Balance
+	if err := db.Model(&models.LedgerTransaction{}).
+		Where("provider_id = ?", providerID).
+		Select("currency, SUM(amount) AS owed").Group("currency").Order("currency").
+		Scan(&owed).Error; err != nil {
+		return nil, err
+	}
+	var transit []Balance
+	if err := db.Model(&models.Payout{}).
+		Where("provider_id = ? AND status = ?", providerID, models.PayoutPending).
+		Select("currency, SUM(amount) AS in_transit").Group("currency").
+		Scan(&transit).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range transit {
+		found := false
+		for i := range owed {
+			if owed[i].Currency == t.Currency {
+				owed[i].InTransit, found = t.InTransit, true
+			}
+		}
+		if !found {
+			owed = append(owed, t)
+		}
+	}
+	return owed, nil
+}
+
+// PeriodStatement returns a provider's statement in a currency from from
+// up to to.
+func PeriodStatement(db *gorm.DB, providerID uint, currency string, from, to time.Time) (*Statement, error) {
+	s := &Statement{ProviderID: providerID, Currency: currency, From: &from, To: &to}
+	query := db.Model(&models.LedgerTransaction{}).
+		Where("provider_id = ? AND currency = ? AND kind <> ?", providerID, currency, models.LedgerPayoutSent).
+		Session(&gorm.Session{})
+	if err := query.Where("occurred_at < ?", from).
+		Select("COALESCE(SUM(amount), 0)").Row().Scan(&s.Opening); err != nil {
+		return nil, err
+	}
+
+	var lines []models.LedgerTransaction
+	if err := query.Where("occurred_at >= ? AND occurred_at < ?", from, to).
+		Order("occurred_at, id").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	s.add(lines)
+	return s, nil
+}
+
+// PayoutStatement returns the transactions a payout settled, ending with
+// the payout itself.
+func PayoutStatement(db *gorm.DB, payout *models.Payout) (*Statement, error) {
+	s := &Statement{ProviderID: payout.ProviderID, Currency: payout.Currency, PayoutID: &payout.ID}
+	var lines []models.LedgerTransaction
+	if err := db.Where("payout_id = ? AND kind <> ?", payout.ID, models.LedgerPayoutSent).
+		Order("kind = 'payout', occurred_at, id").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	s.add(lines)
+	return s, nil
+}
+
+func (s *Statement) add(transactions []models.LedgerTransaction) {
+	s.Lines = []StatementLine{}
+	balance := s.Opening
+	for _, t := range transactions {
+		balance += t.Amount
+		s.Lines = append(s.Lines, StatementLine{
+			TransactionID: t.ID,
+			Date:          t.OccurredAt,
+			Kind:          t.Kind,
+			BookingID:     t.BookingID,
+			PayoutID:      t.PayoutID,
+			Description:   t.Description,
+			Amount:        t.Amount,
+			Balance:       balance,
+		})
+	}
+	s.Closing = balance
+}
+
+// CSV writes the statement as a spreadsheet, between opening and closing
+// balance rows, with amounts as plain decimals.
+func (s *Statement) CSV() []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	decimal := func(amount int64) string { return money.Decimal(amount, s.Currency) }
+
+	w.Write([]string{"date", "kind", "booking", "description", "amount", "balance", "currency"})
+	opening := ""
+	if s.From != nil {
+		opening = s.From.Format(time.RFC3339)
+	}
+	w.Write([]string{opening, "", "", "Opening balance", "", decimal(s.Opening), s.Currency})
+	for _, l := range s.Lines {
+		booking := ""
+		if l.BookingID != nil {
+			booking = strconv.FormatUint(uint64(*l.BookingID), 10)
+		}
+		w.Write([]string{l.Date.Format(time.RFC3339), string(l.Kind), booking, l.Description,
+			decimal(l.Amount), decimal(l.Balance), s.Currency})
+	}
+	closing := ""
+	if s.To != nil {
+		closing = s.To.Format(time.RFC3339)
+	}
+	w.Write([]string{closing, "", "", "Closing balance", "", decimal(s.Closing), s.Currency})
+	w.Flush()
+	return buf.Bytes()
+}
@@ -0,0 +1,246 @@
+// Package loyalty keeps clients' points with providers: earned for
+// completed bookings, spent on rewards and taken back on refunds.
+package loyalty
+
+import (
+	"errors"
+
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Rejection is why a reward cannot be redeemed for a booking, worded for
+// the client.
+type Rejection string
+
+func (r Rejection) Error() string { return string(r) }
+func (Rejection) Rejected()       {}
+
+const (
+	ErrNoProgram       Rejection = "Provider has no loyalty program"
+	ErrRewardNotFound  Rejection = "Reward not found"
+	ErrWrongService    Rejection = "Reward is for another service"
+	ErrNotEnoughPoints Rejection = "Not enough points for this reward"
+	ErrNothingOff      Rejection = "Booking is already free"
+)
+
+// ProgramFor returns a provider's loyalty program, or nil if it has none.
+func ProgramFor(db *gorm.DB, providerID uint) (*models.LoyaltyProgram, error) {
+	var program models.LoyaltyProgram
+	err := db.Where("provider_id = ?", providerID).First(&program).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &program, nil
+}
+
+// Earned returns the points a program gives for a booking, on what the
+// client spent before tax.
+func Earned(program *models.LoyaltyProgram, booking *models.Booking) int64 {
+	points := program.PointsPerBooking
+	if spent := money.New(booking.Net(), booking.Currency).Units(); spent > 0 {
+		points += program.PointsPerUnit * spent
+	}
+	return points
+}
+
+// Accrue credits a completed booking's points to its client's account
+// with the provider. It does nothing if the booking already earned them,
+// so calling it again is safe.
+func Accrue(db *gorm.DB, bookingID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var booking models.Booking
+		if err := tx.First(&booking, bookingID).Error; err != nil {
+			return err
+		}
+		if booking.Status != models.StatusCompleted {
+			return nil
+		}
+		program, err := ProgramFor(tx, booking.ProviderID)
+		if err != nil || program == nil || !program.IsActive {
+			return err
+		}
+		points := Earned(program, &booking)
+		if points <= 0 {
+			return nil
+		}
+
+		account, err := lockAccount(tx, booking.ProviderID, booking.ClientID)
+		if err != nil {
+			return err
+		}
+		earned, err := sum(tx, account.ID, bookingID, models.LoyaltyEarn)
+		if err != nil || earned != 0 {
+			return err
+		}
+		return post(tx, account, models.LoyaltyEarn, points, bookingID)
+	})
+}
+
+// Reverse takes back the points a booking earned in the share of its
+// total that was refunded. It only posts what earlier calls did not, so
+// it can be called each time the booking's refunds change.
+func Reverse(tx *gorm.DB, booking *models.Booking, refunded int64) error {
+	total := booking.Total()
+	if refunded <= 0 || total <= 0 {
+		return nil
+	}
+	var account models.LoyaltyAccount
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("provider_id = ? AND client_id = ?", booking.ProviderID, booking.ClientID).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	earned, err := sum(tx, account.ID, booking.ID, models.LoyaltyEarn)
+	if err != nil || earned <= 0 {
+		return err
+	}
+	reversed, err := sum(tx, account.ID, booking.ID, models.LoyaltyReverse)
+	if err != nil {
+		return err
+	}
+
+	due := earned * min(refunded, total) / total
+	if back := due + reversed; back > 0 {
+		return post(tx, &account, models.LoyaltyReverse, -back, booking.ID)
+	}
+	return nil
+}
+
+// Apply redeems a reward for a new booking, holding the client's account
+// until tx ends so that its points are not spent twice. It sets the
+// booking's RewardID, RewardPoints and RewardDiscount, the latter off
+// what is left after any promo code. Record the redemption once the
+// booking is created.
+func Apply(tx *gorm.DB, rewardID uint, booking *models.Booking) error {
+	program, err := ProgramFor(tx, booking.ProviderID)
+	if err != nil {
+		return err
+	}
+	if program == nil || !program.IsActive {
+		return ErrNoProgram
+	}
+	var reward models.LoyaltyReward
+	err = tx.Where("id = ? AND provider_id = ? AND is_active = ?", rewardID, booking.ProviderID, true).First(&reward).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRewardNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	left := booking.Price - booking.Discount
+	var off int64
+	switch reward.Kind {
+	case models.RewardFreeService:
+		if reward.ServiceID == nil || *reward.ServiceID != booking.ServiceID {
+			return ErrWrongService
+		}
+		off = left
+	case models.RewardDiscount:
+		off = min(reward.Amount, left)
+	}
+	if off <= 0 {
+		return ErrNothingOff
+	}
+
+	account, err := lockAccount(tx, booking.ProviderID, booking.ClientID)
+	if err != nil {
+		return err
+	}
+	if account.Balance < reward.Points {
+		return ErrNotEnoughPoints
+	}
+	booking.RewardID = &reward.ID
+	booking.RewardPoints = reward.Points
+	booking.RewardDiscount = off
+	return nil
+}
+
+// Record takes the points a created booking's reward cost off its
+// client's account.
+func Record(tx *gorm.DB, booking *models.Booking) error {
+	if booking.RewardPoints <= 0 {
+		return nil
+	}
+	account, err := lockAccount(tx, booking.ProviderID, booking.ClientID)
+	if err != nil {
+		return err
+	}
+	return post(tx, account, models.LoyaltyRedeem, -booking.RewardPoints, booking.ID)
+}
+
+// Restore gives back the points spent on a booking that was cancelled or
+// expired. It does nothing if they were already given back, so calling it
+// again is safe.
+func Restore(db *gorm.DB, bookingID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var booking models.Booking
+		if err := tx.First(&booking, bookingID).Error; err != nil {
+			return err
+		}
+		if booking.RewardPoints <= 0 {
+			return nil
+		}
+		account, err := lockAccount(tx, booking.ProviderID, booking.ClientID)
+		if err != nil {
+			return err
+		}
+		restored, err := sum(tx, account.ID, bookingID, models.LoyaltyRestore)
+		if err != nil || restored != 0 {
+			return err
+		}
+		spent, err := sum(tx, account.ID, bookingID, models.LoyaltyRedeem)
+		if err != nil || spent == 0 {
+			return err
+		}
+		return post(tx, account, models.LoyaltyRestore, -spent, bookingID)
+	})
+}
+
+// lockAccount returns a client's account with a provider, opening it if
+// needed, and holds its row until tx ends.
+func lockAccount(tx *gorm.DB, providerID, clientID uint) (*models.LoyaltyAccount, error) {
+	account := models.LoyaltyAccount{ProviderID: providerID, ClientID: clientID}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("provider_id = ? AND client_id = ?", providerID, clientID).First(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// sum adds up the points of one kind an account got for a booking.
+func sum(tx *gorm.DB, accountID, bookingID uint, kind models.LoyaltyEntryKind) (int64, error) {
+	var points int64
+	err := tx.Model(&models.LoyaltyEntry{}).
+		Where("account_id = ? AND booking_id = ? AND kind = ?", accountID, bookingID, kind).
+		Select("COALESCE(SUM(points), 0)").Row().Scan(&points)
+	return points, err
+}
+
+// post moves an account's balance by points and records it in the ledger.
+func post(tx *gorm.DB, account *models.LoyaltyAccount, kind models.LoyaltyEntryKind, points int64, bookingID uint) error {
+	account.Balance += points
+	if err := tx.Model(account).Update("balance", account.Balance).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.LoyaltyEntry{
+		AccountID: account.ID,
+		Kind:      kind,
+		Points:    points,
+		Balance:   account.Balance,
+		BookingID: &bookingID,
+	}).Error
+}
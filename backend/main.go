@@ -1,11 +1,25 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
+	"strconv"
+	"time"
 
+	"pluralink/backend/calendar"
 	"pluralink/backend/config"
 	"pluralink/backend/database"
+	"pluralink/backend/jobs"
+	"pluralink/backend/netguard"
+	"pluralink/backend/notifications"
+	"pluralink/backend/payments"
+	"pluralink/backend/realtime"
+	"pluralink/backend/reminders"
 	"pluralink/backend/routes"
+	"pluralink/backend/secrets"
+	"pluralink/backend/storage"
+	"pluralink/backend/webhooks"
 )
 
 func main() {
@@ -21,6 +35,46 @@ func main() {
 	// Seed initial data
 	database.SeedCategories()
 
+	setupStorage()
+	setupPayments()
+	setupNetGuard()
+	setupSecrets()
+
+	// Start background jobs
+	syncInterval, err := time.ParseDuration(config.AppConfig.CalendarSyncInterval)
+	if err != nil || syncInterval <= 0 {
+		log.Fatal("Invalid CALENDAR_SYNC_INTERVAL:", config.AppConfig.CalendarSyncInterval)
+	}
+	pendingTTL, err := time.ParseDuration(config.AppConfig.PendingBookingTTL)
+	if err != nil || pendingTTL < 0 {
+		log.Fatal("Invalid PENDING_BOOKING_TTL:", config.AppConfig.PendingBookingTTL)
+	}
+	workers, err := strconv.Atoi(config.AppConfig.JobWorkers)
+	if err != nil || workers < 1 {
+		log.Fatal("Invalid JOB_WORKERS:", config.AppConfig.JobWorkers)
+	}
+
+	runner := jobs.NewRunner(database.DB, workers)
+	jobs.RegisterBookingJobs(runner, pendingTTL)
+	jobs.RegisterCalendarJobs(runner, calendar.NewSyncer(database.DB), syncInterval)
+	jobs.RegisterWebhookJobs(runner, webhooks.NewSender(database.DB))
+	jobs.RegisterPaymentJobs(runner, payments.NewService(database.DB))
+	jobs.RegisterMembershipJobs(runner, payments.NewService(database.DB))
+
+	templates, err := notifications.NewTemplates(nil)
+	if err != nil {
+		log.Fatal("Failed to load notification templates:", err)
+	}
+	notifier := notifications.NewService(database.DB, templates)
+	setupNotificationChannels(notifier)
+	notifier.Register(runner)
+	reminders.Register(runner, notifier)
+
+	runner.Start(context.Background())
+
+	// Stream booking changes made on any instance to clients on this one
+	go realtime.NewListener(database.DB, database.DSN(), realtime.DefaultHub).Run(context.Background())
+
 	// Setup routes
 	r := routes.SetupRoutes()
 
@@ -32,3 +86,111 @@ func main() {
 	}
 }
 
+// setupStorage picks where uploaded files live and how download URLs are
+// signed.
+func setupStorage() {
+	cfg := config.AppConfig
+
+	switch cfg.StorageDriver {
+	case "local":
+		storage.Default = storage.NewLocalStore(cfg.UploadDir)
+	case "s3":
+		if cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+			log.Fatal("S3 storage needs S3_ACCESS_KEY and S3_SECRET_KEY")
+		}
+		storage.Default = storage.NewS3Store(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket,
+			cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3PathStyle == "true")
+	default:
+		log.Fatal("Invalid STORAGE_DRIVER:", cfg.StorageDriver)
+	}
+
+	ttl, err := time.ParseDuration(cfg.SignedURLTTL)
+	if err != nil || ttl <= 0 {
+		log.Fatal("Invalid SIGNED_URL_TTL:", cfg.SignedURLTTL)
+	}
+	key := cfg.FileURLSigningKey
+	if key == "" {
+		key = cfg.JWTSecret
+	}
+	storage.DefaultSigner = storage.NewSigner(key, cfg.PublicBaseURL, ttl)
+}
+
+// setupPayments picks the gateway bookings are paid through.
+func setupPayments() {
+	if err := payments.Setup(config.AppConfig); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// setupNetGuard decides whether requests to URLs providers give us may
+// reach private addresses.
+func setupNetGuard() {
+	netguard.AllowPrivate = config.AppConfig.AllowPrivateURLs == "true"
+	if netguard.AllowPrivate {
+		log.Println("Webhooks and calendars may reach private addresses; do not use this in production")
+	}
+}
+
+// setupSecrets sets the key third-party credentials are stored under and
+// encrypts any still stored in plaintext.
+func setupSecrets() {
+	key := config.AppConfig.CredentialsKey
+	if key == "" {
+		key = config.AppConfig.JWTSecret
+	}
+	box, err := secrets.NewBox(key)
+	if err != nil {
+		log.Fatal("Failed to set up credential encryption:", err)
+	}
+	secrets.Default = box
+	if err := calendar.SealCalDAVPasswords(database.DB); err != nil {
+		log.Fatal("Failed to encrypt CalDAV passwords:", err)
+	}
+}
+
+// setupNotificationChannels enables the channels that are configured.
+func setupNotificationChannels(s *notifications.Service) {
+	cfg := config.AppConfig
+
+	// The inbox lives in our own database, so it is always on
+	s.AddChannel(&notifications.InAppChannel{DB: database.DB})
+
+	if cfg.NotificationsFake == "true" {
+		for _, name := range []string{notifications.ChannelEmail, notifications.ChannelSMS, notifications.ChannelPush} {
+			ch := notifications.NewMemoryChannel(name)
+			ch.Log = true
+			s.AddChannel(ch)
+		}
+		log.Println("Notifications are faked: messages are logged, not sent")
+		return
+	}
+
+	switch {
+	case cfg.SMTPSinkAddr != "":
+		sink, err := notifications.NewSMTPSink(cfg.SMTPSinkAddr)
+		if err != nil {
+			log.Fatal("Failed to start SMTP sink:", err)
+		}
+		host, port, _ := net.SplitHostPort(sink.Addr())
+		s.AddChannel(&notifications.SMTPChannel{Host: host, Port: port, From: cfg.SMTPFrom})
+		log.Printf("Email goes to the SMTP sink on %s", sink.Addr())
+	case cfg.SMTPHost != "":
+		s.AddChannel(&notifications.SMTPChannel{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		})
+	}
+
+	if cfg.SMSGatewayURL != "" {
+		s.AddChannel(notifications.NewSMSGatewayChannel(cfg.SMSGatewayURL, cfg.SMSGatewayKey, cfg.SMSFrom))
+	}
+
+	if cfg.PushEnabled == "true" {
+		push := notifications.NewExpoPushChannel(cfg.PushURL, cfg.PushAccessToken)
+		push.OnInvalidToken = s.ForgetDeviceToken
+		s.AddChannel(push)
+	}
+}
@@ -14,6 +14,7 @@ const (
 	StatusCompleted  BookingStatus = "completed"
 	StatusCancelled  BookingStatus = "cancelled"
 	StatusRescheduled BookingStatus = "rescheduled"
+	StatusExpired    BookingStatus = "expired" // Pending booking nobody confirmed in time
 )
 
 type Booking struct {
@@ -26,6 +27,29 @@ type Booking struct {
 	EndTime     string        `gorm:"not null" json:"end_time"`   // Format: "HH:MM"
 	Status      BookingStatus `gorm:"type:varchar(20);default:'pending'" json:"status"`
 	Notes       string        `json:"notes"`
+	Sequence    int           `gorm:"default:0" json:"sequence"` // Bumped on every change to date/time or status
+	ConfirmedAt *time.Time    `json:"confirmed_at"` // When the provider last confirmed it; rescheduling keeps it
+	Price       int64         `gorm:"default:0" json:"price"` // Of the service when booked, in minor units of Currency
+	Currency    string        `gorm:"type:varchar(3)" json:"currency"`
+	Discount    int64         `gorm:"default:0" json:"discount"` // Off Price, in minor units
+	PromoCode   string        `gorm:"type:varchar(40)" json:"promo_code"` // That gave the discount
+	RewardID    *uint         `json:"reward_id"` // Loyalty reward redeemed for the booking
+	RewardPoints int64        `gorm:"default:0" json:"reward_points"` // Spent on the reward
+	RewardDiscount int64      `gorm:"default:0" json:"reward_discount"` // Off Price less Discount, in minor units
+	ClientPackageID *uint     `gorm:"index" json:"client_package_id"` // Package whose session covers the booking
+	MembershipID *uint        `gorm:"index" json:"membership_id"` // Membership whose allowance covers the booking or that priced it
+	MemberDiscount int64      `gorm:"default:0" json:"member_discount"` // Off Price less other discounts, in minor units
+	Prepaid     int64         `gorm:"default:0" json:"prepaid"` // Of Price, covered by a package or membership allowance
+	Tax         int64         `gorm:"default:0" json:"tax"` // On Net, added to it or part of it when TaxInclusive, in minor units
+	TaxName     string        `gorm:"type:varchar(50)" json:"tax_name"`
+	TaxRate     int           `gorm:"default:0" json:"tax_rate"` // In hundredths of a percent
+	TaxInclusive bool         `gorm:"default:false" json:"tax_inclusive"`
+	GiftCardAmount int64      `gorm:"default:0" json:"gift_card_amount"` // Of Total paid with gift cards, in minor units
+	PaymentStatus BookingPaymentStatus `gorm:"type:varchar(20);default:'not_required'" json:"payment_status"`
+	RefundStatus  BookingRefundStatus  `gorm:"type:varchar(20);default:'none'" json:"refund_status"`
+	RefundAmount  int64                `gorm:"default:0" json:"refund_amount"` // Owed back on cancellation, in minor units
+	RefundReason  string               `gorm:"type:varchar(30)" json:"refund_reason"`
+	TipAmount     int64                `gorm:"default:0" json:"tip_amount"` // Tipped by the client after completion, in minor units
 	CreatedAt   time.Time     `json:"created_at"`
 	UpdatedAt   time.Time     `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
@@ -35,5 +59,53 @@ type Booking struct {
 	Provider ServiceProvider `gorm:"foreignKey:ProviderID" json:"provider,omitempty"`
 	Service Service        `gorm:"foreignKey:ServiceID" json:"service,omitempty"`
 	Review  *Review        `gorm:"foreignKey:BookingID" json:"review,omitempty"`
+	Payments []Payment     `gorm:"foreignKey:BookingID" json:"payments,omitempty"`
+	Refunds  []Refund      `gorm:"foreignKey:BookingID" json:"refunds,omitempty"`
 }
 
+
+// Active reports whether the provider confirmed the booking and it is
+// still going ahead, possibly at a new time.
+func (b *Booking) Active() bool {
+	return b.ConfirmedAt != nil && (b.Status == StatusConfirmed || b.Status == StatusRescheduled)
+}
+
+// Net is the booking's price after discounts, rewards and what packages
+// or memberships cover, before tax, in minor units.
+func (b *Booking) Net() int64 {
+	return b.Price - b.Discount - b.RewardDiscount - b.MemberDiscount - b.Prepaid
+}
+
+// Total is what the booking costs the client, after discounts and with
+// tax, in minor units.
+func (b *Booking) Total() int64 {
+	total := b.Net()
+	if !b.TaxInclusive {
+		total += b.Tax
+	}
+	return total
+}
+
+// Payable is what is left of Total once gift cards have paid their part.
+func (b *Booking) Payable() int64 {
+	return b.Total() - b.GiftCardAmount
+}
+
+// StartsAt returns the absolute start of the booking, interpreting Date and
+// StartTime as wall-clock values in loc.
+func (b *Booking) StartsAt(loc *time.Location) time.Time {
+	return combineDateAndClock(b.Date, b.StartTime, loc)
+}
+
+// EndsAt returns the absolute end of the booking in loc.
+func (b *Booking) EndsAt(loc *time.Location) time.Time {
+	return combineDateAndClock(b.Date, b.EndTime, loc)
+}
+
+func combineDateAndClock(date time.Time, clock string, loc *time.Location) time.Time {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+}
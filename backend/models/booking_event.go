@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// BookingEvent is a change to a booking streamed to the provider and client
+// involved. Its ID orders the stream and lets clients resume after a
+// disconnect.
+type BookingEvent struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	BookingID      uint      `gorm:"not null;index" json:"booking_id"`
+	ProviderUserID uint      `gorm:"not null;index" json:"-"`
+	ClientUserID   uint      `gorm:"not null;index" json:"-"`
+	Type           string    `gorm:"type:varchar(50);not null" json:"type"`
+	Payload        string    `gorm:"type:text" json:"-"` // JSON
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
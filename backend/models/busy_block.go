@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+type BusySource string
+
+const (
+	BusySourceICS    BusySource = "ics"
+	BusySourceCalDAV BusySource = "caldav"
+)
+
+// BusyBlock is a span of time a provider is unavailable because of an event
+// in one of their external calendars. ICS blocks are replaced wholesale on
+// each sync; CalDAV blocks are replaced per object (Ref).
+type BusyBlock struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	ProviderID uint       `gorm:"not null;index:idx_busy_provider_range" json:"provider_id"`
+	Source     BusySource `gorm:"type:varchar(20);not null;index:idx_busy_source" json:"source"`
+	SourceID   uint       `gorm:"not null;index:idx_busy_source" json:"source_id"`
+	Ref        string     `gorm:"index" json:"ref"` // Object within the source, e.g. a CalDAV href
+	UID        string     `json:"uid"`
+	Summary    string     `json:"summary"`
+	StartAt    time.Time  `gorm:"not null;index:idx_busy_provider_range" json:"start_at"`
+	EndAt      time.Time  `gorm:"not null;index:idx_busy_provider_range" json:"end_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// CalDAVAccount connects a provider to a calendar collection on a CalDAV
+// server. Bookings are pushed into it and the provider's own events are
+// pulled back as busy blocks.
+type CalDAVAccount struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	ProviderID    uint       `gorm:"uniqueIndex;not null" json:"provider_id"`
+	CollectionURL string     `gorm:"not null" json:"collection_url"`
+	Username      string     `json:"username"`
+	Password      string     `json:"-"` // Sealed with secrets.Default
+	SyncToken     string     `json:"-"`
+	IsActive      bool       `gorm:"default:true" json:"is_active"`
+	LastSyncedAt  *time.Time `json:"last_synced_at"`
+	LastError     string     `json:"last_error"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// CalDAVBookingEvent tracks where a booking was written on the server and
+// the ETag of our last write.
+type CalDAVBookingEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AccountID uint      `gorm:"not null;uniqueIndex:idx_caldav_account_booking" json:"account_id"`
+	BookingID uint      `gorm:"not null;uniqueIndex:idx_caldav_account_booking" json:"booking_id"`
+	Href      string    `gorm:"not null;index" json:"href"`
+	ETag      string    `json:"etag"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CalDAVResource is a provider-owned object pulled from the server. Its
+// ETag lets servers without sync-collection be synced incrementally.
+type CalDAVResource struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AccountID uint      `gorm:"not null;uniqueIndex:idx_caldav_account_href" json:"account_id"`
+	Href      string    `gorm:"not null;uniqueIndex:idx_caldav_account_href" json:"href"`
+	ETag      string    `json:"etag"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
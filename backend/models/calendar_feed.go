@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarFeed holds the secret token behind a user's ICS subscription URL.
+// Deleting the row revokes the feed; regenerating replaces the token.
+type CalendarFeed struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"uniqueIndex;not null" json:"user_id"`
+	Token     string    `gorm:"uniqueIndex;not null" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
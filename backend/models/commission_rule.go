@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// CommissionRule is the share the platform keeps of what clients pay
+// providers. A rule for a provider comes first, then one for the
+// category of the service booked, then the default rule, which has
+// neither.
+type CommissionRule struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProviderID uint      `gorm:"not null;default:0;uniqueIndex:idx_commission_rules_scope" json:"provider_id"` // 0 for any provider
+	CategoryID uint      `gorm:"not null;default:0;uniqueIndex:idx_commission_rules_scope" json:"category_id"` // 0 for any category
+	Rate       int       `gorm:"not null" json:"rate"`                                                         // In hundredths of a percent: 1500 is 15%
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// DeviceToken is a mobile push token registered by the app for a user. A
+// token moves to whichever user signed in on the device last.
+type DeviceToken struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Token     string    `gorm:"uniqueIndex;not null" json:"token"`
+	Platform  string    `gorm:"type:varchar(20)" json:"platform"` // ios, android
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
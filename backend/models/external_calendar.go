@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ExternalCalendar is an ICS URL a provider subscribes us to so their
+// personal events block out booking time.
+type ExternalCalendar struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	ProviderID   uint           `gorm:"not null;index" json:"provider_id"`
+	Name         string         `json:"name"`
+	URL          string         `gorm:"not null" json:"url"`
+	IsActive     bool           `gorm:"default:true" json:"is_active"`
+	LastSyncedAt *time.Time     `json:"last_synced_at"`
+	LastError    string         `json:"last_error"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relationships
+	Provider ServiceProvider `gorm:"foreignKey:ProviderID" json:"provider,omitempty"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+type GiftCardStatus string
+
+const (
+	GiftCardPending GiftCardStatus = "pending" // Bought in the app and waiting for payment
+	GiftCardActive  GiftCardStatus = "active"
+	GiftCardVoid    GiftCardStatus = "void" // Its payment was cancelled
+)
+
+// GiftCard is credit with a provider that bookings can be paid with, in
+// parts, until its balance runs out.
+type GiftCard struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	ProviderID     uint           `gorm:"not null;index" json:"provider_id"`
+	Code           string         `gorm:"type:varchar(19);not null;uniqueIndex" json:"code"` // XXXX-XXXX-XXXX-XXXX
+	Amount         int64          `gorm:"not null" json:"amount"`                            // Face value, in minor units of Currency
+	Balance        int64          `gorm:"not null;default:0" json:"balance"`
+	Currency       string         `gorm:"type:varchar(3);not null" json:"currency"`
+	Status         GiftCardStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	PurchaserID    *uint          `gorm:"index" json:"purchaser_id"` // The client who bought it in the app
+	RecipientName  string         `json:"recipient_name"`
+	RecipientEmail string         `json:"recipient_email"`
+	Message        string         `json:"message"`
+	Gateway        string         `gorm:"type:varchar(20)" json:"gateway,omitempty"`
+	IntentID       *string        `gorm:"type:varchar(255);uniqueIndex" json:"intent_id,omitempty"`
+	ClientSecret   string         `json:"-"`
+	FailureMessage string         `json:"failure_message,omitempty"` // Why the latest payment attempt was declined
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+
+	Entries []GiftCardEntry `gorm:"foreignKey:GiftCardID" json:"entries,omitempty"`
+}
+
+type GiftCardEntryKind string
+
+const (
+	GiftCardIssue      GiftCardEntryKind = "issue"
+	GiftCardRedemption GiftCardEntryKind = "redemption" // Paid towards a booking
+	GiftCardRefund     GiftCardEntryKind = "refund"     // Returned from a cancelled booking
+)
+
+// GiftCardEntry is one change to a gift card's balance. The entries of a
+// card add up to its balance.
+type GiftCardEntry struct {
+	ID         uint              `gorm:"primaryKey" json:"id"`
+	GiftCardID uint              `gorm:"not null;index" json:"gift_card_id"`
+	Kind       GiftCardEntryKind `gorm:"type:varchar(20);not null" json:"kind"`
+	Amount     int64             `gorm:"not null" json:"amount"`  // Negative for redemptions, in minor units
+	Balance    int64             `gorm:"not null" json:"balance"` // After the entry
+	BookingID  *uint             `gorm:"index" json:"booking_id"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Invoice numbers a booking's invoice or receipt. Numbers run from 1 for
+// each provider, without gaps, in the order invoices are first issued.
+type Invoice struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProviderID uint      `gorm:"not null;uniqueIndex:idx_invoices_number" json:"provider_id"`
+	Number     int       `gorm:"not null;uniqueIndex:idx_invoices_number" json:"number"`
+	BookingID  uint      `gorm:"not null;uniqueIndex" json:"booking_id"`
+	IssuedAt   time.Time `gorm:"not null" json:"issued_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Reference is the number as printed, unique across providers.
+func (i *Invoice) Reference() string {
+	return fmt.Sprintf("%d-%06d", i.ProviderID, i.Number)
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job is a unit of background work in the database-backed queue. Workers on
+// any server instance claim due jobs with row locks, so each job runs once.
+type Job struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Type        string     `gorm:"type:varchar(100);not null;index" json:"type"`
+	Payload     string     `gorm:"type:text" json:"payload"` // JSON
+	Status      JobStatus  `gorm:"type:varchar(20);not null;default:'pending';index:idx_jobs_due" json:"status"`
+	RunAt       time.Time  `gorm:"not null;index:idx_jobs_due" json:"run_at"`
+	Attempts    int        `gorm:"default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"default:5" json:"max_attempts"`
+	UniqueKey   *string    `gorm:"uniqueIndex" json:"unique_key,omitempty"` // Deduplicates scheduled jobs across instances
+	LockedBy    string     `json:"locked_by"`
+	LockedAt    *time.Time `json:"locked_at"`
+	LastError   string     `json:"last_error"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+)
+
+// LedgerAccount is one of the platform's books. Provider accounts are
+// kept per provider.
+type LedgerAccount string
+
+const (
+	AccountGateway    LedgerAccount = "gateway"    // Money held with the payment gateway
+	AccountProvider   LedgerAccount = "provider"   // Owed to a provider
+	AccountCommission LedgerAccount = "commission" // Kept by the platform
+	AccountPayouts    LedgerAccount = "payouts"    // Paid out to providers and on the way to their bank
+)
+
+type LedgerKind string
+
+const (
+	LedgerCharge       LedgerKind = "charge" // A client paid for a booking or a purchase
+	LedgerTip          LedgerKind = "tip"
+	LedgerCommission   LedgerKind = "commission" // Negative when a refund gives it back
+	LedgerFee          LedgerKind = "fee"
+	LedgerRefund       LedgerKind = "refund"
+	LedgerChargeback   LedgerKind = "chargeback" // A dispute the provider lost
+	LedgerPayout       LedgerKind = "payout"
+	LedgerPayoutSent   LedgerKind = "payout_sent"
+	LedgerPayoutReturn LedgerKind = "payout_return" // A payout that did not arrive, owed again
+)
+
+// LedgerTransaction is one money movement, recorded as entries that add
+// up to zero. Key names what it records so nothing is posted twice.
+type LedgerTransaction struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Key         string     `gorm:"type:varchar(100);not null;uniqueIndex" json:"-"` // e.g. "payment-12-commission"
+	Kind        LedgerKind `gorm:"type:varchar(20);not null;index" json:"kind"`
+	ProviderID  uint       `gorm:"not null;index" json:"provider_id"`
+	BookingID   *uint      `gorm:"index" json:"booking_id,omitempty"`
+	PayoutID    *uint      `gorm:"index" json:"payout_id,omitempty"` // The payout that settled it
+	Description string     `json:"description"`
+	Amount      int64      `gorm:"not null" json:"amount"` // To the provider, negative when taken from them, in minor units of Currency
+	Currency    string     `gorm:"type:varchar(3);not null" json:"currency"`
+	OccurredAt  time.Time  `gorm:"not null;index" json:"occurred_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	Entries []LedgerEntry `gorm:"foreignKey:TransactionID" json:"entries,omitempty"`
+}
+
+// LedgerEntry is one side of a transaction: a debit when Amount is
+// positive and a credit when it is negative.
+type LedgerEntry struct {
+	ID            uint          `gorm:"primaryKey" json:"id"`
+	TransactionID uint          `gorm:"not null;index" json:"transaction_id"`
+	Account       LedgerAccount `gorm:"type:varchar(20);not null;index" json:"account"`
+	ProviderID    *uint         `gorm:"index" json:"provider_id,omitempty"` // For provider accounts
+	Amount        int64         `gorm:"not null" json:"amount"`             // In minor units of Currency
+	Currency      string        `gorm:"type:varchar(3);not null" json:"currency"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// PayoutBatch is the payouts of every provider owed money at the end of a
+// period.
+type PayoutBatch struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `gorm:"not null" json:"period_end"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	Payouts []Payout `gorm:"foreignKey:BatchID" json:"payouts,omitempty"`
+}
+
+type PayoutStatus string
+
+const (
+	PayoutPending PayoutStatus = "pending" // Waiting to be sent
+	PayoutPaid    PayoutStatus = "paid"
+	PayoutFailed  PayoutStatus = "failed" // Its amount is owed again and goes in the next batch
+)
+
+// Payout is what a provider was owed in one currency when its batch was
+// made, settling the ledger transactions marked with its ID.
+type Payout struct {
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	BatchID       uint         `gorm:"not null;index" json:"batch_id"`
+	ProviderID    uint         `gorm:"not null;index" json:"provider_id"`
+	Amount        int64        `gorm:"not null" json:"amount"` // In minor units of Currency
+	Currency      string       `gorm:"type:varchar(3);not null" json:"currency"`
+	Status        PayoutStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Reference     string       `json:"reference,omitempty"` // The bank transfer's
+	FailureReason string       `json:"failure_reason,omitempty"`
+	PaidAt        *time.Time   `json:"paid_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+
+	Batch    *PayoutBatch     `gorm:"foreignKey:BatchID" json:"batch,omitempty"`
+	Provider *ServiceProvider `gorm:"foreignKey:ProviderID" json:"provider,omitempty"`
+}
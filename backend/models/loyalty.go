@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoyaltyProgram is how a provider rewards clients who come back. Points
+// are earned per completed booking, per unit of currency spent, or both.
+type LoyaltyProgram struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	ProviderID       uint      `gorm:"not null;uniqueIndex" json:"provider_id"`
+	IsActive         bool      `gorm:"default:true" json:"is_active"`
+	PointsPerBooking int64     `gorm:"not null;default:0" json:"points_per_booking"`
+	PointsPerUnit    int64     `gorm:"not null;default:0" json:"points_per_unit"` // For each whole unit of the provider's currency spent
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	Rewards []LoyaltyReward `gorm:"foreignKey:ProviderID;references:ProviderID;constraint:-" json:"rewards,omitempty"`
+}
+
+type LoyaltyRewardKind string
+
+const (
+	RewardFreeService LoyaltyRewardKind = "free_service"
+	RewardDiscount    LoyaltyRewardKind = "discount"
+)
+
+// LoyaltyReward is what a client can trade points for when booking.
+type LoyaltyReward struct {
+	ID         uint              `gorm:"primaryKey" json:"id"`
+	ProviderID uint              `gorm:"not null;index" json:"provider_id"`
+	Name       string            `gorm:"not null" json:"name"`
+	Points     int64             `gorm:"not null" json:"points"` // What it costs
+	Kind       LoyaltyRewardKind `gorm:"type:varchar(20);not null" json:"kind"`
+	ServiceID  *uint             `json:"service_id"`                       // The service given for free
+	Amount     int64             `gorm:"not null;default:0" json:"amount"` // Off the price, in minor units of the provider's currency
+	IsActive   bool              `gorm:"default:true" json:"is_active"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt    `gorm:"index" json:"-"`
+
+	Service *Service `gorm:"foreignKey:ServiceID" json:"service,omitempty"`
+}
+
+// LoyaltyAccount holds a client's points with a provider.
+type LoyaltyAccount struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProviderID uint      `gorm:"not null;uniqueIndex:idx_loyalty_accounts_member" json:"provider_id"`
+	ClientID   uint      `gorm:"not null;uniqueIndex:idx_loyalty_accounts_member;index" json:"client_id"`
+	Balance    int64     `gorm:"not null;default:0" json:"balance"` // Below zero if points spent were later reversed
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	Provider *ServiceProvider `gorm:"foreignKey:ProviderID" json:"provider,omitempty"`
+	Client   *Client          `gorm:"foreignKey:ClientID" json:"client,omitempty"`
+	Entries  []LoyaltyEntry   `gorm:"foreignKey:AccountID" json:"entries,omitempty"`
+}
+
+type LoyaltyEntryKind string
+
+const (
+	LoyaltyEarn    LoyaltyEntryKind = "earn"    // For a completed booking
+	LoyaltyReverse LoyaltyEntryKind = "reverse" // Earned for a booking since refunded
+	LoyaltyRedeem  LoyaltyEntryKind = "redeem"  // Spent on a reward
+	LoyaltyRestore LoyaltyEntryKind = "restore" // Spent on a booking since cancelled
+)
+
+// LoyaltyEntry is one change to a loyalty account's balance. The entries
+// of an account add up to its balance.
+type LoyaltyEntry struct {
+	ID        uint             `gorm:"primaryKey" json:"id"`
+	AccountID uint             `gorm:"not null;index" json:"account_id"`
+	Kind      LoyaltyEntryKind `gorm:"type:varchar(20);not null" json:"kind"`
+	Points    int64            `gorm:"not null" json:"points"`  // Negative for redemptions and reversals
+	Balance   int64            `gorm:"not null" json:"balance"` // After the entry
+	BookingID *uint            `gorm:"index" json:"booking_id"`
+	CreatedAt time.Time        `json:"created_at"`
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MembershipPlan is a monthly subscription a provider offers: a number of
+// sessions included each month and a discount on further bookings.
+type MembershipPlan struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	ProviderID  uint           `gorm:"not null;index" json:"provider_id"`
+	Name        string         `gorm:"not null" json:"name"`
+	Description string         `json:"description"`
+	Price       int64          `gorm:"not null" json:"price"` // Each month, in minor units of Currency
+	Currency    string         `gorm:"type:varchar(3);not null" json:"currency"`
+	Allowance   int            `gorm:"not null;default:0" json:"allowance"` // Sessions included each month
+	Discount    int            `gorm:"not null;default:0" json:"discount"`  // Percent off bookings beyond the allowance
+	IsActive    bool           `gorm:"default:true" json:"is_active"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Services []Service `gorm:"many2many:membership_plan_services" json:"services"` // None for every service
+}
+
+type MembershipStatus string
+
+const (
+	MembershipPending   MembershipStatus = "pending" // Waiting for the first payment
+	MembershipActive    MembershipStatus = "active"
+	MembershipPastDue   MembershipStatus = "past_due" // The current month is not paid yet
+	MembershipCancelled MembershipStatus = "cancelled"
+	MembershipLapsed    MembershipStatus = "lapsed" // A month went unpaid
+)
+
+// Membership is a client's subscription to a plan. Its allowance and
+// discount are copied from the plan when each month starts.
+type Membership struct {
+	ID                uint             `gorm:"primaryKey" json:"id"`
+	PlanID            uint             `gorm:"not null;index" json:"plan_id"`
+	ProviderID        uint             `gorm:"not null;index" json:"provider_id"`
+	ClientID          uint             `gorm:"not null;index" json:"client_id"`
+	Status            MembershipStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	PeriodStart       *time.Time       `json:"period_start"`
+	PeriodEnd         *time.Time       `gorm:"index" json:"period_end"`
+	Allowance         int              `gorm:"not null;default:0" json:"allowance"`
+	Used              int              `gorm:"not null;default:0" json:"used"` // Of the allowance, this month
+	Discount          int              `gorm:"not null;default:0" json:"discount"`
+	CancelAtPeriodEnd bool             `gorm:"default:false" json:"cancel_at_period_end"`
+	PaymentMethod     string           `json:"-"` // Last one that paid, charged again on renewal
+	GatewayCustomer   string           `json:"-"` // Who pays at the gateway, where PaymentMethod is saved
+	CreatedAt         time.Time        `json:"created_at"`
+	UpdatedAt         time.Time        `json:"updated_at"`
+
+	Plan     MembershipPlan      `gorm:"foreignKey:PlanID" json:"plan,omitempty"`
+	Client   *Client             `gorm:"foreignKey:ClientID" json:"client,omitempty"`
+	Payments []MembershipPayment `gorm:"foreignKey:MembershipID" json:"payments,omitempty"`
+}
+
+// Current reports whether the membership's benefits apply at now.
+func (m *Membership) Current(now time.Time) bool {
+	return m.Status == MembershipActive && m.PeriodStart != nil && m.PeriodEnd != nil &&
+		!now.Before(*m.PeriodStart) && now.Before(*m.PeriodEnd)
+}
+
+// MembershipPayment pays for one month of a membership.
+type MembershipPayment struct {
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	MembershipID   uint          `gorm:"not null;uniqueIndex:idx_membership_payments_period" json:"membership_id"`
+	PeriodStart    time.Time     `gorm:"not null;uniqueIndex:idx_membership_payments_period" json:"period_start"`
+	PeriodEnd      time.Time     `gorm:"not null" json:"period_end"`
+	Amount         int64         `gorm:"not null" json:"amount"` // The plan's price, in minor units of Currency
+	Currency       string        `gorm:"type:varchar(3);not null" json:"currency"`
+	Tax            int64         `gorm:"default:0" json:"tax"` // On Amount, added to it or part of it when TaxInclusive
+	TaxName        string        `gorm:"type:varchar(50)" json:"tax_name"`
+	TaxRate        int           `gorm:"default:0" json:"tax_rate"` // In hundredths of a percent
+	TaxInclusive   bool          `gorm:"default:false" json:"tax_inclusive"`
+	Status         PaymentStatus `gorm:"type:varchar(20);not null;default:'requires_payment'" json:"status"`
+	Gateway        string        `gorm:"type:varchar(20)" json:"gateway"`
+	IntentID       *string       `gorm:"type:varchar(255);uniqueIndex" json:"intent_id,omitempty"`
+	ClientSecret   string        `json:"-"`
+	FailureMessage string        `json:"failure_message,omitempty"`
+	SucceededAt    *time.Time    `json:"succeeded_at"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// Total is what the client pays for the month, with tax, in minor units.
+func (p *MembershipPayment) Total() int64 {
+	if p.TaxInclusive {
+		return p.Amount
+	}
+	return p.Amount + p.Tax
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// BookingMessage is a message in the conversation between a booking's
+// client and provider.
+type BookingMessage struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	BookingID uint       `gorm:"not null;index" json:"booking_id"`
+	SenderID  uint       `gorm:"not null" json:"sender_id"` // User who wrote it
+	Body      string     `gorm:"type:text" json:"body"`
+	ReadAt    *time.Time `json:"read_at"` // When the other side first saw it
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+
+	// Relationships
+	Attachments []MessageAttachment `gorm:"foreignKey:MessageID" json:"attachments"`
+}
+
+// MessageAttachment is a file sent with a message.
+type MessageAttachment struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	MessageID   uint      `gorm:"not null;index" json:"message_id"`
+	FileName    string    `gorm:"not null" json:"file_name"` // As uploaded
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Path        string    `gorm:"not null" json:"-"` // Storage key
+	CreatedAt   time.Time `json:"created_at"`
+}
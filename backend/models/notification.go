@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification is an entry in a user's in-app inbox.
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index:idx_notifications_user" json:"user_id"`
+	Type      string     `gorm:"type:varchar(50);not null" json:"type"` // Event type, e.g. booking.confirmed
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	BookingID *uint      `gorm:"index" json:"booking_id"`
+	ReadAt    *time.Time `gorm:"index:idx_notifications_user" json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationPreference holds how a user wants to be notified. Users
+// without a row get every notification on every channel right away.
+type NotificationPreference struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"uniqueIndex;not null" json:"user_id"`
+	Channels        string     `gorm:"type:text" json:"-"` // JSON: event type -> enabled channels; unlisted events use all
+	TimeZone        string     `json:"time_zone"`          // IANA name used for quiet hours and the digest
+	QuietHoursStart string     `json:"quiet_hours_start"`  // "HH:MM", empty for none
+	QuietHoursEnd   string     `json:"quiet_hours_end"`
+	DigestEnabled   bool       `gorm:"default:false" json:"digest_enabled"`
+	DigestTime      string     `gorm:"default:'08:00'" json:"digest_time"` // Local time the daily digest goes out
+	LastDigestAt    *time.Time `json:"last_digest_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Location returns the preference's time zone, falling back to UTC.
+func (p *NotificationPreference) Location() *time.Location {
+	if p.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// DigestItem is a notification held back for a user's next digest.
+type DigestItem struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Event     string    `gorm:"type:varchar(50);not null" json:"event"`
+	Subject   string    `json:"subject"`
+	Short     string    `json:"short"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Package is a bundle of sessions a provider sells upfront, e.g. ten
+// massages, used up by booking any of its services.
+type Package struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	ProviderID   uint           `gorm:"not null;index" json:"provider_id"`
+	Name         string         `gorm:"not null" json:"name"`
+	Description  string         `json:"description"`
+	Price        int64          `gorm:"not null" json:"price"` // In minor units of Currency
+	Currency     string         `gorm:"type:varchar(3);not null" json:"currency"`
+	Sessions     int            `gorm:"not null" json:"sessions"`
+	ValidityDays int            `gorm:"not null;default:0" json:"validity_days"` // From purchase, 0 for no expiry
+	IsActive     bool           `gorm:"default:true" json:"is_active"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Services []Service `gorm:"many2many:package_services" json:"services"`
+}
+
+type ClientPackageStatus string
+
+const (
+	ClientPackagePending ClientPackageStatus = "pending" // Waiting for payment
+	ClientPackageActive  ClientPackageStatus = "active"
+	ClientPackageVoid    ClientPackageStatus = "void" // Its payment was cancelled
+)
+
+// ClientPackage is a package a client bought, with the sessions they have
+// left on it.
+type ClientPackage struct {
+	ID             uint                `gorm:"primaryKey" json:"id"`
+	PackageID      uint                `gorm:"not null;index" json:"package_id"`
+	ProviderID     uint                `gorm:"not null;index" json:"provider_id"`
+	ClientID       uint                `gorm:"not null;index" json:"client_id"`
+	Sessions       int                 `gorm:"not null" json:"sessions"`
+	Remaining      int                 `gorm:"not null;default:0" json:"remaining"`
+	Price          int64               `gorm:"not null" json:"price"` // Of the package when bought, in minor units of Currency
+	Currency       string              `gorm:"type:varchar(3);not null" json:"currency"`
+	Tax            int64               `gorm:"default:0" json:"tax"` // On Price, added to it or part of it when TaxInclusive
+	TaxName        string              `gorm:"type:varchar(50)" json:"tax_name"`
+	TaxRate        int                 `gorm:"default:0" json:"tax_rate"` // In hundredths of a percent
+	TaxInclusive   bool                `gorm:"default:false" json:"tax_inclusive"`
+	Status         ClientPackageStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	PaidAt         *time.Time          `json:"paid_at"`
+	ExpiresAt      *time.Time          `json:"expires_at"` // Set once paid
+	Gateway        string              `gorm:"type:varchar(20)" json:"gateway,omitempty"`
+	IntentID       *string             `gorm:"type:varchar(255);uniqueIndex" json:"intent_id,omitempty"`
+	ClientSecret   string              `json:"-"`
+	FailureMessage string              `json:"failure_message,omitempty"` // Why the latest payment attempt was declined
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+
+	Package Package `gorm:"foreignKey:PackageID" json:"package,omitempty"`
+	Client  *Client `gorm:"foreignKey:ClientID" json:"client,omitempty"`
+}
+
+// Total is what the client pays for the package, with tax, in minor
+// units.
+func (p *ClientPackage) Total() int64 {
+	if p.TaxInclusive {
+		return p.Price
+	}
+	return p.Price + p.Tax
+}
+
+// Usable reports whether the package can cover a booking made at now.
+func (p *ClientPackage) Usable(now time.Time) bool {
+	return p.Status == ClientPackageActive && p.Remaining > 0 && (p.ExpiresAt == nil || now.Before(*p.ExpiresAt))
+}
+
+// CreditUse is a booking covered by a package session or a membership's
+// allowance. RestoredAt is set once a cancellation gave it back.
+type CreditUse struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	BookingID       uint       `gorm:"not null;uniqueIndex" json:"booking_id"`
+	ClientPackageID *uint      `gorm:"index" json:"client_package_id"`
+	MembershipID    *uint      `gorm:"index" json:"membership_id"`
+	PeriodStart     *time.Time `json:"period_start"` // Of the membership allowance used
+	RestoredAt      *time.Time `json:"restored_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"time"
+)
+
+// PaymentPolicy is what a service asks clients to pay when booking.
+type PaymentPolicy string
+
+const (
+	PaymentNone    PaymentPolicy = "none"    // Paid outside the app
+	PaymentDeposit PaymentPolicy = "deposit" // DepositPercent of the price up front
+	PaymentFull    PaymentPolicy = "full"    // The whole price up front
+)
+
+// PaymentTip is the kind of payments clients add after a completed
+// booking. It is not a policy services can ask for.
+const PaymentTip PaymentPolicy = "tip"
+
+// BookingPaymentStatus summarises a booking's payments.
+type BookingPaymentStatus string
+
+const (
+	PaymentNotRequired BookingPaymentStatus = "not_required"
+	PaymentDue         BookingPaymentStatus = "due" // Waiting for the client; the booking stays pending
+	PaymentPaid        BookingPaymentStatus = "paid"
+	PaymentDisputed    BookingPaymentStatus = "disputed"     // The client disputed a payment with their bank
+	PaymentChargedBack BookingPaymentStatus = "charged_back" // The dispute was lost and the money returned
+)
+
+// BookingRefundStatus summarises the refunds of a cancelled booking.
+type BookingRefundStatus string
+
+const (
+	RefundNone     BookingRefundStatus = "none"
+	RefundPending  BookingRefundStatus = "pending" // Being issued or waiting for the gateway
+	RefundRefunded BookingRefundStatus = "refunded"
+	RefundFailed   BookingRefundStatus = "failed"
+)
+
+type DisputeStatus string
+
+const (
+	DisputeOpen DisputeStatus = "open"
+	DisputeWon  DisputeStatus = "won"
+	DisputeLost DisputeStatus = "lost"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentRequiresPayment PaymentStatus = "requires_payment"
+	PaymentProcessing      PaymentStatus = "processing"
+	PaymentSucceeded       PaymentStatus = "succeeded"
+	PaymentCanceled        PaymentStatus = "canceled"
+)
+
+// Payment is a payment intent opened with a gateway for a booking. A
+// booking may collect several over its life, e.g. after a cancelled
+// attempt.
+type Payment struct {
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	BookingID      uint          `gorm:"not null;index" json:"booking_id"`
+	Kind           PaymentPolicy `gorm:"type:varchar(20);not null" json:"kind"` // deposit, full or tip
+	Gateway        string        `gorm:"type:varchar(20);not null" json:"gateway"`
+	IntentID       string        `gorm:"type:varchar(255);not null;uniqueIndex" json:"intent_id"`
+	Amount         int64         `gorm:"not null" json:"amount"` // In minor units, e.g. cents
+	Currency       string        `gorm:"type:varchar(3);not null" json:"currency"`
+	Status         PaymentStatus `gorm:"type:varchar(20);not null;default:'requires_payment'" json:"status"`
+	ClientSecret   string        `json:"-"`
+	FailureMessage string        `json:"failure_message"` // Why the latest attempt was declined
+	SucceededAt    *time.Time    `json:"succeeded_at"`
+	DisputeStatus  DisputeStatus `gorm:"type:varchar(20)" json:"dispute_status,omitempty"`
+	DisputeReason  string        `json:"dispute_reason,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// Open reports whether the payment can still succeed.
+func (p *Payment) Open() bool {
+	return p.Status == PaymentRequiresPayment || p.Status == PaymentProcessing
+}
+
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+// Refund reasons
+const (
+	RefundReasonProviderCancelled = "provider_cancelled"
+	RefundReasonClientCancelled   = "client_cancelled"
+	RefundReasonExpired           = "expired"      // The provider never confirmed
+	RefundReasonLatePayment       = "late_payment" // Paid after the booking was cancelled or expired
+)
+
+// Refund is money returned from one of a booking's payments.
+type Refund struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	BookingID      uint         `gorm:"not null;index" json:"booking_id"`
+	PaymentID      uint         `gorm:"not null;index" json:"payment_id"`
+	Gateway        string       `gorm:"type:varchar(20);not null" json:"gateway"`
+	RefundID       string       `gorm:"type:varchar(255);not null;uniqueIndex" json:"refund_id"`
+	Amount         int64        `gorm:"not null" json:"amount"` // In minor units
+	Currency       string       `gorm:"type:varchar(3);not null" json:"currency"`
+	Status         RefundStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Reason         string       `gorm:"type:varchar(30)" json:"reason"`
+	FailureMessage string       `json:"failure_message"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+type PaymentEventStatus string
+
+const (
+	PaymentEventReceived  PaymentEventStatus = "received"
+	PaymentEventProcessed PaymentEventStatus = "processed"
+	PaymentEventIgnored   PaymentEventStatus = "ignored" // Not about anything the app tracks
+	PaymentEventFailed    PaymentEventStatus = "failed"
+)
+
+// PaymentEvent is a webhook received from a payment gateway, kept as sent
+// so it can be deduplicated and replayed.
+type PaymentEvent struct {
+	ID          uint               `gorm:"primaryKey" json:"id"`
+	Gateway     string             `gorm:"type:varchar(20);not null;uniqueIndex:idx_payment_events_event" json:"gateway"`
+	EventID     string             `gorm:"type:varchar(255);not null;uniqueIndex:idx_payment_events_event" json:"event_id"`
+	Type        string             `gorm:"type:varchar(100)" json:"type"` // As the gateway names it
+	Payload     string             `gorm:"type:text" json:"payload"`
+	Status      PaymentEventStatus `gorm:"type:varchar(20);not null;default:'received';index" json:"status"`
+	Attempts    int                `gorm:"default:0" json:"attempts"`
+	Error       string             `json:"error"`
+	ProcessedAt *time.Time         `json:"processed_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PortfolioItem is an image in a provider's gallery, optionally tagged to
+// one of the provider's categories or services.
+type PortfolioItem struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	ProviderID   uint           `gorm:"not null;index" json:"provider_id"`
+	UploadID     uint           `gorm:"not null" json:"upload_id"`
+	ImageKey     string         `gorm:"not null" json:"-"` // Storage key of the full image
+	ThumbnailKey string         `json:"-"`                 // Empty when no thumbnail could be made
+	Width        int            `json:"width"`
+	Height       int            `json:"height"`
+	Caption      string         `json:"caption"`
+	CategoryID   *uint          `gorm:"index" json:"category_id"`
+	ServiceID    *uint          `gorm:"index" json:"service_id"`
+	Position     int            `gorm:"default:0" json:"position"` // Gallery order, ascending
+	IsCover      bool           `gorm:"default:false" json:"is_cover"`
+	ImageURL     string         `gorm:"-" json:"image_url"`
+	ThumbnailURL string         `gorm:"-" json:"thumbnail_url"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PromoKind string
+
+const (
+	PromoPercent PromoKind = "percent"
+	PromoFixed   PromoKind = "fixed"
+)
+
+// PromoCode is a discount a provider offers on bookings made with its code.
+type PromoCode struct {
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	ProviderID       uint           `gorm:"not null;uniqueIndex:idx_promo_codes_code,where:deleted_at IS NULL" json:"provider_id"`
+	Code             string         `gorm:"type:varchar(40);not null;uniqueIndex:idx_promo_codes_code,where:deleted_at IS NULL" json:"code"` // Upper case
+	Description      string         `json:"description"`
+	Kind             PromoKind      `gorm:"type:varchar(20);not null" json:"kind"`
+	Value            int64          `gorm:"not null" json:"value"` // Percent off, or minor units off in the provider's currency
+	StartsAt         *time.Time     `json:"starts_at"`
+	EndsAt           *time.Time     `json:"ends_at"`
+	MaxRedemptions   int            `gorm:"default:0" json:"max_redemptions"`        // In all, 0 for no limit
+	MaxPerClient     int            `gorm:"default:0" json:"max_per_client"`         // 0 for no limit
+	FirstBookingOnly bool           `gorm:"default:false" json:"first_booking_only"` // With the provider
+	IsActive         bool           `gorm:"default:true" json:"is_active"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+	Redemptions      int64          `gorm:"-" json:"redemptions"` // Bookings using the code, cancelled ones aside
+
+	// The code only applies to these services, or to all when empty
+	Services []Service `gorm:"many2many:promo_code_services;" json:"services"`
+}
+
+// PromoRedemption is a booking made with a promo code. Redemptions of
+// cancelled or expired bookings do not count towards the code's limits.
+type PromoRedemption struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	PromoCodeID uint      `gorm:"not null;index" json:"promo_code_id"`
+	BookingID   uint      `gorm:"not null;uniqueIndex" json:"booking_id"`
+	ClientID    uint      `gorm:"not null;index" json:"client_id"`
+	Amount      int64     `gorm:"not null" json:"amount"` // Discount given, in minor units
+	CreatedAt   time.Time `json:"created_at"`
+
+	Booking Booking `gorm:"foreignKey:BookingID" json:"booking,omitempty"`
+}
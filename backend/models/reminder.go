@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+type ReminderStatus string
+
+const (
+	ReminderScheduled ReminderStatus = "scheduled"
+	ReminderQueued    ReminderStatus = "queued"    // Handed over for delivery
+	ReminderSent      ReminderStatus = "sent"      // Delivered on at least one channel
+	ReminderSkipped   ReminderStatus = "skipped"   // Booking no longer active or moved
+	ReminderCancelled ReminderStatus = "cancelled" // Replaced after a reschedule or cancellation
+	ReminderFailed    ReminderStatus = "failed"
+)
+
+// Reminder is one scheduled reminder for a booking, sent OffsetMinutes
+// before the booking's start.
+type Reminder struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	BookingID     uint           `gorm:"not null;index" json:"booking_id"`
+	OffsetMinutes int            `gorm:"not null" json:"offset_minutes"`
+	StartsAt      time.Time      `gorm:"not null" json:"starts_at"` // Booking start the reminder was scheduled for
+	SendAt        time.Time      `gorm:"not null" json:"send_at"`
+	Status        ReminderStatus `gorm:"type:varchar(20);not null;default:'scheduled'" json:"status"`
+	Attempts      int            `gorm:"default:0" json:"attempts"`
+	Error         string         `json:"error,omitempty"`
+	SentAt        *time.Time     `json:"sent_at"` // When it was first delivered
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"time"
 
+	"pluralink/backend/money"
+
 	"gorm.io/gorm"
 )
 
@@ -12,9 +14,12 @@ type Service struct {
 	CategoryID  uint      `gorm:"not null;index" json:"category_id"`
 	Name        string    `gorm:"not null" json:"name"`
 	Description string    `json:"description"`
-	Price       float64   `gorm:"not null" json:"price"`
+	Price       int64     `gorm:"not null" json:"price"` // In minor units of Currency
+	Currency    string    `gorm:"type:varchar(3);not null;default:'usd'" json:"currency"` // The provider's
 	Duration    int       `gorm:"not null" json:"duration"` // Duration in minutes
 	IsActive    bool      `gorm:"default:true" json:"is_active"`
+	PaymentPolicy  PaymentPolicy `gorm:"type:varchar(20);default:'none'" json:"payment_policy"`
+	DepositPercent int           `gorm:"default:0" json:"deposit_percent"` // Of Price, when PaymentPolicy is deposit
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
@@ -25,3 +30,7 @@ type Service struct {
 	Bookings []Booking       `gorm:"foreignKey:ServiceID" json:"bookings,omitempty"`
 }
 
+// PriceMoney returns the price with its currency.
+func (s *Service) PriceMoney() money.Money {
+	return money.New(s.Price, s.Currency)
+}
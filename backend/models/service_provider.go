@@ -20,6 +20,11 @@ type ServiceProvider struct {
 	Longitude   float64   `json:"longitude"`
 	Phone       string    `json:"phone"`
 	Website     string    `json:"website"`
+	TimeZone    string    `gorm:"default:'UTC'" json:"time_zone"` // IANA name, e.g. "America/New_York"
+	Currency    string    `gorm:"type:varchar(3);not null;default:'usd'" json:"currency"` // ISO 4217, lower case; what services are priced and paid in
+	ReminderOffsets string `gorm:"default:'1440,120'" json:"-"` // Minutes before start, comma-separated
+	CancellationCutoff int `gorm:"default:1440" json:"cancellation_cutoff"` // Minutes before start a client can cancel with a full refund
+	LateCancellationRefund int `gorm:"default:0" json:"late_cancellation_refund"` // Percent refunded to clients cancelling after the cutoff
 	IsVerified  bool      `gorm:"default:false" json:"is_verified"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
@@ -32,5 +37,20 @@ type ServiceProvider struct {
 	Bookings     []Booking    `gorm:"foreignKey:ProviderID" json:"bookings,omitempty"`
 	Reviews      []Review     `gorm:"foreignKey:RevieweeID;where:reviewee_type='provider'" json:"reviews,omitempty"`
 	Categories   []Category   `gorm:"many2many:provider_categories;" json:"categories,omitempty"`
+	Portfolio    []PortfolioItem `gorm:"foreignKey:ProviderID" json:"portfolio,omitempty"` // A preview in listings
+	CoverImage   *PortfolioItem  `gorm:"-" json:"cover_image,omitempty"`
 }
 
+
+// Location returns the provider's time zone, falling back to UTC when it is
+// unset or unknown.
+func (p *ServiceProvider) Location() *time.Location {
+	if p.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// TaxRule is the sales tax or VAT providers in a region charge. Regions are
+// matched on the provider's Country and State as entered, ignoring case. A
+// rule with an empty State covers the rest of its country.
+type TaxRule struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Country   string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_tax_rules_region" json:"country"` // Lower case
+	State     string    `gorm:"type:varchar(100);not null;default:'';uniqueIndex:idx_tax_rules_region" json:"state"`
+	Name      string    `gorm:"type:varchar(50);not null" json:"name"` // As printed, e.g. "VAT" or "Sales tax"
+	Rate      int       `gorm:"not null" json:"rate"`                  // In hundredths of a percent: 2000 is 20%
+	Inclusive bool      `gorm:"default:false" json:"inclusive"`        // Prices already include the tax
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Services in these categories are not taxed
+	ExemptCategories []Category `gorm:"many2many:tax_rule_exemptions;" json:"exempt_categories"`
+}
+
+// Exempts reports whether services of a category are exempt from the rule.
+func (r *TaxRule) Exempts(categoryID uint) bool {
+	for _, c := range r.ExemptCategories {
+		if c.ID == categoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// TaxRegion normalises a country or state the way rules store them.
+func TaxRegion(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
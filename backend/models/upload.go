@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UploadPurpose string
+
+const (
+	UploadAvatar       UploadPurpose = "avatar"
+	UploadPortfolio    UploadPurpose = "portfolio"
+	UploadReviewPhoto  UploadPurpose = "review_photo"
+	UploadReference    UploadPurpose = "reference_image" // Shown by a client to a provider when booking
+	UploadVerification UploadPurpose = "verification_document"
+)
+
+// Upload is a file a user stored. Files with the same content share a Key.
+type Upload struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	UserID      uint           `gorm:"not null;index" json:"user_id"`
+	Purpose     UploadPurpose  `gorm:"type:varchar(30);not null" json:"purpose"`
+	Key         string         `gorm:"not null;index" json:"-"`
+	FileName    string         `json:"file_name"`
+	ContentType string         `json:"content_type"`
+	Size        int64          `json:"size"`
+	SHA256      string         `gorm:"type:varchar(64);not null" json:"sha256"`
+	Private     bool           `gorm:"default:false" json:"private"`
+	URL         string         `gorm:"-" json:"url"` // Filled in on responses; signed and expiring for private files
+	CreatedAt   time.Time      `json:"created_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
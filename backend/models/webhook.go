@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookEndpoint is a URL a provider wants booking and review events
+// POSTed to, signed with Secret.
+type WebhookEndpoint struct {
+	ID                  uint           `gorm:"primaryKey" json:"id"`
+	ProviderID          uint           `gorm:"not null;index" json:"provider_id"`
+	URL                 string         `gorm:"not null" json:"url"`
+	Description         string         `json:"description"`
+	Events              string         `gorm:"type:text" json:"-"` // Comma-separated event types
+	Secret              string         `gorm:"not null" json:"-"`
+	IsActive            bool           `gorm:"default:true" json:"is_active"`
+	ConsecutiveFailures int            `gorm:"default:0" json:"consecutive_failures"` // Deliveries that ran out of retries in a row
+	DisabledAt          *time.Time     `json:"disabled_at"`
+	DisabledReason      string         `json:"disabled_reason"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending"
+	WebhookSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent to one endpoint, with the outcome of
+// its latest attempt. Redeliveries are new rows carrying the same EventID.
+type WebhookDelivery struct {
+	ID             uint                  `gorm:"primaryKey" json:"id"`
+	EndpointID     uint                  `gorm:"not null;index" json:"endpoint_id"`
+	EventID        string                `gorm:"type:varchar(64);not null;index" json:"event_id"`
+	EventType      string                `gorm:"type:varchar(50);not null" json:"event_type"`
+	Payload        string                `gorm:"type:text" json:"payload"` // Exact body sent
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Attempts       int                   `gorm:"default:0" json:"attempts"`
+	ResponseStatus int                   `json:"response_status"`
+	ResponseBody   string                `gorm:"type:text" json:"response_body"` // Truncated
+	Error          string                `json:"error"`
+	DurationMs     int64                 `json:"duration_ms"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at"`
+	CreatedAt      time.Time             `gorm:"index" json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
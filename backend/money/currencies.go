@@ -0,0 +1,150 @@
+package money
+
+import "strings"
+
+type currencyInfo struct {
+	exponent int    // Decimals of the minor unit
+	symbol   string // Empty to write the code
+}
+
+// currencies the app knows how to price in. Others are rejected, so a
+// typo cannot become a currency.
+var currencies = map[string]currencyInfo{
+	"aed": {2, ""},
+	"ars": {2, "AR$"},
+	"aud": {2, "A$"},
+	"bhd": {3, ""},
+	"brl": {2, "R$"},
+	"cad": {2, "CA$"},
+	"chf": {2, ""},
+	"clp": {0, "CL$"},
+	"cny": {2, "CN¥"},
+	"cop": {2, "CO$"},
+	"czk": {2, ""},
+	"dkk": {2, ""},
+	"eur": {2, "€"},
+	"gbp": {2, "£"},
+	"hkd": {2, "HK$"},
+	"huf": {2, ""},
+	"idr": {2, ""},
+	"ils": {2, "₪"},
+	"inr": {2, "₹"},
+	"isk": {0, ""},
+	"jod": {3, ""},
+	"jpy": {0, "¥"},
+	"krw": {0, "₩"},
+	"kwd": {3, ""},
+	"mxn": {2, "MX$"},
+	"myr": {2, ""},
+	"ngn": {2, "₦"},
+	"nok": {2, ""},
+	"nzd": {2, "NZ$"},
+	"omr": {3, ""},
+	"pen": {2, ""},
+	"php": {2, "₱"},
+	"pln": {2, ""},
+	"ron": {2, ""},
+	"sar": {2, ""},
+	"sek": {2, ""},
+	"sgd": {2, "S$"},
+	"thb": {2, "฿"},
+	"try": {2, "₺"},
+	"twd": {2, "NT$"},
+	"uah": {2, "₴"},
+	"usd": {2, "$"},
+	"vnd": {0, "₫"},
+	"zar": {2, ""},
+}
+
+// countryCurrencies maps ISO 3166 country codes, and the names people type,
+// to the currency used there.
+var countryCurrencies = map[string]string{
+	"ae": "aed", "united arab emirates": "aed",
+	"ar": "ars", "argentina": "ars",
+	"at": "eur", "austria": "eur",
+	"au": "aud", "australia": "aud",
+	"be": "eur", "belgium": "eur",
+	"bh": "bhd", "bahrain": "bhd",
+	"br": "brl", "brazil": "brl",
+	"ca": "cad", "canada": "cad",
+	"ch": "chf", "switzerland": "chf",
+	"cl": "clp", "chile": "clp",
+	"cn": "cny", "china": "cny",
+	"co": "cop", "colombia": "cop",
+	"cy": "eur", "cyprus": "eur",
+	"cz": "czk", "czechia": "czk", "czech republic": "czk",
+	"de": "eur", "germany": "eur",
+	"dk": "dkk", "denmark": "dkk",
+	"ee": "eur", "estonia": "eur",
+	"es": "eur", "spain": "eur",
+	"fi": "eur", "finland": "eur",
+	"fr": "eur", "france": "eur",
+	"gb": "gbp", "uk": "gbp", "united kingdom": "gbp",
+	"gr": "eur", "greece": "eur",
+	"hk": "hkd", "hong kong": "hkd",
+	"hr": "eur", "croatia": "eur",
+	"hu": "huf", "hungary": "huf",
+	"id": "idr", "indonesia": "idr",
+	"ie": "eur", "ireland": "eur",
+	"il": "ils", "israel": "ils",
+	"in": "inr", "india": "inr",
+	"is": "isk", "iceland": "isk",
+	"it": "eur", "italy": "eur",
+	"jo": "jod", "jordan": "jod",
+	"jp": "jpy", "japan": "jpy",
+	"kr": "krw", "south korea": "krw", "korea": "krw",
+	"kw": "kwd", "kuwait": "kwd",
+	"lt": "eur", "lithuania": "eur",
+	"lu": "eur", "luxembourg": "eur",
+	"lv": "eur", "latvia": "eur",
+	"mt": "eur", "malta": "eur",
+	"mx": "mxn", "mexico": "mxn",
+	"my": "myr", "malaysia": "myr",
+	"ng": "ngn", "nigeria": "ngn",
+	"nl": "eur", "netherlands": "eur",
+	"no": "nok", "norway": "nok",
+	"nz": "nzd", "new zealand": "nzd",
+	"om": "omr", "oman": "omr",
+	"pe": "pen", "peru": "pen",
+	"ph": "php", "philippines": "php",
+	"pl": "pln", "poland": "pln",
+	"pt": "eur", "portugal": "eur",
+	"ro": "ron", "romania": "ron",
+	"sa": "sar", "saudi arabia": "sar",
+	"se": "sek", "sweden": "sek",
+	"sg": "sgd", "singapore": "sgd",
+	"si": "eur", "slovenia": "eur",
+	"sk": "eur", "slovakia": "eur",
+	"th": "thb", "thailand": "thb",
+	"tr": "try", "turkey": "try", "türkiye": "try",
+	"tw": "twd", "taiwan": "twd",
+	"ua": "uah", "ukraine": "uah",
+	"us": "usd", "usa": "usd", "united states": "usd", "united states of america": "usd",
+	"vn": "vnd", "vietnam": "vnd", "viet nam": "vnd",
+	"za": "zar", "south africa": "zar",
+}
+
+// Valid reports whether currency is one the app prices in.
+func Valid(currency string) bool {
+	_, ok := currencies[strings.ToLower(currency)]
+	return ok
+}
+
+// Exponent returns how many decimals the currency's minor unit has. Unknown
+// currencies are taken to have 2.
+func Exponent(currency string) int {
+	return lookup(currency).exponent
+}
+
+// ForCountry returns the currency of a country, given as an ISO code or
+// its English name, or "" if it is not known.
+func ForCountry(country string) string {
+	return countryCurrencies[strings.ToLower(strings.TrimSpace(country))]
+}
+
+func lookup(currency string) currencyInfo {
+	if info, ok := currencies[strings.ToLower(currency)]; ok {
+		return info
+	}
+	return currencyInfo{exponent: 2}
+}
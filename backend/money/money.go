@@ -0,0 +1,211 @@
+// Package money handles amounts of money as integer minor units of an ISO
+// 4217 currency, e.g. cents of a dollar, so sums never drift.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in a currency.
+type Money struct {
+	Amount   int64  `json:"amount"`   // In minor units, e.g. cents
+	Currency string `json:"currency"` // ISO 4217, lower case
+}
+
+// New returns amount minor units of currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToLower(currency)}
+}
+
+// Add returns m plus o, which must be in the same currency.
+func (m Money) Add(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}
+}
+
+// Sub returns m minus o, which must be in the same currency.
+func (m Money) Sub(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount - o.Amount, Currency: m.Currency}
+}
+
+// Mul returns m times n.
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// Percent returns p percent of m, rounded half away from zero to the
+// nearest minor unit.
+func (m Money) Percent(p int) Money {
+	return Money{Amount: divRound(m.Amount*int64(p), 100), Currency: m.Currency}
+}
+
+// PercentBasis returns bp hundredths of a percent of m, rounded like
+// Percent. 1250 is 12.5%.
+func (m Money) PercentBasis(bp int) Money {
+	return Money{Amount: divRound(m.Amount*int64(bp), 10000), Currency: m.Currency}
+}
+
+// IncludedPercentBasis returns the part of m that bp hundredths of a
+// percent added on top make up, rounded like Percent: for 2000, 1.00 of
+// 6.00.
+func (m Money) IncludedPercentBasis(bp int) Money {
+	base := divRound(m.Amount*10000, 10000+int64(bp))
+	return Money{Amount: m.Amount - base, Currency: m.Currency}
+}
+
+// FormatBasis writes hundredths of a percent for people, e.g. "12.5%" for
+// 1250.
+func FormatBasis(bp int) string {
+	return strconv.FormatFloat(float64(bp)/100, 'f', -1, 64) + "%"
+}
+
+// ParseBasis reads a percentage such as "12.5" or "7.25%" into hundredths
+// of a percent. It rejects rates outside 0 to 100%.
+func ParseBasis(s string) (int, error) {
+	f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || f < 0 || f > 100 {
+		return 0, fmt.Errorf("invalid rate %q, use a percentage, e.g. 12.5", s)
+	}
+	return int(math.Round(f * 100)), nil
+}
+
+// Units returns the whole units of currency in m, e.g. 12 for $12.99.
+func (m Money) Units() int64 {
+	return m.Amount / pow10(Exponent(m.Currency))
+}
+
+func (m Money) IsZero() bool { return m.Amount == 0 }
+
+// String formats m for people, e.g. "$1,234.50" or "¥1,235".
+func (m Money) String() string {
+	return Format(m.Amount, m.Currency)
+}
+
+func (m Money) mustMatch(o Money) {
+	if m.Currency != o.Currency {
+		panic(fmt.Sprintf("money: mixing %s and %s", m.Currency, o.Currency))
+	}
+}
+
+// divRound divides rounding half away from zero.
+func divRound(n, d int64) int64 {
+	if n < 0 {
+		return -((-n + d/2) / d)
+	}
+	return (n + d/2) / d
+}
+
+// Format writes minor units of a currency with its symbol, thousands
+// separators and as many decimals as the currency has.
+func Format(amount int64, currency string) string {
+	info := lookup(currency)
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	scale := pow10(info.exponent)
+	whole := group(strconv.FormatInt(amount/scale, 10))
+	if info.exponent > 0 {
+		whole += fmt.Sprintf(".%0*d", info.exponent, amount%scale)
+	}
+	if info.symbol == "" {
+		return sign + strings.ToUpper(currency) + " " + whole
+	}
+	return sign + info.symbol + whole
+}
+
+// Decimal writes minor units as a plain decimal, e.g. "1234.50".
+func Decimal(amount int64, currency string) string {
+	exp := Exponent(currency)
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	scale := pow10(exp)
+	if exp == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/scale, exp, amount%scale)
+}
+
+// Parse reads a decimal amount such as "12.5" into minor units of the
+// currency. It rejects more decimals than the currency has.
+func Parse(s, currency string) (int64, error) {
+	exp := Exponent(currency)
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, errors.New("empty amount")
+	}
+	if len(frac) > exp {
+		return 0, fmt.Errorf("%s amounts have at most %d decimals", strings.ToUpper(currency), exp)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+	n, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if neg {
+		n = -n
+	}
+	return n, nil
+}
+
+// FromFloat converts a decimal amount to minor units, rounding to the
+// nearest. It is meant for migrating old float amounts only.
+func FromFloat(amount float64, currency string) int64 {
+	scaled := amount * float64(pow10(Exponent(currency)))
+	if scaled < 0 {
+		return -int64(-scaled + 0.5)
+	}
+	return int64(scaled + 0.5)
+}
+
+// Rescale converts minor units of one currency to the same face value in
+// another, e.g. 1250 USD cents to 13 JPY.
+func Rescale(amount int64, from, to string) int64 {
+	diff := Exponent(to) - Exponent(from)
+	switch {
+	case diff > 0:
+		return amount * pow10(diff)
+	case diff < 0:
+		return divRound(amount, pow10(-diff))
+	}
+	return amount
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+// group puts thousands separators into a string of digits.
+func group(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	lead := len(digits) % 3
+	if lead > 0 {
+		b.WriteString(digits[:lead])
+	}
+	for i := lead; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
@@ -0,0 +1,123 @@
+// Package netguard keeps requests to URLs that providers give us, such as
+// webhooks and calendars, away from our own network: loopback, private,
+// link-local and cloud metadata addresses.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// AllowPrivate turns the guard off, so local development can point
+// webhooks and calendars at services on the same machine.
+var AllowPrivate bool
+
+// ErrPrivateAddress is returned for hosts that resolve to an address the
+// guard blocks.
+var ErrPrivateAddress = errors.New("address is not public")
+
+// Blocked ranges not covered by net.IP's own checks
+var blocked = []*net.IPNet{
+	cidr("0.0.0.0/8"),     // "This" network
+	cidr("100.64.0.0/10"), // Carrier-grade NAT
+	cidr("192.0.0.0/24"),  // IETF protocol assignments
+	cidr("198.18.0.0/15"), // Benchmarking
+	cidr("64:ff9b::/96"),  // NAT64, which can embed any of the above
+}
+
+func cidr(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Public reports whether an address may be reached.
+func Public(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range blocked {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL validates a URL given to us before it is saved: it must be
+// http or https and its host must resolve to public addresses only. The
+// client from NewClient checks again on every connection, as DNS can
+// change in between.
+func CheckURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return errors.New("invalid URL")
+	}
+	if scheme := strings.ToLower(u.Scheme); scheme != "http" && scheme != "https" {
+		return errors.New("URL must use http or https")
+	}
+	if AllowPrivate {
+		return nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s", u.Hostname())
+	}
+	for _, ip := range ips {
+		if !Public(ip) {
+			return fmt.Errorf("%s: %w", u.Hostname(), ErrPrivateAddress)
+		}
+	}
+	return nil
+}
+
+// NewClient returns an HTTP client that refuses to connect to addresses
+// that are not public. The check runs on the address actually dialed,
+// after DNS resolution, so a host cannot pass validation and later
+// resolve somewhere else. Proxies from the environment are not used, as
+// they would connect on our behalf.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   control,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
+
+func control(network, address string, _ syscall.RawConn) error {
+	if AllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !Public(ip) {
+		return fmt.Errorf("%s: %w", host, ErrPrivateAddress)
+	}
+	return nil
+}
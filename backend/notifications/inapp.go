@@ -0,0 +1,33 @@
+package notifications
+
+import (
+	"context"
+	"strconv"
+
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// InAppChannel stores messages in the recipient's in-app inbox.
+type InAppChannel struct {
+	DB *gorm.DB
+}
+
+func (c *InAppChannel) Name() string { return ChannelInApp }
+
+func (c *InAppChannel) Reachable(to Recipient) bool { return to.UserID != 0 }
+
+func (c *InAppChannel) Send(ctx context.Context, msg Message) error {
+	n := models.Notification{
+		UserID: msg.To.UserID,
+		Type:   msg.Event,
+		Title:  msg.Subject,
+		Body:   msg.Short,
+	}
+	if id, err := strconv.ParseUint(msg.Data["booking_id"], 10, 32); err == nil {
+		bookingID := uint(id)
+		n.BookingID = &bookingID
+	}
+	return c.DB.WithContext(ctx).Create(&n).Error
+}
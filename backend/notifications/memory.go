@@ -0,0 +1,65 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// MemoryChannel records messages instead of sending them. It stands in for
+// a real channel in tests and local development.
+type MemoryChannel struct {
+	ChannelName string
+	Log         bool // Also write each message to the log
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+func NewMemoryChannel(name string) *MemoryChannel {
+	return &MemoryChannel{ChannelName: name}
+}
+
+func (c *MemoryChannel) Name() string { return c.ChannelName }
+
+// Reachable mirrors the address each real channel would need.
+func (c *MemoryChannel) Reachable(to Recipient) bool {
+	switch c.ChannelName {
+	case ChannelEmail:
+		return to.Email != ""
+	case ChannelSMS:
+		return to.Phone != ""
+	case ChannelPush:
+		return len(to.PushTokens) > 0
+	}
+	return true
+}
+
+func (c *MemoryChannel) Send(ctx context.Context, msg Message) error {
+	if !c.Reachable(msg.To) {
+		return ErrUnreachable
+	}
+
+	c.mu.Lock()
+	c.sent = append(c.sent, msg)
+	c.mu.Unlock()
+
+	if c.Log {
+		log.Printf("[%s] %s to user %d: %s", c.ChannelName, msg.Event, msg.To.UserID, msg.Subject)
+	}
+	return nil
+}
+
+// Sent returns the messages recorded so far.
+func (c *MemoryChannel) Sent() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message(nil), c.sent...)
+}
+
+// Reset forgets the recorded messages.
+func (c *MemoryChannel) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = nil
+}
@@ -0,0 +1,93 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"pluralink/backend/jobs"
+
+	"gorm.io/gorm"
+)
+
+// Event types
+const (
+	EventBookingRequested   = "booking.requested"
+	EventBookingConfirmed   = "booking.confirmed"
+	EventBookingCancelled   = "booking.cancelled"
+	EventBookingRescheduled = "booking.rescheduled"
+	EventBookingReminder    = "booking.reminder"
+	EventReviewReceived     = "review.received"
+	EventMessageReceived    = "message.received"
+	EventDigest             = "digest"
+)
+
+// Channel names
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelPush  = "push"
+	ChannelInApp = "in_app"
+)
+
+// ErrUnreachable is returned by a channel asked to send to a recipient it
+// has no address for.
+var ErrUnreachable = errors.New("recipient has no address for this channel")
+
+// Event is something that happened that people should hear about. Only IDs
+// are kept; names, times and addresses are loaded when it is dispatched.
+type Event struct {
+	Type        string `json:"type"`
+	BookingID   uint   `json:"booking_id"`
+	ReviewID    uint   `json:"review_id,omitempty"`
+	MessageID   uint   `json:"message_id,omitempty"`
+	ReminderID  uint   `json:"reminder_id,omitempty"`
+	ActorUserID uint   `json:"actor_user_id,omitempty"` // User who caused the event; not notified about it
+}
+
+// Recipient is a user with the addresses the channels may use.
+type Recipient struct {
+	UserID     uint     `json:"user_id"`
+	Name       string   `json:"name"`
+	Email      string   `json:"email"`
+	Phone      string   `json:"phone"`
+	PushTokens []string `json:"push_tokens,omitempty"`
+}
+
+// Message is a rendered notification for one recipient. Channels pick the
+// parts that suit them: email uses Subject and Body, SMS uses Short, push
+// uses Subject as the title and Short as the text.
+type Message struct {
+	Event   string            `json:"event"`
+	To      Recipient         `json:"to"`
+	Subject string            `json:"subject"`
+	Body    string            `json:"body"`
+	Short   string            `json:"short"`
+	Data    map[string]string `json:"data,omitempty"` // Passed to the app with push messages
+
+	// ReminderID is the reminder the message delivers, told how it went.
+	ReminderID uint `json:"reminder_id,omitempty"`
+
+	// Mandatory messages ignore opt-outs on email and the inbox and skip
+	// the digest.
+	Mandatory bool `json:"mandatory,omitempty"`
+
+	// Urgent messages skip the digest, as it could arrive too late to
+	// matter. Quiet hours never hold a message past its Deadline, the
+	// start of the booking it is about.
+	Urgent   bool       `json:"urgent,omitempty"`
+	Deadline *time.Time `json:"-"`
+}
+
+// Channel delivers messages over one medium.
+type Channel interface {
+	Name() string
+	Reachable(to Recipient) bool
+	Send(ctx context.Context, msg Message) error
+}
+
+// Publish queues an event for delivery. It only writes a job, so callers
+// in request handlers do not wait on any channel.
+func Publish(db *gorm.DB, event Event) error {
+	return jobs.Enqueue(db, TypeDispatch, event)
+}
@@ -0,0 +1,228 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// EventTypes lists the events users can set preferences for.
+var EventTypes = []string{
+	EventBookingRequested,
+	EventBookingConfirmed,
+	EventBookingCancelled,
+	EventBookingRescheduled,
+	EventBookingReminder,
+	EventReviewReceived,
+	EventMessageReceived,
+}
+
+// ChannelNames lists the channels users can turn on and off.
+var ChannelNames = []string{ChannelEmail, ChannelSMS, ChannelPush, ChannelInApp}
+
+// mandatoryChannels still deliver mandatory messages when the user turned
+// them off, so nobody misses a change made to their booking by the other side.
+var mandatoryChannels = map[string]bool{ChannelEmail: true, ChannelInApp: true}
+
+// quietChannels are held back during quiet hours.
+var quietChannels = map[string]bool{ChannelSMS: true, ChannelPush: true}
+
+// Settings is the editable form of a user's preferences.
+type Settings struct {
+	Channels        map[string][]string `json:"channels"`
+	TimeZone        string              `json:"time_zone"`
+	QuietHoursStart string              `json:"quiet_hours_start"`
+	QuietHoursEnd   string              `json:"quiet_hours_end"`
+	Digest          bool                `json:"digest"`
+	DigestTime      string              `json:"digest_time"`
+}
+
+// LoadPreference returns a user's preferences, or unsaved defaults.
+func LoadPreference(db *gorm.DB, userID uint) (models.NotificationPreference, error) {
+	var pref models.NotificationPreference
+	err := db.Where("user_id = ?", userID).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.NotificationPreference{UserID: userID, DigestTime: "08:00"}, nil
+	}
+	return pref, err
+}
+
+// SettingsOf returns the editable settings of a preference, listing every
+// event type with its enabled channels.
+func SettingsOf(pref *models.NotificationPreference) Settings {
+	enabled := decodeChannels(pref.Channels)
+
+	s := Settings{
+		Channels:        map[string][]string{},
+		TimeZone:        pref.TimeZone,
+		QuietHoursStart: pref.QuietHoursStart,
+		QuietHoursEnd:   pref.QuietHoursEnd,
+		Digest:          pref.DigestEnabled,
+		DigestTime:      pref.DigestTime,
+	}
+	for _, event := range EventTypes {
+		if channels, ok := enabled[event]; ok {
+			s.Channels[event] = channels
+		} else {
+			s.Channels[event] = append([]string{}, ChannelNames...)
+		}
+	}
+	return s
+}
+
+// ApplySettings validates settings and stores them on the preference.
+// Event types missing from Channels keep their current channels.
+func ApplySettings(pref *models.NotificationPreference, s Settings) error {
+	if s.TimeZone != "" {
+		if _, err := time.LoadLocation(s.TimeZone); err != nil {
+			return fmt.Errorf("unknown time zone %q", s.TimeZone)
+		}
+	}
+	if (s.QuietHoursStart == "") != (s.QuietHoursEnd == "") {
+		return errors.New("quiet hours need both a start and an end")
+	}
+	for _, clock := range []string{s.QuietHoursStart, s.QuietHoursEnd, s.DigestTime} {
+		if clock == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", clock); err != nil {
+			return fmt.Errorf("invalid time %q. Use HH:MM", clock)
+		}
+	}
+	if s.QuietHoursStart != "" && s.QuietHoursStart == s.QuietHoursEnd {
+		return errors.New("quiet hours cannot start and end at the same time")
+	}
+
+	known := map[string]bool{}
+	for _, name := range ChannelNames {
+		known[name] = true
+	}
+	enabled := decodeChannels(pref.Channels)
+	for event, channels := range s.Channels {
+		if !isEventType(event) {
+			return fmt.Errorf("unknown event type %q", event)
+		}
+		seen := map[string]bool{}
+		list := []string{}
+		for _, ch := range channels {
+			if !known[ch] {
+				return fmt.Errorf("unknown channel %q", ch)
+			}
+			if !seen[ch] {
+				seen[ch] = true
+				list = append(list, ch)
+			}
+		}
+		sort.Strings(list)
+		enabled[event] = list
+	}
+	data, err := json.Marshal(enabled)
+	if err != nil {
+		return err
+	}
+
+	pref.Channels = string(data)
+	pref.TimeZone = s.TimeZone
+	pref.QuietHoursStart = s.QuietHoursStart
+	pref.QuietHoursEnd = s.QuietHoursEnd
+	if s.Digest && !pref.DigestEnabled {
+		// The first digest covers what arrives from now on
+		now := time.Now()
+		pref.LastDigestAt = &now
+	}
+	pref.DigestEnabled = s.Digest
+	if s.DigestTime != "" {
+		pref.DigestTime = s.DigestTime
+	}
+	return nil
+}
+
+// channelEnabled reports whether the user wants an event on a channel.
+func channelEnabled(pref *models.NotificationPreference, event, channel string) bool {
+	channels, ok := decodeChannels(pref.Channels)[event]
+	if !ok {
+		return true
+	}
+	for _, ch := range channels {
+		if ch == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// quietUntil reports whether t falls in the user's quiet hours and, if so,
+// when they end.
+func quietUntil(pref *models.NotificationPreference, t time.Time) (time.Time, bool) {
+	if pref.QuietHoursStart == "" || pref.QuietHoursEnd == "" {
+		return time.Time{}, false
+	}
+	start, err1 := time.Parse("15:04", pref.QuietHoursStart)
+	end, err2 := time.Parse("15:04", pref.QuietHoursEnd)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, false
+	}
+
+	local := t.In(pref.Location())
+	now := local.Hour()*60 + local.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+
+	var quiet bool
+	if from < to {
+		quiet = now >= from && now < to
+	} else {
+		// Overnight, e.g. 22:00 to 07:00
+		quiet = now >= from || now < to
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, local.Location())
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
+}
+
+// digestDue reports whether a user's digest should go out at t.
+func digestDue(pref *models.NotificationPreference, t time.Time) bool {
+	if !pref.DigestEnabled {
+		return true
+	}
+	clock, err := time.Parse("15:04", pref.DigestTime)
+	if err != nil {
+		clock, _ = time.Parse("15:04", "08:00")
+	}
+
+	local := t.In(pref.Location())
+	due := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, local.Location())
+	if local.Before(due) {
+		due = due.AddDate(0, 0, -1)
+	}
+	return pref.LastDigestAt == nil || pref.LastDigestAt.Before(due)
+}
+
+func decodeChannels(data string) map[string][]string {
+	enabled := map[string][]string{}
+	if data != "" {
+		json.Unmarshal([]byte(data), &enabled)
+	}
+	return enabled
+}
+
+func isEventType(event string) bool {
+	for _, e := range EventTypes {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
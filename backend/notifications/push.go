@@ -0,0 +1,136 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const ExpoPushURL = "https://exp.host/--/api/v2/push/send"
+
+// ExpoPushChannel sends mobile push notifications through Expo's push
+// service, which the app registers its tokens with.
+type ExpoPushChannel struct {
+	HTTP        *http.Client
+	URL         string
+	AccessToken string // Only needed when enhanced push security is on
+
+	// OnInvalidToken is called for tokens Expo reports as unregistered so
+	// they can be removed.
+	OnInvalidToken func(token string)
+}
+
+func NewExpoPushChannel(url, accessToken string) *ExpoPushChannel {
+	if url == "" {
+		url = ExpoPushURL
+	}
+	return &ExpoPushChannel{
+		HTTP:        &http.Client{Timeout: 15 * time.Second},
+		URL:         url,
+		AccessToken: accessToken,
+	}
+}
+
+func (c *ExpoPushChannel) Name() string { return ChannelPush }
+
+func (c *ExpoPushChannel) Reachable(to Recipient) bool { return len(to.PushTokens) > 0 }
+
+type expoMessage struct {
+	To    string            `json:"to"`
+	Title string            `json:"title,omitempty"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+	Sound string            `json:"sound,omitempty"`
+}
+
+type expoResponse struct {
+	Data []struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Details struct {
+			Error string `json:"error"`
+		} `json:"details"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Send pushes the message to all of the recipient's devices. It only fails
+// when no device accepted it, so a retry does not repeat delivered pushes.
+func (c *ExpoPushChannel) Send(ctx context.Context, msg Message) error {
+	if !c.Reachable(msg.To) {
+		return ErrUnreachable
+	}
+
+	batch := make([]expoMessage, len(msg.To.PushTokens))
+	for i, token := range msg.To.PushTokens {
+		batch[i] = expoMessage{
+			To:    token,
+			Title: msg.Subject,
+			Body:  msg.Short,
+			Data:  msg.Data,
+			Sound: "default",
+		}
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("push service returned %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+
+	var result expoResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("invalid push service response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		return errors.New("push service error: " + result.Errors[0].Message)
+	}
+
+	var failures []string
+	delivered := false
+	for i, ticket := range result.Data {
+		if ticket.Status == "ok" {
+			delivered = true
+			continue
+		}
+		if ticket.Details.Error == "DeviceNotRegistered" && i < len(batch) && c.OnInvalidToken != nil {
+			c.OnInvalidToken(batch[i].To)
+			continue
+		}
+		failures = append(failures, ticket.Message)
+	}
+	if !delivered && len(failures) > 0 {
+		return errors.New("push failed: " + strings.Join(failures, "; "))
+	}
+	return nil
+}
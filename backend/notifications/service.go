@@ -0,0 +1,444 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pluralink/backend/jobs"
+	"pluralink/backend/models"
+	"pluralink/backend/reminders"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TypeDispatch    = "notifications.dispatch"
+	TypeSend        = "notifications.send"
+	TypeSendDigests = "notifications.send_digests"
+)
+
+type sendPayload struct {
+	Channel string  `json:"channel"`
+	Message Message `json:"message"`
+}
+
+// Service turns events into messages and hands them to its channels.
+// Dispatching only queues one send job per recipient and channel, so a
+// failing channel is retried on its own without repeating the others.
+type Service struct {
+	DB        *gorm.DB
+	Templates *Templates
+
+	mu       sync.RWMutex
+	channels []Channel
+}
+
+func NewService(db *gorm.DB, templates *Templates) *Service {
+	return &Service{DB: db, Templates: templates}
+}
+
+// AddChannel enables a channel, replacing any with the same name.
+func (s *Service) AddChannel(ch Channel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, existing := range s.channels {
+		if existing.Name() == ch.Name() {
+			s.channels[i] = ch
+			return
+		}
+	}
+	s.channels = append(s.channels, ch)
+}
+
+// Channels returns the enabled channels.
+func (s *Service) Channels() []Channel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Channel(nil), s.channels...)
+}
+
+func (s *Service) channel(name string) Channel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, ch := range s.channels {
+		if ch.Name() == name {
+			return ch
+		}
+	}
+	return nil
+}
+
+// Register wires event dispatch and delivery into the job runner.
+func (s *Service) Register(r *jobs.Runner) {
+	r.Register(TypeDispatch, func(ctx context.Context, job *models.Job) error {
+		var event Event
+		if err := jobs.Decode(job, &event); err != nil {
+			return err
+		}
+		return s.Dispatch(ctx, event)
+	})
+
+	r.Register(TypeSend, func(ctx context.Context, job *models.Job) error {
+		var p sendPayload
+		if err := jobs.Decode(job, &p); err != nil {
+			return err
+		}
+
+		ch := s.channel(p.Channel)
+		if ch == nil {
+			log.Printf("Dropping %s notification to user %d: channel %s is not enabled", p.Message.Event, p.Message.To.UserID, p.Channel)
+			return nil
+		}
+
+		err := ch.Send(ctx, p.Message)
+		if errors.Is(err, ErrUnreachable) {
+			return nil
+		}
+		if id := p.Message.ReminderID; id != 0 {
+			var markErr error
+			if err == nil {
+				markErr = reminders.Delivered(s.DB, id, time.Now())
+			} else if job.Attempts >= job.MaxAttempts {
+				markErr = reminders.Undelivered(s.DB, id, p.Channel+": "+err.Error())
+			}
+			if markErr != nil {
+				log.Printf("Failed to record delivery of reminder %d: %v", id, markErr)
+			}
+		}
+		return err
+	})
+
+	r.Register(TypeSendDigests, func(ctx context.Context, job *models.Job) error {
+		return s.SendDigests(time.Now())
+	})
+	r.Every(TypeSendDigests, 15*time.Minute)
+}
+
+// NotifyReminder lets the service deliver booking reminders. Each send
+// reports back to the reminder.
+func (s *Service) NotifyReminder(ctx context.Context, booking *models.Booking, reminder *models.Reminder) error {
+	queued, err := s.dispatch(ctx, Event{Type: EventBookingReminder, BookingID: booking.ID, ReminderID: reminder.ID})
+	if err == nil && queued == 0 {
+		return reminders.ErrNoChannel
+	}
+	return err
+}
+
+// Dispatch renders an event for everyone who should hear about it and
+// queues a send on each channel that can reach them, following each
+// recipient's preferences.
+func (s *Service) Dispatch(ctx context.Context, event Event) error {
+	_, err := s.dispatch(ctx, event)
+	return err
+}
+
+// dispatch is Dispatch, returning how many sends it queued.
+func (s *Service) dispatch(ctx context.Context, event Event) (int, error) {
+	messages, err := s.Messages(event)
+	if err != nil {
+		return 0, err
+	}
+	channels := s.Channels()
+	now := time.Now()
+
+	queued := 0
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		for _, msg := range messages {
+			pref, err := LoadPreference(tx, msg.To.UserID)
+			if err != nil {
+				return err
+			}
+
+			digested := false
+			for _, ch := range channels {
+				name := ch.Name()
+				if !ch.Reachable(msg.To) {
+					continue
+				}
+				if !channelEnabled(&pref, msg.Event, name) && !(msg.Mandatory && mandatoryChannels[name]) {
+					continue
+				}
+
+				// The inbox is never intrusive; everything else waits for
+				// the digest or the end of quiet hours, unless that would
+				// be too late.
+				runAt := now
+				if name != ChannelInApp {
+					if pref.DigestEnabled && !msg.Mandatory && !msg.Urgent {
+						if !digested {
+							item := models.DigestItem{UserID: msg.To.UserID, Event: msg.Event, Subject: msg.Subject, Short: msg.Short}
+							if err := tx.Create(&item).Error; err != nil {
+								return err
+							}
+							digested = true
+						}
+						continue
+					}
+					if until, quiet := quietUntil(&pref, now); quiet && quietChannels[name] &&
+						(msg.Deadline == nil || until.Before(*msg.Deadline)) {
+						runAt = until
+					}
+				}
+
+				if err := jobs.EnqueueAt(tx, TypeSend, sendPayload{Channel: name, Message: msg}, runAt); err != nil {
+					return err
+				}
+				queued++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return queued, nil
+}
+
+// SendDigests sends the held back notifications of every user whose digest
+// is due, as one email each. Users who turned the digest off get what was
+// left over right away.
+func (s *Service) SendDigests(now time.Time) error {
+	var userIDs []uint
+	if err := s.DB.Model(&models.DigestItem{}).Distinct("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := s.sendDigest(userID, now); err != nil {
+			log.Printf("Failed to send digest to user %d: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) sendDigest(userID uint, now time.Time) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		pref, err := LoadPreference(tx, userID)
+		if err != nil {
+			return err
+		}
+		if !digestDue(&pref, now) {
+			return nil
+		}
+
+		var items []models.DigestItem
+		if err := tx.Where("user_id = ? AND created_at <= ?", userID, now).Order("created_at ASC").Find(&items).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		recipient, err := s.recipient(&user)
+		if err != nil {
+			return err
+		}
+
+		data := TemplateData{Recipient: recipient}
+		ids := make([]uint, len(items))
+		for i, item := range items {
+			ids[i] = item.ID
+			data.Items = append(data.Items, Message{Event: item.Event, Subject: item.Subject, Short: item.Short})
+		}
+		msg, err := s.Templates.Render(EventDigest, data)
+		if err != nil {
+			return err
+		}
+
+		if ch := s.channel(ChannelEmail); ch != nil && ch.Reachable(recipient) {
+			if err := jobs.Enqueue(tx, TypeSend, sendPayload{Channel: ChannelEmail, Message: msg}); err != nil {
+				return err
+			}
+		} else {
+			log.Printf("Dropping digest for user %d: email is not available", userID)
+		}
+
+		if err := tx.Delete(&models.DigestItem{}, ids).Error; err != nil {
+			return err
+		}
+		if pref.ID != 0 {
+			return tx.Model(&pref).Update("last_digest_at", now).Error
+		}
+		return nil
+	})
+}
+
+// Messages renders the messages an event produces, one per recipient.
+func (s *Service) Messages(event Event) ([]Message, error) {
+	var booking models.Booking
+	if err := s.DB.Preload("Client").Preload("Client.User").
+		Preload("Provider").Preload("Provider.User").
+		Preload("Service").
+		First(&booking, event.BookingID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var review *models.Review
+	if event.ReviewID != 0 {
+		review = &models.Review{}
+		if err := s.DB.First(review, event.ReviewID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+
+	var message *models.BookingMessage
+	if event.MessageID != 0 {
+		message = &models.BookingMessage{}
+		if err := s.DB.Preload("Attachments").First(message, event.MessageID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+
+	loc := booking.Provider.Location()
+	providerUser := &booking.Provider.User
+	clientUser := &booking.Client.User
+
+	data := TemplateData{
+		ProviderName: booking.Provider.BusinessName,
+		ClientName:   fullName(clientUser),
+		ServiceName:  booking.Service.Name,
+		Start:        booking.StartsAt(loc),
+		End:          booking.EndsAt(loc),
+		Notes:        booking.Notes,
+	}
+	switch event.ActorUserID {
+	case 0:
+	case providerUser.ID:
+		data.ActorName = data.ProviderName
+	case clientUser.ID:
+		data.ActorName = data.ClientName
+	}
+	if review != nil {
+		data.Rating = review.Rating
+		data.Comment = review.Comment
+	}
+	if message != nil {
+		data.Message = preview(message.Body, 280)
+		data.Attachments = len(message.Attachments)
+	}
+
+	// Anything about a booking starting within a day cannot wait for the
+	// digest
+	var deadline *time.Time
+	if start := booking.StartsAt(loc); start.After(time.Now()) {
+		deadline = &start
+	}
+	urgent := event.Type == EventBookingReminder ||
+		(deadline != nil && deadline.Before(time.Now().Add(24*time.Hour)))
+
+	var users []*models.User
+	switch event.Type {
+	case EventBookingRequested:
+		users = []*models.User{providerUser}
+	case EventBookingConfirmed:
+		users = []*models.User{clientUser}
+	case EventBookingCancelled, EventBookingRescheduled, EventBookingReminder:
+		users = []*models.User{providerUser, clientUser}
+	case EventMessageReceived:
+		if message == nil {
+			return nil, fmt.Errorf("%s event without a message", event.Type)
+		}
+		users = []*models.User{providerUser, clientUser}
+	case EventReviewReceived:
+		if review == nil {
+			return nil, fmt.Errorf("%s event without a review", event.Type)
+		}
+		if review.RevieweeType == models.RevieweeTypeProvider {
+			users = []*models.User{providerUser}
+		} else {
+			users = []*models.User{clientUser}
+		}
+	default:
+		return nil, fmt.Errorf("unknown event type %s", event.Type)
+	}
+
+	var messages []Message
+	for _, u := range users {
+		if u.ID == 0 || u.ID == event.ActorUserID || !u.IsActive {
+			continue
+		}
+
+		recipient, err := s.recipient(u)
+		if err != nil {
+			return nil, err
+		}
+		if u.ID == providerUser.ID && recipient.Name == "" {
+			recipient.Name = data.ProviderName
+		}
+		// Clients must hear about a provider changing their booking
+		mandatory := u.ID == clientUser.ID && event.ActorUserID == providerUser.ID &&
+			(event.Type == EventBookingCancelled || event.Type == EventBookingRescheduled)
+
+		data.Recipient = recipient
+		msg, err := s.Templates.Render(event.Type, data)
+		if err != nil {
+			return nil, err
+		}
+		msg.Data = map[string]string{
+			"event":      event.Type,
+			"booking_id": strconv.FormatUint(uint64(booking.ID), 10),
+		}
+		if message != nil {
+			msg.Data["message_id"] = strconv.FormatUint(uint64(message.ID), 10)
+		}
+		msg.ReminderID = event.ReminderID
+		msg.Mandatory = mandatory
+		msg.Urgent, msg.Deadline = urgent, deadline
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+func (s *Service) recipient(u *models.User) (Recipient, error) {
+	r := Recipient{
+		UserID: u.ID,
+		Name:   u.FirstName,
+		Email:  u.Email,
+		Phone:  u.Phone,
+	}
+	if err := s.DB.Model(&models.DeviceToken{}).Where("user_id = ?", u.ID).Pluck("token", &r.PushTokens).Error; err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// ForgetDeviceToken removes a push token the push service reported as no
+// longer valid.
+func (s *Service) ForgetDeviceToken(token string) {
+	if err := s.DB.Where("token = ?", token).Delete(&models.DeviceToken{}).Error; err != nil {
+		log.Printf("Failed to remove device token: %v", err)
+	}
+}
+
+// preview shortens text to at most n runes for notifications.
+func preview(text string, n int) string {
+	text = strings.TrimSpace(text)
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
+
+func fullName(u *models.User) string {
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
@@ -0,0 +1,147 @@
+package notifications
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// SinkMessage is an email received by an SMTPSink.
+type SinkMessage struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// Parse reads the headers and body of the message.
+func (m SinkMessage) Parse() (*mail.Message, error) {
+	return mail.ReadMessage(bytes.NewReader(m.Data))
+}
+
+// SMTPSink is a minimal SMTP server that keeps every message it receives
+// in memory instead of delivering it. Point an SMTPChannel at it in tests
+// or local development. It supports no authentication or TLS.
+type SMTPSink struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	messages []SinkMessage
+}
+
+// NewSMTPSink listens on addr, e.g. "127.0.0.1:0" for a random port.
+func NewSMTPSink(addr string) (*SMTPSink, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &SMTPSink{ln: ln}
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address the sink listens on.
+func (s *SMTPSink) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Messages returns the messages received so far.
+func (s *SMTPSink) Messages() []SinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SinkMessage(nil), s.messages...)
+}
+
+// Reset forgets the messages received so far.
+func (s *SMTPSink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
+
+func (s *SMTPSink) Close() error {
+	return s.ln.Close()
+}
+
+func (s *SMTPSink) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *SMTPSink) handle(conn net.Conn) {
+	defer conn.Close()
+	tc := textproto.NewConn(conn)
+	reply := func(line string) bool {
+		return tc.PrintfLine("%s", line) == nil
+	}
+
+	if !reply("220 pluralink SMTP sink") {
+		return
+	}
+
+	var current SinkMessage
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250 pluralink")
+		case "MAIL":
+			current = SinkMessage{From: pathArg(arg)}
+			reply("250 OK")
+		case "RCPT":
+			current.To = append(current.To, pathArg(arg))
+			reply("250 OK")
+		case "DATA":
+			if len(current.To) == 0 {
+				reply("503 RCPT first")
+				continue
+			}
+			if !reply("354 End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := io.ReadAll(tc.DotReader())
+			if err != nil {
+				return
+			}
+			current.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, current)
+			s.mu.Unlock()
+			current = SinkMessage{}
+			reply("250 OK")
+		case "RSET":
+			current = SinkMessage{}
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// pathArg extracts the address from "FROM:<a@b>" or "TO:<a@b> SIZE=1".
+func pathArg(arg string) string {
+	_, path, _ := strings.Cut(arg, ":")
+	path = strings.TrimSpace(path)
+	if i := strings.IndexByte(path, '>'); i >= 0 {
+		path = path[:i]
+	}
+	return strings.TrimPrefix(path, "<")
+}
@@ -0,0 +1,70 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SMSGatewayChannel sends text messages through an HTTP SMS gateway. Each
+// message is POSTed as JSON {"from", "to", "text"} with the API key as a
+// bearer token, the shape most gateways accept or can be proxied to.
+type SMSGatewayChannel struct {
+	HTTP   *http.Client
+	URL    string
+	APIKey string
+	From   string
+}
+
+func NewSMSGatewayChannel(url, apiKey, from string) *SMSGatewayChannel {
+	return &SMSGatewayChannel{
+		HTTP:   &http.Client{Timeout: 15 * time.Second},
+		URL:    url,
+		APIKey: apiKey,
+		From:   from,
+	}
+}
+
+func (c *SMSGatewayChannel) Name() string { return ChannelSMS }
+
+func (c *SMSGatewayChannel) Reachable(to Recipient) bool { return to.Phone != "" }
+
+func (c *SMSGatewayChannel) Send(ctx context.Context, msg Message) error {
+	if !c.Reachable(msg.To) {
+		return ErrUnreachable
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"from": c.From,
+		"to":   msg.To.Phone,
+		"text": msg.Short,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sms gateway returned %s: %s", resp.Status, bytes.TrimSpace(snippet))
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Template holds the text of one event type. Each part is a text/template
+// executed with TemplateData.
+type Template struct {
+	Subject string
+	Body    string
+	Short   string
+}
+
+// TemplateData is what templates can refer to.
+type TemplateData struct {
+	Recipient    Recipient
+	ActorName    string // Who caused the event, empty for system events
+	ProviderName string
+	ClientName   string
+	ServiceName  string
+	Start        time.Time // In the provider's time zone
+	End          time.Time
+	Notes        string
+	Rating       int
+	Comment      string
+	Message      string    // Text of a booking message
+	Attachments  int       // Files sent with it
+	Items        []Message // Digest entries
+}
+
+// When formats the booking start for people.
+func (d TemplateData) When() string {
+	return d.Start.Format("Mon, Jan 2 2006 at 15:04 MST")
+}
+
+var defaultTemplates = map[string]Template{
+	EventBookingRequested: {
+		Subject: "New booking request from {{.ClientName}}",
+		Body: `Hi {{.Recipient.Name}},
+
+{{.ClientName}} requested {{.ServiceName}} on {{.When}}.
+{{- if .Notes}}
+
+Notes: {{.Notes}}
+{{- end}}
+
+Open Pluralink to confirm the booking.`,
+		Short: "{{.ClientName}} requested {{.ServiceName}} on {{.When}}.",
+	},
+	EventBookingConfirmed: {
+		Subject: "Your booking with {{.ProviderName}} is confirmed",
+		Body: `Hi {{.Recipient.Name}},
+
+{{.ProviderName}} confirmed your {{.ServiceName}} on {{.When}}.`,
+		Short: "{{.ProviderName}} confirmed your {{.ServiceName}} on {{.When}}.",
+	},
+	EventBookingCancelled: {
+		Subject: "Booking on {{.When}} cancelled",
+		Body: `Hi {{.Recipient.Name}},
+
+{{if .ActorName}}{{.ActorName}} cancelled{{else}}We cancelled{{end}} the {{.ServiceName}} booking on {{.When}}.`,
+		Short: "{{if .ActorName}}{{.ActorName}} cancelled{{else}}Cancelled:{{end}} {{.ServiceName}} on {{.When}}.",
+	},
+	EventBookingRescheduled: {
+		Subject: "Booking moved to {{.When}}",
+		Body: `Hi {{.Recipient.Name}},
+
+{{if .ActorName}}{{.ActorName}} moved{{else}}We moved{{end}} the {{.ServiceName}} booking to {{.When}}.`,
+		Short: "{{.ServiceName}} moved to {{.When}}.",
+	},
+	EventBookingReminder: {
+		Subject: "Reminder: {{.ServiceName}} on {{.When}}",
+		Body: `Hi {{.Recipient.Name}},
+
+This is a reminder of the {{.ServiceName}} booking between {{.ProviderName}} and {{.ClientName}} on {{.When}}.`,
+		Short: "Reminder: {{.ServiceName}} on {{.When}}.",
+	},
+	EventReviewReceived: {
+		Subject: "You received a {{.Rating}}-star review",
+		Body: `Hi {{.Recipient.Name}},
+
+{{.ActorName}} left a {{.Rating}}-star review for {{.ServiceName}} on {{.When}}.
+{{- if .Comment}}
+
+"{{.Comment}}"
+{{- end}}`,
+		Short: "{{.ActorName}} left you a {{.Rating}}-star review.",
+	},
+	EventMessageReceived: {
+		Subject: "New message from {{.ActorName}}",
+		Body: `Hi {{.Recipient.Name}},
+
+{{.ActorName}} sent you a message about {{.ServiceName}} on {{.When}}:
+{{- if .Message}}
+
+{{.Message}}
+{{- end}}
+{{- if .Attachments}}
+
+({{.Attachments}} attachment{{if gt .Attachments 1}}s{{end}})
+{{- end}}
+
+Open Pluralink to reply.`,
+		Short: "{{.ActorName}}: {{if .Message}}{{.Message}}{{else}}sent {{.Attachments}} attachment{{if gt .Attachments 1}}s{{end}}{{end}}",
+	},
+	EventDigest: {
+		Subject: "Your Pluralink summary: {{len .Items}} update{{if gt (len .Items) 1}}s{{end}}",
+		Body: `Hi {{.Recipient.Name}},
+
+Here is what happened since your last summary:
+{{range .Items}}
+- {{.Subject}}: {{.Short}}
+{{- end}}`,
+		Short: "{{len .Items}} update{{if gt (len .Items) 1}}s{{end}} on Pluralink.",
+	},
+}
+
+// Templates renders messages for each event type.
+type Templates struct {
+	parsed map[string][3]*template.Template
+}
+
+// NewTemplates parses the built-in templates with overrides applied on top.
+func NewTemplates(overrides map[string]Template) (*Templates, error) {
+	all := map[string]Template{}
+	for k, v := range defaultTemplates {
+		all[k] = v
+	}
+	for k, v := range overrides {
+		all[k] = v
+	}
+
+	t := &Templates{parsed: map[string][3]*template.Template{}}
+	for event, tmpl := range all {
+		var parts [3]*template.Template
+		for i, text := range []string{tmpl.Subject, tmpl.Body, tmpl.Short} {
+			p, err := template.New(event).Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("template %s: %w", event, err)
+			}
+			parts[i] = p
+		}
+		t.parsed[event] = parts
+	}
+	return t, nil
+}
+
+// Render fills a message for the event type from data.
+func (t *Templates) Render(event string, data TemplateData) (Message, error) {
+	parts, ok := t.parsed[event]
+	if !ok {
+		return Message{}, fmt.Errorf("no template for event %s", event)
+	}
+
+	var out [3]string
+	for i, p := range parts {
+		var buf bytes.Buffer
+		if err := p.Execute(&buf, data); err != nil {
+			return Message{}, fmt.Errorf("template %s: %w", event, err)
+		}
+		out[i] = strings.TrimSpace(buf.String())
+	}
+
+	return Message{
+		Event:   event,
+		To:      data.Recipient,
+		Subject: out[0],
+		Body:    out[1],
+		Short:   out[2],
+	}, nil
+}
@@ -0,0 +1,333 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pluralink/backend/ledger"
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Event types, normalised across gateways
+const (
+	EventPaymentSucceeded  = "payment.succeeded"
+	EventPaymentFailed     = "payment.failed"
+	EventPaymentProcessing = "payment.processing"
+	EventPaymentCanceled   = "payment.canceled"
+	EventRefundUpdated     = "refund.updated"
+	EventDisputeOpened     = "dispute.opened"
+	EventDisputeClosed     = "dispute.closed"
+)
+
+// SignatureTolerance is how old a signed webhook may be before it is
+// rejected as a possible replay by a third party.
+const SignatureTolerance = 5 * time.Minute
+
+// ErrInvalidSignature is returned for webhooks that were not signed with
+// the configured secret, or were signed too long ago.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ErrInvalidEvent is returned for webhooks that do not parse as an event.
+var ErrInvalidEvent = errors.New("invalid event")
+
+type DisputeStatus string
+
+const (
+	DisputeOpen DisputeStatus = "open"
+	DisputeWon  DisputeStatus = "won"
+	DisputeLost DisputeStatus = "lost"
+)
+
+// Dispute is a client contesting a payment with their bank.
+type Dispute struct {
+	ID       string        `json:"id"`
+	IntentID string        `json:"intent_id"`
+	Amount   int64         `json:"amount"`
+	Status   DisputeStatus `json:"status"`
+	Reason   string        `json:"reason"`
+}
+
+// Event is a webhook from a gateway about one intent, refund or dispute.
+// Type is empty for events the app does not act on.
+type Event struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	GatewayType string    `json:"-"` // As the gateway names it
+	Created     time.Time `json:"created"`
+	Intent      *Intent   `json:"intent,omitempty"`
+	Refund      *Refund   `json:"refund,omitempty"`
+	Dispute     *Dispute  `json:"dispute,omitempty"`
+}
+
+// EventSigner is implemented by gateways that can produce signed webhooks
+// like their own, for trying the receiver out locally.
+type EventSigner interface {
+	SignEvent(event *Event, at time.Time) ([]byte, http.Header, error)
+}
+
+// signPayload returns a "t=<unix seconds>,v1=<hex HMAC-SHA256 of
+// "<t>.<payload>">" signature.
+func signPayload(secret string, at time.Time, payload []byte) string {
+	ts := at.Unix()
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", ts)
+	mac.Write(payload)
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// verifyPayload checks a signature made by signPayload. Any of several v1
+// values may match, so secrets can be rolled.
+func verifyPayload(secret, header string, payload []byte, now time.Time) error {
+	if secret == "" {
+		return errors.New("no webhook secret is configured")
+	}
+
+	var ts int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if ts == 0 || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	at := time.Unix(ts, 0)
+	if now.Sub(at) > SignatureTolerance || at.Sub(now) > SignatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	_, expected, _ := strings.Cut(signPayload(secret, at, payload), "v1=")
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// RecordEvent stores a verified webhook for processing. A gateway may send
+// an event more than once; the copy stored first is returned for repeats.
+func (s *Service) RecordEvent(payload []byte) (*models.PaymentEvent, error) {
+	event, err := s.Gateway.ParseEvent(payload)
+	if err != nil {
+		return nil, err
+	}
+	stored := models.PaymentEvent{
+		Gateway: s.Gateway.Name(),
+		EventID: event.ID,
+		Type:    event.GatewayType,
+		Payload: string(payload),
+		Status:  models.PaymentEventReceived,
+	}
+	if err := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&stored).Error; err != nil {
+		return nil, err
+	}
+	if stored.ID == 0 {
+		if err := s.DB.Where("gateway = ? AND event_id = ?", stored.Gateway, stored.EventID).
+			First(&stored).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &stored, nil
+}
+
+// Outcome is what processing an event did to a booking or to something a
+// client bought apart from bookings.
+type Outcome struct {
+	BookingID uint
+	Paid      bool // The booking just became paid
+	Changed   bool // Its payment or refund status changed
+	Tipped    bool // A tip on it went through
+
+	// One of these is set instead of BookingID for payments of purchases
+	GiftCardID          uint
+	ClientPackageID     uint
+	MembershipPaymentID uint
+}
+
+// HandleEvent applies a stored event to the payment, refund or dispute it
+// is about and marks it processed, in one transaction. Events already
+// processed are skipped unless replay is set; applying one twice is
+// harmless, as final states are never walked back.
+func (s *Service) HandleEvent(eventID uint, replay bool) (Outcome, error) {
+	var outcome Outcome
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var stored models.PaymentEvent
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stored, eventID).Error; err != nil {
+			return err
+		}
+		done := stored.Status == models.PaymentEventProcessed || stored.Status == models.PaymentEventIgnored
+		if done && !replay {
+			return nil
+		}
+
+		event, err := s.Gateway.ParseEvent([]byte(stored.Payload))
+		if err != nil {
+			return err
+		}
+		status := models.PaymentEventProcessed
+		if outcome, err = applyEvent(tx, event); errors.Is(err, gorm.ErrRecordNotFound) {
+			status, err = models.PaymentEventIgnored, nil
+		}
+		if err != nil {
+			return err
+		}
+		if event.Type == "" {
+			status = models.PaymentEventIgnored
+		}
+
+		now := time.Now()
+		return tx.Model(&stored).Updates(map[string]interface{}{
+			"status":       status,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"error":        "",
+			"processed_at": &now,
+		}).Error
+	})
+	if err != nil {
+		// Recorded outside the rolled back transaction
+		s.DB.Model(&models.PaymentEvent{}).Where("id = ?", eventID).Updates(map[string]interface{}{
+			"status":   models.PaymentEventFailed,
+			"attempts": gorm.Expr("attempts + 1"),
+			"error":    err.Error(),
+		})
+	}
+	return outcome, err
+}
+
+// applyEvent drives the state an event is about. It returns
+// gorm.ErrRecordNotFound for intents and refunds the app did not create.
+func applyEvent(tx *gorm.DB, event *Event) (Outcome, error) {
+	var outcome Outcome
+
+	switch {
+	case event.Intent != nil:
+		var payment models.Payment
+		err := tx.Where("intent_id = ?", event.Intent.ID).First(&payment).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return applyPurchaseIntent(tx, event.Intent)
+		}
+		if err != nil {
+			return outcome, err
+		}
+		outcome.BookingID = payment.BookingID
+
+		paid, err := applyIntent(tx, payment.ID, event.Intent)
+		if err != nil {
+			return outcome, err
+		}
+		outcome.Paid, outcome.Changed = paid, paid
+		outcome.Tipped = payment.Kind == models.PaymentTip && payment.Status != models.PaymentSucceeded &&
+			event.Intent.Status == IntentSucceeded
+		// Declines leave the status alone but are still news to the client
+		if event.Type == EventPaymentFailed {
+			outcome.Changed = true
+		}
+
+	case event.Refund != nil:
+		bookingID, changed, err := applyRefund(tx, event.Refund)
+		if err != nil {
+			return outcome, err
+		}
+		outcome.BookingID, outcome.Changed = bookingID, changed
+
+	case event.Dispute != nil:
+		bookingID, changed, err := applyDispute(tx, event.Dispute)
+		if err != nil {
+			return outcome, err
+		}
+		outcome.BookingID, outcome.Changed = bookingID, changed
+	}
+	return outcome, nil
+}
+
+// applyPurchaseIntent drives the payment of a gift card, package or
+// membership, which are paid for apart from bookings.
+func applyPurchaseIntent(tx *gorm.DB, intent *Intent) (Outcome, error) {
+	var outcome Outcome
+
+	var card models.GiftCard
+	err := tx.Where("intent_id = ?", intent.ID).First(&card).Error
+	if err == nil {
+		outcome.GiftCardID = card.ID
+		return outcome, applyGiftCardIntent(tx, card.ID, intent)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return outcome, err
+	}
+
+	var cp models.ClientPackage
+	err = tx.Where("intent_id = ?", intent.ID).First(&cp).Error
+	if err == nil {
+		outcome.ClientPackageID = cp.ID
+		return outcome, applyPackageIntent(tx, cp.ID, intent)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return outcome, err
+	}
+
+	var payment models.MembershipPayment
+	if err := tx.Where("intent_id = ?", intent.ID).First(&payment).Error; err != nil {
+		return outcome, err
+	}
+	outcome.MembershipPaymentID = payment.ID
+	return outcome, applyMembershipIntent(tx, payment.ID, intent, "")
+}
+
+// applyDispute records a dispute on its payment and marks the booking
+// disputed while it is open and charged back if it was lost, taking the
+// amount back from the provider in the ledger.
+func applyDispute(tx *gorm.DB, dispute *Dispute) (uint, bool, error) {
+	var payment models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("intent_id = ?", dispute.IntentID).First(&payment).Error; err != nil {
+		return 0, false, err
+	}
+	// A closed dispute stays closed
+	if payment.DisputeStatus == models.DisputeWon || payment.DisputeStatus == models.DisputeLost {
+		return payment.BookingID, false, nil
+	}
+	if err := tx.Model(&payment).Updates(map[string]interface{}{
+		"dispute_status": models.DisputeStatus(dispute.Status),
+		"dispute_reason": dispute.Reason,
+	}).Error; err != nil {
+		return payment.BookingID, false, err
+	}
+
+	from := []models.BookingPaymentStatus{models.PaymentPaid}
+	to := models.PaymentDisputed
+	switch dispute.Status {
+	case DisputeWon:
+		from, to = []models.BookingPaymentStatus{models.PaymentDisputed}, models.PaymentPaid
+	case DisputeLost:
+		from, to = []models.BookingPaymentStatus{models.PaymentPaid, models.PaymentDisputed}, models.PaymentChargedBack
+	}
+	if dispute.Status == DisputeLost {
+		amount := dispute.Amount
+		if amount <= 0 {
+			amount = payment.Amount
+		}
+		if err := ledger.RecordChargeback(tx, &payment, amount, time.Now()); err != nil {
+			return payment.BookingID, false, err
+		}
+	}
+	result := tx.Model(&models.Booking{}).
+		Where("id = ? AND payment_status IN ?", payment.BookingID, from).
+		Update("payment_status", to)
+	return payment.BookingID, result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,274 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"pluralink/backend/utils"
+)
+
+// Payment methods the fake gateway understands. Any other method succeeds.
+const (
+	FakeMethodSucceeds = "pm_card_visa"
+	FakeMethodDeclined = "pm_card_declined"
+)
+
+// FakeSignatureHeader carries the signature of the fake gateway's webhooks.
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// FakeGateway keeps intents in memory. It is meant for development and
+// tests: no money moves. Its webhooks are Events as JSON, signed with
+// WebhookSecret.
+type FakeGateway struct {
+	WebhookSecret string
+
+	mu          sync.Mutex
+	intents     map[string]*Intent
+	refunds     map[string]*Refund
+	refunded    map[string]int64 // By intent
+	idempotency map[string]string
+}
+
+func NewFakeGateway(webhookSecret string) *FakeGateway {
+	return &FakeGateway{
+		WebhookSecret: webhookSecret,
+		intents:       map[string]*Intent{},
+		refunds:       map[string]*Refund{},
+		refunded:      map[string]int64{},
+		idempotency:   map[string]string{},
+	}
+}
+
+func (g *FakeGateway) Name() string { return "fake" }
+
+func (g *FakeGateway) PublishableKey() string { return "" }
+
+func (g *FakeGateway) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if req.IdempotencyKey != "" {
+		if id, ok := g.idempotency[req.IdempotencyKey]; ok {
+			return g.copy(id), nil
+		}
+	}
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+
+	token, err := utils.GenerateToken(12)
+	if err != nil {
+		return nil, err
+	}
+	intent := &Intent{
+		ID:           "pi_fake_" + token,
+		Status:       IntentRequiresPayment,
+		Amount:       req.Amount,
+		Currency:     req.Currency,
+		ClientSecret: "pi_fake_" + token + "_secret",
+	}
+	g.intents[intent.ID] = intent
+	if req.IdempotencyKey != "" {
+		g.idempotency[req.IdempotencyKey] = intent.ID
+	}
+	if req.PaymentMethod != "" {
+		// Like Stripe, only methods saved on a customer can be reused
+		if req.Customer == "" {
+			return nil, fmt.Errorf("payment method %s is not saved on a customer", req.PaymentMethod)
+		}
+		charge(intent, req.PaymentMethod)
+	}
+	return g.copy(intent.ID), nil
+}
+
+func (g *FakeGateway) CreateCustomer(ctx context.Context, req CustomerRequest) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if req.IdempotencyKey != "" {
+		if id, ok := g.idempotency[req.IdempotencyKey]; ok {
+			return id, nil
+		}
+	}
+	token, err := utils.GenerateToken(12)
+	if err != nil {
+		return "", err
+	}
+	id := "cus_fake_" + token
+	if req.IdempotencyKey != "" {
+		g.idempotency[req.IdempotencyKey] = id
+	}
+	return id, nil
+}
+
+func (g *FakeGateway) GetIntent(ctx context.Context, id string) (*Intent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.intents[id]; !ok {
+		return nil, ErrIntentNotFound
+	}
+	return g.copy(id), nil
+}
+
+func (g *FakeGateway) ConfirmIntent(ctx context.Context, id, paymentMethod string) (*Intent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.intents[id]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	if intent.Status != IntentRequiresPayment {
+		return nil, fmt.Errorf("intent %s cannot be confirmed while %s", id, intent.Status)
+	}
+
+	charge(intent, paymentMethod)
+	return g.copy(id), nil
+}
+
+// charge settles an intent as the payment method dictates.
+func charge(intent *Intent, paymentMethod string) {
+	intent.PaymentMethod = paymentMethod
+	if paymentMethod == FakeMethodDeclined {
+		intent.LastError = "Your card was declined."
+	} else {
+		intent.Status = IntentSucceeded
+		intent.LastError = ""
+	}
+}
+
+func (g *FakeGateway) CancelIntent(ctx context.Context, id string) (*Intent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.intents[id]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	if intent.Status == IntentSucceeded {
+		return nil, fmt.Errorf("intent %s already succeeded", id)
+	}
+	intent.Status = IntentCanceled
+	return g.copy(id), nil
+}
+
+// CreateRefund refunds at once; no refund ever stays pending.
+func (g *FakeGateway) CreateRefund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if req.IdempotencyKey != "" {
+		if id, ok := g.idempotency[req.IdempotencyKey]; ok {
+			refund := *g.refunds[id]
+			return &refund, nil
+		}
+	}
+	intent, ok := g.intents[req.IntentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	if intent.Status != IntentSucceeded {
+		return nil, fmt.Errorf("intent %s has not succeeded", intent.ID)
+	}
+	if req.Amount <= 0 || g.refunded[intent.ID]+req.Amount > intent.Amount {
+		return nil, fmt.Errorf("refund of %d exceeds what is left of intent %s", req.Amount, intent.ID)
+	}
+
+	token, err := utils.GenerateToken(12)
+	if err != nil {
+		return nil, err
+	}
+	refund := &Refund{
+		ID:       "re_fake_" + token,
+		IntentID: intent.ID,
+		Status:   RefundSucceeded,
+		Amount:   req.Amount,
+		Currency: intent.Currency,
+	}
+	g.refunds[refund.ID] = refund
+	g.refunded[intent.ID] += req.Amount
+	if req.IdempotencyKey != "" {
+		g.idempotency[req.IdempotencyKey] = refund.ID
+	}
+	c := *refund
+	return &c, nil
+}
+
+func (g *FakeGateway) VerifyWebhook(payload []byte, header http.Header, now time.Time) error {
+	return verifyPayload(g.WebhookSecret, header.Get(FakeSignatureHeader), payload, now)
+}
+
+func (g *FakeGateway) ParseEvent(payload []byte) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if event.ID == "" {
+		return nil, fmt.Errorf("%w: no id", ErrInvalidEvent)
+	}
+	event.GatewayType = event.Type
+	switch event.Type {
+	case EventPaymentSucceeded, EventPaymentFailed, EventPaymentProcessing, EventPaymentCanceled:
+		if event.Intent == nil {
+			return nil, fmt.Errorf("%w: %s without intent", ErrInvalidEvent, event.Type)
+		}
+	case EventRefundUpdated:
+		if event.Refund == nil {
+			return nil, fmt.Errorf("%w: %s without refund", ErrInvalidEvent, event.Type)
+		}
+	case EventDisputeOpened, EventDisputeClosed:
+		if event.Dispute == nil {
+			return nil, fmt.Errorf("%w: %s without dispute", ErrInvalidEvent, event.Type)
+		}
+	default:
+		event.Type = ""
+		event.Intent, event.Refund, event.Dispute = nil, nil, nil
+	}
+	return &event, nil
+}
+
+// SignEvent encodes an event as the fake gateway would send it.
+func (g *FakeGateway) SignEvent(event *Event, at time.Time) ([]byte, http.Header, error) {
+	if event.ID == "" {
+		token, err := utils.GenerateToken(12)
+		if err != nil {
+			return nil, nil, err
+		}
+		event.ID = "evt_fake_" + token
+	}
+	if event.Created.IsZero() {
+		event.Created = at.UTC().Truncate(time.Second)
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(FakeSignatureHeader, signPayload(g.WebhookSecret, at, payload))
+	return payload, header, nil
+}
+
+// SetStatus moves an intent to a status, as if the payer acted outside the
+// app.
+func (g *FakeGateway) SetStatus(id string, status IntentStatus) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.intents[id]
+	if !ok {
+		return ErrIntentNotFound
+	}
+	intent.Status = status
+	return nil
+}
+
+func (g *FakeGateway) copy(id string) *Intent {
+	c := *g.intents[id]
+	return &c
+}
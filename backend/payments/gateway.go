@@ -0,0 +1,115 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// IntentStatus is where a payment intent stands, normalised across
+// gateways.
+type IntentStatus string
+
+const (
+	IntentRequiresPayment IntentStatus = "requires_payment" // Waiting for the payer, also after a decline
+	IntentProcessing      IntentStatus = "processing"
+	IntentSucceeded       IntentStatus = "succeeded"
+	IntentCanceled        IntentStatus = "canceled"
+)
+
+// RefundStatus is where a refund stands, normalised across gateways.
+type RefundStatus string
+
+const (
+	RefundPending   RefundStatus = "pending"
+	RefundSucceeded RefundStatus = "succeeded"
+	RefundFailed    RefundStatus = "failed"
+)
+
+// ErrIntentNotFound is returned for intents the gateway does not know.
+var ErrIntentNotFound = errors.New("payment intent not found")
+
+// IntentRequest describes a payment to collect. Amounts are in the minor
+// unit of the currency, e.g. cents.
+type IntentRequest struct {
+	Amount         int64
+	Currency       string // ISO 4217, lower case
+	Description    string
+	Metadata       map[string]string
+	IdempotencyKey string // Retrying with the same key returns the same intent
+
+	// Customer is who pays at the gateway. With SaveMethod the method that
+	// pays is kept on the customer so it can be charged again later.
+	Customer   string
+	SaveMethod bool
+	// PaymentMethod, saved on Customer, is charged at once without the
+	// payer present, as for a renewal.
+	PaymentMethod string
+}
+
+// CustomerRequest describes someone who pays, so their payment methods can
+// be saved.
+type CustomerRequest struct {
+	Email          string
+	Name           string
+	Metadata       map[string]string
+	IdempotencyKey string
+}
+
+// Intent is a gateway's record of a payment being collected.
+type Intent struct {
+	ID            string       `json:"id"`
+	Status        IntentStatus `json:"status"`
+	Amount        int64        `json:"amount"`
+	Currency      string       `json:"currency"`
+	ClientSecret  string       `json:"client_secret,omitempty"` // Lets the app confirm the payment directly with the gateway
+	LastError     string       `json:"last_error,omitempty"`    // Why the latest attempt failed, if it did
+	PaymentMethod string       `json:"-"`                       // That paid or was last tried
+}
+
+// RefundRequest describes money to return from a succeeded intent.
+type RefundRequest struct {
+	IntentID       string
+	Amount         int64 // In minor units, at most what is left of the intent
+	Metadata       map[string]string
+	IdempotencyKey string
+}
+
+// Refund is a gateway's record of money being returned.
+type Refund struct {
+	ID            string       `json:"id"`
+	IntentID      string       `json:"intent_id"`
+	Status        RefundStatus `json:"status"`
+	Amount        int64        `json:"amount"`
+	Currency      string       `json:"currency"`
+	FailureReason string       `json:"failure_reason,omitempty"`
+}
+
+// Gateway collects payments. Implementations must be safe for concurrent
+// use.
+type Gateway interface {
+	Name() string
+	// PublishableKey is the key apps use to talk to the gateway, if any.
+	PublishableKey() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	GetIntent(ctx context.Context, id string) (*Intent, error)
+	// ConfirmIntent charges a payment method the app collected. A decline
+	// is not an error: the intent comes back with LastError set.
+	ConfirmIntent(ctx context.Context, id, paymentMethod string) (*Intent, error)
+	CancelIntent(ctx context.Context, id string) (*Intent, error)
+	// CreateCustomer registers someone who pays and returns their ID.
+	CreateCustomer(ctx context.Context, req CustomerRequest) (string, error)
+	// CreateRefund returns part or all of a succeeded intent. Refunds may
+	// settle later; their outcome then arrives through the gateway's
+	// webhooks.
+	CreateRefund(ctx context.Context, req RefundRequest) (*Refund, error)
+	// VerifyWebhook checks that a webhook body was signed by the gateway
+	// recently.
+	VerifyWebhook(payload []byte, header http.Header, now time.Time) error
+	// ParseEvent decodes a webhook body that was verified when received.
+	ParseEvent(payload []byte) (*Event, error)
+}
+
+// DefaultGateway is the gateway configured at startup.
+var DefaultGateway Gateway
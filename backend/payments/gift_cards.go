@@ -0,0 +1,97 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"pluralink/backend/giftcards"
+	"pluralink/backend/ledger"
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StartGiftCard opens the payment of a gift card bought in the app. A card
+// whose payment is already open is returned as is.
+func (s *Service) StartGiftCard(ctx context.Context, card *models.GiftCard, providerName string) error {
+	if card.IntentID != nil {
+		return nil
+	}
+	intent, err := s.Gateway.CreateIntent(ctx, IntentRequest{
+		Amount:      card.Amount,
+		Currency:    card.Currency,
+		Description: "Gift card for " + providerName,
+		Metadata: map[string]string{
+			"gift_card_id": strconv.FormatUint(uint64(card.ID), 10),
+		},
+		IdempotencyKey: fmt.Sprintf("gift-card-%d", card.ID),
+	})
+	if err != nil {
+		return err
+	}
+	card.Gateway = s.Gateway.Name()
+	card.IntentID = &intent.ID
+	card.ClientSecret = intent.ClientSecret
+	return s.DB.Model(card).Select("gateway", "intent_id", "client_secret").Updates(card).Error
+}
+
+// ConfirmGiftCard charges a payment method for a gift card. A declined
+// payment leaves the card pending with its FailureMessage set.
+func (s *Service) ConfirmGiftCard(ctx context.Context, card *models.GiftCard, paymentMethod string) error {
+	if card.IntentID == nil {
+		return fmt.Errorf("gift card %d has no payment", card.ID)
+	}
+	intent, err := s.Gateway.ConfirmIntent(ctx, *card.IntentID, paymentMethod)
+	if err != nil {
+		return err
+	}
+	return s.syncGiftCard(card, intent)
+}
+
+// RefreshGiftCard reads a gift card's payment back from the gateway.
+func (s *Service) RefreshGiftCard(ctx context.Context, card *models.GiftCard) error {
+	if card.IntentID == nil {
+		return fmt.Errorf("gift card %d has no payment", card.ID)
+	}
+	intent, err := s.Gateway.GetIntent(ctx, *card.IntentID)
+	if err != nil {
+		return err
+	}
+	return s.syncGiftCard(card, intent)
+}
+
+func (s *Service) syncGiftCard(card *models.GiftCard, intent *Intent) error {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		return applyGiftCardIntent(tx, card.ID, intent)
+	})
+	if err != nil {
+		return err
+	}
+	return s.DB.First(card, card.ID).Error
+}
+
+// applyGiftCardIntent activates a pending gift card once its payment went
+// through, posting it to the ledger, or voids it if the payment was
+// cancelled.
+func applyGiftCardIntent(tx *gorm.DB, cardID uint, intent *Intent) error {
+	var card models.GiftCard
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&card, cardID).Error; err != nil {
+		return err
+	}
+	if card.Status != models.GiftCardPending {
+		return nil
+	}
+	switch intent.Status {
+	case IntentSucceeded:
+		if err := giftcards.Activate(tx, &card); err != nil {
+			return err
+		}
+		return ledger.RecordGiftCard(tx, &card, time.Now())
+	case IntentCanceled:
+		return tx.Model(&card).Update("status", models.GiftCardVoid).Error
+	}
+	return tx.Model(&card).Update("failure_message", intent.LastError).Error
+}
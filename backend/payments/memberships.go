@@ -0,0 +1,263 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pluralink/backend/credits"
+	"pluralink/backend/ledger"
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+	"pluralink/backend/taxes"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OpenPeriod returns the open payment of a membership, or else creates
+// the payment of its next month and opens it with the gateway. The first
+// month starts now; later ones follow on from the current one. The method
+// the client pays with is saved for renewals.
+func (s *Service) OpenPeriod(ctx context.Context, m *models.Membership, now time.Time) (*models.MembershipPayment, error) {
+	return s.openPeriod(ctx, m, now, "")
+}
+
+// Renew opens the payment of a membership's next month and charges it to
+// the payment method that paid last, if there is one, without the client
+// present. A decline leaves the payment open for the client to pay.
+func (s *Service) Renew(ctx context.Context, m *models.Membership, now time.Time) (*models.MembershipPayment, error) {
+	paymentMethod := m.PaymentMethod
+	if m.GatewayCustomer == "" {
+		// Kept before methods were saved on a customer, so the gateway
+		// would refuse it; paying this month in the app saves one
+		paymentMethod = ""
+	}
+	return s.openPeriod(ctx, m, now, paymentMethod)
+}
+
+func (s *Service) openPeriod(ctx context.Context, m *models.Membership, now time.Time, paymentMethod string) (*models.MembershipPayment, error) {
+	var payment models.MembershipPayment
+	err := s.DB.Where("membership_id = ? AND status IN ?", m.ID,
+		[]models.PaymentStatus{models.PaymentRequiresPayment, models.PaymentProcessing}).
+		Order("period_start DESC").First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var plan models.MembershipPlan
+		if err := s.DB.Unscoped().Preload("Services").First(&plan, m.PlanID).Error; err != nil {
+			return nil, err
+		}
+		var provider models.ServiceProvider
+		if err := s.DB.Unscoped().First(&provider, m.ProviderID).Error; err != nil {
+			return nil, err
+		}
+		// Bookings its allowance covers carry no tax, so each month is
+		// taxed instead
+		tax, err := taxes.OnSale(s.DB, &provider, money.New(plan.Price, plan.Currency), plan.Services)
+		if err != nil {
+			return nil, err
+		}
+		start := now
+		if m.PeriodEnd != nil {
+			start = *m.PeriodEnd
+		}
+		payment = models.MembershipPayment{
+			MembershipID: m.ID,
+			PeriodStart:  start,
+			PeriodEnd:    start.AddDate(0, 1, 0),
+			Amount:       plan.Price,
+			Currency:     plan.Currency,
+			Tax:          tax.Tax,
+			TaxName:      tax.Name,
+			TaxRate:      tax.Rate,
+			TaxInclusive: tax.Inclusive,
+			Status:       models.PaymentRequiresPayment,
+		}
+		if err := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&payment).Error; err != nil {
+			return nil, err
+		}
+		if payment.ID == 0 {
+			err = s.DB.Where("membership_id = ? AND period_start = ?", m.ID, start).First(&payment).Error
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if payment.IntentID != nil || payment.Status == models.PaymentSucceeded {
+		return &payment, nil
+	}
+
+	customer, err := s.membershipCustomer(ctx, m)
+	if err != nil {
+		return &payment, err
+	}
+	req := IntentRequest{
+		Amount:      payment.Total(),
+		Currency:    payment.Currency,
+		Description: fmt.Sprintf("Membership from %s", payment.PeriodStart.Format("2 January 2006")),
+		Metadata: map[string]string{
+			"membership_id":         strconv.FormatUint(uint64(m.ID), 10),
+			"membership_payment_id": strconv.FormatUint(uint64(payment.ID), 10),
+		},
+		IdempotencyKey: fmt.Sprintf("membership-payment-%d", payment.ID),
+		Customer:       customer,
+	}
+	if paymentMethod != "" {
+		req.PaymentMethod = paymentMethod
+	} else {
+		req.SaveMethod = true
+	}
+	intent, err := s.Gateway.CreateIntent(ctx, req)
+	if err != nil {
+		return &payment, err
+	}
+	payment.Gateway = s.Gateway.Name()
+	payment.IntentID = &intent.ID
+	payment.ClientSecret = intent.ClientSecret
+	if err := s.DB.Model(&payment).Select("gateway", "intent_id", "client_secret").Updates(&payment).Error; err != nil {
+		return &payment, err
+	}
+	if paymentMethod == "" {
+		return &payment, nil
+	}
+	return &payment, s.syncMembershipPayment(&payment, intent, paymentMethod)
+}
+
+// membershipCustomer returns the gateway customer a membership's payment
+// method is saved on, registering the client the first time.
+func (s *Service) membershipCustomer(ctx context.Context, m *models.Membership) (string, error) {
+	if m.GatewayCustomer != "" {
+		return m.GatewayCustomer, nil
+	}
+	var client models.Client
+	if err := s.DB.Unscoped().Preload("User").First(&client, m.ClientID).Error; err != nil {
+		return "", err
+	}
+	customer, err := s.Gateway.CreateCustomer(ctx, CustomerRequest{
+		Email: client.User.Email,
+		Name:  strings.TrimSpace(client.User.FirstName + " " + client.User.LastName),
+		Metadata: map[string]string{
+			"client_id":     strconv.FormatUint(uint64(client.ID), 10),
+			"membership_id": strconv.FormatUint(uint64(m.ID), 10),
+		},
+		IdempotencyKey: fmt.Sprintf("membership-customer-%d", m.ID),
+	})
+	if err != nil {
+		return "", err
+	}
+	m.GatewayCustomer = customer
+	return customer, s.DB.Model(m).Update("gateway_customer", customer).Error
+}
+
+// ConfirmMembershipPayment charges a payment method for a month of a
+// membership. A method that pays is kept for renewals.
+func (s *Service) ConfirmMembershipPayment(ctx context.Context, payment *models.MembershipPayment, paymentMethod string) error {
+	if payment.IntentID == nil {
+		return fmt.Errorf("membership payment %d has no intent", payment.ID)
+	}
+	intent, err := s.Gateway.ConfirmIntent(ctx, *payment.IntentID, paymentMethod)
+	if err != nil {
+		return err
+	}
+	return s.syncMembershipPayment(payment, intent, paymentMethod)
+}
+
+// RefreshMembershipPayment reads a membership payment back from the
+// gateway.
+func (s *Service) RefreshMembershipPayment(ctx context.Context, payment *models.MembershipPayment) error {
+	if payment.IntentID == nil {
+		return fmt.Errorf("membership payment %d has no intent", payment.ID)
+	}
+	intent, err := s.Gateway.GetIntent(ctx, *payment.IntentID)
+	if err != nil {
+		return err
+	}
+	return s.syncMembershipPayment(payment, intent, "")
+}
+
+// CancelMembershipPayments cancels the open payments of a membership that
+// ended, so the client can no longer pay them.
+func (s *Service) CancelMembershipPayments(ctx context.Context, membershipID uint) error {
+	var open []models.MembershipPayment
+	if err := s.DB.Where("membership_id = ? AND status IN ?", membershipID,
+		[]models.PaymentStatus{models.PaymentRequiresPayment, models.PaymentProcessing}).Find(&open).Error; err != nil {
+		return err
+	}
+	for i := range open {
+		p := &open[i]
+		if p.IntentID == nil {
+			if err := s.DB.Model(p).Update("status", models.PaymentCanceled).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		intent, err := s.Gateway.CancelIntent(ctx, *p.IntentID)
+		if err != nil {
+			return fmt.Errorf("cancel membership payment %d: %w", p.ID, err)
+		}
+		if err := s.syncMembershipPayment(p, intent, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) syncMembershipPayment(payment *models.MembershipPayment, intent *Intent, paymentMethod string) error {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		return applyMembershipIntent(tx, payment.ID, intent, paymentMethod)
+	})
+	if err != nil {
+		return err
+	}
+	return s.DB.First(payment, payment.ID).Error
+}
+
+// applyMembershipIntent records where a membership payment stands and
+// starts the month it paid for once it went through, posting it to the
+// ledger.
+func applyMembershipIntent(tx *gorm.DB, paymentID uint, intent *Intent, paymentMethod string) error {
+	var payment models.MembershipPayment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentID).Error; err != nil {
+		return err
+	}
+	// A final state is never walked back by a late or stale read
+	if payment.Status == models.PaymentSucceeded || payment.Status == models.PaymentCanceled {
+		return nil
+	}
+
+	updates := map[string]interface{}{
+		"status":          paymentStatus(intent.Status),
+		"failure_message": intent.LastError,
+	}
+	now := time.Now()
+	if intent.Status == IntentSucceeded {
+		updates["succeeded_at"] = now
+		updates["failure_message"] = ""
+	}
+	if err := tx.Model(&payment).Updates(updates).Error; err != nil {
+		return err
+	}
+	if intent.Status != IntentSucceeded {
+		return nil
+	}
+
+	var m models.Membership
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&m, payment.MembershipID).Error; err != nil {
+		return err
+	}
+	if paymentMethod == "" {
+		// Paid in the app, straight with the gateway
+		paymentMethod = intent.PaymentMethod
+	}
+	if paymentMethod != "" {
+		if err := tx.Model(&m).Update("payment_method", paymentMethod).Error; err != nil {
+			return err
+		}
+	}
+	if err := ledger.RecordMembershipPayment(tx, &m, &payment, now); err != nil {
+		return err
+	}
+	return credits.StartPeriod(tx, &m, &payment)
+}
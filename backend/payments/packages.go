@@ -0,0 +1,98 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"pluralink/backend/credits"
+	"pluralink/backend/ledger"
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StartPackage opens the payment of a package a client is buying. A
+// package whose payment is already open is returned as is.
+func (s *Service) StartPackage(ctx context.Context, cp *models.ClientPackage, name string) error {
+	if cp.IntentID != nil {
+		return nil
+	}
+	intent, err := s.Gateway.CreateIntent(ctx, IntentRequest{
+		Amount:      cp.Total(),
+		Currency:    cp.Currency,
+		Description: name,
+		Metadata: map[string]string{
+			"client_package_id": strconv.FormatUint(uint64(cp.ID), 10),
+		},
+		IdempotencyKey: fmt.Sprintf("client-package-%d", cp.ID),
+	})
+	if err != nil {
+		return err
+	}
+	cp.Gateway = s.Gateway.Name()
+	cp.IntentID = &intent.ID
+	cp.ClientSecret = intent.ClientSecret
+	return s.DB.Model(cp).Select("gateway", "intent_id", "client_secret").Updates(cp).Error
+}
+
+// ConfirmPackage charges a payment method for a package. A declined
+// payment leaves the package pending with its FailureMessage set.
+func (s *Service) ConfirmPackage(ctx context.Context, cp *models.ClientPackage, paymentMethod string) error {
+	if cp.IntentID == nil {
+		return fmt.Errorf("package %d has no payment", cp.ID)
+	}
+	intent, err := s.Gateway.ConfirmIntent(ctx, *cp.IntentID, paymentMethod)
+	if err != nil {
+		return err
+	}
+	return s.syncPackage(cp, intent)
+}
+
+// RefreshPackage reads a package's payment back from the gateway.
+func (s *Service) RefreshPackage(ctx context.Context, cp *models.ClientPackage) error {
+	if cp.IntentID == nil {
+		return fmt.Errorf("package %d has no payment", cp.ID)
+	}
+	intent, err := s.Gateway.GetIntent(ctx, *cp.IntentID)
+	if err != nil {
+		return err
+	}
+	return s.syncPackage(cp, intent)
+}
+
+func (s *Service) syncPackage(cp *models.ClientPackage, intent *Intent) error {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		return applyPackageIntent(tx, cp.ID, intent)
+	})
+	if err != nil {
+		return err
+	}
+	return s.DB.First(cp, cp.ID).Error
+}
+
+// applyPackageIntent activates a pending package once its payment went
+// through, posting it to the ledger, or voids it if the payment was
+// cancelled.
+func applyPackageIntent(tx *gorm.DB, id uint, intent *Intent) error {
+	var cp models.ClientPackage
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cp, id).Error; err != nil {
+		return err
+	}
+	if cp.Status != models.ClientPackagePending {
+		return nil
+	}
+	switch intent.Status {
+	case IntentSucceeded:
+		now := time.Now()
+		if err := credits.ActivatePackage(tx, &cp, now); err != nil {
+			return err
+		}
+		return ledger.RecordPackage(tx, &cp, now)
+	case IntentCanceled:
+		return tx.Model(&cp).Update("status", models.ClientPackageVoid).Error
+	}
+	return tx.Model(&cp).Update("failure_message", intent.LastError).Error
+}
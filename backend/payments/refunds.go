@@ -0,0 +1,245 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"pluralink/backend/ledger"
+	"pluralink/backend/loyalty"
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RefundDue works out how much of what was paid for a booking goes back
+// when it is cancelled at the given time, and why. Provider cancellations
+// and bookings the provider never confirmed are refunded in full. Clients
+// cancelling a confirmed booking get everything back up to the provider's
+// cutoff and the provider's late share after it. Whether the provider
+// confirmed it is told by ConfirmedAt, as a rescheduled booking may or
+// may not have been.
+func RefundDue(booking *models.Booking, provider *models.ServiceProvider, paid int64, byProvider bool, at time.Time) (int64, string) {
+	if paid <= 0 {
+		return 0, ""
+	}
+	if byProvider {
+		return paid, models.RefundReasonProviderCancelled
+	}
+	if booking.ConfirmedAt == nil {
+		return paid, models.RefundReasonClientCancelled
+	}
+
+	cutoff := booking.StartsAt(provider.Location()).Add(-time.Duration(provider.CancellationCutoff) * time.Minute)
+	if at.Before(cutoff) {
+		return paid, models.RefundReasonClientCancelled
+	}
+	return paid * int64(provider.LateCancellationRefund) / 100, models.RefundReasonClientCancelled
+}
+
+// Paid returns what the booking's succeeded payments add up to, in minor
+// units. Tips are not included.
+func (s *Service) Paid(bookingID uint) (int64, error) {
+	var paid int64
+	err := s.DB.Model(&models.Payment{}).
+		Where("booking_id = ? AND status = ? AND kind <> ?", bookingID, models.PaymentSucceeded, models.PaymentTip).
+		Select("COALESCE(SUM(amount), 0)").Row().Scan(&paid)
+	return paid, err
+}
+
+// IssueRefunds asks the gateway to return what is still owed on a booking
+// whose refund is pending, spreading it over its payments oldest first.
+// It is safe to run again after a failure: refunds already recorded count
+// toward what is owed, and requests are idempotent per attempt. It reports
+// whether the booking's refund status changed.
+func (s *Service) IssueRefunds(ctx context.Context, bookingID uint) (bool, error) {
+	var booking models.Booking
+	if err := s.DB.First(&booking, bookingID).Error; err != nil {
+		return false, err
+	}
+	if booking.RefundStatus != models.RefundPending {
+		return false, nil
+	}
+
+	var payments []models.Payment
+	if err := s.DB.Where("booking_id = ? AND status = ? AND kind <> ?", bookingID, models.PaymentSucceeded, models.PaymentTip).
+		Order("id").Find(&payments).Error; err != nil {
+		return false, err
+	}
+	var refunds []models.Refund
+	if err := s.DB.Where("booking_id = ?", bookingID).Find(&refunds).Error; err != nil {
+		return false, err
+	}
+
+	owed := booking.RefundAmount
+	refunded := map[uint]int64{}
+	attempts := map[uint]int{}
+	for _, r := range refunds {
+		attempts[r.PaymentID]++
+		if r.Status != models.RefundStatusFailed {
+			refunded[r.PaymentID] += r.Amount
+			owed -= r.Amount
+		}
+	}
+
+	var issueErr error
+	for _, p := range payments {
+		if owed <= 0 {
+			break
+		}
+		amount := min(owed, p.Amount-refunded[p.ID])
+		if amount <= 0 {
+			continue
+		}
+
+		refund, err := s.Gateway.CreateRefund(ctx, RefundRequest{
+			IntentID: p.IntentID,
+			Amount:   amount,
+			Metadata: map[string]string{
+				"booking_id": strconv.FormatUint(uint64(bookingID), 10),
+				"reason":     booking.RefundReason,
+			},
+			// Numbered by attempt so a lost response is not refunded twice,
+			// while a new attempt after a failed refund goes through
+			IdempotencyKey: fmt.Sprintf("booking-%d-payment-%d-refund-%d", bookingID, p.ID, attempts[p.ID]+1),
+		})
+		if err != nil {
+			issueErr = fmt.Errorf("refund payment %d: %w", p.ID, err)
+			break
+		}
+
+		row := models.Refund{
+			BookingID:      bookingID,
+			PaymentID:      p.ID,
+			Gateway:        s.Gateway.Name(),
+			RefundID:       refund.ID,
+			Amount:         refund.Amount,
+			Currency:       p.Currency,
+			Status:         refundStatus(refund.Status),
+			Reason:         booking.RefundReason,
+			FailureMessage: refund.FailureReason,
+		}
+		if err := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			issueErr = err
+			break
+		}
+		if row.Status != models.RefundStatusFailed {
+			owed -= refund.Amount
+		}
+	}
+
+	var changed bool
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		changed, err = settleRefunds(tx, bookingID)
+		return err
+	})
+	if issueErr != nil {
+		return changed, issueErr
+	}
+	return changed, err
+}
+
+// SyncRefund records a refund's latest state, as reported by the gateway,
+// and updates its booking's refund status to match.
+func (s *Service) SyncRefund(refund *Refund) (bool, error) {
+	changed := false
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		_, changed, err = applyRefund(tx, refund)
+		return err
+	})
+	return changed, err
+}
+
+// applyRefund is SyncRefund within a transaction. It also returns the
+// refunded booking.
+func applyRefund(tx *gorm.DB, refund *Refund) (uint, bool, error) {
+	var row models.Refund
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("refund_id = ?", refund.ID).First(&row).Error; err != nil {
+		return 0, false, err
+	}
+	if row.Status != models.RefundStatusPending {
+		return row.BookingID, false, nil
+	}
+	if err := tx.Model(&row).Updates(map[string]interface{}{
+		"status":          refundStatus(refund.Status),
+		"failure_message": refund.FailureReason,
+	}).Error; err != nil {
+		return row.BookingID, false, err
+	}
+
+	changed, err := settleRefunds(tx, row.BookingID)
+	return row.BookingID, changed, err
+}
+
+// RetryRefunds puts a booking whose refunds failed back in line to be
+// refunded. It reports whether there was anything to retry.
+func (s *Service) RetryRefunds(bookingID uint) (bool, error) {
+	result := s.DB.Model(&models.Booking{}).
+		Where("id = ? AND refund_status = ?", bookingID, models.RefundFailed).
+		Update("refund_status", models.RefundPending)
+	return result.RowsAffected > 0, result.Error
+}
+
+// settleRefunds derives a booking's refund status from its refunds and
+// reports whether it changed. Refunds that went through are posted to the
+// ledger, and loyalty points the booking earned are taken back in
+// proportion to what was refunded.
+func settleRefunds(tx *gorm.DB, bookingID uint) (bool, error) {
+	var booking models.Booking
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, bookingID).Error; err != nil {
+		return false, err
+	}
+	var refunds []models.Refund
+	if err := tx.Where("booking_id = ?", bookingID).Find(&refunds).Error; err != nil {
+		return false, err
+	}
+
+	var settled, pending int64
+	failed := false
+	for _, r := range refunds {
+		switch r.Status {
+		case models.RefundStatusSucceeded:
+			settled += r.Amount
+			if err := ledger.RecordRefund(tx, &r, r.UpdatedAt); err != nil {
+				return false, err
+			}
+		case models.RefundStatusPending:
+			pending += r.Amount
+		case models.RefundStatusFailed:
+			failed = true
+		}
+	}
+
+	if err := loyalty.Reverse(tx, &booking, settled); err != nil {
+		return false, err
+	}
+
+	status := models.RefundPending
+	switch {
+	case booking.RefundAmount <= 0:
+		status = models.RefundNone
+	case settled >= booking.RefundAmount:
+		status = models.RefundRefunded
+	case pending == 0 && failed:
+		status = models.RefundFailed
+	}
+	if status == booking.RefundStatus {
+		return false, nil
+	}
+	return true, tx.Model(&booking).Update("refund_status", status).Error
+}
+
+func refundStatus(status RefundStatus) models.RefundStatus {
+	switch status {
+	case RefundSucceeded:
+		return models.RefundStatusSucceeded
+	case RefundFailed:
+		return models.RefundStatusFailed
+	}
+	return models.RefundStatusPending
+}
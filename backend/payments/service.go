@@ -0,0 +1,219 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"pluralink/backend/ledger"
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultCurrency is what providers price in when their country's
+// currency is not known.
+var DefaultCurrency = "usd"
+
+// ErrAlreadyPaid is returned when asking to pay for a booking that is paid.
+var ErrAlreadyPaid = errors.New("booking is already paid")
+
+// Service opens and tracks the payments bookings require.
+type Service struct {
+	DB      *gorm.DB
+	Gateway Gateway
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db, Gateway: DefaultGateway}
+}
+
+// Due returns what a service asks to be paid up front of total, what a
+// booking leaves to pay. Zero means nothing is collected in the app.
+func Due(service *models.Service, total money.Money) (models.PaymentPolicy, money.Money) {
+	switch service.PaymentPolicy {
+	case models.PaymentFull:
+		return models.PaymentFull, total
+	case models.PaymentDeposit:
+		return models.PaymentDeposit, total.Percent(service.DepositPercent)
+	}
+	return models.PaymentNone, money.New(0, total.Currency)
+}
+
+// Start opens a payment for what the booking's service asks up front. An
+// open payment is returned as is, so calling Start again is safe.
+func (s *Service) Start(ctx context.Context, booking *models.Booking, service *models.Service) (*models.Payment, error) {
+	if booking.PaymentStatus == models.PaymentPaid {
+		return nil, ErrAlreadyPaid
+	}
+	kind, due := Due(service, money.New(booking.Payable(), booking.Currency))
+	if due.Amount <= 0 {
+		return nil, fmt.Errorf("service %d does not take payments", service.ID)
+	}
+
+	var payments []models.Payment
+	if err := s.DB.Where("booking_id = ? AND kind <> ?", booking.ID, models.PaymentTip).
+		Order("id").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	for i := range payments {
+		if payments[i].Open() {
+			return &payments[i], nil
+		}
+	}
+
+	intent, err := s.Gateway.CreateIntent(ctx, IntentRequest{
+		Amount:      due.Amount,
+		Currency:    due.Currency,
+		Description: fmt.Sprintf("%s on %s at %s", service.Name, booking.Date.Format("2006-01-02"), booking.StartTime),
+		Metadata: map[string]string{
+			"booking_id": strconv.FormatUint(uint64(booking.ID), 10),
+			"kind":       string(kind),
+		},
+		// Numbered by attempt so a retry after a lost response finds the
+		// same intent, while a new attempt after a cancellation does not
+		IdempotencyKey: fmt.Sprintf("booking-%d-payment-%d", booking.ID, len(payments)+1),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payment := models.Payment{
+		BookingID:    booking.ID,
+		Kind:         kind,
+		Gateway:      s.Gateway.Name(),
+		IntentID:     intent.ID,
+		Amount:       intent.Amount,
+		Currency:     intent.Currency,
+		Status:       paymentStatus(intent.Status),
+		ClientSecret: intent.ClientSecret,
+	}
+	if err := s.DB.Create(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// Confirm charges a payment method for an open payment. A declined payment
+// stays open with its FailureMessage set.
+func (s *Service) Confirm(ctx context.Context, payment *models.Payment, paymentMethod string) (bool, error) {
+	intent, err := s.Gateway.ConfirmIntent(ctx, payment.IntentID, paymentMethod)
+	if err != nil {
+		return false, err
+	}
+	return s.sync(payment, intent)
+}
+
+// Refresh reads a payment's state back from the gateway, for payments
+// confirmed by the app directly with the gateway.
+func (s *Service) Refresh(ctx context.Context, payment *models.Payment) (bool, error) {
+	intent, err := s.Gateway.GetIntent(ctx, payment.IntentID)
+	if err != nil {
+		return false, err
+	}
+	return s.sync(payment, intent)
+}
+
+// CancelOpen cancels the booking's payments that have not gone through.
+func (s *Service) CancelOpen(ctx context.Context, bookingID uint) error {
+	var payments []models.Payment
+	if err := s.DB.Where("booking_id = ? AND status IN ?", bookingID,
+		[]models.PaymentStatus{models.PaymentRequiresPayment, models.PaymentProcessing}).Find(&payments).Error; err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range payments {
+		intent, err := s.Gateway.CancelIntent(ctx, payments[i].IntentID)
+		if err != nil {
+			// It may have gone through in the meantime
+			if intent, err = s.Gateway.GetIntent(ctx, payments[i].IntentID); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if _, err := s.sync(&payments[i], intent); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) sync(payment *models.Payment, intent *Intent) (bool, error) {
+	changed, err := s.Sync(payment.ID, intent)
+	if err != nil {
+		return false, err
+	}
+	return changed, s.DB.First(payment, payment.ID).Error
+}
+
+// Sync records an intent's state on its payment and updates the booking's
+// payment status to match, in one transaction. It reports whether the
+// booking's payment status changed.
+func (s *Service) Sync(paymentID uint, intent *Intent) (bool, error) {
+	changed := false
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		changed, err = applyIntent(tx, paymentID, intent)
+		return err
+	})
+	return changed, err
+}
+
+// applyIntent is Sync within a transaction. It reports whether the
+// booking became paid. A tip going through adds to the booking's tips
+// instead. Payments that go through are posted to the ledger.
+func applyIntent(tx *gorm.DB, paymentID uint, intent *Intent) (bool, error) {
+	var payment models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentID).Error; err != nil {
+		return false, err
+	}
+	// A final state is never walked back by a late or stale read
+	if payment.Status == models.PaymentSucceeded || payment.Status == models.PaymentCanceled {
+		return false, nil
+	}
+
+	updates := map[string]interface{}{
+		"status":          paymentStatus(intent.Status),
+		"failure_message": intent.LastError,
+	}
+	now := time.Now()
+	if intent.Status == IntentSucceeded {
+		updates["succeeded_at"] = now
+		updates["failure_message"] = ""
+	}
+	if err := tx.Model(&payment).Updates(updates).Error; err != nil {
+		return false, err
+	}
+	if intent.Status != IntentSucceeded {
+		return false, nil
+	}
+	if err := ledger.RecordPayment(tx, &payment, now); err != nil {
+		return false, err
+	}
+	if payment.Kind == models.PaymentTip {
+		return false, tx.Model(&models.Booking{}).Where("id = ?", payment.BookingID).
+			Update("tip_amount", gorm.Expr("tip_amount + ?", payment.Amount)).Error
+	}
+
+	result := tx.Model(&models.Booking{}).
+		Where("id = ? AND payment_status = ?", payment.BookingID, models.PaymentDue).
+		Update("payment_status", models.PaymentPaid)
+	return result.RowsAffected > 0, result.Error
+}
+
+func paymentStatus(status IntentStatus) models.PaymentStatus {
+	switch status {
+	case IntentSucceeded:
+		return models.PaymentSucceeded
+	case IntentProcessing:
+		return models.PaymentProcessing
+	case IntentCanceled:
+		return models.PaymentCanceled
+	}
+	return models.PaymentRequiresPayment
+}
@@ -0,0 +1,59 @@
+package payments
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"pluralink/backend/config"
+	"pluralink/backend/ledger"
+	"pluralink/backend/money"
+)
+
+// Setup picks the gateway bookings are paid through, the currency they are
+// paid in, the fee the gateway keeps and how long clients may tip. It is
+// shared by the server and the command line tools.
+func Setup(cfg *config.Config) error {
+	switch cfg.PaymentGateway {
+	case "fake":
+		secret := cfg.PaymentWebhookSecret
+		if secret == "" {
+			secret = cfg.JWTSecret
+		}
+		DefaultGateway = NewFakeGateway(secret)
+	case "stripe":
+		if cfg.StripeSecretKey == "" {
+			return fmt.Errorf("Stripe payments need STRIPE_SECRET_KEY")
+		}
+		if cfg.PaymentWebhookSecret == "" {
+			return fmt.Errorf("Stripe payments need PAYMENT_WEBHOOK_SECRET")
+		}
+		DefaultGateway = NewStripeGateway(cfg.StripeSecretKey, cfg.StripePublishableKey, cfg.PaymentWebhookSecret)
+	default:
+		return fmt.Errorf("Invalid PAYMENT_GATEWAY: %s", cfg.PaymentGateway)
+	}
+
+	if !money.Valid(cfg.PaymentCurrency) {
+		return fmt.Errorf("Invalid PAYMENT_CURRENCY: %s", cfg.PaymentCurrency)
+	}
+	DefaultCurrency = strings.ToLower(cfg.PaymentCurrency)
+
+	rate, err := strconv.ParseFloat(cfg.PaymentFeeRate, 64)
+	if err != nil || rate < 0 || rate >= 100 {
+		return fmt.Errorf("Invalid PAYMENT_FEE_RATE: %s", cfg.PaymentFeeRate)
+	}
+	fixed, err := strconv.ParseInt(cfg.PaymentFeeFixed, 10, 64)
+	if err != nil || fixed < 0 {
+		return fmt.Errorf("Invalid PAYMENT_FEE_FIXED: %s", cfg.PaymentFeeFixed)
+	}
+	ledger.GatewayFee = ledger.Fee{Rate: int(math.Round(rate * 100)), Fixed: fixed}
+
+	window, err := time.ParseDuration(cfg.TipWindow)
+	if err != nil || window < 0 {
+		return fmt.Errorf("Invalid TIP_WINDOW: %s", cfg.TipWindow)
+	}
+	TipWindow = window
+	return nil
+}
@@ -0,0 +1,258 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const stripeAPI = "https://api.stripe.com/v1"
+
+// StripeGateway collects payments with Stripe PaymentIntents over its REST
+// API.
+type StripeGateway struct {
+	SecretKey     string
+	PublicKey     string
+	WebhookSecret string // Of the webhook endpoint, "whsec_..."
+	BaseURL       string
+	HTTP          *http.Client
+}
+
+func NewStripeGateway(secretKey, publishableKey, webhookSecret string) *StripeGateway {
+	return &StripeGateway{
+		SecretKey:     secretKey,
+		PublicKey:     publishableKey,
+		WebhookSecret: webhookSecret,
+		BaseURL:       stripeAPI,
+		HTTP:          &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (g *StripeGateway) Name() string { return "stripe" }
+
+func (g *StripeGateway) PublishableKey() string { return g.PublicKey }
+
+type stripeIntent struct {
+	ID               string         `json:"id"`
+	Status           string         `json:"status"`
+	Amount           int64          `json:"amount"`
+	Currency         string         `json:"currency"`
+	ClientSecret     string         `json:"client_secret"`
+	LastPaymentError *stripeMessage `json:"last_payment_error"`
+	PaymentMethod    string         `json:"payment_method"`
+}
+
+type stripeMessage struct {
+	Message string `json:"message"`
+}
+
+type stripeRefund struct {
+	ID            string `json:"id"`
+	PaymentIntent string `json:"payment_intent"`
+	Status        string `json:"status"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	FailureReason string `json:"failure_reason"`
+}
+
+type stripeAPIError struct {
+	Type          string        `json:"type"`
+	Code          string        `json:"code"`
+	Message       string        `json:"message"`
+	PaymentIntent *stripeIntent `json:"payment_intent"`
+}
+
+func (e *stripeAPIError) Error() string {
+	return "stripe: " + e.Message
+}
+
+func (g *StripeGateway) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(req.Amount, 10))
+	form.Set("currency", strings.ToLower(req.Currency))
+	form.Set("automatic_payment_methods[enabled]", "true")
+	form.Set("automatic_payment_methods[allow_redirects]", "never")
+	if req.Description != "" {
+		form.Set("description", req.Description)
+	}
+	for k, v := range req.Metadata {
+		form.Set("metadata["+k+"]", v)
+	}
+	if req.Customer != "" {
+		form.Set("customer", req.Customer)
+	}
+	if req.SaveMethod {
+		form.Set("setup_future_usage", "off_session")
+	}
+	if req.PaymentMethod != "" {
+		form.Set("payment_method", req.PaymentMethod)
+		form.Set("confirm", "true")
+		form.Set("off_session", "true")
+	}
+	return g.intent(ctx, http.MethodPost, "/payment_intents", form, req.IdempotencyKey)
+}
+
+func (g *StripeGateway) CreateCustomer(ctx context.Context, req CustomerRequest) (string, error) {
+	form := url.Values{}
+	if req.Email != "" {
+		form.Set("email", req.Email)
+	}
+	if req.Name != "" {
+		form.Set("name", req.Name)
+	}
+	for k, v := range req.Metadata {
+		form.Set("metadata["+k+"]", v)
+	}
+
+	var customer struct {
+		ID string `json:"id"`
+	}
+	if err := g.do(ctx, http.MethodPost, "/customers", form, req.IdempotencyKey, &customer); err != nil {
+		return "", err
+	}
+	return customer.ID, nil
+}
+
+func (g *StripeGateway) GetIntent(ctx context.Context, id string) (*Intent, error) {
+	return g.intent(ctx, http.MethodGet, "/payment_intents/"+url.PathEscape(id), nil, "")
+}
+
+func (g *StripeGateway) ConfirmIntent(ctx context.Context, id, paymentMethod string) (*Intent, error) {
+	form := url.Values{}
+	form.Set("payment_method", paymentMethod)
+	return g.intent(ctx, http.MethodPost, "/payment_intents/"+url.PathEscape(id)+"/confirm", form, "")
+}
+
+func (g *StripeGateway) CancelIntent(ctx context.Context, id string) (*Intent, error) {
+	return g.intent(ctx, http.MethodPost, "/payment_intents/"+url.PathEscape(id)+"/cancel", url.Values{}, "")
+}
+
+func (g *StripeGateway) CreateRefund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", req.IntentID)
+	form.Set("amount", strconv.FormatInt(req.Amount, 10))
+	for k, v := range req.Metadata {
+		form.Set("metadata["+k+"]", v)
+	}
+
+	var refund stripeRefund
+	if err := g.do(ctx, http.MethodPost, "/refunds", form, req.IdempotencyKey, &refund); err != nil {
+		return nil, err
+	}
+	return refund.normalise(), nil
+}
+
+func (g *StripeGateway) intent(ctx context.Context, method, path string, form url.Values, idempotencyKey string) (*Intent, error) {
+	var intent stripeIntent
+	if err := g.do(ctx, method, path, form, idempotencyKey, &intent); err != nil {
+		// A declined card is an answer about the intent, not a failure to talk
+		var apiErr *stripeAPIError
+		if errors.As(err, &apiErr) && apiErr.Type == "card_error" && apiErr.PaymentIntent != nil {
+			declined := apiErr.PaymentIntent.normalise()
+			if declined.LastError == "" {
+				declined.LastError = apiErr.Message
+			}
+			return declined, nil
+		}
+		return nil, err
+	}
+	return intent.normalise(), nil
+}
+
+func (g *StripeGateway) do(ctx context.Context, method, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(g.SecretKey, "")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := g.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var envelope struct {
+			Error stripeAPIError `json:"error"`
+		}
+		json.Unmarshal(data, &envelope)
+		if resp.StatusCode == http.StatusNotFound || envelope.Error.Code == "resource_missing" {
+			return ErrIntentNotFound
+		}
+		if envelope.Error.Message == "" {
+			envelope.Error.Message = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		}
+		return &envelope.Error
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("stripe: invalid response: %w", err)
+	}
+	return nil
+}
+
+// normalise maps Stripe's statuses onto the gateway-neutral ones.
+func (s *stripeIntent) normalise() *Intent {
+	intent := &Intent{
+		ID:            s.ID,
+		Amount:        s.Amount,
+		Currency:      s.Currency,
+		ClientSecret:  s.ClientSecret,
+		PaymentMethod: s.PaymentMethod,
+	}
+	switch s.Status {
+	case "succeeded":
+		intent.Status = IntentSucceeded
+	case "processing", "requires_capture":
+		intent.Status = IntentProcessing
+	case "canceled":
+		intent.Status = IntentCanceled
+	default: // requires_payment_method, requires_confirmation, requires_action
+		intent.Status = IntentRequiresPayment
+	}
+	if s.LastPaymentError != nil {
+		intent.LastError = s.LastPaymentError.Message
+	}
+	return intent
+}
+
+func (s *stripeRefund) normalise() *Refund {
+	refund := &Refund{
+		ID:            s.ID,
+		IntentID:      s.PaymentIntent,
+		Amount:        s.Amount,
+		Currency:      s.Currency,
+		FailureReason: s.FailureReason,
+	}
+	switch s.Status {
+	case "succeeded":
+		refund.Status = RefundSucceeded
+	case "failed", "canceled":
+		refund.Status = RefundFailed
+	default: // pending, requires_action
+		refund.Status = RefundPending
+	}
+	return refund
+}
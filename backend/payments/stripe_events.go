@@ -0,0 +1,199 @@
+package payments
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"pluralink/backend/utils"
+)
+
+// StripeSignatureHeader carries the signature of Stripe's webhooks.
+const StripeSignatureHeader = "Stripe-Signature"
+
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+type stripeDispute struct {
+	ID            string `json:"id"`
+	Object        string `json:"object"`
+	PaymentIntent string `json:"payment_intent"`
+	Amount        int64  `json:"amount"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason"`
+}
+
+// stripeEventTypes maps the Stripe events acted on to their normalised
+// types.
+var stripeEventTypes = map[string]string{
+	"payment_intent.succeeded":      EventPaymentSucceeded,
+	"payment_intent.payment_failed": EventPaymentFailed,
+	"payment_intent.processing":     EventPaymentProcessing,
+	"payment_intent.canceled":       EventPaymentCanceled,
+	"refund.updated":                EventRefundUpdated,
+	"refund.failed":                 EventRefundUpdated,
+	"charge.refund.updated":         EventRefundUpdated,
+	"charge.dispute.created":        EventDisputeOpened,
+	"charge.dispute.closed":         EventDisputeClosed,
+}
+
+// stripeSignedTypes is what SignEvent calls each normalised type.
+var stripeSignedTypes = map[string]string{
+	EventPaymentSucceeded:  "payment_intent.succeeded",
+	EventPaymentFailed:     "payment_intent.payment_failed",
+	EventPaymentProcessing: "payment_intent.processing",
+	EventPaymentCanceled:   "payment_intent.canceled",
+	EventRefundUpdated:     "refund.updated",
+	EventDisputeOpened:     "charge.dispute.created",
+	EventDisputeClosed:     "charge.dispute.closed",
+}
+
+func (g *StripeGateway) VerifyWebhook(payload []byte, header http.Header, now time.Time) error {
+	return verifyPayload(g.WebhookSecret, header.Get(StripeSignatureHeader), payload, now)
+}
+
+func (g *StripeGateway) ParseEvent(payload []byte) (*Event, error) {
+	var raw stripeEvent
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if raw.ID == "" {
+		return nil, fmt.Errorf("%w: no id", ErrInvalidEvent)
+	}
+	event := &Event{
+		ID:          raw.ID,
+		Type:        stripeEventTypes[raw.Type],
+		GatewayType: raw.Type,
+		Created:     time.Unix(raw.Created, 0).UTC(),
+	}
+
+	var err error
+	switch event.Type {
+	case EventPaymentSucceeded, EventPaymentFailed, EventPaymentProcessing, EventPaymentCanceled:
+		var intent stripeIntent
+		if err = json.Unmarshal(raw.Data.Object, &intent); err == nil {
+			event.Intent = intent.normalise()
+		}
+	case EventRefundUpdated:
+		var refund stripeRefund
+		if err = json.Unmarshal(raw.Data.Object, &refund); err == nil {
+			event.Refund = refund.normalise()
+		}
+	case EventDisputeOpened, EventDisputeClosed:
+		var dispute stripeDispute
+		if err = json.Unmarshal(raw.Data.Object, &dispute); err == nil {
+			event.Dispute = dispute.normalise()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidEvent, raw.Type, err)
+	}
+	return event, nil
+}
+
+// SignEvent encodes an event the way Stripe would send it, signed with the
+// webhook secret.
+func (g *StripeGateway) SignEvent(event *Event, at time.Time) ([]byte, http.Header, error) {
+	if event.ID == "" {
+		token, err := utils.GenerateToken(12)
+		if err != nil {
+			return nil, nil, err
+		}
+		event.ID = "evt_local_" + token
+	}
+	if event.Created.IsZero() {
+		event.Created = at
+	}
+
+	var object interface{}
+	switch {
+	case event.Intent != nil:
+		intent := stripeIntent{
+			ID:       event.Intent.ID,
+			Status:   stripeIntentStatus(event.Intent.Status),
+			Amount:   event.Intent.Amount,
+			Currency: event.Intent.Currency,
+		}
+		if event.Intent.LastError != "" {
+			intent.LastPaymentError = &stripeMessage{Message: event.Intent.LastError}
+		}
+		object = intent
+	case event.Refund != nil:
+		object = stripeRefund{
+			ID:            event.Refund.ID,
+			PaymentIntent: event.Refund.IntentID,
+			Status:        string(event.Refund.Status),
+			Amount:        event.Refund.Amount,
+			Currency:      event.Refund.Currency,
+			FailureReason: event.Refund.FailureReason,
+		}
+	case event.Dispute != nil:
+		status := string(event.Dispute.Status)
+		if event.Dispute.Status == DisputeOpen {
+			status = "needs_response"
+		}
+		object = stripeDispute{
+			ID:            event.Dispute.ID,
+			Object:        "dispute",
+			PaymentIntent: event.Dispute.IntentID,
+			Amount:        event.Dispute.Amount,
+			Status:        status,
+			Reason:        event.Dispute.Reason,
+		}
+	default:
+		return nil, nil, fmt.Errorf("event has no intent, refund or dispute")
+	}
+
+	stripeType, ok := stripeSignedTypes[event.Type]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown event type %q", event.Type)
+	}
+
+	raw := stripeEvent{ID: event.ID, Object: "event", Type: stripeType, Created: event.Created.Unix()}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw.Data.Object = data
+	payload, err := json.Marshal(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(StripeSignatureHeader, signPayload(g.WebhookSecret, at, payload))
+	return payload, header, nil
+}
+
+func (s *stripeDispute) normalise() *Dispute {
+	dispute := &Dispute{
+		ID:       s.ID,
+		IntentID: s.PaymentIntent,
+		Amount:   s.Amount,
+		Reason:   s.Reason,
+		Status:   DisputeOpen,
+	}
+	switch s.Status {
+	case "won", "warning_closed":
+		dispute.Status = DisputeWon
+	case "lost":
+		dispute.Status = DisputeLost
+	}
+	return dispute
+}
+
+func stripeIntentStatus(status IntentStatus) string {
+	if status == IntentRequiresPayment {
+		return "requires_payment_method"
+	}
+	return string(status)
+}
@@ -0,0 +1,99 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"pluralink/backend/models"
+)
+
+// TipWindow is how long after a booking ends its client may tip.
+var TipWindow = 72 * time.Hour
+
+var (
+	ErrAlreadyTipped = errors.New("booking is already tipped")
+	ErrTipClosed     = errors.New("booking can no longer be tipped")
+)
+
+// TipUntil returns until when a booking can be tipped, or nil if it cannot
+// be: only completed bookings that were not tipped yet can.
+func TipUntil(booking *models.Booking, provider *models.ServiceProvider) *time.Time {
+	if booking.Status != models.StatusCompleted || booking.TipAmount > 0 {
+		return nil
+	}
+	until := booking.EndsAt(provider.Location()).Add(TipWindow)
+	return &until
+}
+
+// StartTip opens a payment for a tip on a completed booking. An open tip of
+// the same amount is returned as is; one of another amount is cancelled
+// first, so the client can change their mind before paying.
+func (s *Service) StartTip(ctx context.Context, booking *models.Booking, provider *models.ServiceProvider, amount int64) (*models.Payment, error) {
+	if booking.TipAmount > 0 {
+		return nil, ErrAlreadyTipped
+	}
+	until := TipUntil(booking, provider)
+	if until == nil || time.Now().After(*until) {
+		return nil, ErrTipClosed
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("tip must be positive")
+	}
+
+	var tips []models.Payment
+	if err := s.DB.Where("booking_id = ? AND kind = ?", booking.ID, models.PaymentTip).
+		Order("id").Find(&tips).Error; err != nil {
+		return nil, err
+	}
+	for i := range tips {
+		if !tips[i].Open() {
+			continue
+		}
+		if tips[i].Amount == amount {
+			return &tips[i], nil
+		}
+		intent, err := s.Gateway.CancelIntent(ctx, tips[i].IntentID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.sync(&tips[i], intent); err != nil {
+			return nil, err
+		}
+		// It went through before it could be cancelled
+		if tips[i].Status == models.PaymentSucceeded {
+			return nil, ErrAlreadyTipped
+		}
+	}
+
+	intent, err := s.Gateway.CreateIntent(ctx, IntentRequest{
+		Amount:      amount,
+		Currency:    provider.Currency,
+		Description: fmt.Sprintf("Tip for booking %d", booking.ID),
+		Metadata: map[string]string{
+			"booking_id": strconv.FormatUint(uint64(booking.ID), 10),
+			"kind":       string(models.PaymentTip),
+		},
+		IdempotencyKey: fmt.Sprintf("booking-%d-tip-%d", booking.ID, len(tips)+1),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payment := models.Payment{
+		BookingID:    booking.ID,
+		Kind:         models.PaymentTip,
+		Gateway:      s.Gateway.Name(),
+		IntentID:     intent.ID,
+		Amount:       intent.Amount,
+		Currency:     intent.Currency,
+		Status:       paymentStatus(intent.Status),
+		ClientSecret: intent.ClientSecret,
+	}
+	if err := s.DB.Create(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
@@ -0,0 +1,168 @@
+// Package promos checks and redeems providers' promo codes on bookings.
+package promos
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Rejection is why a promo code cannot be used, worded for the client.
+type Rejection string
+
+func (r Rejection) Error() string { return string(r) }
+func (Rejection) Rejected()       {}
+
+const (
+	ErrNotFound        Rejection = "Promo code not found"
+	ErrNotStarted      Rejection = "Promo code is not valid yet"
+	ErrExpired         Rejection = "Promo code has expired"
+	ErrWrongService    Rejection = "Promo code does not apply to this service"
+	ErrNotFirstBooking Rejection = "Promo code is for first bookings only"
+	ErrUsedUp          Rejection = "Promo code has been used up"
+	ErrUsedByClient    Rejection = "You have already used this promo code"
+)
+
+// Normalize writes a code the way it is stored.
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Find returns the provider's active promo code with its services.
+func Find(db *gorm.DB, providerID uint, code string) (*models.PromoCode, error) {
+	var promo models.PromoCode
+	err := db.Preload("Services").
+		Where("provider_id = ? AND code = ? AND is_active = ?", providerID, Normalize(code), true).
+		First(&promo).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+// Check returns the discount a promo code gives the client on a service at
+// now, or a Rejection. The discount never exceeds the price.
+func Check(db *gorm.DB, promo *models.PromoCode, clientID uint, service *models.Service, now time.Time) (money.Money, error) {
+	none := money.New(0, service.Currency)
+	if promo.StartsAt != nil && now.Before(*promo.StartsAt) {
+		return none, ErrNotStarted
+	}
+	if promo.EndsAt != nil && !now.Before(*promo.EndsAt) {
+		return none, ErrExpired
+	}
+	if len(promo.Services) > 0 {
+		applies := false
+		for _, s := range promo.Services {
+			applies = applies || s.ID == service.ID
+		}
+		if !applies {
+			return none, ErrWrongService
+		}
+	}
+
+	if promo.FirstBookingOnly {
+		var booked int64
+		if err := live(db.Model(&models.Booking{})).
+			Where("bookings.provider_id = ? AND bookings.client_id = ?", promo.ProviderID, clientID).
+			Count(&booked).Error; err != nil {
+			return none, err
+		}
+		if booked > 0 {
+			return none, ErrNotFirstBooking
+		}
+	}
+	if promo.MaxRedemptions > 0 {
+		used, err := Redemptions(db, promo.ID)
+		if err != nil {
+			return none, err
+		}
+		if used >= int64(promo.MaxRedemptions) {
+			return none, ErrUsedUp
+		}
+	}
+	if promo.MaxPerClient > 0 {
+		var used int64
+		if err := redemptions(db, promo.ID).Where("promo_redemptions.client_id = ?", clientID).
+			Count(&used).Error; err != nil {
+			return none, err
+		}
+		if used >= int64(promo.MaxPerClient) {
+			return none, ErrUsedByClient
+		}
+	}
+
+	return Discount(promo, service.PriceMoney()), nil
+}
+
+// Discount returns what a promo code takes off a price.
+func Discount(promo *models.PromoCode, price money.Money) money.Money {
+	var off money.Money
+	switch promo.Kind {
+	case models.PromoPercent:
+		off = price.Percent(int(promo.Value))
+	case models.PromoFixed:
+		off = money.New(promo.Value, price.Currency)
+	default:
+		return money.New(0, price.Currency)
+	}
+	if off.Amount > price.Amount {
+		return price
+	}
+	return off
+}
+
+// Apply discounts a new booking with a promo code, holding the code's row
+// until tx ends so that its limits hold against concurrent bookings. The
+// booking's ClientID, ProviderID, Price and Currency must be set. Record
+// the redemption once the booking is created.
+func Apply(tx *gorm.DB, code string, booking *models.Booking, service *models.Service, now time.Time) (*models.PromoCode, error) {
+	promo, err := Find(tx.Clauses(clause.Locking{Strength: "UPDATE"}), booking.ProviderID, code)
+	if err != nil {
+		return nil, err
+	}
+	discount, err := Check(tx, promo, booking.ClientID, service, now)
+	if err != nil {
+		return nil, err
+	}
+	booking.Discount = discount.Amount
+	booking.PromoCode = promo.Code
+	return promo, nil
+}
+
+// Record tracks that a created booking redeemed a promo code.
+func Record(tx *gorm.DB, promo *models.PromoCode, booking *models.Booking) error {
+	return tx.Create(&models.PromoRedemption{
+		PromoCodeID: promo.ID,
+		BookingID:   booking.ID,
+		ClientID:    booking.ClientID,
+		Amount:      booking.Discount,
+	}).Error
+}
+
+// Redemptions counts the bookings that used a promo code and still stand.
+func Redemptions(db *gorm.DB, promoID uint) (int64, error) {
+	var n int64
+	err := redemptions(db, promoID).Count(&n).Error
+	return n, err
+}
+
+func redemptions(db *gorm.DB, promoID uint) *gorm.DB {
+	return live(db.Model(&models.PromoRedemption{}).
+		Joins("JOIN bookings ON bookings.id = promo_redemptions.booking_id").
+		Where("promo_redemptions.promo_code_id = ?", promoID))
+}
+
+// live leaves out bookings that were cancelled or expired.
+func live(db *gorm.DB) *gorm.DB {
+	return db.Where("bookings.status NOT IN ? AND bookings.deleted_at IS NULL",
+		[]models.BookingStatus{models.StatusCancelled, models.StatusExpired})
+}
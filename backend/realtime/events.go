@@ -0,0 +1,97 @@
+package realtime
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Event types
+const (
+	EventBookingCreated        = "booking.created"
+	EventBookingStatusChanged  = "booking.status_changed"
+	EventBookingRescheduled    = "booking.rescheduled"
+	EventBookingPaymentUpdated = "booking.payment_updated"
+)
+
+// Channel is the Postgres NOTIFY channel events are announced on.
+const Channel = "booking_events"
+
+// BookingUpdate is the payload of a booking event.
+type BookingUpdate struct {
+	BookingID      uint                        `json:"booking_id"`
+	Status         models.BookingStatus        `json:"status"`
+	PreviousStatus models.BookingStatus        `json:"previous_status,omitempty"`
+	Date           time.Time                   `json:"date"`
+	StartTime      string                      `json:"start_time"`
+	EndTime        string                      `json:"end_time"`
+	Sequence       int                         `json:"sequence"`
+	PaymentStatus  models.BookingPaymentStatus `json:"payment_status"`
+	RefundStatus   models.BookingRefundStatus  `json:"refund_status"`
+	TipAmount      int64                       `json:"tip_amount"`
+}
+
+// Publish records a booking event and announces it to every backend
+// instance. The announcement is sent when the transaction commits, so
+// listeners never see an event before it can be read back.
+func Publish(db *gorm.DB, eventType string, booking *models.Booking, previous models.BookingStatus) error {
+	payload, err := json.Marshal(BookingUpdate{
+		BookingID:      booking.ID,
+		Status:         booking.Status,
+		PreviousStatus: previous,
+		Date:           booking.Date,
+		StartTime:      booking.StartTime,
+		EndTime:        booking.EndTime,
+		Sequence:       booking.Sequence,
+		PaymentStatus:  booking.PaymentStatus,
+		RefundStatus:   booking.RefundStatus,
+		TipAmount:      booking.TipAmount,
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		event := models.BookingEvent{
+			BookingID: booking.ID,
+			Type:      eventType,
+			Payload:   string(payload),
+		}
+		if err := tx.Model(&models.ServiceProvider{}).Select("user_id").Where("id = ?", booking.ProviderID).Row().Scan(&event.ProviderUserID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Client{}).Select("user_id").Where("id = ?", booking.ClientID).Row().Scan(&event.ClientUserID); err != nil {
+			return err
+		}
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+		return tx.Exec("SELECT pg_notify(?, ?)", Channel, strconv.FormatUint(uint64(event.ID), 10)).Error
+	})
+}
+
+// Encode renders an event as sent to clients.
+func Encode(event *models.BookingEvent) ([]byte, error) {
+	return json.Marshal(struct {
+		ID        uint            `json:"id"`
+		Type      string          `json:"type"`
+		BookingID uint            `json:"booking_id"`
+		Data      json.RawMessage `json:"data"`
+		CreatedAt time.Time       `json:"created_at"`
+	}{event.ID, event.Type, event.BookingID, json.RawMessage(event.Payload), event.CreatedAt})
+}
+
+// Since returns up to limit events for a user after the given event ID,
+// oldest first.
+func Since(db *gorm.DB, userID, afterID uint, limit int) ([]models.BookingEvent, error) {
+	var events []models.BookingEvent
+	err := db.Where("id > ? AND (provider_user_id = ? OR client_user_id = ?)", afterID, userID, userID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,89 @@
+package realtime
+
+import (
+	"sync"
+
+	"pluralink/backend/models"
+)
+
+// subscriptionBuffer is how many events a slow client may fall behind
+// before it is disconnected and has to resume from its last event.
+const subscriptionBuffer = 64
+
+// DefaultHub fans out events to the clients connected to this instance.
+var DefaultHub = NewHub()
+
+// Hub tracks the open streams on this instance by user.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[uint]map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: map[uint]map[*Subscription]struct{}{}}
+}
+
+// Subscription receives the events of one user. C is closed when the
+// subscription ends, including when the client fell too far behind.
+type Subscription struct {
+	UserID uint
+	C      chan *models.BookingEvent
+
+	hub  *Hub
+	once sync.Once
+}
+
+func (h *Hub) Subscribe(userID uint) *Subscription {
+	s := &Subscription{
+		UserID: userID,
+		C:      make(chan *models.BookingEvent, subscriptionBuffer),
+		hub:    h,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = map[*Subscription]struct{}{}
+	}
+	h.subs[userID][s] = struct{}{}
+	return s
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *Subscription) closeLocked() {
+	s.once.Do(func() {
+		if set := s.hub.subs[s.UserID]; set != nil {
+			delete(set, s)
+			if len(set) == 0 {
+				delete(s.hub.subs, s.UserID)
+			}
+		}
+		close(s.C)
+	})
+}
+
+// Broadcast hands an event to the streams of the provider and client
+// involved. It never blocks: subscribers that are full are dropped.
+func (h *Hub) Broadcast(event *models.BookingEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, userID := range []uint{event.ProviderUserID, event.ClientUserID} {
+		if i == 1 && userID == event.ProviderUserID {
+			break
+		}
+		for s := range h.subs[userID] {
+			select {
+			case s.C <- event:
+			default:
+				s.closeLocked()
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package realtime
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"pluralink/backend/models"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const reconnectDelay = 5 * time.Second
+
+// Listener receives event announcements from Postgres on a dedicated
+// connection and broadcasts the events to the local hub. After losing the
+// connection it catches up on events committed in the meantime.
+type Listener struct {
+	DB  *gorm.DB
+	DSN string
+	Hub *Hub
+
+	lastID uint
+}
+
+func NewListener(db *gorm.DB, dsn string, hub *Hub) *Listener {
+	return &Listener{DB: db, DSN: dsn, Hub: hub}
+}
+
+// Run listens until ctx is done, reconnecting as needed.
+func (l *Listener) Run(ctx context.Context) {
+	// Only events from now on; clients resume older ones themselves
+	if err := l.DB.Model(&models.BookingEvent{}).Select("COALESCE(MAX(id), 0)").Row().Scan(&l.lastID); err != nil {
+		log.Println("Failed to read last booking event:", err)
+	}
+
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Booking event listener disconnected, retrying in %s: %v", reconnectDelay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func (l *Listener) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, l.DSN)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+	if err := l.catchUp(); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		id, err := strconv.ParseUint(n.Payload, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		var event models.BookingEvent
+		if err := l.DB.First(&event, id).Error; err != nil {
+			log.Printf("Failed to load booking event %d: %v", id, err)
+			continue
+		}
+		l.deliver(&event)
+	}
+}
+
+func (l *Listener) catchUp() error {
+	var events []models.BookingEvent
+	if err := l.DB.Where("id > ?", l.lastID).Order("id ASC").Find(&events).Error; err != nil {
+		return err
+	}
+	for i := range events {
+		l.deliver(&events[i])
+	}
+	return nil
+}
+
+func (l *Listener) deliver(event *models.BookingEvent) {
+	if event.ID > l.lastID {
+		l.lastID = event.ID
+	}
+	l.Hub.Broadcast(event)
+}
@@ -0,0 +1,217 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pluralink/backend/jobs"
+	"pluralink/backend/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TypeSendReminder = "reminders.send"
+
+	MaxOffsets       = 5
+	MaxOffsetMinutes = 30 * 24 * 60
+)
+
+// ErrNoChannel is returned by a notifier that has no way to reach anyone
+// in the booking.
+var ErrNoChannel = errors.New("no channel reaches anyone in the booking")
+
+// Notifier hands a reminder over for delivery to the people involved in a
+// booking. The reminder is queued once NotifyReminder returns; the notifier
+// reports how delivery went with Delivered and Undelivered.
+type Notifier interface {
+	NotifyReminder(ctx context.Context, booking *models.Booking, reminder *models.Reminder) error
+}
+
+// LogNotifier only logs reminders. It is the default until a real delivery
+// channel is configured.
+type LogNotifier struct {
+	DB *gorm.DB
+}
+
+func (n LogNotifier) NotifyReminder(ctx context.Context, booking *models.Booking, reminder *models.Reminder) error {
+	log.Printf("Reminder %d: booking %d starts at %s", reminder.ID, booking.ID, reminder.StartsAt.Format(time.RFC3339))
+	return Delivered(n.DB, reminder.ID, time.Now())
+}
+
+// Delivered marks a queued reminder sent once it reached someone on any
+// channel, including one that had given up on another.
+func Delivered(db *gorm.DB, reminderID uint, at time.Time) error {
+	return db.Model(&models.Reminder{}).
+		Where("id = ? AND status IN ?", reminderID, []models.ReminderStatus{models.ReminderQueued, models.ReminderFailed}).
+		Updates(map[string]interface{}{"status": models.ReminderSent, "sent_at": at, "error": ""}).Error
+}
+
+// Undelivered marks a queued reminder failed when a channel gave up on it.
+// Another channel delivering it later still marks it sent.
+func Undelivered(db *gorm.DB, reminderID uint, reason string) error {
+	return db.Model(&models.Reminder{}).
+		Where("id = ? AND status = ?", reminderID, models.ReminderQueued).
+		Updates(map[string]interface{}{"status": models.ReminderFailed, "error": reason}).Error
+}
+
+type reminderPayload struct {
+	ReminderID uint `json:"reminder_id"`
+}
+
+// Register wires reminder delivery into the job runner.
+func Register(r *jobs.Runner, notifier Notifier) {
+	r.Register(TypeSendReminder, send(r.DB, notifier))
+}
+
+// Offsets returns a provider's reminder offsets in minutes, largest first.
+func Offsets(provider *models.ServiceProvider) []int {
+	var offsets []int
+	for _, part := range strings.Split(provider.ReminderOffsets, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err == nil && n > 0 {
+			offsets = append(offsets, n)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+	return offsets
+}
+
+// FormatOffsets validates offsets and encodes them for storage.
+func FormatOffsets(offsets []int) (string, error) {
+	if len(offsets) > MaxOffsets {
+		return "", fmt.Errorf("at most %d reminders are allowed", MaxOffsets)
+	}
+
+	seen := map[int]bool{}
+	var parts []string
+	for _, n := range offsets {
+		if n <= 0 || n > MaxOffsetMinutes {
+			return "", fmt.Errorf("reminder offsets must be between 1 and %d minutes", MaxOffsetMinutes)
+		}
+		if !seen[n] {
+			seen[n] = true
+			parts = append(parts, strconv.Itoa(n))
+		}
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// Schedule replaces any outstanding reminders of a booking with fresh ones
+// for its current start time. The booking's Provider must be loaded.
+// Offsets that already passed are not scheduled.
+func Schedule(db *gorm.DB, booking *models.Booking) error {
+	start := booking.StartsAt(booking.Provider.Location())
+	now := time.Now()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := cancelOutstanding(tx, booking.ID); err != nil {
+			return err
+		}
+
+		for _, offset := range Offsets(&booking.Provider) {
+			sendAt := start.Add(-time.Duration(offset) * time.Minute)
+			if !sendAt.After(now) {
+				continue
+			}
+
+			reminder := models.Reminder{
+				BookingID:     booking.ID,
+				OffsetMinutes: offset,
+				StartsAt:      start,
+				SendAt:        sendAt,
+				Status:        models.ReminderScheduled,
+			}
+			if err := tx.Create(&reminder).Error; err != nil {
+				return err
+			}
+			if err := jobs.EnqueueAt(tx, TypeSendReminder, reminderPayload{ReminderID: reminder.ID}, sendAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Cancel marks every outstanding reminder of a booking as cancelled.
+func Cancel(db *gorm.DB, bookingID uint) error {
+	return cancelOutstanding(db, bookingID)
+}
+
+func cancelOutstanding(db *gorm.DB, bookingID uint) error {
+	return db.Model(&models.Reminder{}).
+		Where("booking_id = ? AND status = ?", bookingID, models.ReminderScheduled).
+		Update("status", models.ReminderCancelled).Error
+}
+
+func send(db *gorm.DB, notifier Notifier) jobs.Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		var p reminderPayload
+		if err := jobs.Decode(job, &p); err != nil {
+			return err
+		}
+
+		var reminder models.Reminder
+		if err := db.First(&reminder, p.ReminderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if reminder.Status != models.ReminderScheduled {
+			return nil
+		}
+
+		var booking models.Booking
+		if err := db.Preload("Client").Preload("Client.User").
+			Preload("Provider").Preload("Provider.User").
+			Preload("Service").
+			First(&booking, reminder.BookingID).Error; err != nil {
+			return err
+		}
+
+		if !booking.Active() {
+			return skip(db, &reminder, "booking is "+string(booking.Status))
+		}
+		if !booking.StartsAt(booking.Provider.Location()).Equal(reminder.StartsAt) {
+			return skip(db, &reminder, "booking was rescheduled")
+		}
+
+		reminder.Attempts++
+		// Queued before it is handed over, as a notifier may report it
+		// delivered right away
+		reminder.Status = models.ReminderQueued
+		reminder.Error = ""
+		if err := db.Save(&reminder).Error; err != nil {
+			return err
+		}
+		err := notifier.NotifyReminder(ctx, &booking, &reminder)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrNoChannel) {
+			return Undelivered(db, reminder.ID, err.Error())
+		}
+		reminder.Status = models.ReminderScheduled
+		reminder.Error = err.Error()
+		if job.Attempts >= job.MaxAttempts {
+			reminder.Status = models.ReminderFailed
+		}
+		if saveErr := db.Save(&reminder).Error; saveErr != nil {
+			log.Printf("Failed to record reminder %d failure: %v", reminder.ID, saveErr)
+		}
+		return err
+	}
+}
+
+func skip(db *gorm.DB, reminder *models.Reminder, reason string) error {
+	reminder.Status = models.ReminderSkipped
+	reminder.Error = reason
+	return db.Save(reminder).Error
+}
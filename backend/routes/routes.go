@@ -5,6 +5,7 @@ import (
 	"pluralink/backend/handlers"
 	"pluralink/backend/middleware"
 	"pluralink/backend/models"
+	"pluralink/backend/realtime"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,6 +25,25 @@ func SetupRoutes() *gin.Engine {
 	availabilityHandler := handlers.NewAvailabilityHandler(database.DB)
 	reviewHandler := handlers.NewReviewHandler(database.DB)
 	searchHandler := handlers.NewSearchHandler(database.DB)
+	calendarHandler := handlers.NewCalendarHandler(database.DB)
+	externalCalendarHandler := handlers.NewExternalCalendarHandler(database.DB)
+	caldavHandler := handlers.NewCalDAVHandler(database.DB)
+	reminderHandler := handlers.NewReminderHandler(database.DB)
+	notificationHandler := handlers.NewNotificationHandler(database.DB)
+	webhookHandler := handlers.NewWebhookHandler(database.DB)
+	messageHandler := handlers.NewMessageHandler(database.DB)
+	uploadHandler := handlers.NewUploadHandler(database.DB)
+	portfolioHandler := handlers.NewPortfolioHandler(database.DB)
+	paymentHandler := handlers.NewPaymentHandler(database.DB)
+	earningsHandler := handlers.NewEarningsHandler(database.DB)
+	taxHandler := handlers.NewTaxHandler(database.DB)
+	promoHandler := handlers.NewPromoHandler(database.DB)
+	giftCardHandler := handlers.NewGiftCardHandler(database.DB)
+	loyaltyHandler := handlers.NewLoyaltyHandler(database.DB)
+	packageHandler := handlers.NewPackageHandler(database.DB)
+	membershipHandler := handlers.NewMembershipHandler(database.DB)
+	ledgerHandler := handlers.NewLedgerHandler(database.DB)
+	streamHandler := handlers.NewStreamHandler(database.DB, realtime.DefaultHub)
 
 	// Public routes
 	api := r.Group("/api")
@@ -51,7 +71,23 @@ func SetupRoutes() *gin.Engine {
 			providers.GET("/:id", providerHandler.GetProvider)
 			providers.GET("/:id/availability", availabilityHandler.GetAvailabilities)
 			providers.GET("/:id/reviews", providerHandler.GetProviderReviews)
+			providers.GET("/:id/portfolio", portfolioHandler.GetProviderPortfolio)
+			providers.GET("/:id/loyalty", loyaltyHandler.GetProviderLoyalty)
+			providers.GET("/:id/packages", packageHandler.GetProviderPackages)
+			providers.GET("/:id/membership-plans", membershipHandler.GetProviderMembershipPlans)
 		}
+
+		// Calendar subscription (authenticated by the token in the URL)
+		api.GET("/calendar/feed/:token", calendarHandler.ServeFeed)
+
+		// Stored files (private ones need a signed URL)
+		api.GET("/files/*key", uploadHandler.ServeFile)
+
+		// Payment gateway webhooks (authenticated by their signature)
+		api.POST("/payments/webhook", paymentHandler.ReceiveWebhook)
+
+		// Gift card balance lookup, for whoever holds the code
+		api.POST("/gift-cards/balance", giftCardHandler.GetGiftCardBalance)
 	}
 
 	// Protected routes
@@ -96,9 +132,138 @@ func SetupRoutes() *gin.Engine {
 			bookings.GET("/:id", bookingHandler.GetBooking)
 			bookings.POST("", bookingHandler.CreateBooking)
 			bookings.PUT("/:id/reschedule", bookingHandler.RescheduleBooking)
+			bookings.GET("/events", streamHandler.StreamBookings)
+			bookings.PUT("/:id/confirm", middleware.RequireRole(models.RoleProvider), bookingHandler.ConfirmBooking)
+			bookings.GET("/:id/reminders", reminderHandler.GetBookingReminders)
+			bookings.GET("/:id/messages", messageHandler.GetMessages)
+			bookings.POST("/:id/messages", messageHandler.SendMessage)
+			bookings.POST("/:id/messages/read", messageHandler.MarkMessagesRead)
+			bookings.GET("/:id/messages/:message_id/attachments/:attachment_id", messageHandler.GetAttachment)
+			bookings.GET("/:id/payment", paymentHandler.GetPayment)
+			bookings.POST("/:id/payment", paymentHandler.StartPayment)
+			bookings.POST("/:id/payment/confirm", paymentHandler.ConfirmPayment)
+			bookings.POST("/:id/payment/refresh", paymentHandler.RefreshPayment)
+			bookings.POST("/:id/refund/retry", middleware.RequireRole(models.RoleProvider), paymentHandler.RetryRefund)
+			bookings.POST("/:id/tip", paymentHandler.StartTip)
+			bookings.POST("/:id/tip/confirm", paymentHandler.ConfirmTip)
+			bookings.GET("/:id/receipt", paymentHandler.GetReceipt)
 			bookings.DELETE("/:id", bookingHandler.CancelBooking)
 		}
 
+		// Service payment settings (provider only)
+		services := protected.Group("/services")
+		services.Use(middleware.RequireRole(models.RoleProvider))
+		{
+			services.PUT("/:id/payment-policy", paymentHandler.UpdatePaymentPolicy)
+		}
+
+		// Refund policy (provider only)
+		refundPolicy := protected.Group("/refund-policy")
+		refundPolicy.Use(middleware.RequireRole(models.RoleProvider))
+		{
+			refundPolicy.GET("", paymentHandler.GetRefundPolicy)
+			refundPolicy.PUT("", paymentHandler.UpdateRefundPolicy)
+		}
+
+		// Earnings (provider only)
+		protected.GET("/earnings", middleware.RequireRole(models.RoleProvider), earningsHandler.GetEarnings)
+
+		// What the platform owes providers and has paid out (provider only)
+		ledger := protected.Group("/ledger")
+		ledger.Use(middleware.RequireRole(models.RoleProvider))
+		{
+			ledger.GET("/balance", ledgerHandler.GetLedgerBalance)
+			ledger.GET("/statement", ledgerHandler.GetLedgerStatement)
+		}
+		payouts := protected.Group("/payouts")
+		payouts.Use(middleware.RequireRole(models.RoleProvider))
+		{
+			payouts.GET("", ledgerHandler.GetPayouts)
+			payouts.GET("/:id/statement", ledgerHandler.GetPayoutStatement)
+		}
+
+		// Taxes (provider only)
+		taxes := protected.Group("/taxes")
+		taxes.Use(middleware.RequireRole(models.RoleProvider))
+		{
+			taxes.GET("/rule", taxHandler.GetTaxRule)
+			taxes.GET("/summary", taxHandler.GetTaxSummary)
+		}
+
+		// Promo codes (provider only), checked by clients before booking
+		protected.POST("/promo-codes/check", middleware.RequireRole(models.RoleClient), promoHandler.CheckPromoCode)
+		promoCodes := protected.Group("/promo-codes")
+		promoCodes.Use(middleware.RequireRole(models.RoleProvider))
+		{
+			promoCodes.GET("", promoHandler.GetPromoCodes)
+			promoCodes.POST("", promoHandler.CreatePromoCode)
+			promoCodes.PUT("/:id", promoHandler.UpdatePromoCode)
+			promoCodes.DELETE("/:id", promoHandler.DeletePromoCode)
+			promoCodes.GET("/:id/redemptions", promoHandler.GetPromoRedemptions)
+		}
+
+		// Gift cards, issued by providers or bought by clients
+		giftCards := protected.Group("/gift-cards")
+		{
+			giftCards.GET("", middleware.RequireRole(models.RoleProvider), giftCardHandler.GetGiftCards)
+			giftCards.POST("", middleware.RequireRole(models.RoleProvider), giftCardHandler.IssueGiftCard)
+			giftCards.GET("/:id", middleware.RequireRole(models.RoleProvider), giftCardHandler.GetGiftCard)
+			giftCards.GET("/purchased", middleware.RequireRole(models.RoleClient), giftCardHandler.GetPurchasedGiftCards)
+			giftCards.POST("/purchase", middleware.RequireRole(models.RoleClient), giftCardHandler.PurchaseGiftCard)
+			giftCards.POST("/purchase/:id/confirm", middleware.RequireRole(models.RoleClient), giftCardHandler.ConfirmGiftCardPurchase)
+			giftCards.POST("/purchase/:id/refresh", middleware.RequireRole(models.RoleClient), giftCardHandler.RefreshGiftCardPurchase)
+		}
+
+		// Loyalty programs, run by providers, with points held by clients
+		loyalty := protected.Group("/loyalty")
+		{
+			loyalty.GET("/program", middleware.RequireRole(models.RoleProvider), loyaltyHandler.GetLoyaltyProgram)
+			loyalty.PUT("/program", middleware.RequireRole(models.RoleProvider), loyaltyHandler.UpdateLoyaltyProgram)
+			loyalty.POST("/rewards", middleware.RequireRole(models.RoleProvider), loyaltyHandler.CreateLoyaltyReward)
+			loyalty.PUT("/rewards/:id", middleware.RequireRole(models.RoleProvider), loyaltyHandler.UpdateLoyaltyReward)
+			loyalty.DELETE("/rewards/:id", middleware.RequireRole(models.RoleProvider), loyaltyHandler.DeleteLoyaltyReward)
+			loyalty.GET("/members", middleware.RequireRole(models.RoleProvider), loyaltyHandler.GetLoyaltyMembers)
+			loyalty.GET("/accounts", middleware.RequireRole(models.RoleClient), loyaltyHandler.GetLoyaltyAccounts)
+			loyalty.GET("/accounts/:provider_id", middleware.RequireRole(models.RoleClient), loyaltyHandler.GetLoyaltyAccount)
+		}
+
+		// Prepaid packages of sessions, sold by providers to clients
+		packages := protected.Group("/packages")
+		{
+			packages.GET("", middleware.RequireRole(models.RoleProvider), packageHandler.GetPackages)
+			packages.POST("", middleware.RequireRole(models.RoleProvider), packageHandler.CreatePackage)
+			packages.PUT("/:id", middleware.RequireRole(models.RoleProvider), packageHandler.UpdatePackage)
+			packages.DELETE("/:id", middleware.RequireRole(models.RoleProvider), packageHandler.DeletePackage)
+			packages.GET("/sold", middleware.RequireRole(models.RoleProvider), packageHandler.GetSoldPackages)
+			packages.GET("/purchased", middleware.RequireRole(models.RoleClient), packageHandler.GetPurchasedPackages)
+			packages.POST("/purchase", middleware.RequireRole(models.RoleClient), packageHandler.PurchasePackage)
+			packages.POST("/purchase/:id/confirm", middleware.RequireRole(models.RoleClient), packageHandler.ConfirmPackagePurchase)
+			packages.POST("/purchase/:id/refresh", middleware.RequireRole(models.RoleClient), packageHandler.RefreshPackagePurchase)
+		}
+
+		// Monthly membership plans, offered by providers
+		membershipPlans := protected.Group("/membership-plans")
+		membershipPlans.Use(middleware.RequireRole(models.RoleProvider))
+		{
+			membershipPlans.GET("", membershipHandler.GetMembershipPlans)
+			membershipPlans.POST("", membershipHandler.CreateMembershipPlan)
+			membershipPlans.PUT("/:id", membershipHandler.UpdateMembershipPlan)
+			membershipPlans.DELETE("/:id", membershipHandler.DeleteMembershipPlan)
+			membershipPlans.GET("/members", membershipHandler.GetMembers)
+		}
+
+		// Clients' memberships
+		memberships := protected.Group("/memberships")
+		memberships.Use(middleware.RequireRole(models.RoleClient))
+		{
+			memberships.GET("", membershipHandler.GetMemberships)
+			memberships.POST("", membershipHandler.JoinMembership)
+			memberships.POST("/:id/pay", membershipHandler.PayMembership)
+			memberships.POST("/:id/payments/:payment_id/confirm", membershipHandler.ConfirmMembershipPayment)
+			memberships.POST("/:id/payments/:payment_id/refresh", membershipHandler.RefreshMembershipPayment)
+			memberships.POST("/:id/cancel", membershipHandler.CancelMembership)
+		}
+
 		// Review routes
 		reviews := protected.Group("/reviews")
 		{
@@ -106,6 +271,89 @@ func SetupRoutes() *gin.Engine {
 			reviews.GET("/provider/:id", reviewHandler.GetProviderReviews)
 			reviews.GET("/client/:id", reviewHandler.GetClientReviews)
 		}
+
+		// Reminder settings (provider only)
+		reminderSettings := protected.Group("/reminders")
+		reminderSettings.Use(middleware.RequireRole(models.RoleProvider))
+		{
+			reminderSettings.GET("/settings", reminderHandler.GetSettings)
+			reminderSettings.PUT("/settings", reminderHandler.UpdateSettings)
+		}
+
+		// Notifications
+		notifications := protected.Group("/notifications")
+		{
+			notifications.GET("", notificationHandler.GetNotifications)
+			notifications.POST("/read", notificationHandler.MarkRead)
+			notifications.GET("/preferences", notificationHandler.GetPreferences)
+			notifications.PUT("/preferences", notificationHandler.UpdatePreferences)
+			notifications.POST("/devices", notificationHandler.RegisterDevice)
+			notifications.DELETE("/devices/:token", notificationHandler.UnregisterDevice)
+		}
+
+		// Calendar feed management
+		calendar := protected.Group("/calendar")
+		{
+			calendar.GET("/feed", calendarHandler.GetFeed)
+			calendar.POST("/feed", calendarHandler.RegenerateFeed)
+			calendar.DELETE("/feed", calendarHandler.RevokeFeed)
+		}
+
+		// External calendar imports (provider only)
+		externalCalendars := protected.Group("/external-calendars")
+		externalCalendars.Use(middleware.RequireRole(models.RoleProvider))
+		{
+			externalCalendars.GET("", externalCalendarHandler.GetExternalCalendars)
+			externalCalendars.POST("", externalCalendarHandler.CreateExternalCalendar)
+			externalCalendars.GET("/busy", externalCalendarHandler.GetBusyBlocks)
+			externalCalendars.POST("/:id/sync", externalCalendarHandler.SyncExternalCalendar)
+			externalCalendars.DELETE("/:id", externalCalendarHandler.DeleteExternalCalendar)
+		}
+
+		// CalDAV two-way sync (provider only)
+		caldav := protected.Group("/caldav")
+		caldav.Use(middleware.RequireRole(models.RoleProvider))
+		{
+			caldav.GET("", caldavHandler.GetAccount)
+			caldav.PUT("", caldavHandler.ConnectAccount)
+			caldav.POST("/sync", caldavHandler.SyncAccount)
+			caldav.DELETE("", caldavHandler.DisconnectAccount)
+		}
+
+		// Uploads
+		uploads := protected.Group("/uploads")
+		{
+			uploads.GET("", uploadHandler.GetUploads)
+			uploads.POST("", uploadHandler.CreateUpload)
+			uploads.GET("/:id", uploadHandler.GetUpload)
+			uploads.DELETE("/:id", uploadHandler.DeleteUpload)
+		}
+
+		// Portfolio gallery (provider only)
+		portfolio := protected.Group("/portfolio")
+		portfolio.Use(middleware.RequireRole(models.RoleProvider))
+		{
+			portfolio.GET("", portfolioHandler.GetPortfolio)
+			portfolio.POST("", portfolioHandler.CreatePortfolioItem)
+			portfolio.PUT("/order", portfolioHandler.ReorderPortfolio)
+			portfolio.PUT("/:id", portfolioHandler.UpdatePortfolioItem)
+			portfolio.DELETE("/:id", portfolioHandler.DeletePortfolioItem)
+		}
+
+		// Outgoing webhooks (provider only)
+		webhooks := protected.Group("/webhooks")
+		webhooks.Use(middleware.RequireRole(models.RoleProvider))
+		{
+			webhooks.GET("", webhookHandler.GetWebhooks)
+			webhooks.POST("", webhookHandler.CreateWebhook)
+			webhooks.GET("/event-types", webhookHandler.GetWebhookEventTypes)
+			webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+			webhooks.POST("/:id/rotate-secret", webhookHandler.RotateWebhookSecret)
+			webhooks.POST("/:id/test", webhookHandler.TestWebhook)
+			webhooks.GET("/:id/deliveries", webhookHandler.GetWebhookDeliveries)
+			webhooks.POST("/:id/deliveries/:delivery_id/redeliver", webhookHandler.RedeliverWebhook)
+		}
 	}
 
 	return r
@@ -0,0 +1,77 @@
+// Package secrets encrypts credentials we keep for third-party services,
+// such as CalDAV passwords, so they are not readable from the database or
+// its backups alone.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// prefix marks sealed values, so they can be told from plaintext
+const prefix = "v1:"
+
+// ErrNotSealed is returned when opening a value that was not sealed.
+var ErrNotSealed = errors.New("secrets: value is not sealed")
+
+// Default is the box configured at startup.
+var Default *Box
+
+// Box seals values with AES-256-GCM.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox returns a box keyed by the SHA-256 of key.
+func NewBox(key string) (*Box, error) {
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts a value under a fresh nonce.
+func (b *Box) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value from Seal.
+func (b *Box) Open(sealed string) (string, error) {
+	if !IsSealed(sealed) {
+		return "", ErrNotSealed
+	}
+	data, err := base64.RawStdEncoding.DecodeString(sealed[len(prefix):])
+	if err != nil {
+		return "", err
+	}
+	n := b.aead.NonceSize()
+	if len(data) < n {
+		return "", errors.New("secrets: sealed value is too short")
+	}
+	plaintext, err := b.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// IsSealed reports whether a value came from Seal.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
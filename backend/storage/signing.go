@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSigner is the URL signer configured at startup.
+var DefaultSigner *Signer
+
+// Signer builds download URLs for files served by GET /api/files/*key.
+// URLs of private files carry an expiry and an HMAC over key and expiry.
+type Signer struct {
+	Key     []byte
+	BaseURL string        // Public URL of the API server
+	TTL     time.Duration // Lifetime of signed URLs
+}
+
+func NewSigner(key, baseURL string, ttl time.Duration) *Signer {
+	return &Signer{Key: []byte(key), BaseURL: strings.TrimRight(baseURL, "/"), TTL: ttl}
+}
+
+// URL returns where a file can be downloaded: a plain URL for public files
+// and one that expires after the signer's TTL for private files.
+func (s *Signer) URL(key string) string {
+	if IsPublic(key) {
+		return s.fileURL(key)
+	}
+	return s.SignedURL(key, time.Now().Add(s.TTL))
+}
+
+// SignedURL returns a URL for key that is valid until expires.
+func (s *Signer) SignedURL(key string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", exp)
+	q.Set("signature", s.signature(key, exp))
+	return s.fileURL(key) + "?" + q.Encode()
+}
+
+// Verify checks the expires and signature query values of a request for
+// key at time now.
+func (s *Signer) Verify(key, expires, signature string, now time.Time) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > exp {
+		return false
+	}
+	want := s.signature(key, expires)
+	return hmac.Equal([]byte(want), []byte(signature))
+}
+
+func (s *Signer) signature(key, expires string) string {
+	mac := hmac.New(sha256.New, s.Key)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Signer) fileURL(key string) string {
+	return s.BaseURL + "/api/files/" + escapePath(key)
+}
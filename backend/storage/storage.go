@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned for keys that hold no file.
+var ErrNotFound = errors.New("file not found")
+
+// Store keeps files by key. Keys are slash-separated relative paths such
+// as "public/portfolio/<sha256>.jpg".
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (*File, error)
+	Delete(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// File is an open stored file. Callers must close Body.
+type File struct {
+	Body        io.ReadCloser
+	Size        int64
+	ContentType string
+}
+
+// Default is the store configured at startup.
+var Default Store
+
+// Key prefixes decide who may download a file: public files are served to
+// anyone, private files only through a signed URL.
+const (
+	PublicPrefix  = "public/"
+	PrivatePrefix = "private/"
+)
+
+// IsPublic reports whether a key may be served without a signature.
+func IsPublic(key string) bool {
+	return strings.HasPrefix(key, PublicPrefix)
+}
+
+// ValidKey reports whether key is a clean relative path that cannot
+// escape the store.
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	// Decoders for the image types thumbnails are made of
+	_ "image/gif"
+	_ "image/png"
+)
+
+// maxThumbnailPixels bounds the images decoded for thumbnails.
+const maxThumbnailPixels = 50_000_000
+
+// ErrUnsupportedImage is returned for images that cannot be decoded here,
+// such as WebP. Callers fall back to the original image.
+var ErrUnsupportedImage = errors.New("unsupported image format")
+
+// Thumbnail describes a stored thumbnail and the image it was made from.
+type Thumbnail struct {
+	Key    string
+	Width  int // Of the original image
+	Height int
+}
+
+// MakeThumbnail reads the image at key and stores a JPEG no larger than
+// maxSide pixels on either side next to it. Thumbnails are named after the
+// source key, so existing ones are reused.
+func MakeThumbnail(ctx context.Context, store Store, key string, maxSide int) (Thumbnail, error) {
+	var thumb Thumbnail
+
+	file, err := store.Get(ctx, key)
+	if err != nil {
+		return thumb, err
+	}
+	defer file.Body.Close()
+
+	data, err := io.ReadAll(file.Body)
+	if err != nil {
+		return thumb, err
+	}
+
+	// Check the size first so a small file cannot claim a huge canvas
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return thumb, ErrUnsupportedImage
+		}
+		return thumb, err
+	}
+	if cfg.Width*cfg.Height > maxThumbnailPixels {
+		return thumb, fmt.Errorf("image of %dx%d pixels is too large for a thumbnail", cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return thumb, err
+	}
+	bounds := src.Bounds()
+	thumb.Width, thumb.Height = bounds.Dx(), bounds.Dy()
+	thumb.Key = ThumbnailKey(key, maxSide)
+
+	exists, err := store.Exists(ctx, thumb.Key)
+	if err != nil || exists {
+		return thumb, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleDown(src, maxSide), &jpeg.Options{Quality: 82}); err != nil {
+		return thumb, err
+	}
+	return thumb, store.Put(ctx, thumb.Key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "image/jpeg")
+}
+
+// ThumbnailKey is the key MakeThumbnail stores the thumbnail of key at.
+func ThumbnailKey(key string, maxSide int) string {
+	base := key
+	for i := len(key) - 1; i >= 0 && key[i] != '/'; i-- {
+		if key[i] == '.' {
+			base = key[:i]
+			break
+		}
+	}
+	return fmt.Sprintf("%s_%d.jpg", base, maxSide)
+}
+
+// scaleDown shrinks an image to fit within maxSide by averaging the source
+// pixels each target pixel covers. Smaller images are only flattened onto
+// white, as JPEG has no transparency.
+func scaleDown(src image.Image, maxSide int) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dw, dh := sw, sh
+	if sw > maxSide || sh > maxSide {
+		if sw >= sh {
+			dw, dh = maxSide, max(1, sh*maxSide/sw)
+		} else {
+			dw, dh = max(1, sw*maxSide/sh), maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0 := b.Min.Y + y*sh/dh
+		y1 := max(y0+1, b.Min.Y+(y+1)*sh/dh)
+		for x := 0; x < dw; x++ {
+			x0 := b.Min.X + x*sw/dw
+			x1 := max(x0+1, b.Min.X+(x+1)*sw/dw)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			// Composite the premultiplied average over white
+			white := (0xffff*n - a)
+			dst.Set(x, y, color.RGBA64{
+				R: uint16((r + white) / n),
+				G: uint16((g + white) / n),
+				B: uint16((bl + white) / n),
+				A: 0xffff,
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Content types accepted for each kind of upload, sniffed from the file
+// itself, with the extension stored files get.
+var (
+	ImageTypes = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+		"image/gif":  ".gif",
+		"image/webp": ".webp",
+	}
+	DocumentTypes = map[string]string{
+		"image/jpeg":      ".jpg",
+		"image/png":       ".png",
+		"application/pdf": ".pdf",
+	}
+	AttachmentTypes = map[string]string{
+		"image/jpeg":      ".jpg",
+		"image/png":       ".png",
+		"image/gif":       ".gif",
+		"image/webp":      ".webp",
+		"application/pdf": ".pdf",
+		"text/plain":      ".txt",
+	}
+)
+
+// Policy limits what an upload may be.
+type Policy struct {
+	MaxSize int64
+	Types   map[string]string
+}
+
+// ValidationError is an upload rejected by its policy. Its message can be
+// shown to users.
+type ValidationError struct {
+	msg string
+}
+
+func (e *ValidationError) Error() string { return e.msg }
+
+func invalid(format string, args ...interface{}) error {
+	return &ValidationError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Object describes a stored upload.
+type Object struct {
+	Key         string
+	FileName    string // As uploaded, without directories
+	ContentType string
+	Size        int64
+	SHA256      string
+}
+
+// Save checks an uploaded file against a policy and stores it under
+// prefix, named by the SHA-256 of its content. Identical files share one
+// key and are only stored once.
+func Save(ctx context.Context, store Store, prefix string, header *multipart.FileHeader, policy Policy) (Object, error) {
+	obj := Object{FileName: filepath.Base(header.Filename)}
+	if header.Size > policy.MaxSize {
+		return obj, invalid("%s is too large. Files may be at most %s", obj.FileName, formatSize(policy.MaxSize))
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		return obj, err
+	}
+	defer src.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return obj, err
+	}
+	if n == 0 {
+		return obj, invalid("%s is empty", obj.FileName)
+	}
+	obj.ContentType = strings.TrimSpace(strings.SplitN(http.DetectContentType(head[:n]), ";", 2)[0])
+	ext, ok := policy.Types[obj.ContentType]
+	if !ok {
+		return obj, invalid("%s is not an allowed file type. Use %s", obj.FileName, describeTypes(policy.Types))
+	}
+
+	// Spool to disk to hash the content before choosing the key
+	tmp, err := os.CreateTemp("", "pluralink-upload-*")
+	if err != nil {
+		return obj, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	content := io.MultiReader(bytes.NewReader(head[:n]), src)
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(content, policy.MaxSize+1))
+	if err != nil {
+		return obj, err
+	}
+	if size > policy.MaxSize {
+		return obj, invalid("%s is too large. Files may be at most %s", obj.FileName, formatSize(policy.MaxSize))
+	}
+
+	obj.Size = size
+	obj.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	obj.Key = strings.TrimRight(prefix, "/") + "/" + obj.SHA256 + ext
+
+	exists, err := store.Exists(ctx, obj.Key)
+	if err != nil || exists {
+		return obj, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return obj, err
+	}
+	return obj, store.Put(ctx, obj.Key, tmp, size, obj.ContentType)
+}
+
+func describeTypes(types map[string]string) string {
+	seen := map[string]bool{}
+	var names []string
+	for _, ext := range []string{".jpg", ".png", ".gif", ".webp", ".pdf", ".txt"} {
+		for _, e := range types {
+			if e == ext && !seen[ext] {
+				seen[ext] = true
+				names = append(names, strings.ToUpper(strings.TrimPrefix(ext, ".")))
+			}
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func formatSize(n int64) string {
+	if n >= 1<<20 {
+		return fmt.Sprintf("%d MB", n>>20)
+	}
+	if n >= 1<<10 {
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
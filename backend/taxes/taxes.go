@@ -0,0 +1,97 @@
+// Package taxes works out the sales tax or VAT on bookings from the rules
+// of the provider's region.
+package taxes
+
+import (
+	"errors"
+
+	"pluralink/backend/models"
+	"pluralink/backend/money"
+
+	"gorm.io/gorm"
+)
+
+// RuleFor returns the rule of the provider's state, or else of its
+// country, with its exemptions loaded. It returns nil when the provider's
+// region charges no tax.
+func RuleFor(db *gorm.DB, provider *models.ServiceProvider) (*models.TaxRule, error) {
+	country := models.TaxRegion(provider.Country)
+	if country == "" {
+		return nil, nil
+	}
+	var rule models.TaxRule
+	err := db.Preload("ExemptCategories").
+		Where("country = ? AND state IN ?", country, []string{models.TaxRegion(provider.State), ""}).
+		Order("state DESC").First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Amount returns the tax a rule puts on a price: on top of it, or the part
+// of it that is tax when the rule's prices include tax.
+func Amount(rule *models.TaxRule, price money.Money) money.Money {
+	if rule.Inclusive {
+		return price.IncludedPercentBasis(rule.Rate)
+	}
+	return price.PercentBasis(rule.Rate)
+}
+
+// Apply sets the tax of a new booking from its provider's rule, on its
+// Net price. The booking's Price, discounts and Currency must be set.
+// Services exempt from the rule are booked without tax.
+func Apply(db *gorm.DB, booking *models.Booking, provider *models.ServiceProvider, service *models.Service) error {
+	booking.Tax, booking.TaxName, booking.TaxRate, booking.TaxInclusive = 0, "", 0, false
+
+	rule, err := RuleFor(db, provider)
+	if err != nil || rule == nil || rule.Rate <= 0 || rule.Exempts(service.CategoryID) {
+		return err
+	}
+	booking.Tax = Amount(rule, money.New(booking.Net(), booking.Currency)).Amount
+	booking.TaxName = rule.Name
+	booking.TaxRate = rule.Rate
+	booking.TaxInclusive = rule.Inclusive
+	return nil
+}
+
+// Sale is the tax on something sold apart from bookings, such as a
+// package or a month of a membership.
+type Sale struct {
+	Tax       int64
+	Name      string
+	Rate      int
+	Inclusive bool
+}
+
+// OnSale returns the tax on a provider's sale at price of something used
+// to book services, none meaning any of the provider's. Bookings it covers
+// carry no tax of their own, so it is taxed when sold, unless every
+// service it covers is exempt.
+func OnSale(db *gorm.DB, provider *models.ServiceProvider, price money.Money, services []models.Service) (Sale, error) {
+	rule, err := RuleFor(db, provider)
+	if err != nil || rule == nil || rule.Rate <= 0 {
+		return Sale{}, err
+	}
+	exempt := len(services) > 0
+	for _, service := range services {
+		exempt = exempt && rule.Exempts(service.CategoryID)
+	}
+	if exempt {
+		return Sale{}, nil
+	}
+	return Sale{Tax: Amount(rule, price).Amount, Name: rule.Name, Rate: rule.Rate, Inclusive: rule.Inclusive}, nil
+}
+
+// Split divides an amount paid for a booking, tax included, into what it
+// is net of tax and the booking's tax in it.
+func Split(booking *models.Booking, amount int64) (net, tax int64) {
+	if booking.TaxRate <= 0 {
+		return amount, 0
+	}
+	tax = money.New(amount, booking.Currency).IncludedPercentBasis(booking.TaxRate).Amount
+	return amount - tax, tax
+}
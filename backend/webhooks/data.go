@@ -0,0 +1,111 @@
+package webhooks
+
+import (
+	"strings"
+	"time"
+
+	"pluralink/backend/models"
+)
+
+// BookingData is the data of booking events.
+type BookingData struct {
+	ID             uint                        `json:"id"`
+	Status         models.BookingStatus        `json:"status"`
+	PreviousStatus models.BookingStatus        `json:"previous_status,omitempty"`
+	StartsAt       time.Time                   `json:"starts_at"`
+	EndsAt         time.Time                   `json:"ends_at"`
+	TimeZone       string                      `json:"time_zone"`
+	Notes          string                      `json:"notes"`
+	PaymentStatus  models.BookingPaymentStatus `json:"payment_status"`
+	RefundStatus   models.BookingRefundStatus  `json:"refund_status"`
+	RefundAmount   int64                       `json:"refund_amount"`
+	TipAmount      int64                       `json:"tip_amount"`
+	Discount       int64                       `json:"discount"`
+	PromoCode      string                      `json:"promo_code,omitempty"`
+	RewardDiscount int64                       `json:"reward_discount"` // For loyalty points
+	MemberDiscount int64                       `json:"member_discount"`
+	Prepaid        int64                       `json:"prepaid"` // Covered by a package or membership
+	Tax            int64                       `json:"tax"`
+	Total          int64                       `json:"total"`            // Price less discounts, with tax
+	GiftCardAmount int64                       `json:"gift_card_amount"` // Of Total paid with gift cards
+	Service        ServiceData                 `json:"service"`
+	Client         ClientData                  `json:"client"`
+}
+
+type ServiceData struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Price    int64  `json:"price"` // When booked, in minor units of Currency
+	Currency string `json:"currency"`
+	Duration int    `json:"duration"`
+}
+
+type ClientData struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+// ReviewData is the data of review events.
+type ReviewData struct {
+	ID           uint      `json:"id"`
+	BookingID    uint      `json:"booking_id"`
+	ReviewerType string    `json:"reviewer_type"`
+	RevieweeType string    `json:"reviewee_type"`
+	Rating       int       `json:"rating"`
+	Comment      string    `json:"comment"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// NewBookingData describes a booking. Its Provider, Service and
+// Client.User must be loaded.
+func NewBookingData(b *models.Booking, previous models.BookingStatus) BookingData {
+	loc := b.Provider.Location()
+	return BookingData{
+		ID:             b.ID,
+		Status:         b.Status,
+		PreviousStatus: previous,
+		StartsAt:       b.StartsAt(loc),
+		EndsAt:         b.EndsAt(loc),
+		TimeZone:       loc.String(),
+		Notes:          b.Notes,
+		PaymentStatus:  b.PaymentStatus,
+		RefundStatus:   b.RefundStatus,
+		RefundAmount:   b.RefundAmount,
+		TipAmount:      b.TipAmount,
+		Discount:       b.Discount,
+		PromoCode:      b.PromoCode,
+		RewardDiscount: b.RewardDiscount,
+		MemberDiscount: b.MemberDiscount,
+		Prepaid:        b.Prepaid,
+		Tax:            b.Tax,
+		Total:          b.Total(),
+		GiftCardAmount: b.GiftCardAmount,
+		Service: ServiceData{
+			ID:       b.Service.ID,
+			Name:     b.Service.Name,
+			Price:    b.Price,
+			Currency: b.Currency,
+			Duration: b.Service.Duration,
+		},
+		Client: ClientData{
+			ID:    b.Client.ID,
+			Name:  strings.TrimSpace(b.Client.User.FirstName + " " + b.Client.User.LastName),
+			Email: b.Client.User.Email,
+			Phone: b.Client.User.Phone,
+		},
+	}
+}
+
+func NewReviewData(r *models.Review) ReviewData {
+	return ReviewData{
+		ID:           r.ID,
+		BookingID:    r.BookingID,
+		ReviewerType: string(r.ReviewerType),
+		RevieweeType: string(r.RevieweeType),
+		Rating:       r.Rating,
+		Comment:      r.Comment,
+		CreatedAt:    r.CreatedAt,
+	}
+}
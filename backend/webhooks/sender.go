@@ -0,0 +1,157 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/netguard"
+
+	"gorm.io/gorm"
+)
+
+// maxResponseBody is how much of an endpoint's response is kept.
+const maxResponseBody = 2048
+
+// Sender performs webhook deliveries and records their outcome.
+type Sender struct {
+	DB   *gorm.DB
+	HTTP *http.Client
+}
+
+// NewSender returns a sender whose client only reaches public addresses,
+// as providers choose where webhooks go.
+func NewSender(db *gorm.DB) *Sender {
+	client := netguard.NewClient(10 * time.Second)
+	// A redirect could silently send events elsewhere
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &Sender{DB: db, HTTP: client}
+}
+
+// Deliver makes one attempt at a delivery. It returns an error when the
+// attempt failed so the caller can retry; final marks the last attempt,
+// after which the delivery fails and counts against the endpoint.
+func (s *Sender) Deliver(ctx context.Context, deliveryID uint, final bool) error {
+	var delivery models.WebhookDelivery
+	if err := s.DB.First(&delivery, deliveryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if delivery.Status != models.WebhookPending {
+		return nil
+	}
+
+	var endpoint models.WebhookEndpoint
+	if err := s.DB.First(&endpoint, delivery.EndpointID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			delivery.Status = models.WebhookFailed
+			delivery.Error = "endpoint deleted"
+			return s.DB.Save(&delivery).Error
+		}
+		return err
+	}
+	if !endpoint.IsActive && delivery.EventType != EventPing {
+		delivery.Status = models.WebhookFailed
+		delivery.Error = "endpoint disabled"
+		return s.DB.Save(&delivery).Error
+	}
+
+	started := time.Now()
+	status, body, err := s.post(ctx, &endpoint, &delivery)
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &started
+	delivery.DurationMs = time.Since(started).Milliseconds()
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+	delivery.Error = ""
+	if err == nil && (status < 200 || status > 299) {
+		err = fmt.Errorf("endpoint returned status %d", status)
+	}
+
+	if err == nil {
+		now := time.Now()
+		delivery.Status = models.WebhookSucceeded
+		delivery.DeliveredAt = &now
+		if err := s.DB.Save(&delivery).Error; err != nil {
+			return err
+		}
+		return s.DB.Model(&endpoint).Update("consecutive_failures", 0).Error
+	}
+
+	delivery.Error = err.Error()
+	if !final {
+		if saveErr := s.DB.Save(&delivery).Error; saveErr != nil {
+			log.Printf("Failed to record webhook delivery %d: %v", delivery.ID, saveErr)
+		}
+		return err
+	}
+
+	delivery.Status = models.WebhookFailed
+	if err := s.DB.Save(&delivery).Error; err != nil {
+		return err
+	}
+	if delivery.EventType != EventPing {
+		if err := s.recordFailure(&endpoint); err != nil {
+			log.Printf("Failed to record failure of webhook endpoint %d: %v", endpoint.ID, err)
+		}
+	}
+	return err
+}
+
+// recordFailure counts a failed delivery against an endpoint and disables
+// it once too many failed in a row.
+func (s *Sender) recordFailure(endpoint *models.WebhookEndpoint) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(endpoint).Update("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.First(endpoint, endpoint.ID).Error; err != nil {
+			return err
+		}
+		if endpoint.ConsecutiveFailures < DisableAfter || !endpoint.IsActive {
+			return nil
+		}
+
+		now := time.Now()
+		log.Printf("Disabling webhook endpoint %d after %d failed deliveries", endpoint.ID, endpoint.ConsecutiveFailures)
+		return tx.Model(endpoint).Updates(map[string]interface{}{
+			"is_active":       false,
+			"disabled_at":     now,
+			"disabled_reason": fmt.Sprintf("%d deliveries in a row failed", endpoint.ConsecutiveFailures),
+		}).Error
+	})
+}
+
+func (s *Sender) post(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Pluralink-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.EventID)
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, time.Now(), body))
+
+	resp, err := s.HTTP.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, string(snippet), nil
+}
@@ -0,0 +1,235 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"pluralink/backend/models"
+	"pluralink/backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// Event types
+const (
+	EventBookingCreated     = "booking.created"
+	EventBookingConfirmed   = "booking.confirmed"
+	EventBookingCancelled   = "booking.cancelled"
+	EventBookingRescheduled = "booking.rescheduled"
+	EventBookingCompleted   = "booking.completed"
+	EventBookingExpired     = "booking.expired"
+	EventBookingPaid        = "booking.paid"
+	EventBookingRefunded    = "booking.refunded"
+	EventBookingTipped      = "booking.tipped"
+	EventReviewCreated      = "review.created"
+	EventPing               = "ping" // Sent on demand to test an endpoint
+)
+
+// EventTypes lists the events endpoints can subscribe to.
+var EventTypes = []string{
+	EventBookingCreated,
+	EventBookingConfirmed,
+	EventBookingCancelled,
+	EventBookingRescheduled,
+	EventBookingCompleted,
+	EventBookingExpired,
+	EventBookingPaid,
+	EventBookingRefunded,
+	EventBookingTipped,
+	EventReviewCreated,
+}
+
+const (
+	// MaxAttempts is how often a delivery is tried before it fails.
+	MaxAttempts = 8
+	// DisableAfter is how many deliveries in a row may fail before the
+	// endpoint is disabled.
+	DisableAfter = 5
+
+	SignatureHeader = "X-Pluralink-Signature"
+	EventHeader     = "X-Pluralink-Event"
+	DeliveryHeader  = "X-Pluralink-Delivery"
+)
+
+// Envelope is the JSON body of every delivery.
+type Envelope struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// StatusEvent returns the event type for a booking entering status, or ""
+// if there is none.
+func StatusEvent(status models.BookingStatus) string {
+	switch status {
+	case models.StatusConfirmed:
+		return EventBookingConfirmed
+	case models.StatusCancelled:
+		return EventBookingCancelled
+	case models.StatusRescheduled:
+		return EventBookingRescheduled
+	case models.StatusCompleted:
+		return EventBookingCompleted
+	case models.StatusExpired:
+		return EventBookingExpired
+	}
+	return ""
+}
+
+// PaymentEvent returns the event sent when a booking's payment or refund
+// status changes, if any.
+func PaymentEvent(booking *models.Booking) string {
+	switch {
+	case booking.RefundStatus == models.RefundRefunded:
+		return EventBookingRefunded
+	case booking.RefundStatus == models.RefundNone && booking.PaymentStatus == models.PaymentPaid:
+		return EventBookingPaid
+	}
+	return ""
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() (string, error) {
+	token, err := utils.GenerateToken(24)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + token, nil
+}
+
+// Sign returns the value of the signature header for a body sent at t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">". Receivers should
+// recompute it and reject stale timestamps.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := t.Unix()
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", ts)
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// ParseEvents validates a list of event types and encodes it for storage.
+func ParseEvents(events []string) (string, error) {
+	if len(events) == 0 {
+		return "", fmt.Errorf("choose at least one event type")
+	}
+	seen := map[string]bool{}
+	var list []string
+	for _, e := range events {
+		if !isEventType(e) {
+			return "", fmt.Errorf("unknown event type %q", e)
+		}
+		if !seen[e] {
+			seen[e] = true
+			list = append(list, e)
+		}
+	}
+	return strings.Join(list, ","), nil
+}
+
+// Events returns the event types an endpoint subscribes to.
+func Events(endpoint *models.WebhookEndpoint) []string {
+	if endpoint.Events == "" {
+		return []string{}
+	}
+	return strings.Split(endpoint.Events, ",")
+}
+
+// Subscribed reports whether an endpoint wants an event type.
+func Subscribed(endpoint *models.WebhookEndpoint, eventType string) bool {
+	for _, e := range Events(endpoint) {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateDeliveries records an event for every active endpoint of the
+// provider that subscribes to it. All deliveries share one event ID so
+// receivers can deduplicate.
+func CreateDeliveries(tx *gorm.DB, providerID uint, eventType string, data interface{}) ([]models.WebhookDelivery, error) {
+	var endpoints []models.WebhookEndpoint
+	if err := tx.Where("provider_id = ? AND is_active = ?", providerID, true).Find(&endpoints).Error; err != nil {
+		return nil, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	var eventID string
+	var body []byte
+	for i := range endpoints {
+		if !Subscribed(&endpoints[i], eventType) {
+			continue
+		}
+
+		if body == nil {
+			var err error
+			if eventID, body, err = newEnvelope(eventType, data); err != nil {
+				return nil, err
+			}
+		}
+
+		delivery, err := createDelivery(tx, endpoints[i].ID, eventID, eventType, body)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// Ping records a test event for one endpoint, whatever it subscribes to.
+func Ping(tx *gorm.DB, endpoint *models.WebhookEndpoint) (models.WebhookDelivery, error) {
+	eventID, body, err := newEnvelope(EventPing, map[string]interface{}{"endpoint_id": endpoint.ID})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	return createDelivery(tx, endpoint.ID, eventID, EventPing, body)
+}
+
+// Redeliver records another delivery of the same event and body.
+func Redeliver(tx *gorm.DB, previous *models.WebhookDelivery) (models.WebhookDelivery, error) {
+	return createDelivery(tx, previous.EndpointID, previous.EventID, previous.EventType, []byte(previous.Payload))
+}
+
+func newEnvelope(eventType string, data interface{}) (string, []byte, error) {
+	token, err := utils.GenerateToken(12)
+	if err != nil {
+		return "", nil, err
+	}
+	id := "evt_" + token
+	body, err := json.Marshal(Envelope{
+		ID:        id,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	return id, body, err
+}
+
+func createDelivery(tx *gorm.DB, endpointID uint, eventID, eventType string, body []byte) (models.WebhookDelivery, error) {
+	delivery := models.WebhookDelivery{
+		EndpointID: endpointID,
+		EventID:    eventID,
+		EventType:  eventType,
+		Payload:    string(body),
+		Status:     models.WebhookPending,
+	}
+	err := tx.Create(&delivery).Error
+	return delivery, err
+}
+
+func isEventType(e string) bool {
+	for _, t := range EventTypes {
+		if t == e {
+			return true
+		}
+	}
+	return false
+}
@@ -10,6 +10,7 @@ import BookingScreen from '../screens/booking/BookingScreen';
 import BookingDetailsScreen from '../screens/booking/BookingDetailsScreen';
 import AvailabilityScreen from '../screens/provider/AvailabilityScreen';
 import ReviewScreen from '../screens/review/ReviewScreen';
+import NotificationsScreen from '../screens/notifications/NotificationsScreen';
 import { useAuth } from '../context/AuthContext';
 
 export type MainStackParamList = {
@@ -22,6 +23,7 @@ export type MainStackParamList = {
   ProviderDashboard: undefined;
   Availability: undefined;
   Review: { bookingId: number };
+  Notifications: undefined;
 };
 
 const Tab = createBottomTabNavigator();
@@ -54,6 +56,14 @@ const BookingsStack = () => (
   </Stack.Navigator>
 );
 
+const NotificationsStack = () => (
+  <Stack.Navigator>
+    <Stack.Screen name="Notifications" component={NotificationsScreen} />
+    <Stack.Screen name="BookingDetails" component={BookingDetailsScreen} />
+    <Stack.Screen name="Review" component={ReviewScreen} />
+  </Stack.Navigator>
+);
+
 export const MainNavigator: React.FC = () => {
   const { user } = useAuth();
 
@@ -62,6 +72,7 @@ export const MainNavigator: React.FC = () => {
       <Tab.Screen name="Home" component={HomeStack} />
       <Tab.Screen name="Search" component={SearchStack} />
       <Tab.Screen name="Bookings" component={BookingsStack} />
+      <Tab.Screen name="Inbox" component={NotificationsStack} />
       {user?.role === 'provider' && (
         <>
           <Tab.Screen name="Dashboard" component={ProviderDashboardScreen} />
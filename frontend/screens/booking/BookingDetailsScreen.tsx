@@ -14,7 +14,7 @@ import { useRoute, useNavigation } from '@react-navigation/native';
 import { StackNavigationProp } from '@react-navigation/stack';
 import { MainStackParamList } from '../../navigation/MainNavigator';
 import { useAuth } from '../../context/AuthContext';
-import { formatDate, formatTime, getStatusColor } from '../../utils/helpers';
+import { formatDate, formatTime, formatMoney, getStatusColor } from '../../utils/helpers';
 
 type BookingDetailsScreenRouteProp = {
   params: {
@@ -107,7 +107,7 @@ const BookingDetailsScreen: React.FC = () => {
         <Text style={styles.sectionTitle}>Service</Text>
         <Text style={styles.sectionText}>{booking.service?.name}</Text>
         <Text style={styles.sectionSubtext}>
-          ${booking.service?.price} • {booking.service?.duration} minutes
+          {formatMoney(booking.price, booking.currency)} • {booking.service?.duration} minutes
         </Text>
       </View>
 
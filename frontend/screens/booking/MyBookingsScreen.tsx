@@ -8,6 +8,7 @@ import {
   ActivityIndicator,
 } from 'react-native';
 import { bookingService } from '../../services/booking.service';
+import { subscribeToBookings } from '../../services/realtime.service';
 import { Booking } from '../../types/booking.types';
 import { useNavigation } from '@react-navigation/native';
 import { StackNavigationProp } from '@react-navigation/stack';
@@ -28,6 +29,14 @@ const MyBookingsScreen: React.FC = () => {
     loadBookings();
   }, []);
 
+  // Refresh when bookings change elsewhere instead of polling
+  useEffect(() => {
+    return subscribeToBookings({
+      onEvent: () => loadBookings(),
+      onReset: () => loadBookings(),
+    });
+  }, []);
+
   const loadBookings = async () => {
     try {
       const data = await bookingService.getBookings();
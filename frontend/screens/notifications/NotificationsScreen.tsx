@@ -0,0 +1,238 @@
+import React, { useState, useEffect, useCallback } from 'react';
+import {
+  View,
+  Text,
+  StyleSheet,
+  FlatList,
+  TouchableOpacity,
+  ActivityIndicator,
+} from 'react-native';
+import { notificationService } from '../../services/notification.service';
+import { AppNotification } from '../../types/notification.types';
+import { useNavigation } from '@react-navigation/native';
+import { StackNavigationProp } from '@react-navigation/stack';
+import { MainStackParamList } from '../../navigation/MainNavigator';
+import { formatDate } from '../../utils/helpers';
+
+type NotificationsScreenNavigationProp = StackNavigationProp<
+  MainStackParamList,
+  'Notifications'
+>;
+
+const PAGE_SIZE = 20;
+
+const NotificationsScreen: React.FC = () => {
+  const [notifications, setNotifications] = useState<AppNotification[]>([]);
+  const [unreadCount, setUnreadCount] = useState(0);
+  const [page, setPage] = useState(1);
+  const [total, setTotal] = useState(0);
+  const [loading, setLoading] = useState(true);
+  const [refreshing, setRefreshing] = useState(false);
+  const [loadingMore, setLoadingMore] = useState(false);
+  const navigation = useNavigation<NotificationsScreenNavigationProp>();
+
+  const loadPage = useCallback(async (nextPage: number) => {
+    try {
+      const data = await notificationService.getNotifications(nextPage, PAGE_SIZE);
+      setNotifications((current) =>
+        nextPage === 1 ? data.notifications : [...current, ...data.notifications]
+      );
+      setUnreadCount(data.unread_count);
+      setTotal(data.total);
+      setPage(nextPage);
+    } catch (error) {
+      console.error('Failed to load notifications:', error);
+    } finally {
+      setLoading(false);
+      setRefreshing(false);
+      setLoadingMore(false);
+    }
+  }, []);
+
+  useEffect(() => {
+    loadPage(1);
+  }, [loadPage]);
+
+  const refresh = () => {
+    setRefreshing(true);
+    loadPage(1);
+  };
+
+  const loadMore = () => {
+    if (!loading && !loadingMore && notifications.length < total) {
+      setLoadingMore(true);
+      loadPage(page + 1);
+    }
+  };
+
+  const markAllRead = async () => {
+    try {
+      const result = await notificationService.markRead({ all: true });
+      const now = new Date().toISOString();
+      setNotifications((current) =>
+        current.map((n) => (n.read_at ? n : { ...n, read_at: now }))
+      );
+      setUnreadCount(result.unread_count);
+    } catch (error) {
+      console.error('Failed to mark notifications as read:', error);
+    }
+  };
+
+  const openNotification = async (item: AppNotification) => {
+    if (!item.read_at) {
+      try {
+        const result = await notificationService.markRead({ ids: [item.id] });
+        const now = new Date().toISOString();
+        setNotifications((current) =>
+          current.map((n) => (n.id === item.id ? { ...n, read_at: now } : n))
+        );
+        setUnreadCount(result.unread_count);
+      } catch (error) {
+        console.error('Failed to mark notification as read:', error);
+      }
+    }
+    if (item.booking_id) {
+      navigation.navigate('BookingDetails', { bookingId: item.booking_id });
+    }
+  };
+
+  const renderNotification = ({ item }: { item: AppNotification }) => (
+    <TouchableOpacity
+      style={[styles.card, !item.read_at && styles.unreadCard]}
+      onPress={() => openNotification(item)}
+    >
+      <View style={styles.cardHeader}>
+        <Text style={[styles.cardTitle, !item.read_at && styles.unreadTitle]}>
+          {item.title}
+        </Text>
+        {!item.read_at && <View style={styles.unreadDot} />}
+      </View>
+      <Text style={styles.cardBody}>{item.body}</Text>
+      <Text style={styles.cardDate}>{formatDate(item.created_at)}</Text>
+    </TouchableOpacity>
+  );
+
+  if (loading) {
+    return (
+      <View style={styles.loadingContainer}>
+        <ActivityIndicator size="large" />
+      </View>
+    );
+  }
+
+  return (
+    <View style={styles.container}>
+      <View style={styles.header}>
+        <Text style={styles.title}>Notifications</Text>
+        {unreadCount > 0 && (
+          <TouchableOpacity onPress={markAllRead}>
+            <Text style={styles.markAllText}>Mark all read ({unreadCount})</Text>
+          </TouchableOpacity>
+        )}
+      </View>
+
+      {notifications.length === 0 ? (
+        <View style={styles.emptyContainer}>
+          <Text style={styles.emptyText}>No notifications yet</Text>
+        </View>
+      ) : (
+        <FlatList
+          data={notifications}
+          renderItem={renderNotification}
+          keyExtractor={(item) => item.id.toString()}
+          contentContainerStyle={styles.listContainer}
+          refreshing={refreshing}
+          onRefresh={refresh}
+          onEndReached={loadMore}
+          onEndReachedThreshold={0.5}
+        />
+      )}
+    </View>
+  );
+};
+
+const styles = StyleSheet.create({
+  container: {
+    flex: 1,
+    backgroundColor: '#f5f5f5',
+  },
+  loadingContainer: {
+    flex: 1,
+    justifyContent: 'center',
+    alignItems: 'center',
+  },
+  header: {
+    padding: 20,
+    backgroundColor: '#fff',
+    marginBottom: 10,
+    flexDirection: 'row',
+    justifyContent: 'space-between',
+    alignItems: 'center',
+  },
+  title: {
+    fontSize: 28,
+    fontWeight: 'bold',
+  },
+  markAllText: {
+    fontSize: 14,
+    color: '#007AFF',
+  },
+  listContainer: {
+    padding: 15,
+  },
+  card: {
+    backgroundColor: '#fff',
+    borderRadius: 12,
+    padding: 15,
+    marginBottom: 10,
+    shadowColor: '#000',
+    shadowOffset: { width: 0, height: 2 },
+    shadowOpacity: 0.1,
+    shadowRadius: 4,
+    elevation: 3,
+  },
+  unreadCard: {
+    borderLeftWidth: 4,
+    borderLeftColor: '#007AFF',
+  },
+  cardHeader: {
+    flexDirection: 'row',
+    justifyContent: 'space-between',
+    alignItems: 'center',
+    marginBottom: 5,
+  },
+  cardTitle: {
+    fontSize: 16,
+    flex: 1,
+  },
+  unreadTitle: {
+    fontWeight: 'bold',
+  },
+  unreadDot: {
+    width: 10,
+    height: 10,
+    borderRadius: 5,
+    backgroundColor: '#007AFF',
+    marginLeft: 10,
+  },
+  cardBody: {
+    fontSize: 14,
+    color: '#666',
+    marginBottom: 5,
+  },
+  cardDate: {
+    fontSize: 12,
+    color: '#999',
+  },
+  emptyContainer: {
+    flex: 1,
+    justifyContent: 'center',
+    alignItems: 'center',
+  },
+  emptyText: {
+    fontSize: 16,
+    color: '#999',
+  },
+});
+
+export default NotificationsScreen;
@@ -13,6 +13,7 @@ import { useRoute, useNavigation } from '@react-navigation/native';
 import { StackNavigationProp } from '@react-navigation/stack';
 import { MainStackParamList } from '../../navigation/MainNavigator';
 import { useAuth } from '../../context/AuthContext';
+import { formatMoney } from '../../utils/helpers';
 
 type ProviderProfileScreenRouteProp = {
   params: {
@@ -84,7 +85,7 @@ const ProviderProfileScreen: React.FC = () => {
           {provider.services.map((service) => (
             <View key={service.id} style={styles.serviceItem}>
               <Text style={styles.serviceName}>{service.name}</Text>
-              <Text style={styles.servicePrice}>${service.price}</Text>
+              <Text style={styles.servicePrice}>{formatMoney(service.price, service.currency)}</Text>
               <Text style={styles.serviceDuration}>{service.duration} min</Text>
             </View>
           ))}
@@ -57,6 +57,14 @@ class ApiClient {
     return response.data;
   }
 
+  async upload<T>(url: string, form: FormData): Promise<ApiResponse<T>> {
+    const response = await this.client.post<ApiResponse<T>>(url, form, {
+      headers: { 'Content-Type': 'multipart/form-data' },
+      timeout: 60000,
+    });
+    return response.data;
+  }
+
   async put<T>(url: string, data?: any): Promise<ApiResponse<T>> {
     const response = await this.client.put<ApiResponse<T>>(url, data);
     return response.data;
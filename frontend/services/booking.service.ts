@@ -42,5 +42,13 @@ export const bookingService = {
     }
     throw new Error(response.error || 'Failed to reschedule booking');
   },
+
+  async confirmBooking(id: number): Promise<Booking> {
+    const response = await apiClient.put<Booking>(`/bookings/${id}/confirm`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to confirm booking');
+  },
 };
 
@@ -0,0 +1,104 @@
+import { apiClient } from './api';
+import {
+  CalendarFeed,
+  ExternalCalendar,
+  CreateExternalCalendarRequest,
+  BusyBlock,
+  CalDAVAccount,
+  ConnectCalDAVRequest,
+} from '../types/calendar.types';
+
+export const calendarService = {
+  async getFeed(): Promise<CalendarFeed> {
+    const response = await apiClient.get<CalendarFeed>('/calendar/feed');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch calendar feed');
+  },
+
+  async regenerateFeed(): Promise<CalendarFeed> {
+    const response = await apiClient.post<CalendarFeed>('/calendar/feed');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to generate calendar feed');
+  },
+
+  async revokeFeed(): Promise<void> {
+    const response = await apiClient.delete<void>('/calendar/feed');
+    if (!response.success) {
+      throw new Error(response.error || 'Failed to revoke calendar feed');
+    }
+  },
+
+  async getExternalCalendars(): Promise<ExternalCalendar[]> {
+    const response = await apiClient.get<ExternalCalendar[]>('/external-calendars');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch external calendars');
+  },
+
+  async addExternalCalendar(data: CreateExternalCalendarRequest): Promise<ExternalCalendar> {
+    const response = await apiClient.post<ExternalCalendar>('/external-calendars', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to add external calendar');
+  },
+
+  async syncExternalCalendar(id: number): Promise<ExternalCalendar> {
+    const response = await apiClient.post<ExternalCalendar>(`/external-calendars/${id}/sync`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to sync external calendar');
+  },
+
+  async removeExternalCalendar(id: number): Promise<void> {
+    const response = await apiClient.delete<void>(`/external-calendars/${id}`);
+    if (!response.success) {
+      throw new Error(response.error || 'Failed to remove external calendar');
+    }
+  },
+
+  async getBusyBlocks(from?: string, to?: string): Promise<BusyBlock[]> {
+    const response = await apiClient.get<BusyBlock[]>('/external-calendars/busy', { from, to });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch busy blocks');
+  },
+
+  async getCalDAVAccount(): Promise<CalDAVAccount> {
+    const response = await apiClient.get<CalDAVAccount>('/caldav');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch CalDAV account');
+  },
+
+  async connectCalDAV(data: ConnectCalDAVRequest): Promise<CalDAVAccount> {
+    const response = await apiClient.put<CalDAVAccount>('/caldav', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to connect CalDAV account');
+  },
+
+  async syncCalDAV(): Promise<CalDAVAccount> {
+    const response = await apiClient.post<CalDAVAccount>('/caldav/sync');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to sync CalDAV account');
+  },
+
+  async disconnectCalDAV(): Promise<void> {
+    const response = await apiClient.delete<void>('/caldav');
+    if (!response.success) {
+      throw new Error(response.error || 'Failed to disconnect CalDAV account');
+    }
+  },
+};
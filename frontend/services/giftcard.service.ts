@@ -0,0 +1,78 @@
+import { apiClient } from './api';
+import {
+  GiftCard,
+  IssueGiftCardRequest,
+  PurchaseGiftCardRequest,
+  GiftCardPurchase,
+  GiftCardBalance,
+} from '../types/giftcard.types';
+
+export const giftCardService = {
+  async getGiftCards(status?: string): Promise<GiftCard[]> {
+    const response = await apiClient.get<GiftCard[]>('/gift-cards', status ? { status } : undefined);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch gift cards');
+  },
+
+  // With its entries
+  async getGiftCard(id: number): Promise<GiftCard> {
+    const response = await apiClient.get<GiftCard>(`/gift-cards/${id}`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch gift card');
+  },
+
+  // A card sold outside the app, active at once
+  async issueGiftCard(data: IssueGiftCardRequest): Promise<GiftCard> {
+    const response = await apiClient.post<GiftCard>('/gift-cards', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to issue gift card');
+  },
+
+  async purchaseGiftCard(data: PurchaseGiftCardRequest): Promise<GiftCardPurchase> {
+    const response = await apiClient.post<GiftCardPurchase>('/gift-cards/purchase', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to start gift card purchase');
+  },
+
+  async confirmPurchase(id: number, paymentMethod: string): Promise<GiftCard> {
+    const response = await apiClient.post<GiftCard>(`/gift-cards/purchase/${id}/confirm`, {
+      payment_method: paymentMethod,
+    });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Payment failed');
+  },
+
+  async refreshPurchase(id: number): Promise<GiftCard> {
+    const response = await apiClient.post<GiftCard>(`/gift-cards/purchase/${id}/refresh`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to refresh gift card');
+  },
+
+  async getPurchasedGiftCards(): Promise<GiftCard[]> {
+    const response = await apiClient.get<GiftCard[]>('/gift-cards/purchased');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch gift cards');
+  },
+
+  async getBalance(code: string): Promise<GiftCardBalance> {
+    const response = await apiClient.post<GiftCardBalance>('/gift-cards/balance', { code });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Gift card not found');
+  },
+};
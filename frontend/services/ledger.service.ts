@@ -0,0 +1,39 @@
+import { apiClient } from './api';
+import { LedgerBalance, Payout, Statement, StatementQuery } from '../types/ledger.types';
+
+export const ledgerService = {
+  // What the platform owes the provider, by currency
+  async getBalance(): Promise<LedgerBalance[]> {
+    const response = await apiClient.get<LedgerBalance[]>('/ledger/balance');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch balance');
+  },
+
+  // The CSV is at the same path without format=json
+  async getStatement(query: StatementQuery = {}): Promise<Statement> {
+    const response = await apiClient.get<Statement>('/ledger/statement', { ...query, format: 'json' });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch statement');
+  },
+
+  async getPayouts(): Promise<Payout[]> {
+    const response = await apiClient.get<Payout[]>('/payouts');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch payouts');
+  },
+
+  // What a payout settled; the CSV is at the same path without format=json
+  async getPayoutStatement(id: number): Promise<Statement> {
+    const response = await apiClient.get<Statement>(`/payouts/${id}/statement`, { format: 'json' });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch statement');
+  },
+};
@@ -0,0 +1,84 @@
+import { apiClient } from './api';
+import {
+  LoyaltyProgram,
+  LoyaltyProgramRequest,
+  LoyaltyReward,
+  LoyaltyRewardRequest,
+  LoyaltyAccount,
+  LoyaltyStatus,
+} from '../types/loyalty.types';
+
+export const loyaltyService = {
+  // A provider's program and the rewards on offer
+  async getProviderProgram(providerId: number): Promise<LoyaltyProgram> {
+    const response = await apiClient.get<LoyaltyProgram>(`/providers/${providerId}/loyalty`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch loyalty program');
+  },
+
+  async getProgram(): Promise<LoyaltyProgram> {
+    const response = await apiClient.get<LoyaltyProgram>('/loyalty/program');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch loyalty program');
+  },
+
+  async updateProgram(data: LoyaltyProgramRequest): Promise<LoyaltyProgram> {
+    const response = await apiClient.put<LoyaltyProgram>('/loyalty/program', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to update loyalty program');
+  },
+
+  async createReward(data: LoyaltyRewardRequest): Promise<LoyaltyReward> {
+    const response = await apiClient.post<LoyaltyReward>('/loyalty/rewards', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to create reward');
+  },
+
+  async updateReward(id: number, data: LoyaltyRewardRequest): Promise<LoyaltyReward> {
+    const response = await apiClient.put<LoyaltyReward>(`/loyalty/rewards/${id}`, data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to update reward');
+  },
+
+  async deleteReward(id: number): Promise<void> {
+    const response = await apiClient.delete<void>(`/loyalty/rewards/${id}`);
+    if (!response.success) {
+      throw new Error(response.error || 'Failed to delete reward');
+    }
+  },
+
+  async getMembers(): Promise<LoyaltyAccount[]> {
+    const response = await apiClient.get<LoyaltyAccount[]>('/loyalty/members');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch members');
+  },
+
+  // The client's points with each provider
+  async getAccounts(): Promise<LoyaltyAccount[]> {
+    const response = await apiClient.get<LoyaltyAccount[]>('/loyalty/accounts');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch loyalty points');
+  },
+
+  async getAccount(providerId: number): Promise<LoyaltyStatus> {
+    const response = await apiClient.get<LoyaltyStatus>(`/loyalty/accounts/${providerId}`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch loyalty points');
+  },
+};
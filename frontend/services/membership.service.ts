@@ -0,0 +1,110 @@
+import { apiClient } from './api';
+import {
+  MembershipPlan,
+  MembershipPlanRequest,
+  Membership,
+  MembershipCheckout,
+} from '../types/membership.types';
+
+export const membershipService = {
+  // Plans on offer, for anyone
+  async getProviderPlans(providerId: number): Promise<MembershipPlan[]> {
+    const response = await apiClient.get<MembershipPlan[]>(`/providers/${providerId}/membership-plans`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch membership plans');
+  },
+
+  async getPlans(): Promise<MembershipPlan[]> {
+    const response = await apiClient.get<MembershipPlan[]>('/membership-plans');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch membership plans');
+  },
+
+  async createPlan(data: MembershipPlanRequest): Promise<MembershipPlan> {
+    const response = await apiClient.post<MembershipPlan>('/membership-plans', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to create membership plan');
+  },
+
+  async updatePlan(id: number, data: MembershipPlanRequest): Promise<MembershipPlan> {
+    const response = await apiClient.put<MembershipPlan>(`/membership-plans/${id}`, data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to update membership plan');
+  },
+
+  async deletePlan(id: number): Promise<void> {
+    const response = await apiClient.delete<void>(`/membership-plans/${id}`);
+    if (!response.success) {
+      throw new Error(response.error || 'Failed to delete membership plan');
+    }
+  },
+
+  async getMembers(status?: string): Promise<Membership[]> {
+    const response = await apiClient.get<Membership[]>('/membership-plans/members', status ? { status } : undefined);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch members');
+  },
+
+  // Opens the payment of the first month
+  async join(planId: number): Promise<MembershipCheckout> {
+    const response = await apiClient.post<MembershipCheckout>('/memberships', { plan_id: planId });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to join membership');
+  },
+
+  async getMemberships(): Promise<Membership[]> {
+    const response = await apiClient.get<Membership[]>('/memberships');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch memberships');
+  },
+
+  // For a membership waiting for its first month or past due
+  async pay(id: number): Promise<MembershipCheckout> {
+    const response = await apiClient.post<MembershipCheckout>(`/memberships/${id}/pay`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to start membership payment');
+  },
+
+  async confirmPayment(id: number, paymentId: number, paymentMethod: string): Promise<MembershipCheckout> {
+    const response = await apiClient.post<MembershipCheckout>(`/memberships/${id}/payments/${paymentId}/confirm`, {
+      payment_method: paymentMethod,
+    });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Payment failed');
+  },
+
+  async refreshPayment(id: number, paymentId: number): Promise<MembershipCheckout> {
+    const response = await apiClient.post<MembershipCheckout>(`/memberships/${id}/payments/${paymentId}/refresh`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to refresh membership payment');
+  },
+
+  // A paid-up membership runs to the end of its month
+  async cancel(id: number): Promise<Membership> {
+    const response = await apiClient.post<Membership>(`/memberships/${id}/cancel`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to cancel membership');
+  },
+};
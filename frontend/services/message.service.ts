@@ -0,0 +1,52 @@
+import { apiClient } from './api';
+import { API_BASE_URL } from '../utils/constants';
+import {
+  BookingMessage,
+  MessagePage,
+  SendMessageRequest,
+  MarkMessagesReadResult,
+} from '../types/message.types';
+
+export const messageService = {
+  async getMessages(bookingId: number, page = 1, limit = 50): Promise<MessagePage> {
+    const response = await apiClient.get<MessagePage>(`/bookings/${bookingId}/messages`, { page, limit });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch messages');
+  },
+
+  async sendMessage(bookingId: number, data: SendMessageRequest): Promise<BookingMessage> {
+    const url = `/bookings/${bookingId}/messages`;
+    let response;
+    if (data.attachments && data.attachments.length > 0) {
+      const form = new FormData();
+      if (data.body) {
+        form.append('body', data.body);
+      }
+      for (const file of data.attachments) {
+        form.append('attachments', file as any);
+      }
+      response = await apiClient.upload<BookingMessage>(url, form);
+    } else {
+      response = await apiClient.post<BookingMessage>(url, { body: data.body });
+    }
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to send message');
+  },
+
+  async markRead(bookingId: number): Promise<MarkMessagesReadResult> {
+    const response = await apiClient.post<MarkMessagesReadResult>(`/bookings/${bookingId}/messages/read`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to mark messages as read');
+  },
+
+  // URL of an attachment; requests need the Authorization header
+  attachmentUrl(bookingId: number, messageId: number, attachmentId: number): string {
+    return `${API_BASE_URL}/bookings/${bookingId}/messages/${messageId}/attachments/${attachmentId}`;
+  },
+};
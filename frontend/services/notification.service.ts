@@ -0,0 +1,62 @@
+import { apiClient } from './api';
+import {
+  DeviceToken,
+  RegisterDeviceRequest,
+  NotificationPage,
+  MarkNotificationsReadRequest,
+  MarkNotificationsReadResult,
+  NotificationPreferencesResponse,
+  UpdateNotificationPreferencesRequest,
+} from '../types/notification.types';
+
+export const notificationService = {
+  async getNotifications(page = 1, limit = 20, unreadOnly = false): Promise<NotificationPage> {
+    const params = unreadOnly ? { page, limit, unread: true } : { page, limit };
+    const response = await apiClient.get<NotificationPage>('/notifications', params);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch notifications');
+  },
+
+  async markRead(data: MarkNotificationsReadRequest): Promise<MarkNotificationsReadResult> {
+    const response = await apiClient.post<MarkNotificationsReadResult>('/notifications/read', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to mark notifications as read');
+  },
+
+  async getPreferences(): Promise<NotificationPreferencesResponse> {
+    const response = await apiClient.get<NotificationPreferencesResponse>('/notifications/preferences');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch notification preferences');
+  },
+
+  async updatePreferences(
+    data: UpdateNotificationPreferencesRequest
+  ): Promise<NotificationPreferencesResponse> {
+    const response = await apiClient.put<NotificationPreferencesResponse>('/notifications/preferences', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to update notification preferences');
+  },
+
+  async registerDevice(data: RegisterDeviceRequest): Promise<DeviceToken> {
+    const response = await apiClient.post<DeviceToken>('/notifications/devices', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to register device');
+  },
+
+  async unregisterDevice(token: string): Promise<void> {
+    const response = await apiClient.delete<void>(`/notifications/devices/${encodeURIComponent(token)}`);
+    if (!response.success) {
+      throw new Error(response.error || 'Failed to unregister device');
+    }
+  },
+};
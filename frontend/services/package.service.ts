@@ -0,0 +1,88 @@
+import { apiClient } from './api';
+import { Package, PackageRequest, ClientPackage, PackagePurchase } from '../types/package.types';
+
+export const packageService = {
+  // Packages on sale, for anyone
+  async getProviderPackages(providerId: number): Promise<Package[]> {
+    const response = await apiClient.get<Package[]>(`/providers/${providerId}/packages`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch packages');
+  },
+
+  async getPackages(): Promise<Package[]> {
+    const response = await apiClient.get<Package[]>('/packages');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch packages');
+  },
+
+  async createPackage(data: PackageRequest): Promise<Package> {
+    const response = await apiClient.post<Package>('/packages', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to create package');
+  },
+
+  async updatePackage(id: number, data: PackageRequest): Promise<Package> {
+    const response = await apiClient.put<Package>(`/packages/${id}`, data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to update package');
+  },
+
+  async deletePackage(id: number): Promise<void> {
+    const response = await apiClient.delete<void>(`/packages/${id}`);
+    if (!response.success) {
+      throw new Error(response.error || 'Failed to delete package');
+    }
+  },
+
+  // Bought by the provider's clients
+  async getSoldPackages(status?: string): Promise<ClientPackage[]> {
+    const response = await apiClient.get<ClientPackage[]>('/packages/sold', status ? { status } : undefined);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch packages');
+  },
+
+  async purchasePackage(packageId: number): Promise<PackagePurchase> {
+    const response = await apiClient.post<PackagePurchase>('/packages/purchase', { package_id: packageId });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to start package purchase');
+  },
+
+  async confirmPurchase(id: number, paymentMethod: string): Promise<PackagePurchase> {
+    const response = await apiClient.post<PackagePurchase>(`/packages/purchase/${id}/confirm`, {
+      payment_method: paymentMethod,
+    });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Payment failed');
+  },
+
+  async refreshPurchase(id: number): Promise<PackagePurchase> {
+    const response = await apiClient.post<PackagePurchase>(`/packages/purchase/${id}/refresh`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to refresh package');
+  },
+
+  // The client's packages, with the sessions left
+  async getPurchasedPackages(): Promise<ClientPackage[]> {
+    const response = await apiClient.get<ClientPackage[]>('/packages/purchased');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch packages');
+  },
+};
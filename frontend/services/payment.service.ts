@@ -0,0 +1,113 @@
+import { apiClient } from './api';
+import { Service } from '../types/provider.types';
+import {
+  Payment,
+  OpenPayment,
+  BookingPayment,
+  UpdatePaymentPolicyRequest,
+  RefundPolicy,
+  Earnings,
+  Receipt,
+} from '../types/payment.types';
+
+export const paymentService = {
+  async getPayment(bookingId: number): Promise<BookingPayment> {
+    const response = await apiClient.get<BookingPayment>(`/bookings/${bookingId}/payment`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch payment');
+  },
+
+  async startPayment(bookingId: number): Promise<OpenPayment> {
+    const response = await apiClient.post<OpenPayment>(`/bookings/${bookingId}/payment`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to start payment');
+  },
+
+  async confirmPayment(bookingId: number, paymentMethod: string): Promise<Payment> {
+    const response = await apiClient.post<Payment>(`/bookings/${bookingId}/payment/confirm`, {
+      payment_method: paymentMethod,
+    });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Payment failed');
+  },
+
+  async refreshPayment(bookingId: number): Promise<Payment> {
+    const response = await apiClient.post<Payment>(`/bookings/${bookingId}/payment/refresh`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to refresh payment');
+  },
+
+  async startTip(bookingId: number, amount: number): Promise<OpenPayment> {
+    const response = await apiClient.post<OpenPayment>(`/bookings/${bookingId}/tip`, { amount });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to start tip');
+  },
+
+  async confirmTip(bookingId: number, paymentMethod: string): Promise<Payment> {
+    const response = await apiClient.post<Payment>(`/bookings/${bookingId}/tip/confirm`, {
+      payment_method: paymentMethod,
+    });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Tip failed');
+  },
+
+  async getEarnings(from?: string, to?: string): Promise<Earnings> {
+    const response = await apiClient.get<Earnings>('/earnings', { from, to });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch earnings');
+  },
+
+  // The PDF is at the same path without format=json
+  async getReceipt(bookingId: number): Promise<Receipt> {
+    const response = await apiClient.get<Receipt>(`/bookings/${bookingId}/receipt`, { format: 'json' });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch receipt');
+  },
+
+  async updatePaymentPolicy(serviceId: number, data: UpdatePaymentPolicyRequest): Promise<Service> {
+    const response = await apiClient.put<Service>(`/services/${serviceId}/payment-policy`, data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to update payment policy');
+  },
+
+  async retryRefund(bookingId: number): Promise<void> {
+    const response = await apiClient.post<void>(`/bookings/${bookingId}/refund/retry`);
+    if (!response.success) {
+      throw new Error(response.error || 'Failed to retry refund');
+    }
+  },
+
+  async getRefundPolicy(): Promise<RefundPolicy> {
+    const response = await apiClient.get<RefundPolicy>('/refund-policy');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch refund policy');
+  },
+
+  async updateRefundPolicy(data: RefundPolicy): Promise<RefundPolicy> {
+    const response = await apiClient.put<RefundPolicy>('/refund-policy', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to update refund policy');
+  },
+};
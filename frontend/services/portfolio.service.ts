@@ -0,0 +1,57 @@
+import { apiClient } from './api';
+import {
+  PortfolioItem,
+  CreatePortfolioItemRequest,
+  UpdatePortfolioItemRequest,
+  PortfolioFilter,
+} from '../types/portfolio.types';
+
+export const portfolioService = {
+  async getProviderPortfolio(providerId: number, filter?: PortfolioFilter): Promise<PortfolioItem[]> {
+    const response = await apiClient.get<PortfolioItem[]>(`/providers/${providerId}/portfolio`, filter);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch portfolio');
+  },
+
+  async getMyPortfolio(): Promise<PortfolioItem[]> {
+    const response = await apiClient.get<PortfolioItem[]>('/portfolio');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch portfolio');
+  },
+
+  async addItem(data: CreatePortfolioItemRequest): Promise<PortfolioItem> {
+    const response = await apiClient.post<PortfolioItem>('/portfolio', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to add portfolio item');
+  },
+
+  async updateItem(id: number, data: UpdatePortfolioItemRequest): Promise<PortfolioItem> {
+    const response = await apiClient.put<PortfolioItem>(`/portfolio/${id}`, data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to update portfolio item');
+  },
+
+  async deleteItem(id: number): Promise<void> {
+    const response = await apiClient.delete<void>(`/portfolio/${id}`);
+    if (!response.success) {
+      throw new Error(response.error || 'Failed to delete portfolio item');
+    }
+  },
+
+  // ids must list every item of the portfolio in the new order
+  async reorder(ids: number[]): Promise<PortfolioItem[]> {
+    const response = await apiClient.put<PortfolioItem[]>('/portfolio/order', { ids });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to reorder portfolio');
+  },
+};
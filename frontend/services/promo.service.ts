@@ -0,0 +1,58 @@
+import { apiClient } from './api';
+import {
+  PromoCode,
+  PromoCodeRequest,
+  PromoRedemption,
+  CheckPromoCodeRequest,
+  PromoQuote,
+} from '../types/promo.types';
+
+export const promoService = {
+  async getPromoCodes(): Promise<PromoCode[]> {
+    const response = await apiClient.get<PromoCode[]>('/promo-codes');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch promo codes');
+  },
+
+  async createPromoCode(data: PromoCodeRequest): Promise<PromoCode> {
+    const response = await apiClient.post<PromoCode>('/promo-codes', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to create promo code');
+  },
+
+  async updatePromoCode(id: number, data: PromoCodeRequest): Promise<PromoCode> {
+    const response = await apiClient.put<PromoCode>(`/promo-codes/${id}`, data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to update promo code');
+  },
+
+  async deletePromoCode(id: number): Promise<void> {
+    const response = await apiClient.delete<void>(`/promo-codes/${id}`);
+    if (!response.success) {
+      throw new Error(response.error || 'Failed to delete promo code');
+    }
+  },
+
+  async getRedemptions(id: number): Promise<PromoRedemption[]> {
+    const response = await apiClient.get<PromoRedemption[]>(`/promo-codes/${id}/redemptions`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch redemptions');
+  },
+
+  // What the code would take off the service if booked now
+  async checkPromoCode(data: CheckPromoCodeRequest): Promise<PromoQuote> {
+    const response = await apiClient.post<PromoQuote>('/promo-codes/check', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Promo code does not apply');
+  },
+};
@@ -0,0 +1,95 @@
+import AsyncStorage from '@react-native-async-storage/async-storage';
+import { API_BASE_URL } from '../utils/constants';
+import { BookingEvent } from '../types/realtime.types';
+
+const RECONNECT_DELAY_MS = 5000;
+
+export interface BookingStreamHandlers {
+  onEvent: (event: BookingEvent) => void;
+  // Called when too much was missed; reload bookings from the API
+  onReset?: () => void;
+}
+
+// Subscribes to /bookings/events (Server-Sent Events). React Native's fetch
+// cannot stream, so this reads the response incrementally through XHR and
+// reconnects with the last event ID after a drop. Returns an unsubscribe
+// function.
+export const subscribeToBookings = (handlers: BookingStreamHandlers): (() => void) => {
+  let xhr: XMLHttpRequest | null = null;
+  let lastEventId = '';
+  let closed = false;
+  let reconnectTimer: ReturnType<typeof setTimeout> | null = null;
+
+  const dispatch = (block: string) => {
+    let id = '';
+    let type = 'message';
+    const data: string[] = [];
+    for (const line of block.split('\n')) {
+      if (line.startsWith(':')) continue;
+      const sep = line.indexOf(':');
+      const field = sep === -1 ? line : line.slice(0, sep);
+      const value = sep === -1 ? '' : line.slice(sep + 1).replace(/^ /, '');
+      if (field === 'id') id = value;
+      else if (field === 'event') type = value;
+      else if (field === 'data') data.push(value);
+    }
+    if (id) lastEventId = id;
+
+    if (type === 'reset') {
+      handlers.onReset?.();
+    } else if (data.length > 0) {
+      try {
+        handlers.onEvent(JSON.parse(data.join('\n')) as BookingEvent);
+      } catch (error) {
+        console.error('Failed to parse booking event:', error);
+      }
+    }
+  };
+
+  const scheduleReconnect = () => {
+    if (closed || reconnectTimer) return;
+    reconnectTimer = setTimeout(() => {
+      reconnectTimer = null;
+      connect();
+    }, RECONNECT_DELAY_MS);
+  };
+
+  const connect = async () => {
+    const token = await AsyncStorage.getItem('auth_token');
+    if (closed || !token) return;
+
+    let seen = 0;
+    let buffer = '';
+    const request = new XMLHttpRequest();
+    xhr = request;
+
+    request.open('GET', `${API_BASE_URL}/bookings/events`);
+    request.setRequestHeader('Accept', 'text/event-stream');
+    request.setRequestHeader('Authorization', `Bearer ${token}`);
+    if (lastEventId) {
+      request.setRequestHeader('Last-Event-ID', lastEventId);
+    }
+
+    request.onprogress = () => {
+      buffer += request.responseText.slice(seen).replace(/\r\n/g, '\n');
+      seen = request.responseText.length;
+      let end = buffer.indexOf('\n\n');
+      while (end !== -1) {
+        dispatch(buffer.slice(0, end));
+        buffer = buffer.slice(end + 2);
+        end = buffer.indexOf('\n\n');
+      }
+    };
+    request.onerror = scheduleReconnect;
+    request.onload = scheduleReconnect;
+    request.send();
+  };
+
+  connect();
+
+  return () => {
+    closed = true;
+    if (reconnectTimer) clearTimeout(reconnectTimer);
+    xhr?.abort();
+  };
+};
@@ -0,0 +1,28 @@
+import { apiClient } from './api';
+import { Reminder, ReminderSettings } from '../types/reminder.types';
+
+export const reminderService = {
+  async getSettings(): Promise<ReminderSettings> {
+    const response = await apiClient.get<ReminderSettings>('/reminders/settings');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch reminder settings');
+  },
+
+  async updateSettings(data: ReminderSettings): Promise<ReminderSettings> {
+    const response = await apiClient.put<ReminderSettings>('/reminders/settings', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to update reminder settings');
+  },
+
+  async getBookingReminders(bookingId: number): Promise<Reminder[]> {
+    const response = await apiClient.get<Reminder[]>(`/bookings/${bookingId}/reminders`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch reminders');
+  },
+};
@@ -0,0 +1,21 @@
+import { apiClient } from './api';
+import { TaxRule, TaxSummary } from '../types/tax.types';
+
+export const taxService = {
+  // Null when the provider's region charges no tax
+  async getRule(): Promise<TaxRule | null> {
+    const response = await apiClient.get<TaxRule | null>('/taxes/rule');
+    if (response.success) {
+      return response.data ?? null;
+    }
+    throw new Error(response.error || 'Failed to fetch tax rule');
+  },
+
+  async getSummary(from?: string, to?: string): Promise<TaxSummary> {
+    const response = await apiClient.get<TaxSummary>('/taxes/summary', { from, to });
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch tax summary');
+  },
+};
@@ -0,0 +1,38 @@
+import { apiClient } from './api';
+import { Upload, UploadPurpose, FileUpload } from '../types/upload.types';
+
+export const uploadService = {
+  async uploadFile(purpose: UploadPurpose, file: FileUpload): Promise<Upload> {
+    const form = new FormData();
+    form.append('purpose', purpose);
+    form.append('file', file as any);
+    const response = await apiClient.upload<Upload>('/uploads', form);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to upload file');
+  },
+
+  async getUploads(purpose?: UploadPurpose): Promise<Upload[]> {
+    const response = await apiClient.get<Upload[]>('/uploads', purpose ? { purpose } : undefined);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch uploads');
+  },
+
+  async getUpload(id: number): Promise<Upload> {
+    const response = await apiClient.get<Upload>(`/uploads/${id}`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch upload');
+  },
+
+  async deleteUpload(id: number): Promise<void> {
+    const response = await apiClient.delete<void>(`/uploads/${id}`);
+    if (!response.success) {
+      throw new Error(response.error || 'Failed to delete upload');
+    }
+  },
+};
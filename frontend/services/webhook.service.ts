@@ -0,0 +1,91 @@
+import { apiClient } from './api';
+import {
+  WebhookEndpoint,
+  WebhookEventType,
+  CreateWebhookRequest,
+  UpdateWebhookRequest,
+  WebhookDelivery,
+  WebhookDeliveryPage,
+  WebhookDeliveryStatus,
+} from '../types/webhook.types';
+
+export const webhookService = {
+  async getWebhooks(): Promise<WebhookEndpoint[]> {
+    const response = await apiClient.get<WebhookEndpoint[]>('/webhooks');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch webhooks');
+  },
+
+  async getEventTypes(): Promise<WebhookEventType[]> {
+    const response = await apiClient.get<WebhookEventType[]>('/webhooks/event-types');
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch webhook event types');
+  },
+
+  async createWebhook(data: CreateWebhookRequest): Promise<WebhookEndpoint> {
+    const response = await apiClient.post<WebhookEndpoint>('/webhooks', data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to create webhook');
+  },
+
+  async updateWebhook(id: number, data: UpdateWebhookRequest): Promise<WebhookEndpoint> {
+    const response = await apiClient.put<WebhookEndpoint>(`/webhooks/${id}`, data);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to update webhook');
+  },
+
+  async deleteWebhook(id: number): Promise<void> {
+    const response = await apiClient.delete<void>(`/webhooks/${id}`);
+    if (!response.success) {
+      throw new Error(response.error || 'Failed to delete webhook');
+    }
+  },
+
+  async rotateSecret(id: number): Promise<WebhookEndpoint> {
+    const response = await apiClient.post<WebhookEndpoint>(`/webhooks/${id}/rotate-secret`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to rotate webhook secret');
+  },
+
+  async testWebhook(id: number): Promise<WebhookDelivery> {
+    const response = await apiClient.post<WebhookDelivery>(`/webhooks/${id}/test`);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to send test event');
+  },
+
+  async getDeliveries(
+    id: number,
+    page = 1,
+    limit = 20,
+    status?: WebhookDeliveryStatus
+  ): Promise<WebhookDeliveryPage> {
+    const params = status ? { page, limit, status } : { page, limit };
+    const response = await apiClient.get<WebhookDeliveryPage>(`/webhooks/${id}/deliveries`, params);
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to fetch webhook deliveries');
+  },
+
+  async redeliver(id: number, deliveryId: number): Promise<WebhookDelivery> {
+    const response = await apiClient.post<WebhookDelivery>(
+      `/webhooks/${id}/deliveries/${deliveryId}/redeliver`
+    );
+    if (response.success && response.data) {
+      return response.data;
+    }
+    throw new Error(response.error || 'Failed to redeliver webhook');
+  },
+};
@@ -1,7 +1,8 @@
 import { ServiceProvider, Client } from './user.types';
 import { Service } from './provider.types';
+import { BookingPaymentStatus, BookingRefundStatus, Payment, Refund, RefundReason } from './payment.types';
 
-export type BookingStatus = 'pending' | 'confirmed' | 'completed' | 'cancelled' | 'rescheduled';
+export type BookingStatus = 'pending' | 'confirmed' | 'completed' | 'cancelled' | 'rescheduled' | 'expired';
 
 export interface Booking {
   id: number;
@@ -13,12 +14,37 @@ export interface Booking {
   end_time: string;
   status: BookingStatus;
   notes?: string;
+  sequence: number;
+  confirmed_at?: string; // When the provider last confirmed it, kept when rescheduled
+  price: number; // Of the service when booked, in minor units of currency
+  currency: string;
+  discount: number; // Off price, in minor units
+  promo_code?: string;
+  reward_id?: number; // Loyalty reward redeemed for the booking
+  reward_points: number; // Spent on the reward
+  reward_discount: number; // Off price less discount, in minor units
+  client_package_id?: number; // Package whose session covered the booking
+  membership_id?: number; // Membership whose allowance covered it or that gave member_discount
+  member_discount: number; // Off price less other discounts, in minor units
+  prepaid: number; // Covered by a package or membership, in minor units
+  tax: number; // On price less discounts, added to it or part of it when tax_inclusive
+  tax_name?: string;
+  tax_rate: number; // In hundredths of a percent
+  tax_inclusive: boolean;
+  gift_card_amount: number; // Of the total paid with gift cards, in minor units
+  payment_status: BookingPaymentStatus;
+  refund_status: BookingRefundStatus;
+  refund_amount: number; // Owed back on cancellation, in minor units
+  refund_reason?: RefundReason;
+  tip_amount: number; // Tipped after completion, in minor units
   created_at: string;
   updated_at: string;
   client?: Client;
   provider?: ServiceProvider;
   service?: Service;
   review?: Review;
+  payments?: Payment[];
+  refunds?: Refund[];
 }
 
 export interface CreateBookingRequest {
@@ -27,6 +53,10 @@ export interface CreateBookingRequest {
   date: string;
   start_time: string;
   notes?: string;
+  promo_code?: string;
+  gift_card_code?: string; // Pays as much of the booking as its balance covers
+  reward_id?: number; // Loyalty reward to redeem points for
+  use_credits?: boolean; // Cover with a package or membership if one includes the service, defaults to true
 }
 
 export interface RescheduleBookingRequest {
@@ -0,0 +1,52 @@
+export interface CalendarFeed {
+  url: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ExternalCalendar {
+  id: number;
+  provider_id: number;
+  name?: string;
+  url: string;
+  is_active: boolean;
+  last_synced_at?: string;
+  last_error?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CreateExternalCalendarRequest {
+  name?: string;
+  url: string;
+}
+
+export interface BusyBlock {
+  id: number;
+  provider_id: number;
+  source: string;
+  source_id: number;
+  uid: string;
+  summary?: string;
+  start_at: string;
+  end_at: string;
+  created_at: string;
+}
+
+export interface CalDAVAccount {
+  id: number;
+  provider_id: number;
+  collection_url: string;
+  username?: string;
+  is_active: boolean;
+  last_synced_at?: string;
+  last_error?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ConnectCalDAVRequest {
+  collection_url: string;
+  username?: string;
+  password?: string;
+}
@@ -0,0 +1,56 @@
+export type GiftCardStatus = 'pending' | 'active' | 'void';
+
+export type GiftCardEntryKind = 'issue' | 'redemption' | 'refund';
+
+export interface GiftCardEntry {
+  id: number;
+  gift_card_id: number;
+  kind: GiftCardEntryKind;
+  amount: number; // Negative for redemptions, in minor units
+  balance: number; // After the entry
+  booking_id?: number;
+  created_at: string;
+}
+
+export interface GiftCard {
+  id: number;
+  provider_id: number;
+  code: string; // XXXX-XXXX-XXXX-XXXX, empty until paid for
+  amount: number; // Face value, in minor units of currency
+  balance: number;
+  currency: string;
+  status: GiftCardStatus;
+  purchaser_id?: number;
+  recipient_name?: string;
+  recipient_email?: string;
+  message?: string;
+  failure_message?: string; // Why the latest payment attempt was declined
+  created_at: string;
+  updated_at: string;
+  entries?: GiftCardEntry[];
+}
+
+export interface IssueGiftCardRequest {
+  amount: number; // In minor units of the provider's currency
+  recipient_name?: string;
+  recipient_email?: string;
+  message?: string;
+}
+
+export interface PurchaseGiftCardRequest extends IssueGiftCardRequest {
+  provider_id: number;
+}
+
+export interface GiftCardPurchase extends GiftCard {
+  client_secret?: string;
+  publishable_key?: string;
+}
+
+export interface GiftCardBalance {
+  code: string; // Masked
+  provider_id: number;
+  provider_name: string;
+  status: GiftCardStatus;
+  balance: number; // In minor units of currency
+  currency: string;
+}
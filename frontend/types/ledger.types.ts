@@ -0,0 +1,68 @@
+export type LedgerKind =
+  | 'charge'
+  | 'tip'
+  | 'commission'
+  | 'fee'
+  | 'refund'
+  | 'chargeback'
+  | 'payout'
+  | 'payout_sent'
+  | 'payout_return';
+
+export interface LedgerBalance {
+  currency: string;
+  owed: number; // Not paid out yet, in minor units; negative if the provider owes the platform
+  in_transit: number; // Paid out and waiting to reach the bank
+}
+
+export interface StatementLine {
+  transaction_id: number;
+  date: string;
+  kind: LedgerKind;
+  booking_id?: number;
+  payout_id?: number;
+  description: string;
+  amount: number; // To the provider, negative if taken from them
+  balance: number; // After the line
+}
+
+export interface Statement {
+  provider_id: number;
+  currency: string;
+  from?: string;
+  to?: string;
+  payout_id?: number;
+  opening: number;
+  lines: StatementLine[];
+  closing: number;
+}
+
+export interface StatementQuery {
+  from?: string; // RFC 3339, defaults to the start of the month
+  to?: string; // RFC 3339, defaults to now
+  currency?: string;
+}
+
+export type PayoutStatus = 'pending' | 'paid' | 'failed';
+
+export interface PayoutBatch {
+  id: number;
+  period_start: string;
+  period_end: string;
+  created_at: string;
+}
+
+export interface Payout {
+  id: number;
+  batch_id: number;
+  provider_id: number;
+  amount: number; // In minor units of currency
+  currency: string;
+  status: PayoutStatus;
+  reference?: string; // The bank transfer's, once paid
+  failure_reason?: string;
+  paid_at?: string;
+  created_at: string;
+  updated_at: string;
+  batch?: PayoutBatch;
+}
@@ -0,0 +1,73 @@
+import { Service } from './provider.types';
+import { ServiceProvider, Client } from './user.types';
+
+export type LoyaltyRewardKind = 'free_service' | 'discount';
+
+export interface LoyaltyReward {
+  id: number;
+  provider_id: number;
+  name: string;
+  points: number; // What it costs
+  kind: LoyaltyRewardKind;
+  service_id?: number; // The service given for free
+  amount: number; // Off the price, in minor units of the provider's currency
+  is_active: boolean;
+  created_at: string;
+  updated_at: string;
+  service?: Service;
+}
+
+export interface LoyaltyProgram {
+  id: number;
+  provider_id: number;
+  is_active: boolean;
+  points_per_booking: number;
+  points_per_unit: number; // For each whole unit of the provider's currency spent
+  created_at: string;
+  updated_at: string;
+  rewards?: LoyaltyReward[];
+}
+
+export interface LoyaltyProgramRequest {
+  is_active?: boolean;
+  points_per_booking: number;
+  points_per_unit: number;
+}
+
+export interface LoyaltyRewardRequest {
+  name: string;
+  points: number;
+  kind: LoyaltyRewardKind;
+  service_id?: number;
+  amount?: number;
+  is_active?: boolean;
+}
+
+export type LoyaltyEntryKind = 'earn' | 'reverse' | 'redeem' | 'restore';
+
+export interface LoyaltyEntry {
+  id: number;
+  account_id: number;
+  kind: LoyaltyEntryKind;
+  points: number; // Negative for redemptions and reversals
+  balance: number; // After the entry
+  booking_id?: number;
+  created_at: string;
+}
+
+export interface LoyaltyAccount {
+  id: number;
+  provider_id: number;
+  client_id: number;
+  balance: number;
+  created_at: string;
+  updated_at: string;
+  provider?: ServiceProvider;
+  client?: Client;
+  entries?: LoyaltyEntry[];
+}
+
+export interface LoyaltyStatus {
+  program: LoyaltyProgram | null; // Null if the provider no longer runs one
+  account: LoyaltyAccount;
+}
@@ -0,0 +1,75 @@
+import { Service } from './provider.types';
+import { Client } from './user.types';
+import { PaymentStatus } from './payment.types';
+
+export interface MembershipPlan {
+  id: number;
+  provider_id: number;
+  name: string;
+  description?: string;
+  price: number; // Each month, in minor units of currency
+  currency: string;
+  allowance: number; // Sessions included each month
+  discount: number; // Percent off bookings beyond the allowance
+  is_active: boolean;
+  created_at: string;
+  updated_at: string;
+  services: Service[]; // None for every service
+}
+
+export interface MembershipPlanRequest {
+  name: string;
+  description?: string;
+  price: number; // Each month, in minor units of the provider's currency
+  allowance?: number;
+  discount?: number;
+  service_ids?: number[];
+  is_active?: boolean; // Defaults to true
+}
+
+export type MembershipStatus = 'pending' | 'active' | 'past_due' | 'cancelled' | 'lapsed';
+
+export interface MembershipPayment {
+  id: number;
+  membership_id: number;
+  period_start: string;
+  period_end: string;
+  amount: number; // The plan's price, in minor units of currency
+  currency: string;
+  tax: number; // On amount, added to it or part of it when tax_inclusive
+  tax_name?: string;
+  tax_rate: number; // In hundredths of a percent
+  tax_inclusive: boolean;
+  status: PaymentStatus;
+  gateway: string;
+  failure_message?: string;
+  succeeded_at?: string;
+  created_at: string;
+  updated_at: string;
+  client_secret?: string; // While it is open
+  publishable_key?: string;
+}
+
+export interface Membership {
+  id: number;
+  plan_id: number;
+  provider_id: number;
+  client_id: number;
+  status: MembershipStatus;
+  period_start?: string;
+  period_end?: string;
+  allowance: number;
+  used: number; // Of the allowance, this month
+  discount: number;
+  cancel_at_period_end: boolean;
+  created_at: string;
+  updated_at: string;
+  plan?: MembershipPlan;
+  client?: Client;
+  payments?: MembershipPayment[];
+}
+
+export interface MembershipCheckout {
+  membership: Membership;
+  payment?: MembershipPayment;
+}
@@ -0,0 +1,40 @@
+import { FileUpload } from './upload.types';
+
+export interface MessageAttachment {
+  id: number;
+  message_id: number;
+  file_name: string;
+  content_type: string;
+  size: number;
+  created_at: string;
+}
+
+export interface BookingMessage {
+  id: number;
+  booking_id: number;
+  sender_id: number;
+  body: string;
+  // Set once the other side has read the message
+  read_at?: string;
+  attachments: MessageAttachment[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface MessagePage {
+  messages: BookingMessage[];
+  unread_count: number;
+  total: number;
+  page: number;
+  limit: number;
+}
+
+export interface SendMessageRequest {
+  body?: string;
+  attachments?: FileUpload[];
+}
+
+export interface MarkMessagesReadResult {
+  updated: number;
+  read_at: string;
+}
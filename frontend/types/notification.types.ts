@@ -0,0 +1,74 @@
+export interface DeviceToken {
+  id: number;
+  user_id: number;
+  token: string;
+  platform?: 'ios' | 'android' | 'web';
+  created_at: string;
+  updated_at: string;
+}
+
+export interface RegisterDeviceRequest {
+  token: string;
+  platform?: 'ios' | 'android' | 'web';
+}
+
+export type NotificationType =
+  | 'booking.requested'
+  | 'booking.confirmed'
+  | 'booking.cancelled'
+  | 'booking.rescheduled'
+  | 'booking.reminder'
+  | 'review.received'
+  | 'message.received';
+
+export interface AppNotification {
+  id: number;
+  user_id: number;
+  type: NotificationType;
+  title: string;
+  body: string;
+  booking_id?: number;
+  read_at?: string;
+  created_at: string;
+}
+
+export interface NotificationPage {
+  notifications: AppNotification[];
+  unread_count: number;
+  total: number;
+  page: number;
+  limit: number;
+}
+
+export interface MarkNotificationsReadRequest {
+  ids?: number[];
+  all?: boolean;
+}
+
+export interface MarkNotificationsReadResult {
+  updated: number;
+  unread_count: number;
+}
+
+export type NotificationChannel = 'email' | 'sms' | 'push' | 'in_app';
+
+export interface NotificationPreferences {
+  channels: Record<NotificationType, NotificationChannel[]>;
+  time_zone: string;
+  quiet_hours_start: string;
+  quiet_hours_end: string;
+  digest: boolean;
+  digest_time: string;
+}
+
+export interface NotificationPreferencesResponse {
+  preferences: NotificationPreferences;
+  event_types: NotificationType[];
+  channels: NotificationChannel[];
+}
+
+export type UpdateNotificationPreferencesRequest = Partial<
+  Omit<NotificationPreferences, 'channels'>
+> & {
+  channels?: Partial<Record<NotificationType, NotificationChannel[]>>;
+};
@@ -0,0 +1,57 @@
+import { Service } from './provider.types';
+import { Client } from './user.types';
+
+export interface Package {
+  id: number;
+  provider_id: number;
+  name: string;
+  description?: string;
+  price: number; // In minor units of currency
+  currency: string;
+  sessions: number;
+  validity_days: number; // From purchase, 0 for no expiry
+  is_active: boolean;
+  created_at: string;
+  updated_at: string;
+  services: Service[];
+}
+
+export interface PackageRequest {
+  name: string;
+  description?: string;
+  price: number; // In minor units of the provider's currency
+  sessions: number;
+  validity_days?: number;
+  service_ids: number[];
+  is_active?: boolean; // Defaults to true
+}
+
+export type ClientPackageStatus = 'pending' | 'active' | 'void';
+
+export interface ClientPackage {
+  id: number;
+  package_id: number;
+  provider_id: number;
+  client_id: number;
+  sessions: number;
+  remaining: number;
+  price: number; // Of the package when bought, in minor units of currency
+  currency: string;
+  tax: number; // On price, added to it or part of it when tax_inclusive
+  tax_name?: string;
+  tax_rate: number; // In hundredths of a percent
+  tax_inclusive: boolean;
+  status: ClientPackageStatus;
+  paid_at?: string;
+  expires_at?: string; // Set once paid
+  failure_message?: string; // Why the latest payment attempt was declined
+  created_at: string;
+  updated_at: string;
+  package?: Package;
+  client?: Client;
+}
+
+export interface PackagePurchase extends ClientPackage {
+  client_secret?: string;
+  publishable_key?: string;
+}
@@ -0,0 +1,146 @@
+export type PaymentPolicy = 'none' | 'deposit' | 'full';
+
+export type PaymentKind = 'deposit' | 'full' | 'tip';
+
+export type BookingPaymentStatus = 'not_required' | 'due' | 'paid' | 'disputed' | 'charged_back';
+
+export type BookingRefundStatus = 'none' | 'pending' | 'refunded' | 'failed';
+
+export type RefundStatus = 'pending' | 'succeeded' | 'failed';
+
+export type RefundReason = 'provider_cancelled' | 'client_cancelled' | 'expired' | 'late_payment';
+
+export type PaymentStatus = 'requires_payment' | 'processing' | 'succeeded' | 'canceled';
+
+export type DisputeStatus = 'open' | 'won' | 'lost';
+
+export interface Payment {
+  id: number;
+  booking_id: number;
+  kind: PaymentKind;
+  gateway: string;
+  intent_id: string;
+  amount: number; // In minor units, e.g. cents
+  currency: string;
+  status: PaymentStatus;
+  failure_message: string;
+  succeeded_at?: string;
+  dispute_status?: DisputeStatus;
+  dispute_reason?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface Refund {
+  id: number;
+  booking_id: number;
+  payment_id: number;
+  gateway: string;
+  refund_id: string;
+  amount: number; // In minor units
+  currency: string;
+  status: RefundStatus;
+  reason: RefundReason;
+  failure_message: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface OpenPayment extends Payment {
+  client_secret?: string; // Only sent to the client
+  publishable_key?: string;
+}
+
+export interface BookingPayment {
+  booking_id: number;
+  payment_status: BookingPaymentStatus;
+  payments: Payment[];
+  open?: OpenPayment;
+  refund_status: BookingRefundStatus;
+  refund_amount: number;
+  refund_reason?: RefundReason;
+  refunds: Refund[];
+  tip_amount: number;
+  tip_until?: string; // Set while the client can tip
+  cancellation_refund?: number; // What cancelling now would refund
+}
+
+export interface TipEarning {
+  booking_id: number;
+  payment_id: number;
+  service_name: string;
+  client_name: string;
+  amount: number;
+  tipped_at: string;
+}
+
+export interface Earnings {
+  from: string;
+  to: string;
+  currency: string;
+  collected: number; // Deposits and prepayments, in minor units
+  refunded: number;
+  tips: number;
+  net: number;
+  tip_list: TipEarning[];
+}
+
+export interface UpdatePaymentPolicyRequest {
+  payment_policy: PaymentPolicy;
+  deposit_percent?: number;
+}
+
+export interface RefundPolicy {
+  cancellation_cutoff: number; // Minutes before start
+  late_cancellation_refund: number; // Percent, after the cutoff
+}
+
+export interface ReceiptParty {
+  name: string;
+  address: string[];
+  email?: string;
+  phone?: string;
+  website?: string;
+}
+
+export interface ReceiptLine {
+  description: string;
+  quantity: number;
+  amount: number;
+}
+
+export interface ReceiptEntry {
+  date: string;
+  description: string;
+  amount: number; // Negative for refunds
+}
+
+export interface Receipt {
+  title: 'Invoice' | 'Receipt';
+  reference: string; // Numbered per provider
+  issued_at: string;
+  booking_id: number;
+  start: string;
+  status: string;
+  from: ReceiptParty;
+  to: ReceiptParty;
+  currency: string;
+  lines: ReceiptLine[];
+  subtotal: number; // In minor units
+  discount: number;
+  promo_code?: string;
+  reward_discount: number;
+  reward?: string; // Loyalty reward that gave reward_discount
+  member_discount: number;
+  prepaid: number;
+  prepaid_by?: string; // Package or membership plan that covered prepaid
+  tax: number;
+  tax_name?: string;
+  tax_rate: number; // In hundredths of a percent
+  tax_included: boolean; // Part of the lines rather than added to them
+  tips: number;
+  total: number;
+  entries: ReceiptEntry[];
+  paid: number; // Net of refunds
+  balance: number;
+}
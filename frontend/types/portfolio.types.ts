@@ -0,0 +1,38 @@
+export interface PortfolioItem {
+  id: number;
+  provider_id: number;
+  upload_id: number;
+  width: number;
+  height: number;
+  caption: string;
+  category_id?: number;
+  service_id?: number;
+  position: number;
+  is_cover: boolean;
+  image_url: string;
+  // Same as image_url when no thumbnail could be made
+  thumbnail_url: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CreatePortfolioItemRequest {
+  upload_id: number;
+  caption?: string;
+  category_id?: number;
+  service_id?: number;
+  is_cover?: boolean;
+}
+
+// Pass 0 as category_id or service_id to remove the tag
+export interface UpdatePortfolioItemRequest {
+  caption?: string;
+  category_id?: number;
+  service_id?: number;
+  is_cover?: boolean;
+}
+
+export interface PortfolioFilter {
+  category_id?: number;
+  service_id?: number;
+}
@@ -0,0 +1,61 @@
+import { Booking } from './booking.types';
+import { Service } from './provider.types';
+
+export type PromoKind = 'percent' | 'fixed';
+
+export interface PromoCode {
+  id: number;
+  provider_id: number;
+  code: string; // Upper case
+  description?: string;
+  kind: PromoKind;
+  value: number; // Percent off, or minor units off in the provider's currency
+  starts_at?: string;
+  ends_at?: string;
+  max_redemptions: number; // 0 for no limit
+  max_per_client: number; // 0 for no limit
+  first_booking_only: boolean;
+  is_active: boolean;
+  redemptions: number; // Bookings using the code, cancelled ones aside
+  services: Service[]; // None for every service
+  created_at: string;
+  updated_at: string;
+}
+
+export interface PromoCodeRequest {
+  code: string;
+  description?: string;
+  kind: PromoKind;
+  value: number;
+  starts_at?: string;
+  ends_at?: string;
+  max_redemptions?: number;
+  max_per_client?: number;
+  first_booking_only?: boolean;
+  is_active?: boolean;
+  service_ids?: number[];
+}
+
+export interface PromoRedemption {
+  id: number;
+  promo_code_id: number;
+  booking_id: number;
+  client_id: number;
+  amount: number; // Discount given, in minor units
+  created_at: string;
+  booking?: Booking;
+}
+
+export interface CheckPromoCodeRequest {
+  provider_id: number;
+  service_id: number;
+  code: string;
+}
+
+export interface PromoQuote {
+  code: string;
+  description?: string;
+  price: number; // In minor units of currency
+  discount: number;
+  currency: string;
+}
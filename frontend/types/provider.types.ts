@@ -1,5 +1,6 @@
 import { Category } from './api.types';
 import { Availability } from './api.types';
+import { PaymentPolicy } from './payment.types';
 
 export interface Service {
   id: number;
@@ -7,9 +8,12 @@ export interface Service {
   category_id: number;
   name: string;
   description?: string;
-  price: number;
+  price: number; // In minor units of currency, e.g. cents
+  currency: string;
   duration: number; // in minutes
   is_active: boolean;
+  payment_policy: PaymentPolicy;
+  deposit_percent: number; // Of price, when payment_policy is deposit
   created_at: string;
   updated_at: string;
   provider?: ServiceProvider;
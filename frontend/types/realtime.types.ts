@@ -0,0 +1,29 @@
+import { BookingStatus } from './booking.types';
+import { BookingPaymentStatus, BookingRefundStatus } from './payment.types';
+
+export type BookingEventType =
+  | 'booking.created'
+  | 'booking.status_changed'
+  | 'booking.rescheduled'
+  | 'booking.payment_updated';
+
+export interface BookingUpdate {
+  booking_id: number;
+  status: BookingStatus;
+  previous_status?: BookingStatus;
+  date: string;
+  start_time: string;
+  end_time: string;
+  sequence: number;
+  payment_status: BookingPaymentStatus;
+  refund_status: BookingRefundStatus;
+  tip_amount: number;
+}
+
+export interface BookingEvent {
+  id: number;
+  type: BookingEventType;
+  booking_id: number;
+  data: BookingUpdate;
+  created_at: string;
+}
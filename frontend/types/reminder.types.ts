@@ -0,0 +1,19 @@
+export type ReminderStatus = 'scheduled' | 'queued' | 'sent' | 'skipped' | 'cancelled' | 'failed';
+
+export interface Reminder {
+  id: number;
+  booking_id: number;
+  offset_minutes: number;
+  starts_at: string;
+  send_at: string;
+  status: ReminderStatus;
+  attempts: number;
+  error?: string;
+  sent_at?: string; // When it was first delivered
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ReminderSettings {
+  offsets_minutes: number[];
+}
@@ -0,0 +1,33 @@
+import { Category } from './api.types';
+
+export interface TaxRule {
+  id: number;
+  country: string;
+  state: string; // Empty for the whole country
+  name: string; // e.g. VAT or Sales tax
+  rate: number; // In hundredths of a percent: 2000 is 20%
+  inclusive: boolean; // Prices already include the tax
+  exempt_categories: Category[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface TaxSummaryLine {
+  name: string;
+  rate: number;
+  inclusive: boolean;
+  bookings: number;
+  sales: number; // Packages and membership months
+  net: number; // In minor units
+  tax: number;
+}
+
+export interface TaxSummary {
+  from: string;
+  to: string;
+  currency: string;
+  rates: TaxSummaryLine[];
+  taxable: number; // Sales taxed, net of tax, in minor units
+  exempt: number;
+  tax: number;
+}
@@ -0,0 +1,27 @@
+export type UploadPurpose =
+  | 'avatar'
+  | 'portfolio'
+  | 'review_photo'
+  | 'reference_image'
+  | 'verification_document';
+
+export interface Upload {
+  id: number;
+  user_id: number;
+  purpose: UploadPurpose;
+  file_name: string;
+  content_type: string;
+  size: number;
+  sha256: string;
+  private: boolean;
+  // Private files get a signed URL that expires; fetch the upload again for a new one
+  url: string;
+  created_at: string;
+}
+
+// A file picked on the device, as React Native's FormData expects it
+export interface FileUpload {
+  uri: string;
+  name: string;
+  type: string;
+}
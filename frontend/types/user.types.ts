@@ -1,3 +1,5 @@
+import { PortfolioItem } from './portfolio.types';
+
 export type UserRole = 'provider' | 'client';
 
 export interface User {
@@ -30,6 +32,10 @@ export interface ServiceProvider {
   longitude?: number;
   phone?: string;
   website?: string;
+  time_zone?: string;
+  currency: string; // ISO 4217, lower case
+  cancellation_cutoff: number; // Minutes before start a client can cancel with a full refund
+  late_cancellation_refund: number; // Percent refunded to clients cancelling after the cutoff
   is_verified: boolean;
   created_at: string;
   updated_at: string;
@@ -39,6 +45,9 @@ export interface ServiceProvider {
   bookings?: Booking[];
   reviews?: Review[];
   categories?: Category[];
+  // The first few gallery items and the cover, in listings and profiles
+  portfolio?: PortfolioItem[];
+  cover_image?: PortfolioItem;
 }
 
 export interface Client {
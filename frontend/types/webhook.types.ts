@@ -0,0 +1,67 @@
+export type WebhookEventType =
+  | 'booking.created'
+  | 'booking.confirmed'
+  | 'booking.cancelled'
+  | 'booking.rescheduled'
+  | 'booking.completed'
+  | 'booking.expired'
+  | 'booking.paid'
+  | 'booking.refunded'
+  | 'booking.tipped'
+  | 'review.created';
+
+export interface WebhookEndpoint {
+  id: number;
+  provider_id: number;
+  url: string;
+  description: string;
+  events: WebhookEventType[];
+  is_active: boolean;
+  consecutive_failures: number;
+  disabled_at?: string;
+  disabled_reason: string;
+  // Only returned when the endpoint is created or its secret rotated
+  secret?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CreateWebhookRequest {
+  url: string;
+  description?: string;
+  events: WebhookEventType[];
+}
+
+export interface UpdateWebhookRequest {
+  url?: string;
+  description?: string;
+  events?: WebhookEventType[];
+  is_active?: boolean;
+}
+
+export type WebhookDeliveryStatus = 'pending' | 'succeeded' | 'failed';
+
+export interface WebhookDelivery {
+  id: number;
+  endpoint_id: number;
+  event_id: string;
+  event_type: WebhookEventType | 'ping';
+  payload: string;
+  status: WebhookDeliveryStatus;
+  attempts: number;
+  response_status: number;
+  response_body: string;
+  error: string;
+  duration_ms: number;
+  last_attempt_at?: string;
+  delivered_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface WebhookDeliveryPage {
+  deliveries: WebhookDelivery[];
+  total: number;
+  page: number;
+  limit: number;
+}
@@ -18,6 +18,7 @@ export const BOOKING_STATUSES = {
   COMPLETED: 'completed',
   CANCELLED: 'cancelled',
   RESCHEDULED: 'rescheduled',
+  EXPIRED: 'expired',
 } as const;
 
 export const USER_ROLES = {
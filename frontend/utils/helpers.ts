@@ -21,6 +21,17 @@ export const formatDateTime = (date: string, time: string): string => {
   return `${formatDate(date)} at ${formatTime(time)}`;
 };
 
+// Formats an amount in minor units, e.g. cents, with the currency's symbol
+// and decimals
+export const formatMoney = (amount: number, currency: string): string => {
+  const format = new Intl.NumberFormat(undefined, {
+    style: 'currency',
+    currency: currency.toUpperCase(),
+  });
+  const decimals = format.resolvedOptions().maximumFractionDigits ?? 2;
+  return format.format(amount / 10 ** decimals);
+};
+
 export const getStatusColor = (status: BookingStatus): string => {
   switch (status) {
     case 'pending':
@@ -33,6 +44,8 @@ export const getStatusColor = (status: BookingStatus): string => {
       return '#F44336';
     case 'rescheduled':
       return '#9C27B0';
+    case 'expired':
+      return '#9E9E9E';
     default:
       return '#757575';
   }